@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// RECENT ACTIVITY
+// ============================================================================
+
+// recentActivityIndex is a ring buffer of the latest ratings and dispute
+// changes system-wide, keyed RECENT_ACTIVITY~slot. The slot is derived from
+// the transaction time rather than a stored sequence number, so writers
+// never read the ring and do not conflict with each other; an entry is
+// simply overwritten when the ring wraps.
+const recentActivityIndex = "RECENT_ACTIVITY"
+
+// recentActivitySlots is the number of entries the ring holds
+const recentActivitySlots = 256
+
+// ActivityEntry is one item of the recent activity feed
+type ActivityEntry struct {
+	Kind      string `json:"kind"` // rating, dispute
+	ID        string `json:"id"`
+	ActorID   string `json:"actorId"`
+	Dimension string `json:"dimension"`
+	Status    string `json:"status,omitempty"` // dispute status after the change
+	Timestamp int64  `json:"timestamp"`       // transaction time, unix milliseconds
+	TxID      string `json:"txId"`
+}
+
+// GetRecentActivity returns the latest ratings and dispute changes held in
+// the ring, newest first. Activity about unlisted actors is shown only to
+// admins and arbitrators.
+func (rc *ReputationContract) GetRecentActivity(
+	ctx contractapi.TransactionContextInterface,
+) ([]ActivityEntry, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(recentActivityIndex, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent activity: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	showUnlisted := canSeeUnlisted(ctx)
+
+	entries := []ActivityEntry{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry ActivityEntry
+		if err := json.Unmarshal(queryResponse.Value, &entry); err != nil {
+			continue
+		}
+		if !showUnlisted {
+			if listed, _ := isListed(ctx, entry.ActorID); !listed {
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp > entries[j].Timestamp
+	})
+
+	return entries, nil
+}
+
+// recordActivity writes an entry into the recent activity ring
+func recordActivity(ctx contractapi.TransactionContextInterface, entry ActivityEntry) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	entry.Timestamp = now.UnixMilli()
+	entry.TxID = ctx.GetStub().GetTxID()
+
+	// Several entries from one transaction take consecutive slots
+	slot := (entry.Timestamp + int64(nextActivitySequence(ctx))) % recentActivitySlots
+
+	key, err := ctx.GetStub().CreateCompositeKey(recentActivityIndex, []string{fmt.Sprintf("%04d", slot)})
+	if err != nil {
+		return fmt.Errorf("failed to create %s key: %v", recentActivityIndex, err)
+	}
+
+	entryJSON, err := marshalCanonical(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, entryJSON); err != nil {
+		return fmt.Errorf("failed to store activity: %v", err)
+	}
+
+	return nil
+}
+
+// ============================================================================
+// ACTOR ACTIVITY
+// ============================================================================
+
+// ActorActivity is the lightweight per-actor activity record, updated as the
+// actor gives or receives ratings and as disputes involving them open and
+// close
+type ActorActivity struct {
+	ActorID              string           `json:"actorId"`
+	LastRatingGivenID    string           `json:"lastRatingGivenId,omitempty"`
+	LastRatingGivenAt    int64            `json:"lastRatingGivenAt"`
+	LastRatingReceivedID string           `json:"lastRatingReceivedId,omitempty"`
+	LastRatingReceivedAt int64            `json:"lastRatingReceivedAt"`
+	OpenDisputes         int              `json:"openDisputes"` // pending disputes as rater or rated actor
+	LastEventAt          map[string]int64 `json:"lastEventAt"`  // by dimension
+}
+
+// ActorActivityView is the GetActorActivity result
+type ActorActivityView struct {
+	Activity           *ActorActivity     `json:"activity"`
+	DaysSinceLastEvent map[string]float64 `json:"daysSinceLastEvent"` // by dimension
+}
+
+// GetActorActivity returns when an actor last gave and received a rating,
+// how many of their disputes are open, and the days since their last event
+// in each dimension
+func (rc *ReputationContract) GetActorActivity(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*ActorActivityView, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	activity, err := getActorActivity(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &ActorActivityView{
+		Activity:           activity,
+		DaysSinceLastEvent: make(map[string]float64),
+	}
+	for dimension, lastEventAt := range activity.LastEventAt {
+		view.DaysSinceLastEvent[dimension] = float64(now-lastEventAt) / 86400
+	}
+
+	return view, nil
+}
+
+// ============================================================================
+// ACTOR ACTIVITY HELPERS
+// ============================================================================
+
+// recordRatingActivity notes a rating on the rater's and the actor's
+// activity records
+func recordRatingActivity(ctx contractapi.TransactionContextInterface, rating *Rating) error {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	rater, err := getActorActivity(ctx, rating.RaterID)
+	if err != nil {
+		return err
+	}
+	rater.LastRatingGivenID = rating.RatingID
+	rater.LastRatingGivenAt = now
+	rater.LastEventAt[rating.Dimension] = now
+	if err := putActorActivity(ctx, rater); err != nil {
+		return err
+	}
+
+	actor, err := getActorActivity(ctx, rating.ActorID)
+	if err != nil {
+		return err
+	}
+	actor.LastRatingReceivedID = rating.RatingID
+	actor.LastRatingReceivedAt = now
+	actor.LastEventAt[rating.Dimension] = now
+	return putActorActivity(ctx, actor)
+}
+
+// recordDisputeActivity adjusts the open dispute counts of a dispute's
+// rater and rated actor when it is filed or leaves pending
+func recordDisputeActivity(ctx contractapi.TransactionContextInterface, dispute *Dispute, previousStatus string) error {
+	delta := 0
+	switch {
+	case previousStatus == "" && dispute.Status == "pending":
+		delta = 1
+	case previousStatus == "pending" && dispute.Status != "pending":
+		delta = -1
+	default:
+		return nil
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, actorID := range []string{dispute.RaterID, dispute.ActorID} {
+		activity, err := getActorActivity(ctx, actorID)
+		if err != nil {
+			return err
+		}
+		activity.OpenDisputes += delta
+		if activity.OpenDisputes < 0 {
+			activity.OpenDisputes = 0
+		}
+		activity.LastEventAt[dispute.Dimension] = now
+		if err := putActorActivity(ctx, activity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getActorActivity loads an actor's activity record, preferring the copy
+// already updated by this transaction
+func getActorActivity(ctx contractapi.TransactionContextInterface, actorID string) (*ActorActivity, error) {
+	if activity := pendingActorActivity(ctx, actorID); activity != nil {
+		return activity, nil
+	}
+
+	activityJSON, err := ctx.GetStub().GetState(activityStateKey(actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read activity: %v", err)
+	}
+
+	activity := &ActorActivity{ActorID: actorID}
+	if activityJSON != nil {
+		if err := json.Unmarshal(activityJSON, activity); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal activity: %v", err)
+		}
+	}
+	if activity.LastEventAt == nil {
+		activity.LastEventAt = make(map[string]int64)
+	}
+
+	return activity, nil
+}
+
+// putActorActivity stores an actor's activity record and remembers it for
+// the rest of the transaction
+func putActorActivity(ctx contractapi.TransactionContextInterface, activity *ActorActivity) error {
+	activityJSON, err := marshalCanonical(activity)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(activityStateKey(activity.ActorID), activityJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store activity: %v", err)
+	}
+
+	setPendingActorActivity(ctx, activity)
+	return nil
+}
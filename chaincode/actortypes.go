@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// ACTOR TYPES
+// ============================================================================
+
+// validActorTypes lists the marketplace roles an actor can register as
+var validActorTypes = map[string]bool{
+	"buyer":     true,
+	"supplier":  true,
+	"auditor":   true,
+	"logistics": true,
+}
+
+// RatingRule restricts who may rate actors of a given type. A rule with
+// Dimension "*" applies to every dimension.
+type RatingRule struct {
+	Dimension  string   `json:"dimension"`
+	ActorType  string   `json:"actorType"`
+	RaterTypes []string `json:"raterTypes"`
+}
+
+// RegisterActorType records the caller's actor type. It can only be set
+// once by the actor; later changes go through SetActorType.
+func (rc *ReputationContract) RegisterActorType(
+	ctx contractapi.TransactionContextInterface,
+	actorType string,
+) error {
+	normalizedID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get actor ID: %v", err)
+	}
+
+	profile, err := getOrInitProfile(ctx, normalizedID)
+	if err != nil {
+		return err
+	}
+	if profile.ActorType != "" {
+		return fmt.Errorf("actor type already registered: %s", profile.ActorType)
+	}
+
+	return setActorType(ctx, profile, actorType, normalizedID)
+}
+
+// SetActorType lets an admin assign or correct an actor's type
+func (rc *ReputationContract) SetActorType(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	actorType string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	adminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+
+	profile, err := getOrInitProfile(ctx, resolveIdentity(ctx, actorID))
+	if err != nil {
+		return err
+	}
+
+	return setActorType(ctx, profile, actorType, adminID)
+}
+
+// SetRatingRule sets which rater types may rate actors of actorType on
+// dimension ("*" for all dimensions). An empty raterTypes removes the rule.
+func (gc *GovernanceContract) SetRatingRule(
+	ctx contractapi.TransactionContextInterface,
+	dimension string,
+	actorType string,
+	raterTypes string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	rule := RatingRule{Dimension: dimension, ActorType: actorType}
+	for _, raterType := range strings.Split(raterTypes, ",") {
+		if raterType = strings.TrimSpace(raterType); raterType != "" {
+			rule.RaterTypes = append(rule.RaterTypes, raterType)
+		}
+	}
+
+	var rules []RatingRule
+	for _, existing := range config.RatingRules {
+		if existing.Dimension != dimension || existing.ActorType != actorType {
+			rules = append(rules, existing)
+		}
+	}
+	if len(rule.RaterTypes) > 0 {
+		rules = append(rules, rule)
+	}
+	config.RatingRules = rules
+
+	if err := validateConfig(config); err != nil {
+		return fmt.Errorf("invalid rating rule: %v", err)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	config.Version++
+	config.LastUpdated = now
+
+	configJSON, err := marshalCanonical(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(configKey, configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update config: %v", err)
+	}
+
+	// Emit event
+	emitEvent(ctx, "RatingRuleUpdated", rule)
+
+	return nil
+}
+
+// ============================================================================
+// ACTOR TYPE HELPERS
+// ============================================================================
+
+// setActorType validates and stores a new actor type on the profile
+func setActorType(
+	ctx contractapi.TransactionContextInterface,
+	profile *ActorProfile,
+	actorType string,
+	changedBy string,
+) error {
+	actorType = strings.ToLower(strings.TrimSpace(actorType))
+	if !validActorTypes[actorType] {
+		return fmt.Errorf("invalid actor type: %s", actorType)
+	}
+	if profile.ActorType == actorType {
+		return nil
+	}
+
+	profile.ActorType = actorType
+	return putProfile(ctx, profile, changedBy, []string{"actorType"}, "ActorTypeRegistered")
+}
+
+// checkRatingRules enforces the configured rater/actor type rules
+func checkRatingRules(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	raterID string,
+	actorID string,
+	dimension string,
+) error {
+	if len(config.RatingRules) == 0 {
+		return nil
+	}
+
+	actorProfile, err := getOrInitProfile(ctx, actorID)
+	if err != nil {
+		return err
+	}
+
+	var allowed []string
+	for _, rule := range config.RatingRules {
+		if rule.ActorType == actorProfile.ActorType && (rule.Dimension == "*" || rule.Dimension == dimension) {
+			allowed = append(allowed, rule.RaterTypes...)
+		}
+	}
+	if allowed == nil {
+		return nil
+	}
+
+	raterProfile, err := getOrInitProfile(ctx, raterID)
+	if err != nil {
+		return err
+	}
+	for _, raterType := range allowed {
+		if raterProfile.ActorType == raterType {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("only %s actors may rate %s actors on %s", strings.Join(allowed, "/"), actorProfile.ActorType, dimension)
+}
+
+// validateRatingRules checks rule types and dimensions against the config
+func validateRatingRules(config *SystemConfig) error {
+	for _, rule := range config.RatingRules {
+		if rule.Dimension != "*" && !config.ValidDimensions[rule.Dimension] {
+			return fmt.Errorf("rating rule has unknown dimension: %s", rule.Dimension)
+		}
+		if !validActorTypes[rule.ActorType] {
+			return fmt.Errorf("rating rule has unknown actor type: %s", rule.ActorType)
+		}
+		for _, raterType := range rule.RaterTypes {
+			if !validActorTypes[raterType] {
+				return fmt.Errorf("rating rule has unknown rater type: %s", raterType)
+			}
+		}
+	}
+	return nil
+}
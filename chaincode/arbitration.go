@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// ARBITRATION WORKLIST
+// ============================================================================
+
+// GetPendingDisputesForArbitrator returns the pending disputes an arbitrator
+// may resolve, oldest first. Disputes the arbitrator is a party to are left
+// out, as ResolveDispute would refuse them; with arbitration panels, so are
+// disputes whose panel the arbitrator is not on or has already voted on.
+func (dc *DisputeContract) GetPendingDisputesForArbitrator(
+	ctx contractapi.TransactionContextInterface,
+	arbitratorID string,
+) ([]Dispute, error) {
+	normalizedArbitratorID := resolveIdentity(ctx, arbitratorID)
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	disputes, err := disputesWithStatus(ctx, "pending", func(dispute *Dispute) bool {
+		return awaitingVerdict(dispute, normalizedArbitratorID, config.ArbitrationPanelSize)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(disputes, func(i, j int) bool {
+		return disputes[i].CreatedAt < disputes[j].CreatedAt
+	})
+
+	return disputes, nil
+}
+
+// arbitratorConflicted reports whether an arbitrator is a party to a dispute
+// and so may not resolve it
+func arbitratorConflicted(dispute *Dispute, arbitratorID string) bool {
+	return arbitratorID == dispute.RaterID ||
+		arbitratorID == dispute.ActorID ||
+		arbitratorID == dispute.InitiatorID
+}
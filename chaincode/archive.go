@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// RATING ARCHIVAL
+// ============================================================================
+
+// Ratings older than SystemConfig.ArchiveAge are rolled into one archive
+// record per actor, dimension and UTC month holding their count, weight
+// sums and the Beta evidence they added, and the individual records and
+// their index entries are deleted. With ArchiveCollection set each record
+// is first copied into that private data collection. Archives take the
+// place of their ratings when a reputation is recomputed, so reputations
+// stay reproducible. Ratings that are confidential, not yet applied after
+// their challenge window, in a pending dispute or part of a running
+// recomputation are kept. An ArchiveAge of 0 turns archival off.
+
+// archivePeriodLayout formats the UTC month an archive covers
+const archivePeriodLayout = "2006-01"
+
+// RatingArchive summarizes an actor's archived ratings in a dimension over
+// one month
+type RatingArchive struct {
+	ActorID          string `json:"actorId"`
+	Dimension        string `json:"dimension"`
+	Period           string `json:"period"`   // UTC month, YYYY-MM
+	Count            int    `json:"count"`    // archived ratings that count towards the reputation
+	Reversed         int    `json:"reversed"` // archived ratings that were overturned
+	WeightSum        Fixed  `json:"weightSumMicros"`
+	WeightedValueSum Fixed  `json:"weightedValueSumMicros"`
+	Alpha            Fixed  `json:"alphaMicros"` // evidence the counted ratings added
+	Beta             Fixed  `json:"betaMicros"`
+	FirstTs          int64  `json:"firstTs"`
+	LastTs           int64  `json:"lastTs"`
+}
+
+// ArchiveScan is the position of the archival pass over the ratings
+type ArchiveScan struct {
+	LastKey   string `json:"lastKey"` // last rating key scanned, "" to start a pass
+	ScannedAt int64  `json:"scannedAt"`
+}
+
+// ArchiveResult reports one batch of ArchiveRatings
+type ArchiveResult struct {
+	Scanned  int  `json:"scanned"`
+	Archived int  `json:"archived"`
+	Done     bool `json:"done"` // the pass reached the last rating; the next call starts over
+}
+
+// ArchiveRatings scans the next pageSize ratings and archives those older
+// than archiveAge. The call is repeated until done is true, and the pass
+// run again as ratings age. With archiveAge 0 nothing is archived.
+func (gc *GovernanceContract) ArchiveRatings(
+	ctx contractapi.TransactionContextInterface,
+	pageSize int,
+) (*ArchiveResult, error) {
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config.ArchiveAge == 0 {
+		return &ArchiveResult{Done: true}, nil
+	}
+	before := now - config.ArchiveAge
+
+	scan, err := getArchiveScan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	startKey := ratingIDPrefix
+	if scan.LastKey != "" {
+		startKey = scan.LastKey + "\x00"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, "RATING;")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ratings: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	result := &ArchiveResult{Done: true}
+	archives := map[string]*RatingArchive{}
+	for resultsIterator.HasNext() {
+		if result.Scanned == pageSize {
+			result.Done = false
+			break
+		}
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		scan.LastKey = queryResponse.Key
+		result.Scanned++
+
+		var rating Rating
+		if err := json.Unmarshal(queryResponse.Value, &rating); err != nil || rating.RatingID == "" {
+			continue
+		}
+		if rating.Timestamp >= before || rating.Confidential || rating.PendingUntil != 0 {
+			continue
+		}
+
+		archived, err := archiveRating(ctx, config, &rating, queryResponse.Value, archives)
+		if err != nil {
+			return nil, err
+		}
+		if archived {
+			result.Archived++
+		}
+	}
+	if result.Done {
+		scan.LastKey = ""
+	}
+
+	// Store archives in key order so every endorser emits the same event
+	keys := make([]string, 0, len(archives))
+	for key := range archives {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := putRatingArchive(ctx, archives[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	scan.ScannedAt = now
+	if err := putArchiveScan(ctx, scan); err != nil {
+		return nil, err
+	}
+
+	if result.Archived > 0 {
+		emitEvent(ctx, "RatingsArchived", map[string]interface{}{
+			"archived":   result.Archived,
+			"before":     before,
+			"collection": config.ArchiveCollection,
+		}, keys...)
+	}
+
+	return result, nil
+}
+
+// GetRatingArchives lists an actor's rating archives in a dimension, oldest
+// period first
+func (rc *ReputationContract) GetRatingArchives(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+) ([]RatingArchive, error) {
+	return ratingArchives(ctx, resolveIdentity(ctx, actorID), dimension)
+}
+
+// ============================================================================
+// ARCHIVAL HELPERS
+// ============================================================================
+
+// archiveRating adds a rating to its period's archive in archives, loading
+// the stored archive on first use, and deletes the rating. ratingJSON is the
+// stored record. It reports false for a rating that must be kept.
+func archiveRating(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	rating *Rating,
+	ratingJSON []byte,
+	archives map[string]*RatingArchive,
+) (bool, error) {
+	pending, overturned, err := ratingDisputeState(ctx, rating.RatingID)
+	if err != nil || pending {
+		return false, err
+	}
+	recomputing, err := ctx.GetStub().GetState(recomputeStateKey(rating.ActorID, rating.Dimension))
+	if err != nil {
+		return false, fmt.Errorf("failed to read recompute cursor: %v", err)
+	}
+	if recomputing != nil {
+		return false, nil
+	}
+
+	period := time.Unix(rating.Timestamp, 0).UTC().Format(archivePeriodLayout)
+	key := ratingArchiveKey(rating.ActorID, rating.Dimension, period)
+	archive := archives[key]
+	if archive == nil {
+		if archive, err = getRatingArchive(ctx, key); err != nil {
+			return false, err
+		}
+		if archive == nil {
+			archive = &RatingArchive{ActorID: rating.ActorID, Dimension: rating.Dimension, Period: period}
+		}
+		archives[key] = archive
+	}
+
+	if overturned {
+		archive.Reversed++
+	} else {
+		weight := fixedFromFloat(rating.Weight)
+		alpha, beta := ratingEvidence(rating.Weight, rating.Value)
+		archive.Count++
+		archive.WeightSum += weight
+		archive.WeightedValueSum += weight.Mul(fixedFromFloat(rating.Value))
+		archive.Alpha += alpha
+		archive.Beta += beta
+	}
+	if archive.FirstTs == 0 || rating.Timestamp < archive.FirstTs {
+		archive.FirstTs = rating.Timestamp
+	}
+	if rating.Timestamp > archive.LastTs {
+		archive.LastTs = rating.Timestamp
+	}
+
+	if config.ArchiveCollection != "" {
+		if err := ctx.GetStub().PutPrivateData(config.ArchiveCollection, rating.RatingID, ratingJSON); err != nil {
+			return false, fmt.Errorf("failed to store archived rating in %s: %v", config.ArchiveCollection, err)
+		}
+	}
+	if err := unindexRating(ctx, rating); err != nil {
+		return false, err
+	}
+	if err := ctx.GetStub().DelState(rating.RatingID); err != nil {
+		return false, fmt.Errorf("failed to delete rating: %v", err)
+	}
+
+	return true, nil
+}
+
+// ratingDisputeState reports whether a rating has a pending dispute and
+// whether any dispute of it was overturned
+func ratingDisputeState(ctx contractapi.TransactionContextInterface, ratingID string) (bool, bool, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(disputeByRatingIndex, []string{ratingID})
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read disputes of %s: %v", ratingID, err)
+	}
+	defer resultsIterator.Close()
+
+	pending, overturned := false, false
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return false, false, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			continue
+		}
+		dispute, err := getDispute(ctx, parts[1])
+		if err != nil {
+			return false, false, err
+		}
+		if dispute == nil {
+			continue
+		}
+		switch dispute.Status {
+		case "pending":
+			pending = true
+		case "overturned":
+			overturned = true
+		}
+	}
+
+	return pending, overturned, nil
+}
+
+// ratingArchives loads an actor's rating archives in a dimension, oldest
+// period first
+func ratingArchives(ctx contractapi.TransactionContextInterface, actorID string, dimension string) ([]RatingArchive, error) {
+	prefix := ratingArchivePrefix(actorID, dimension)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rating archives: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	archives := []RatingArchive{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var archive RatingArchive
+		if err := json.Unmarshal(queryResponse.Value, &archive); err != nil {
+			continue
+		}
+		archives = append(archives, archive)
+	}
+	return archives, nil
+}
+
+// getRatingArchive loads a rating archive, returning nil if none exists
+func getRatingArchive(ctx contractapi.TransactionContextInterface, key string) (*RatingArchive, error) {
+	archiveJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rating archive: %v", err)
+	}
+	if archiveJSON == nil {
+		return nil, nil
+	}
+
+	var archive RatingArchive
+	if err := json.Unmarshal(archiveJSON, &archive); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rating archive: %v", err)
+	}
+	return &archive, nil
+}
+
+// putRatingArchive stores a rating archive
+func putRatingArchive(ctx contractapi.TransactionContextInterface, archive *RatingArchive) error {
+	archiveJSON, err := marshalCanonical(archive)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rating archive: %v", err)
+	}
+	key := ratingArchiveKey(archive.ActorID, archive.Dimension, archive.Period)
+	if err := ctx.GetStub().PutState(key, archiveJSON); err != nil {
+		return fmt.Errorf("failed to store rating archive: %v", err)
+	}
+	return nil
+}
+
+// getArchiveScan loads the archival scan position
+func getArchiveScan(ctx contractapi.TransactionContextInterface) (*ArchiveScan, error) {
+	scanJSON, err := ctx.GetStub().GetState(archiveScanKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive scan: %v", err)
+	}
+
+	var scan ArchiveScan
+	if scanJSON != nil {
+		if err := json.Unmarshal(scanJSON, &scan); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal archive scan: %v", err)
+		}
+	}
+	return &scan, nil
+}
+
+// putArchiveScan stores the archival scan position
+func putArchiveScan(ctx contractapi.TransactionContextInterface, scan *ArchiveScan) error {
+	scanJSON, err := marshalCanonical(scan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal archive scan: %v", err)
+	}
+	if err := ctx.GetStub().PutState(archiveScanKey, scanJSON); err != nil {
+		return fmt.Errorf("failed to store archive scan: %v", err)
+	}
+	return nil
+}
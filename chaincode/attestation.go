@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// REPUTATION ATTESTATIONS
+// ============================================================================
+
+// attestationType tags attestation payloads so a verifier can tell them
+// apart from other signed data
+const attestationType = "am-reputation/attestation/v1"
+
+// reputationAttestation is the attested statement. Fields are declared in
+// lexicographic order of their JSON names and the score is a fixed-point
+// decimal string, so json.Marshal yields the same bytes on every peer.
+type reputationAttestation struct {
+	ActorID       string `json:"actorId"`
+	AsOf          int64  `json:"asOf"`
+	ConfigVersion int    `json:"configVersion"`
+	Dimension     string `json:"dimension"`
+	Score         string `json:"score"`
+	Type          string `json:"type"`
+}
+
+// GetReputationAttestation returns a canonical JSON statement of an actor's
+// decayed score in a dimension as of the transaction timestamp. Endorsing
+// peers compute identical bytes, so the endorsed proposal response serves as
+// a reputation proof that can be checked off-network against the peers'
+// certificates.
+func (rc *ReputationContract) GetReputationAttestation(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+) (string, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return "", err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+	rep, err := getOrInitReputation(ctx, normalizedActorID, dimension, config)
+	if err != nil {
+		return "", err
+	}
+
+	effectiveRep := applyDynamicDecayAt(rep, config, now)
+	score := scoreFixed(effectiveRep)
+
+	attestation := reputationAttestation{
+		ActorID:       normalizedActorID,
+		AsOf:          now,
+		ConfigVersion: config.Version,
+		Dimension:     dimension,
+		Score:         score.String(),
+		Type:          attestationType,
+	}
+
+	attestationJSON, err := json.Marshal(attestation)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal attestation: %v", err)
+	}
+
+	return string(attestationJSON), nil
+}
+
+// thresholdAttestationType tags threshold attestation payloads
+const thresholdAttestationType = "am-reputation/threshold/v1"
+
+// thresholdAttestation is the attested yes/no statement. It carries the bar
+// that was checked but not the score, and is canonical like
+// reputationAttestation.
+type thresholdAttestation struct {
+	ActorID       string `json:"actorId"`
+	AsOf          int64  `json:"asOf"`
+	ConfigVersion int    `json:"configVersion"`
+	Dimension     string `json:"dimension"`
+	MinEvents     int    `json:"minEvents"`
+	MinScore      string `json:"minScore"`
+	Pass          bool   `json:"pass"`
+	Type          string `json:"type"`
+}
+
+// CheckReputationThreshold returns a canonical JSON statement of whether an
+// actor's decayed score in a dimension is at least minScore over at least
+// minEvents events, as of the transaction timestamp. It is meant for
+// low-trust consumers such as access-control gateways, which learn only
+// whether the actor clears the bar. Suspended actors never pass.
+func (rc *ReputationContract) CheckReputationThreshold(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+	minScoreStr string,
+	minEventsStr string,
+) (string, error) {
+	minScore, err := strconv.ParseFloat(minScoreStr, 64)
+	if err != nil || minScore < 0 || minScore > 1 {
+		return "", fmt.Errorf("invalid minScore: must be between 0 and 1")
+	}
+	minEvents, err := strconv.Atoi(minEventsStr)
+	if err != nil || minEvents < 0 {
+		return "", fmt.Errorf("invalid minEvents: must be a non-negative integer")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return "", err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+	rep, err := getOrInitReputation(ctx, normalizedActorID, dimension, config)
+	if err != nil {
+		return "", err
+	}
+
+	suspended, err := isBanned(ctx, normalizedActorID)
+	if err != nil {
+		return "", err
+	}
+
+	effectiveRep := applyDynamicDecayAt(rep, config, now)
+	score := scoreFixed(effectiveRep)
+	threshold := fixedFromFloat(minScore)
+
+	attestation := thresholdAttestation{
+		ActorID:       normalizedActorID,
+		AsOf:          now,
+		ConfigVersion: config.Version,
+		Dimension:     dimension,
+		MinEvents:     minEvents,
+		MinScore:      threshold.String(),
+		Pass:          !suspended && score >= threshold && rep.TotalEvents >= minEvents,
+		Type:          thresholdAttestationType,
+	}
+
+	attestationJSON, err := json.Marshal(attestation)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal attestation: %v", err)
+	}
+
+	return string(attestationJSON), nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// BAN DATA STRUCTURES
+// ============================================================================
+
+// Ban records the ban state of an actor. Both banning and lifting a ban
+// require approvals from distinct admins.
+type Ban struct {
+	ActorID        string          `json:"actorId"`
+	Reason         string          `json:"reason"`
+	Status         string          `json:"status"` // pending, active, lifted
+	ProposedBy     string          `json:"proposedBy,omitempty"`
+	Approvals      map[string]bool `json:"approvals"`
+	UnbanApprovals map[string]bool `json:"unbanApprovals"`
+	ProposedAt     int64           `json:"proposedAt"`
+	BannedAt       int64           `json:"bannedAt"`
+	LiftedAt       int64           `json:"liftedAt"`
+}
+
+// ============================================================================
+// BAN FUNCTIONS
+// ============================================================================
+
+// BanActor records an admin's approval to ban an actor. The ban takes effect
+// once the configured number of distinct admins have approved it.
+func (gc *GovernanceContract) BanActor(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	reason string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	normalizedAdminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	ban, err := getBan(ctx, normalizedActorID)
+	if err != nil {
+		return err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case ban == nil || ban.Status == "lifted":
+		if reason == "" {
+			return fmt.Errorf("reason is required")
+		}
+		ban = &Ban{
+			ActorID:        normalizedActorID,
+			Reason:         reason,
+			Status:         "pending",
+			ProposedBy:     normalizedAdminID,
+			Approvals:      make(map[string]bool),
+			UnbanApprovals: make(map[string]bool),
+			ProposedAt:     now,
+		}
+	case ban.Status == "active":
+		return fmt.Errorf("actor already banned: %s", normalizedActorID)
+	}
+
+	if ban.Approvals[normalizedAdminID] {
+		return fmt.Errorf("ban already approved by %s", normalizedAdminID)
+	}
+	ban.Approvals[normalizedAdminID] = true
+
+	correlateEvents(ctx, banStateKey(normalizedActorID))
+
+	if len(ban.Approvals) >= config.BanApprovals {
+		ban.Status = "active"
+		ban.BannedAt = now
+		ban.UnbanApprovals = make(map[string]bool)
+
+		// Whoever vouched for the actor shares the blame
+		if err := penalizeVouchers(ctx, normalizedActorID, "ban"); err != nil {
+			return err
+		}
+
+		// Emit event
+		eventPayload := map[string]interface{}{
+			"actorId":   normalizedActorID,
+			"reason":    ban.Reason,
+			"approvals": len(ban.Approvals),
+		}
+		emitEvent(ctx, "ActorBanned", eventPayload)
+	} else {
+		// Emit event
+		eventPayload := map[string]interface{}{
+			"actorId":    normalizedActorID,
+			"approverId": normalizedAdminID,
+			"approvals":  len(ban.Approvals),
+		}
+		emitEvent(ctx, "ActorBanApproved", eventPayload)
+	}
+
+	return putBan(ctx, ban)
+}
+
+// UnbanActor records an admin's approval to lift an active ban. The ban is
+// lifted once the configured number of distinct admins have approved. A ban
+// still pending is withdrawn by its proposer alone, or by the same number of
+// approvals.
+func (gc *GovernanceContract) UnbanActor(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	normalizedAdminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	ban, err := getBan(ctx, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	if ban == nil || ban.Status == "lifted" {
+		return fmt.Errorf("actor is not banned: %s", normalizedActorID)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	correlateEvents(ctx, banStateKey(normalizedActorID))
+
+	withdrawnByProposer := ban.Status == "pending" && normalizedAdminID == ban.ProposedBy
+	if !withdrawnByProposer {
+		if ban.UnbanApprovals[normalizedAdminID] {
+			return fmt.Errorf("unban already approved by %s", normalizedAdminID)
+		}
+		ban.UnbanApprovals[normalizedAdminID] = true
+	}
+
+	switch {
+	case ban.Status == "pending" && (withdrawnByProposer || len(ban.UnbanApprovals) >= config.BanApprovals):
+		// A ban that never took effect is withdrawn
+		ban.Status = "lifted"
+		ban.LiftedAt = now
+		ban.UnbanApprovals = make(map[string]bool)
+
+		// Emit event
+		eventPayload := map[string]interface{}{
+			"actorId":     normalizedActorID,
+			"withdrawnBy": normalizedAdminID,
+		}
+		emitEvent(ctx, "BanWithdrawn", eventPayload)
+	case len(ban.UnbanApprovals) >= config.BanApprovals:
+		ban.Status = "lifted"
+		ban.LiftedAt = now
+		ban.UnbanApprovals = make(map[string]bool)
+
+		// Emit event
+		eventPayload := map[string]interface{}{
+			"actorId": normalizedActorID,
+		}
+		emitEvent(ctx, "ActorUnbanned", eventPayload)
+	default:
+		// Emit event
+		eventPayload := map[string]interface{}{
+			"actorId":    normalizedActorID,
+			"approverId": normalizedAdminID,
+			"approvals":  len(ban.UnbanApprovals),
+		}
+		emitEvent(ctx, "ActorUnbanApproved", eventPayload)
+	}
+
+	return putBan(ctx, ban)
+}
+
+// GetBan retrieves an actor's ban record
+func (gc *GovernanceContract) GetBan(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*Ban, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	ban, err := getBan(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+	if ban == nil {
+		return nil, fmt.Errorf("no ban record for %s", normalizedActorID)
+	}
+
+	return ban, nil
+}
+
+// ============================================================================
+// BAN HELPERS
+// ============================================================================
+
+// isBanned reports whether an actor (normalized ID) is under an active ban
+func isBanned(ctx contractapi.TransactionContextInterface, actorID string) (bool, error) {
+	ban, err := getBan(ctx, actorID)
+	if err != nil {
+		return false, err
+	}
+	return ban != nil && ban.Status == "active", nil
+}
+
+// getBan loads an actor's ban record, returning nil if none exists
+func getBan(ctx contractapi.TransactionContextInterface, actorID string) (*Ban, error) {
+	banKey := banStateKey(actorID)
+	banJSON, err := readState(ctx, banKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ban: %v", err)
+	}
+	if banJSON == nil {
+		return nil, nil
+	}
+
+	var ban Ban
+	if err := json.Unmarshal(banJSON, &ban); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ban: %v", err)
+	}
+	if ban.Approvals == nil {
+		ban.Approvals = make(map[string]bool)
+	}
+	if ban.UnbanApprovals == nil {
+		ban.UnbanApprovals = make(map[string]bool)
+	}
+
+	return &ban, nil
+}
+
+// putBan stores an actor's ban record
+func putBan(ctx contractapi.TransactionContextInterface, ban *Ban) error {
+	banJSON, err := marshalCanonical(ban)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ban: %v", err)
+	}
+
+	banKey := banStateKey(ban.ActorID)
+	err = ctx.GetStub().PutState(banKey, banJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store ban: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// PERFORMANCE BONDS
+// ============================================================================
+
+// A supplier backs a high-value order with a bond: part of its stake locked
+// until the buyer rates it in the bond's dimension. A positive rating (value
+// of at least 0.5) releases the bond. A negative one claims it and opens a
+// dispute of the rating on the supplier's behalf, at no dispute cost since
+// the bond is at stake: if the rating is overturned the bond is released,
+// and if it is upheld, or the supplier withdraws the dispute by offboarding,
+// the bond is forfeited to the buyer's stake.
+
+// Bond statuses
+const (
+	bondPosted    = "posted"
+	bondClaimed   = "claimed" // a negative rating is in dispute
+	bondReleased  = "released"
+	bondForfeited = "forfeited"
+)
+
+// bondReleaseValue is the lowest rating value that releases a bond; a lower
+// one claims it
+const bondReleaseValue = 0.5
+
+// Bond is a supplier's performance bond for one order
+type Bond struct {
+	BondID     string  `json:"bondId"`
+	SupplierID string  `json:"supplierId"`
+	BuyerID    string  `json:"buyerId"`
+	OrderID    string  `json:"orderId"`
+	Dimension  string  `json:"dimension"`
+	Amount     float64 `json:"amount"`
+	Status     string  `json:"status"`              // posted, claimed, released, forfeited
+	RatingID   string  `json:"ratingId,omitempty"`  // the buyer's rating that decided it
+	DisputeID  string  `json:"disputeId,omitempty"` // the dispute of a claim
+	CreatedAt  int64   `json:"createdAt"`
+	SettledAt  int64   `json:"settledAt"`
+}
+
+// bondByPairIndex files posted bonds by the parties, oldest first:
+// supplierId~buyerId~dimension~createdAt~bondId
+const bondByPairIndex = "BOND_BY_PAIR"
+
+// PostBond locks amount of the caller's stake as a bond for an order placed
+// by buyerID, decided by the buyer's next rating of the caller in dimension.
+// An order can be bonded once.
+func (sc *StakeContract) PostBond(
+	ctx contractapi.TransactionContextInterface,
+	orderID string,
+	buyerID string,
+	dimension string,
+	amountStr string,
+) (string, error) {
+	amount, err := strconv.ParseFloat(amountStr, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid amount: %v", err)
+	}
+
+	supplierID, err := callerIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get supplier ID: %v", err)
+	}
+	normalizedBuyerID := resolveIdentity(ctx, buyerID)
+	if normalizedBuyerID == supplierID {
+		return "", fmt.Errorf("cannot post a bond to yourself")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !config.ValidDimensions[dimension] {
+		return "", fmt.Errorf("invalid dimension: %s", dimension)
+	}
+
+	for _, actorID := range []string{supplierID, normalizedBuyerID} {
+		banned, err := isBanned(ctx, actorID)
+		if err != nil {
+			return "", err
+		}
+		if banned {
+			return "", fmt.Errorf("actor is banned: %s", actorID)
+		}
+	}
+	deactivated, err := isDeactivated(ctx, supplierID)
+	if err != nil {
+		return "", err
+	}
+	if deactivated {
+		return "", fmt.Errorf("supplier is offboarding: %s", supplierID)
+	}
+
+	bondID := generateBondID(supplierID, orderID)
+	existing, err := getBond(ctx, bondID)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", fmt.Errorf("order %s is already bonded: %s", orderID, bondID)
+	}
+
+	stake, err := getOrInitStake(ctx, supplierID)
+	if err != nil {
+		return "", err
+	}
+	if stake.Balance < amount {
+		return "", fmt.Errorf("insufficient stake for bond: have %f, require %f", stake.Balance, amount)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	stake.Balance -= amount
+	stake.Locked += amount
+	stake.UpdatedAt = now
+	if err := putStake(ctx, stake); err != nil {
+		return "", err
+	}
+
+	bond := &Bond{
+		BondID:     bondID,
+		SupplierID: supplierID,
+		BuyerID:    normalizedBuyerID,
+		OrderID:    orderID,
+		Dimension:  dimension,
+		Amount:     amount,
+		Status:     bondPosted,
+		CreatedAt:  now,
+	}
+	if err := putBond(ctx, bond); err != nil {
+		return "", err
+	}
+	if err := putIndexEntry(ctx, bondByPairIndex, bondPairAttributes(bond)); err != nil {
+		return "", err
+	}
+
+	emitStakeMovement(ctx, stakeLockedEvent, stake, amount, "")
+	emitEvent(ctx, "BondPosted", bond, bondID, stakeStateKey(supplierID))
+
+	return bondID, nil
+}
+
+// GetBond retrieves a performance bond
+func (sc *StakeContract) GetBond(
+	ctx contractapi.TransactionContextInterface,
+	bondID string,
+) (*Bond, error) {
+	bond, err := getBond(ctx, bondID)
+	if err != nil {
+		return nil, err
+	}
+	if bond == nil {
+		return nil, fmt.Errorf("bond not found: %s", bondID)
+	}
+	return bond, nil
+}
+
+// ============================================================================
+// BOND HELPERS
+// ============================================================================
+
+// settleBondForRating lets a rating decide the oldest bond its actor posted
+// for its rater in its dimension, if any: a positive value releases the
+// bond and a negative one claims it
+func settleBondForRating(
+	ctx contractapi.TransactionContextInterface,
+	rating *Rating,
+	value float64,
+) error {
+	bond, err := oldestPostedBond(ctx, rating.ActorID, rating.RaterID, rating.Dimension)
+	if err != nil || bond == nil {
+		return err
+	}
+
+	if err := delIndexEntry(ctx, bondByPairIndex, bondPairAttributes(bond)); err != nil {
+		return err
+	}
+	bond.RatingID = rating.RatingID
+
+	if value >= bondReleaseValue {
+		stake, err := getOrInitStake(ctx, bond.SupplierID)
+		if err != nil {
+			return err
+		}
+		if err := settleBond(ctx, bond, stake, false); err != nil {
+			return err
+		}
+		return putStake(ctx, stake)
+	}
+
+	dispute, err := openDispute(ctx, rating, bond.SupplierID, "negative rating claims bond for order "+bond.OrderID, bond.BondID)
+	if err != nil {
+		return err
+	}
+	bond.Status = bondClaimed
+	bond.DisputeID = dispute.DisputeID
+	if err := putBond(ctx, bond); err != nil {
+		return err
+	}
+
+	emitEvent(ctx, "BondClaimed", bond, bond.BondID)
+	emitDisputeInitiated(ctx, dispute)
+	return nil
+}
+
+// settleClaimedBond settles the bond a dispute claims, forfeiting it if the
+// rating stands. supplierStake is the bond supplier's stake record, updated
+// in memory for the caller to store.
+func settleClaimedBond(
+	ctx contractapi.TransactionContextInterface,
+	dispute *Dispute,
+	supplierStake *Stake,
+	forfeit bool,
+) error {
+	bond, err := getBond(ctx, dispute.BondID)
+	if err != nil {
+		return err
+	}
+	if bond == nil || bond.Status != bondClaimed {
+		return fmt.Errorf("no claimed bond %s for dispute %s", dispute.BondID, dispute.DisputeID)
+	}
+	return settleBond(ctx, bond, supplierStake, forfeit)
+}
+
+// settleBond unlocks a bond, returning it to the supplier's balance or
+// paying it into the buyer's. supplierStake is updated in memory for the
+// caller to store.
+func settleBond(
+	ctx contractapi.TransactionContextInterface,
+	bond *Bond,
+	supplierStake *Stake,
+	forfeit bool,
+) error {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	supplierStake.Locked -= bond.Amount
+	supplierStake.UpdatedAt = now
+	eventType := "BondReleased"
+	if forfeit {
+		buyerStake, err := getOrInitStake(ctx, bond.BuyerID)
+		if err != nil {
+			return err
+		}
+		buyerStake.Balance += bond.Amount
+		buyerStake.UpdatedAt = now
+		if err := putStake(ctx, buyerStake); err != nil {
+			return err
+		}
+		bond.Status = bondForfeited
+		eventType = "BondForfeited"
+	} else {
+		supplierStake.Balance += bond.Amount
+		bond.Status = bondReleased
+	}
+	bond.SettledAt = now
+
+	if err := putBond(ctx, bond); err != nil {
+		return err
+	}
+
+	emitEvent(ctx, eventType, bond, bond.BondID, stakeStateKey(bond.SupplierID))
+	return nil
+}
+
+// oldestPostedBond returns the supplier's oldest posted bond for the buyer
+// in a dimension, or nil
+func oldestPostedBond(
+	ctx contractapi.TransactionContextInterface,
+	supplierID string,
+	buyerID string,
+	dimension string,
+) (*Bond, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(bondByPairIndex, []string{supplierID, buyerID, dimension})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", bondByPairIndex, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) == 0 {
+			continue
+		}
+		bond, err := getBond(ctx, parts[len(parts)-1])
+		if err != nil {
+			return nil, err
+		}
+		if bond != nil && bond.Status == bondPosted {
+			return bond, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// bondPairAttributes returns a bond's bondByPairIndex key attributes
+func bondPairAttributes(bond *Bond) []string {
+	return []string{bond.SupplierID, bond.BuyerID, bond.Dimension, fmt.Sprintf("%020d", bond.CreatedAt), bond.BondID}
+}
+
+// generateBondID derives a bond's ID from its supplier and order, so an
+// order can be bonded once
+func generateBondID(supplierID string, orderID string) string {
+	hash := sha256.Sum256([]byte(supplierID + ":" + orderID))
+	return fmt.Sprintf("%s%x", bondIDPrefix, hash[:16])
+}
+
+// getBond loads a bond, returning nil if it does not exist
+func getBond(ctx contractapi.TransactionContextInterface, bondID string) (*Bond, error) {
+	if err := validateRecordID(bondID, bondIDPrefix); err != nil {
+		return nil, err
+	}
+
+	bondJSON, err := ctx.GetStub().GetState(bondID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bond: %v", err)
+	}
+	if bondJSON == nil {
+		return nil, nil
+	}
+
+	var bond Bond
+	if err := json.Unmarshal(bondJSON, &bond); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bond: %v", err)
+	}
+	return &bond, nil
+}
+
+// putBond stores a bond
+func putBond(ctx contractapi.TransactionContextInterface, bond *Bond) error {
+	bondJSON, err := marshalCanonical(bond)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bond: %v", err)
+	}
+	if err := ctx.GetStub().PutState(bond.BondID, bondJSON); err != nil {
+		return fmt.Errorf("failed to store bond: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ============================================================================
+// CANONICAL JSON
+// ============================================================================
+
+// marshalCanonical serializes v as canonical JSON: object keys sorted
+// bytewise, no insignificant whitespace, no HTML escaping and numbers in a
+// single fixed form. Everything written to the ledger, to private data or to
+// events goes through it, so that endorsing peers produce byte-identical
+// write sets regardless of map iteration order or float formatting.
+func marshalCanonical(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCanonical appends the canonical encoding of a decoded JSON value
+func writeCanonical(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		number, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	case string:
+		writeCanonicalString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, k)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON value of type %T", value)
+	}
+	return nil
+}
+
+// canonicalNumber keeps integers as written and reformats everything else
+// as the shortest round-tripping float64, with negative zero as 0
+func canonicalNumber(n json.Number) (string, error) {
+	s := n.String()
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return s, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", fmt.Errorf("invalid JSON number %q", s)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	formatted, err := json.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+	return string(formatted), nil
+}
+
+// writeCanonicalString appends a JSON string without HTML escaping
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	buf.Truncate(buf.Len() - 1) // Encode terminates with a newline
+}
+
+// storedJSONHasKey reports whether a stored JSON object sets key, telling a
+// zero value stored on purpose from a field added after the record was
+// written
+func storedJSONHasKey(recordJSON []byte, key string) bool {
+	var stored map[string]json.RawMessage
+	if err := json.Unmarshal(recordJSON, &stored); err != nil {
+		return true
+	}
+	_, exists := stored[key]
+	return exists
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// CHALLENGE WINDOW
+// ============================================================================
+
+// With SystemConfig.ChallengeWindow set, a submitted rating is stored with
+// its pendingUntil set to the end of the window, and its effects - the
+// actor's reputation, the bond it decides and the actor's probation - wait
+// until ApplyPendingRatings applies it after the window. A rating whose
+// dispute is pending is held until the dispute closes, and one a dispute
+// overturns is never applied, so there is nothing to reverse. Confidential
+// ratings apply at once: their value is only held by the parties' peers,
+// which need not endorse ApplyPendingRatings.
+
+// ApplyPendingRatingsResult reports one batch of ApplyPendingRatings
+type ApplyPendingRatingsResult struct {
+	Applied []string `json:"applied"` // IDs of the ratings applied
+	Held    []string `json:"held"`    // IDs of due ratings left queued for a pending dispute or a rating of the same actor and dimension applied in this batch
+	Done    bool     `json:"done"`    // no due rating is left but the held ones
+}
+
+// ApplyPendingRatings applies up to pageSize ratings whose challenge window
+// has passed, oldest window first. Anyone may call it; the call is repeated
+// until done is true.
+func (rtc *RatingContract) ApplyPendingRatings(
+	ctx contractapi.TransactionContextInterface,
+	pageSize int,
+) (*ApplyPendingRatingsResult, error) {
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange(pendingRatingPrefix, pendingRatingKey(now+1, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending ratings: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	result := &ApplyPendingRatingsResult{Applied: []string{}, Held: []string{}, Done: true}
+	applied := map[string]bool{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		ratingID := string(queryResponse.Value)
+
+		rating, err := getRating(ctx, ratingID)
+		if err != nil {
+			return nil, err
+		}
+		if rating == nil || rating.PendingUntil == 0 {
+			// Archived, purged or already settled by a dispute
+			if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+				return nil, fmt.Errorf("failed to clear pending rating: %v", err)
+			}
+			continue
+		}
+
+		// Reputation and bond reads see committed state only, so each
+		// actor and dimension takes one rating per batch
+		pair := rating.ActorID + "~" + rating.Dimension
+		disputed, _, err := ratingDisputeState(ctx, rating.RatingID)
+		if err != nil {
+			return nil, err
+		}
+		if disputed {
+			result.Held = append(result.Held, rating.RatingID)
+			continue
+		}
+		if applied[pair] {
+			result.Held = append(result.Held, rating.RatingID)
+			result.Done = false
+			continue
+		}
+
+		if len(result.Applied) == pageSize {
+			result.Done = false
+			break
+		}
+		if err := applyPendingRating(ctx, config, rating, queryResponse.Key); err != nil {
+			return nil, err
+		}
+		applied[pair] = true
+		result.Applied = append(result.Applied, rating.RatingID)
+	}
+
+	return result, nil
+}
+
+// ============================================================================
+// CHALLENGE WINDOW HELPERS
+// ============================================================================
+
+// queuePendingRating holds back a new rating's effects until the challenge
+// window ends, reporting false if the rating applies at once
+func queuePendingRating(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	rating *Rating,
+) (bool, error) {
+	if config.ChallengeWindow == 0 || rating.Confidential {
+		return false, nil
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	rating.PendingUntil = now + config.ChallengeWindow
+
+	key := pendingRatingKey(rating.PendingUntil, rating.RatingID)
+	if err := ctx.GetStub().PutState(key, []byte(rating.RatingID)); err != nil {
+		return false, fmt.Errorf("failed to queue pending rating: %v", err)
+	}
+	return true, nil
+}
+
+// applyRatingEffects updates what a rating decides: the actor's reputation,
+// the oldest bond the actor posted for the rater and the actor's probation
+func applyRatingEffects(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	rating *Rating,
+	value float64,
+) error {
+	if err := updateReputation(ctx, rating, value); err != nil {
+		return fmt.Errorf("failed to update reputation: %v", err)
+	}
+
+	// The rating decides the oldest bond the actor posted for the rater
+	if err := settleBondForRating(ctx, rating, value); err != nil {
+		return err
+	}
+
+	// Clean ratings count towards the actor's probation
+	return recordProbationEvent(ctx, config, rating, value)
+}
+
+// applyPendingRating applies a rating whose challenge window has passed and
+// removes it from the queue under key
+func applyPendingRating(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	rating *Rating,
+	key string,
+) error {
+	correlateEvents(ctx, rating.RatingID)
+
+	pendingUntil := rating.PendingUntil
+	rating.PendingUntil = 0
+	if err := putRating(ctx, rating); err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("failed to clear pending rating: %v", err)
+	}
+
+	if err := applyRatingEffects(ctx, config, rating, rating.Value); err != nil {
+		return err
+	}
+
+	emitEvent(ctx, "RatingApplied", map[string]interface{}{
+		"ratingId":     rating.RatingID,
+		"actorId":      rating.ActorID,
+		"dimension":    rating.Dimension,
+		"pendingUntil": pendingUntil,
+	})
+	return nil
+}
+
+// withholdPendingRating drops a rating a dispute overturned from the queue
+// before it was applied, reporting false if the rating was already applied
+// and needs reversing instead
+func withholdPendingRating(ctx contractapi.TransactionContextInterface, ratingID string) (bool, error) {
+	rating, err := getRating(ctx, ratingID)
+	if err != nil {
+		return false, err
+	}
+	if rating == nil || rating.PendingUntil == 0 {
+		return false, nil
+	}
+
+	if err := ctx.GetStub().DelState(pendingRatingKey(rating.PendingUntil, rating.RatingID)); err != nil {
+		return false, fmt.Errorf("failed to clear pending rating: %v", err)
+	}
+	rating.PendingUntil = 0
+	if err := putRating(ctx, rating); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// putRating stores an updated rating record
+func putRating(ctx contractapi.TransactionContextInterface, rating *Rating) error {
+	ratingJSON, err := marshalCanonical(rating)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rating: %v", err)
+	}
+	if err := ctx.GetStub().PutState(rating.RatingID, ratingJSON); err != nil {
+		return fmt.Errorf("failed to store rating: %v", err)
+	}
+	return nil
+}
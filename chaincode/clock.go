@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// TRANSACTION CLOCK
+// ============================================================================
+
+// Every timestamp the chaincode stores or compares against comes from the
+// transaction proposal, never from the peer's wall clock, so all endorsers
+// compute the same write set. Off-chain tools under cmd/ may use time.Now.
+
+// txTime returns the transaction timestamp, which is the same on every
+// endorsing peer
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read transaction timestamp: %v", err)
+	}
+	if ts == nil {
+		return time.Time{}, fmt.Errorf("transaction has no timestamp")
+	}
+	return ts.AsTime(), nil
+}
+
+// txUnixTime returns the transaction timestamp in seconds
+func txUnixTime(ctx contractapi.TransactionContextInterface) (int64, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return now.Unix(), nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// CONFIDENTIAL RATINGS
+// ============================================================================
+
+// A confidential rating keeps its value out of world state. The rater passes
+// the value and a salt in the transient map; the value is stored in the
+// implicit private collections of the rater's and the actor's organizations,
+// and the public rating carries only its weight and a salted hash of the
+// value. The reputation update is computed from the private value by the
+// endorsing peers, which must belong to one of the two organizations, so
+// the transaction has to be endorsed by both.
+
+// Transient map keys of SubmitConfidentialRating
+const (
+	confidentialValueKey = "value"
+	confidentialSaltKey  = "salt"
+)
+
+// minConfidentialSaltLength keeps the value hash from being brute-forced
+// over the small space of plausible rating values
+const minConfidentialSaltLength = 16
+
+// ConfidentialRatingValue is the private part of a confidential rating
+type ConfidentialRatingValue struct {
+	RatingID string  `json:"ratingId"`
+	Value    float64 `json:"value"`
+	Salt     string  `json:"salt"`
+}
+
+// confidentialRating describes where the private part of a rating is stored
+type confidentialRating struct {
+	salt        string
+	collections []string
+}
+
+// SubmitConfidentialRating rates an actor without recording the value in
+// world state. The value and salt are read from the transient map under
+// "value" and "salt". actorMSPID names the actor's organization and may be
+// empty when the actor ID is MSP-qualified.
+func (rtc *RatingContract) SubmitConfidentialRating(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	actorMSPID string,
+	dimension string,
+	evidence string,
+	timestampStr string,
+) (string, error) {
+	normalizedRaterID, err := callerIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get rater ID: %v", err)
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("failed to read transient data: %v", err)
+	}
+	valueStr := string(transient[confidentialValueKey])
+	if valueStr == "" {
+		return "", fmt.Errorf("rating value must be passed in the transient map under %q", confidentialValueKey)
+	}
+	salt := string(transient[confidentialSaltKey])
+	if len(salt) < minConfidentialSaltLength {
+		return "", fmt.Errorf("salt must be passed in the transient map under %q and be at least %d characters", confidentialSaltKey, minConfidentialSaltLength)
+	}
+
+	raterMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get rater MSP ID: %v", err)
+	}
+
+	namespace := actorNamespace(resolveIdentity(ctx, actorID))
+	switch {
+	case actorMSPID == "":
+		actorMSPID = namespace
+	case namespace != "" && namespace != actorMSPID:
+		return "", fmt.Errorf("actor %s does not belong to %s", actorID, actorMSPID)
+	}
+	if actorMSPID == "" {
+		return "", fmt.Errorf("actor MSP ID is required for unqualified actor IDs")
+	}
+
+	peerMSPID, err := shim.GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get peer MSP ID: %v", err)
+	}
+	if peerMSPID != raterMSPID && peerMSPID != actorMSPID {
+		return "", fmt.Errorf("confidential ratings must be endorsed by %s or %s, not %s", raterMSPID, actorMSPID, peerMSPID)
+	}
+
+	confidential := &confidentialRating{
+		salt:        salt,
+		collections: []string{implicitCollection(raterMSPID)},
+	}
+	if actorMSPID != raterMSPID {
+		confidential.collections = append(confidential.collections, implicitCollection(actorMSPID))
+	}
+
+	return submitRating(ctx, normalizedRaterID, "", actorID, dimension, valueStr, evidence, timestampStr, confidential)
+}
+
+// GetConfidentialRatingValue returns the private value of a confidential
+// rating to its rater, the rated actor or an arbitrator. It must be
+// evaluated on a peer of the rater's or the actor's organization.
+func (rtc *RatingContract) GetConfidentialRatingValue(
+	ctx contractapi.TransactionContextInterface,
+	ratingID string,
+) (*ConfidentialRatingValue, error) {
+	callerID, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller ID: %v", err)
+	}
+
+	rating, err := getRating(ctx, ratingID)
+	if err != nil {
+		return nil, err
+	}
+	if !rating.Confidential {
+		return nil, fmt.Errorf("rating %s is not confidential", ratingID)
+	}
+	if callerID != rating.RaterID && callerID != rating.ActorID && !isArbitrator(ctx) {
+		return nil, fmt.Errorf("unauthorized: only the rater, the actor or an arbitrator may read a confidential value")
+	}
+
+	return getConfidentialValue(ctx, rating)
+}
+
+// VerifyConfidentialRating reports whether a disclosed value and salt match
+// the hash recorded on a confidential rating
+func (rtc *RatingContract) VerifyConfidentialRating(
+	ctx contractapi.TransactionContextInterface,
+	ratingID string,
+	valueStr string,
+	salt string,
+) (bool, error) {
+	rating, err := getRating(ctx, ratingID)
+	if err != nil {
+		return false, err
+	}
+	if !rating.Confidential {
+		return false, fmt.Errorf("rating %s is not confidential", ratingID)
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid value: %v", err)
+	}
+
+	return confidentialValueHash(salt, value) == rating.ValueHash, nil
+}
+
+// ============================================================================
+// CONFIDENTIAL RATING HELPERS
+// ============================================================================
+
+// implicitCollection returns the name of an organization's implicit private
+// data collection
+func implicitCollection(mspID string) string {
+	return "_implicit_org_" + mspID
+}
+
+
+// confidentialValueHash returns the public commitment to a rating value
+func confidentialValueHash(salt string, value float64) string {
+	hash := sha256.Sum256([]byte(salt + strconv.FormatFloat(value, 'f', -1, 64)))
+	return hex.EncodeToString(hash[:])
+}
+
+// putConfidentialValue stores a rating's value in the implicit collections
+// and strips it from the public rating, leaving the salted hash
+func putConfidentialValue(
+	ctx contractapi.TransactionContextInterface,
+	confidential *confidentialRating,
+	rating *Rating,
+) error {
+	record := ConfidentialRatingValue{
+		RatingID: rating.RatingID,
+		Value:    rating.Value,
+		Salt:     confidential.salt,
+	}
+	recordJSON, err := marshalCanonical(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal confidential value: %v", err)
+	}
+
+	for _, collection := range confidential.collections {
+		if err := ctx.GetStub().PutPrivateData(collection, confidentialValueStateKey(rating.RatingID), recordJSON); err != nil {
+			return fmt.Errorf("failed to store confidential value in %s: %v", collection, err)
+		}
+	}
+
+	rating.Confidential = true
+	rating.ValueHash = confidentialValueHash(record.Salt, record.Value)
+	rating.Value = 0
+
+	return nil
+}
+
+// getConfidentialValue reads a confidential rating's value from the
+// implicit collection of the executing peer's organization and checks it
+// against the public hash
+func getConfidentialValue(ctx contractapi.TransactionContextInterface, rating *Rating) (*ConfidentialRatingValue, error) {
+	peerMSPID, err := shim.GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer MSP ID: %v", err)
+	}
+
+	recordJSON, err := ctx.GetStub().GetPrivateData(implicitCollection(peerMSPID), confidentialValueStateKey(rating.RatingID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read confidential value: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, fmt.Errorf("confidential value of %s is not held by %s", rating.RatingID, peerMSPID)
+	}
+
+	var record ConfidentialRatingValue
+	if err := json.Unmarshal(recordJSON, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal confidential value: %v", err)
+	}
+	if confidentialValueHash(record.Salt, record.Value) != rating.ValueHash {
+		return nil, fmt.Errorf("confidential value of %s does not match its hash", rating.RatingID)
+	}
+
+	return &record, nil
+}
+
+// ratingValue returns a rating's value, reading it from the private
+// collection for confidential ratings
+func ratingValue(ctx contractapi.TransactionContextInterface, rating *Rating) (float64, error) {
+	if !rating.Confidential {
+		return rating.Value, nil
+	}
+	record, err := getConfidentialValue(ctx, rating)
+	if err != nil {
+		return 0, err
+	}
+	return record.Value, nil
+}
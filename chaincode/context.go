@@ -0,0 +1,334 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// TRANSACTION CONTEXT
+// ============================================================================
+
+// ReputationContext is the per-transaction context. It memoizes the stored
+// config, the caller's canonical ID and committed record reads, which
+// helpers otherwise look up several times per invocation. A fresh context is created for every
+// transaction, and reads within a transaction never observe its own writes,
+// so the cached values are exactly what GetState would return.
+type ReputationContext struct {
+	contractapi.TransactionContext
+
+	configJSON []byte            // raw SYSTEM_CONFIG, unmarshalled afresh for each caller
+	callerID   string            // canonical caller ID, "" until resolved
+	tenant     string            // the transaction's tenant, "" for the default space
+	committed  map[string][]byte // committed values read so far, nil for absent keys
+
+	// Records written by this transaction. Unlike the cached values above
+	// these are the transaction's own writes, kept so that several updates
+	// to one aggregate within a transaction accumulate instead of each
+	// starting from the committed value.
+	reputations    map[string]*Reputation // by reputation state key
+	dimensionStats map[string]*DimensionStats
+	indexCounts    map[string]int // by counter state key
+	activities     map[string]*ActorActivity
+	activitySeq    int                          // recent activity entries written so far
+	metrics        map[string]*MetricsDay       // by day
+	stakes         map[string]*Stake            // by actor, written through putStake
+	memberships    map[string]*Membership       // by actor
+	listings       map[string]*DirectoryListing // by actor
+	events         []EventEnvelope              // events emitted so far, see emitEvent
+	eventKeys      []string                     // record keys events are correlated with, see correlateEvents
+}
+
+// cachedConfigJSON returns the stored config bytes, reading them at most
+// once per transaction
+func cachedConfigJSON(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	rctx, ok := ctx.(*ReputationContext)
+	if ok && rctx.configJSON != nil {
+		return rctx.configJSON, nil
+	}
+
+	configJSON, err := readState(ctx, configKey)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		rctx.configJSON = configJSON
+	}
+
+	return configJSON, nil
+}
+
+// setCachedConfigJSON records config bytes written during this transaction,
+// so later reads in it see them
+func setCachedConfigJSON(ctx contractapi.TransactionContextInterface, configJSON []byte) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		rctx.configJSON = configJSON
+	}
+}
+
+// multiStateReader is implemented by shims (fabric-chaincode-go v2.1+) that
+// can read several keys in one request
+type multiStateReader interface {
+	GetMultipleStates(keys ...string) ([][]byte, error)
+}
+
+// prefetchState reads the committed values of several keys, in one round
+// trip to the peer where the shim supports it, so later readState calls for
+// them are served locally
+func prefetchState(ctx contractapi.TransactionContextInterface, keys ...string) error {
+	rctx, ok := ctx.(*ReputationContext)
+	if !ok {
+		return nil
+	}
+
+	missing := []string{}
+	for _, key := range keys {
+		if _, cached := rctx.committed[key]; !cached {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var values [][]byte
+	if reader, batched := ctx.GetStub().(multiStateReader); batched {
+		var err error
+		if values, err = reader.GetMultipleStates(missing...); err != nil {
+			return err
+		}
+	} else {
+		for _, key := range missing {
+			value, err := ctx.GetStub().GetState(key)
+			if err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+	}
+	if rctx.committed == nil {
+		rctx.committed = make(map[string][]byte)
+	}
+	for i, key := range missing {
+		rctx.committed[key] = values[i]
+	}
+
+	return nil
+}
+
+// readState returns the committed value of a key, reading it at most once
+// per transaction. Like GetState it does not observe this transaction's own
+// writes.
+func readState(ctx contractapi.TransactionContextInterface, key string) ([]byte, error) {
+	rctx, ok := ctx.(*ReputationContext)
+	if ok {
+		if value, cached := rctx.committed[key]; cached {
+			return value, nil
+		}
+	}
+
+	value, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if rctx.committed == nil {
+			rctx.committed = make(map[string][]byte)
+		}
+		rctx.committed[key] = value
+	}
+
+	return value, nil
+}
+
+// pendingReputation returns the reputation record this transaction already
+// wrote for an actor and dimension, or nil
+func pendingReputation(ctx contractapi.TransactionContextInterface, actorID string, dimension string) *Reputation {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		return rctx.reputations[reputationStateKey(actorID, dimension)]
+	}
+	return nil
+}
+
+// setPendingReputation records a reputation record written by this
+// transaction
+func setPendingReputation(ctx contractapi.TransactionContextInterface, rep *Reputation) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		if rctx.reputations == nil {
+			rctx.reputations = make(map[string]*Reputation)
+		}
+		written := *rep
+		rctx.reputations[reputationStateKey(rep.ActorID, rep.Dimension)] = &written
+	}
+}
+
+// pendingDimensionStats returns the statistics this transaction already
+// wrote for a dimension, or nil
+func pendingDimensionStats(ctx contractapi.TransactionContextInterface, dimension string) *DimensionStats {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		return rctx.dimensionStats[dimension]
+	}
+	return nil
+}
+
+// setPendingDimensionStats records statistics written by this transaction
+func setPendingDimensionStats(ctx contractapi.TransactionContextInterface, stats *DimensionStats) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		if rctx.dimensionStats == nil {
+			rctx.dimensionStats = make(map[string]*DimensionStats)
+		}
+		rctx.dimensionStats[stats.Dimension] = stats
+	}
+}
+
+// pendingIndexCount returns the index count this transaction already wrote
+// under a counter key
+func pendingIndexCount(ctx contractapi.TransactionContextInterface, key string) (int, bool) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		count, written := rctx.indexCounts[key]
+		return count, written
+	}
+	return 0, false
+}
+
+// setPendingIndexCount records an index count written by this transaction
+func setPendingIndexCount(ctx contractapi.TransactionContextInterface, key string, count int) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		if rctx.indexCounts == nil {
+			rctx.indexCounts = make(map[string]int)
+		}
+		rctx.indexCounts[key] = count
+	}
+}
+
+// pendingActorActivity returns the activity record this transaction already
+// wrote for an actor, or nil
+func pendingActorActivity(ctx contractapi.TransactionContextInterface, actorID string) *ActorActivity {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		return rctx.activities[actorID]
+	}
+	return nil
+}
+
+// setPendingActorActivity records an activity record written by this
+// transaction
+func setPendingActorActivity(ctx contractapi.TransactionContextInterface, activity *ActorActivity) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		if rctx.activities == nil {
+			rctx.activities = make(map[string]*ActorActivity)
+		}
+		rctx.activities[activity.ActorID] = activity
+	}
+}
+
+// pendingMetricsDay returns a day's metrics as written earlier in this
+// transaction, or nil
+func pendingMetricsDay(ctx contractapi.TransactionContextInterface, day string) *MetricsDay {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		return rctx.metrics[day]
+	}
+	return nil
+}
+
+// setPendingMetricsDay records a day's metrics written by this transaction
+func setPendingMetricsDay(ctx contractapi.TransactionContextInterface, metrics *MetricsDay) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		if rctx.metrics == nil {
+			rctx.metrics = make(map[string]*MetricsDay)
+		}
+		rctx.metrics[metrics.Day] = metrics
+	}
+}
+
+// pendingStake returns the stake record this transaction already wrote for
+// an actor through putStake, or nil
+func pendingStake(ctx contractapi.TransactionContextInterface, actorID string) *Stake {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		return rctx.stakes[actorID]
+	}
+	return nil
+}
+
+// setPendingStake records a stake record written by this transaction
+func setPendingStake(ctx contractapi.TransactionContextInterface, stake *Stake) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		if rctx.stakes == nil {
+			rctx.stakes = make(map[string]*Stake)
+		}
+		written := *stake
+		rctx.stakes[stake.ActorID] = &written
+	}
+}
+
+// pendingMembership returns the membership record this transaction already
+// wrote for an actor, or nil
+func pendingMembership(ctx contractapi.TransactionContextInterface, actorID string) *Membership {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		return rctx.memberships[actorID]
+	}
+	return nil
+}
+
+// setPendingMembership records a membership record written by this
+// transaction
+func setPendingMembership(ctx contractapi.TransactionContextInterface, membership *Membership) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		if rctx.memberships == nil {
+			rctx.memberships = make(map[string]*Membership)
+		}
+		written := *membership
+		rctx.memberships[membership.ActorID] = &written
+	}
+}
+
+// pendingListing returns the directory listing this transaction already
+// wrote for an actor, or nil
+func pendingListing(ctx contractapi.TransactionContextInterface, actorID string) *DirectoryListing {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		return rctx.listings[actorID]
+	}
+	return nil
+}
+
+// setPendingListing records a directory listing written by this transaction
+func setPendingListing(ctx contractapi.TransactionContextInterface, listing *DirectoryListing) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		if rctx.listings == nil {
+			rctx.listings = make(map[string]*DirectoryListing)
+		}
+		rctx.listings[listing.ActorID] = listing.clone()
+	}
+}
+
+// nextActivitySequence numbers the recent activity entries written by this
+// transaction, starting at 0
+func nextActivitySequence(ctx contractapi.TransactionContextInterface) int {
+	rctx, ok := ctx.(*ReputationContext)
+	if !ok {
+		return 0
+	}
+	seq := rctx.activitySeq
+	rctx.activitySeq++
+	return seq
+}
+
+// cachedCallerIdentity returns the caller's canonical ID, resolving it at
+// most once per transaction
+func cachedCallerIdentity(
+	ctx contractapi.TransactionContextInterface,
+	resolve func() (string, error),
+) (string, error) {
+	rctx, ok := ctx.(*ReputationContext)
+	if ok && rctx.callerID != "" {
+		return rctx.callerID, nil
+	}
+
+	id, err := resolve()
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		rctx.callerID = id
+	}
+
+	return id, nil
+}
@@ -1,14 +1,12 @@
 package main
 
 import (
-	"crypto/sha256"
 	"encoding/json"
         "encoding/base64" 
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
 )
@@ -16,6 +14,9 @@ import (
 // ============================================================================
 // SMART CONTRACT DEFINITION
 // ============================================================================
+
+// ReputationContract is the default contract: reputation queries, actor
+// records, identity and analytics. The other contracts are in contracts.go.
 type ReputationContract struct {
 	contractapi.Contract
 }
@@ -39,6 +40,89 @@ type SystemConfig struct {
 	MinRaterWeight float64 `json:"minRaterWeight"`
 	MaxRaterWeight float64 `json:"maxRaterWeight"`
 
+	// Service Account Parameters
+	ServiceMaxRaterWeight float64 `json:"serviceMaxRaterWeight"` // weight cap for automated raters
+
+	// Onboarding Parameters
+	ProbationEvents         int     `json:"probationEvents"`         // clean events a supplier on probation needs to graduate
+	ProbationMaxRaterWeight float64 `json:"probationMaxRaterWeight"` // weight cap for ratings by applicants and actors on probation
+
+	// Treasury Parameters
+	TreasuryApprovals   int     `json:"treasuryApprovals"`   // distinct admin approvals per withdrawal
+	SlashInitiatorShare float64 `json:"slashInitiatorShare"` // fraction of a slash paid to the dispute initiator, the rest going to the treasury
+
+	// Enforcement Parameters
+	BanApprovals int `json:"banApprovals"` // distinct admin approvals to ban or unban
+
+	// Scheduled linear parameter changes, keyed by parameter JSON name
+	ParameterRamps map[string]*ParameterRamp `json:"parameterRamps,omitempty"`
+
+	// Required client identity attributes, keyed by function then attribute
+	EligibilityRules map[string]map[string]string `json:"eligibilityRules,omitempty"`
+
+	// Which rater types may rate which actor types
+	RatingRules []RatingRule `json:"ratingRules,omitempty"`
+
+	// Attestation Parameters
+	CredentialDimensions map[string]string `json:"credentialDimensions,omitempty"` // credential type -> dimension
+	AttestationWeight    float64           `json:"attestationWeight"`              // alpha added per accepted credential
+
+	// Vouching Parameters
+	VouchMinMetaScore float64 `json:"vouchMinMetaScore"` // metareputation score required to vouch
+	VouchPriorWeight  float64 `json:"vouchPriorWeight"`  // alpha added to the newcomer's prior
+	VouchPenalty      float64 `json:"vouchPenalty"`      // beta added to the voucher's metareputation
+
+	// Offboarding Parameters
+	OffboardingWindow int64 `json:"offboardingWindow"` // seconds before a deactivated actor's stake is released
+
+	// Stake Withdrawal Parameters
+	UnbondingPeriod int64 `json:"unbondingPeriod"` // seconds before withdrawn stake can be claimed
+
+	// Dispute Deadline Parameters
+	DisputeTimeout int64 `json:"disputeTimeout"` // seconds after filing before a pending dispute can be expired
+
+	// Challenge Window Parameters
+	ChallengeWindow int64 `json:"challengeWindow"` // seconds a rating waits for disputes before it is applied, 0 to apply at once
+
+	// Rating Cooldown Parameters
+	RatingCooldown int64 `json:"ratingCooldown"` // seconds before a rater may rate the same actor and dimension again, 0 to disable
+
+	// Arbitration Parameters
+	ArbitrationPanelSize int `json:"arbitrationPanelSize"` // arbitrators deciding each dispute by majority, 0 for a single arbitrator
+
+	// Identity Parameters
+	IdentityMode       string `json:"identityMode"`       // cn, msp, hash
+	TrustedCAApprovals int    `json:"trustedCaApprovals"` // distinct admin approvals to register a rotation CA
+
+	// Evidence Parameters
+	EvidencePinRequests bool `json:"evidencePinRequests"` // emit EvidencePinRequested for IPFS evidence
+
+	// SLA Parameters
+	SLARules map[string]SLARule `json:"slaRules,omitempty"` // dimension -> how an SLA metric maps to rating values
+
+	// Key-level Endorsement Parameters
+	KeyEndorsementOrgs   []string `json:"keyEndorsementOrgs,omitempty"` // MSP IDs whose peers must endorse config, role and treasury changes
+	KeyEndorsementQuorum int      `json:"keyEndorsementQuorum"`         // how many of them, 0 for all
+
+	// Settlement Parameters
+	SettlementChaincode string `json:"settlementChaincode,omitempty"` // token chaincode compensating actors on overturned disputes, "" to disable
+	SettlementChannel   string `json:"settlementChannel,omitempty"`   // channel of the settlement chaincode, "" for this one
+	SettlementFunction  string `json:"settlementFunction,omitempty"`  // called as function(recipientId, amount, disputeId)
+
+	// Fraud Detection Parameters
+	FraudWindow          int64   `json:"fraudWindow"`          // seconds of activity a fraud pattern may span
+	FraudBurstRatings    int     `json:"fraudBurstRatings"`    // ratings received within the window before a bond that make a burst
+	FraudReciprocalScore float64 `json:"fraudReciprocalScore"` // lowest value counted as a high rating between a pair
+	FraudThresholdMargin float64 `json:"fraudThresholdMargin"` // how far above bondReleaseValue a bond-releasing rating is suspicious
+
+	// Archival Parameters
+	ArchiveAge        int64  `json:"archiveAge"`                  // seconds after which ratings are rolled into archives, 0 for never
+	ArchiveCollection string `json:"archiveCollection,omitempty"` // private data collection archived ratings are moved to, "" to delete them
+
+	// Query Parameters
+	DefaultPageSize int `json:"defaultPageSize"` // used when a paged query passes pageSize 0
+	MaxPageSize     int `json:"maxPageSize"`     // largest pageSize a paged query accepts
+
 	// Dimension Registry
 	ValidDimensions map[string]bool   `json:"validDimensions"`
 	MetaDimensions  map[string]string `json:"metaDimensions"` // base -> meta mapping
@@ -48,14 +132,14 @@ type SystemConfig struct {
 	LastUpdated int64 `json:"lastUpdated"`
 }
 
-// Reputation represents the Beta distribution parameters
+// Reputation represents the Beta distribution parameters, in fixed point
 type Reputation struct {
-	ActorID     string  `json:"actorId"`
-	Dimension   string  `json:"dimension"`
-	Alpha       float64 `json:"alpha"`
-	Beta        float64 `json:"beta"`
-	TotalEvents int     `json:"totalEvents"`
-	LastTs      int64   `json:"lastTs"`
+	ActorID     string `json:"actorId"`
+	Dimension   string `json:"dimension"`
+	Alpha       Fixed  `json:"alphaMicros"`
+	Beta        Fixed  `json:"betaMicros"`
+	TotalEvents int    `json:"totalEvents"`
+	LastTs      int64  `json:"lastTs"`
 }
 
 // Rating represents a single rating event
@@ -69,11 +153,20 @@ type Rating struct {
 	Evidence  string  `json:"evidence"`
 	Timestamp int64   `json:"timestamp"`
 	TxID      string  `json:"txId"`
+
+	SubmittedBy string `json:"submittedBy,omitempty"` // delegated submitter, if not the rater
+	Source      string `json:"source,omitempty"`      // human, automated
+
+	Confidential bool   `json:"confidential,omitempty"` // value held in the parties' implicit collections
+	ValueHash    string `json:"valueHash,omitempty"`    // sha256 of salt and value, confidential ratings only
+
+	PendingUntil int64 `json:"pendingUntil,omitempty"` // end of the challenge window while the rating awaits application, 0 once applied or overturned
 }
 
 // Stake represents an actor's financial commitment
 type Stake struct {
 	ActorID   string  `json:"actorId"`
+	MSPID     string  `json:"mspId,omitempty"` // MSP of the certificate that first staked
 	Balance   float64 `json:"balance"`
 	Locked    float64 `json:"locked"`
 	UpdatedAt int64   `json:"updatedAt"`
@@ -81,18 +174,24 @@ type Stake struct {
 
 // Dispute represents a challenge to a rating
 type Dispute struct {
-	DisputeID       string `json:"disputeId"`
-	RatingID        string `json:"ratingId"`
-	InitiatorID     string `json:"initiatorId"`
-	RaterID         string `json:"raterId"`
-	ActorID         string `json:"actorId"`
-	Dimension       string `json:"dimension"`
-	Reason          string `json:"reason"`
-	Status          string `json:"status"` // pending, upheld, overturned
-	ArbitratorID    string `json:"arbitratorId"`
-	ArbitratorNotes string `json:"arbitratorNotes"`
-	CreatedAt       int64  `json:"createdAt"`
-	ResolvedAt      int64  `json:"resolvedAt"`
+	DisputeID       string  `json:"disputeId"`
+	RatingID        string  `json:"ratingId"`
+	InitiatorID     string  `json:"initiatorId"`
+	RaterID         string  `json:"raterId"`
+	ActorID         string  `json:"actorId"`
+	Dimension       string  `json:"dimension"`
+	Reason          string  `json:"reason"`
+	Status          string  `json:"status"` // pending, upheld, overturned, withdrawn, expired
+	ArbitratorID    string  `json:"arbitratorId"`
+	ArbitratorNotes string  `json:"arbitratorNotes"`
+	BondID          string  `json:"bondId,omitempty"` // bond claimed by the rating, instead of a locked dispute cost
+	LockedCost      float64 `json:"lockedCost"`       // dispute cost the initiator locked, refunded when the dispute closes
+	CreatedAt       int64   `json:"createdAt"`
+	ResolvedAt      int64   `json:"resolvedAt"`
+	Deadline        int64   `json:"deadline,omitempty"` // when the dispute can be expired, 0 for disputes filed before deadlines
+
+	Panel []string       `json:"panel,omitempty"` // arbitrators assigned to decide the dispute by majority
+	Votes []PanelVerdict `json:"votes,omitempty"` // verdicts cast by the panel, in order
 }
 
 // ============================================================================
@@ -100,9 +199,9 @@ type Dispute struct {
 // ============================================================================
 
 // InitConfig initializes the system configuration with default values
-func (rc *ReputationContract) InitConfig(ctx contractapi.TransactionContextInterface) error {
+func (gc *GovernanceContract) InitConfig(ctx contractapi.TransactionContextInterface) error {
 	// Check if config already exists
-	existing, err := ctx.GetStub().GetState("SYSTEM_CONFIG")
+	existing, err := ctx.GetStub().GetState(configKey)
 	if err != nil {
 		return fmt.Errorf("failed to read config: %v", err)
 	}
@@ -111,53 +210,35 @@ func (rc *ReputationContract) InitConfig(ctx contractapi.TransactionContextInter
 	}
 
 	// Allow anyone to initialize if config doesn't exist (bootstrap)
-	config := SystemConfig{
-		MinStakeRequired: 10000.0,
-		DisputeCost:      100.0,
-		SlashPercentage:  0.1,
-
-		DecayRate:      0.98,
-		DecayPeriod:    86400.0, // 1 day in seconds
-		InitialAlpha:   2.0,
-		InitialBeta:    2.0,
-		MinRaterWeight: 0.1,
-		MaxRaterWeight: 5.0,
-
-		ValidDimensions: map[string]bool{
-			"quality":    true,
-			"delivery":   true,
-			"compliance": true,
-			"warranty":   true,
-		},
-		MetaDimensions: map[string]string{
-			"quality":    "rating_quality",
-			"delivery":   "rating_delivery",
-			"compliance": "rating_compliance",
-			"warranty":   "rating_warranty",
-		},
-
-		Version:     1,
-		LastUpdated: time.Now().Unix(),
+	config := defaultConfig()
+	if config.LastUpdated, err = txUnixTime(ctx); err != nil {
+		return err
 	}
 
-	configJSON, err := json.Marshal(config)
+	configJSON, err := marshalCanonical(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	err = ctx.GetStub().PutState("SYSTEM_CONFIG", configJSON)
+	err = ctx.GetStub().PutState(configKey, configJSON)
 	if err != nil {
 		return fmt.Errorf("failed to store config: %v", err)
 	}
+	setCachedConfigJSON(ctx, configJSON)
+
+	// A new ledger has no records to migrate
+	if err := putStateVersion(ctx, currentStateVersion); err != nil {
+		return err
+	}
 
 	// Emit event
-	ctx.GetStub().SetEvent("ConfigInitialized", configJSON)
+	emitEvent(ctx, "ConfigInitialized", config)
 
 	return nil
 }
 
 // UpdateConfig allows admin to modify system parameters
-func (rc *ReputationContract) UpdateConfig(
+func (gc *GovernanceContract) UpdateConfig(
 	ctx contractapi.TransactionContextInterface,
 	configJSON string,
 ) error {
@@ -175,27 +256,44 @@ func (rc *ReputationContract) UpdateConfig(
 		return fmt.Errorf("invalid configuration: %v", err)
 	}
 
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// A parameter set directly ends its ramp
+	currentConfig, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	dropOverriddenRamps(currentConfig, &newConfig)
+
 	newConfig.Version++
-	newConfig.LastUpdated = time.Now().Unix()
+	newConfig.LastUpdated = now
 
-	updatedJSON, err := json.Marshal(newConfig)
+	updatedJSON, err := marshalCanonical(newConfig)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	err = ctx.GetStub().PutState("SYSTEM_CONFIG", updatedJSON)
+	err = ctx.GetStub().PutState(configKey, updatedJSON)
 	if err != nil {
 		return fmt.Errorf("failed to update config: %v", err)
 	}
 
+	// The endorsement policy of sensitive keys follows the config
+	if err := applyKeyEndorsementPolicies(ctx, &newConfig); err != nil {
+		return err
+	}
+
 	// Emit event
-	ctx.GetStub().SetEvent("ConfigUpdated", updatedJSON)
+	emitEvent(ctx, "ConfigUpdated", newConfig)
 
 	return nil
 }
 
 // *** FIX 1: ADD UpdateDecayRate function ***
-func (rc *ReputationContract) UpdateDecayRate(
+func (gc *GovernanceContract) UpdateDecayRate(
 	ctx contractapi.TransactionContextInterface,
 	newRateStr string,
 ) error {
@@ -213,16 +311,22 @@ func (rc *ReputationContract) UpdateDecayRate(
 		return err
 	}
 
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
 	config.DecayRate = newRate
+	delete(config.ParameterRamps, "decayRate")
 	config.Version++
-	config.LastUpdated = time.Now().Unix()
+	config.LastUpdated = now
 
-	configJSON, err := json.Marshal(config)
+	configJSON, err := marshalCanonical(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	err = ctx.GetStub().PutState("SYSTEM_CONFIG", configJSON)
+	err = ctx.GetStub().PutState(configKey, configJSON)
 	if err != nil {
 		return fmt.Errorf("failed to update config: %v", err)
 	}
@@ -232,19 +336,18 @@ func (rc *ReputationContract) UpdateDecayRate(
 		"decayRate": newRate,
 		"version":   config.Version,
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("DecayRateUpdated", eventJSON)
+	emitEvent(ctx, "DecayRateUpdated", eventPayload)
 
 	return nil
 }
 
 // GetConfig retrieves current system configuration
-func (rc *ReputationContract) GetConfig(ctx contractapi.TransactionContextInterface) (*SystemConfig, error) {
+func (gc *GovernanceContract) GetConfig(ctx contractapi.TransactionContextInterface) (*SystemConfig, error) {
 	return getConfig(ctx)
 }
 
 // AddDimension allows admin to add a new reputation dimension
-func (rc *ReputationContract) AddDimension(
+func (gc *GovernanceContract) AddDimension(
 	ctx contractapi.TransactionContextInterface,
 	baseDimension string,
 	metaDimension string,
@@ -258,18 +361,23 @@ func (rc *ReputationContract) AddDimension(
 		return err
 	}
 
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Add dimension
 	config.ValidDimensions[baseDimension] = true
 	config.MetaDimensions[baseDimension] = metaDimension
 	config.Version++
-	config.LastUpdated = time.Now().Unix()
+	config.LastUpdated = now
 
-	configJSON, err := json.Marshal(config)
+	configJSON, err := marshalCanonical(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	err = ctx.GetStub().PutState("SYSTEM_CONFIG", configJSON)
+	err = ctx.GetStub().PutState(configKey, configJSON)
 	if err != nil {
 		return fmt.Errorf("failed to update config: %v", err)
 	}
@@ -279,8 +387,7 @@ func (rc *ReputationContract) AddDimension(
 		"baseDimension": baseDimension,
 		"metaDimension": metaDimension,
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("DimensionAdded", eventJSON)
+	emitEvent(ctx, "DimensionAdded", eventPayload)
 
 	return nil
 }
@@ -290,7 +397,7 @@ func (rc *ReputationContract) AddDimension(
 // ============================================================================
 
 // AddStake allows an actor to add financial stake
-func (rc *ReputationContract) AddStake(
+func (sc *StakeContract) AddStake(
 	ctx contractapi.TransactionContextInterface,
 	amountStr string,
 ) error {
@@ -300,13 +407,18 @@ func (rc *ReputationContract) AddStake(
 	}
 
 	// *** FIX 2: Use normalized identity for stake key ***
-	actorID, err := ctx.GetClientIdentity().GetID()
+	normalizedID, err := callerIdentity(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get actor ID: %v", err)
 	}
 
-	// Normalize identity
-	normalizedID := normalizeIdentity(actorID)
+	banned, err := isBanned(ctx, normalizedID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return fmt.Errorf("actor is banned: %s", normalizedID)
+	}
 
 	// Load or initialize stake
 	stake, err := getOrInitStake(ctx, normalizedID)
@@ -314,17 +426,32 @@ func (rc *ReputationContract) AddStake(
 		return err
 	}
 
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Record which MSP the actor stakes from; in the cn identity mode only
+	// that MSP's CAs can vouch for its certificates on rotation
+	if stake.MSPID == "" {
+		mspID, err := ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return fmt.Errorf("failed to get caller MSP: %v", err)
+		}
+		stake.MSPID = mspID
+	}
+
 	// Update balance
 	stake.Balance += amount
-	stake.UpdatedAt = time.Now().Unix()
+	stake.UpdatedAt = now
 
 	// Store updated stake
-	stakeJSON, err := json.Marshal(stake)
+	stakeJSON, err := marshalCanonical(stake)
 	if err != nil {
 		return fmt.Errorf("failed to marshal stake: %v", err)
 	}
 
-	stakeKey := fmt.Sprintf("STAKE:%s", normalizedID)
+	stakeKey := stakeStateKey(normalizedID)
 	err = ctx.GetStub().PutState(stakeKey, stakeJSON)
 	if err != nil {
 		return fmt.Errorf("failed to store stake: %v", err)
@@ -336,18 +463,17 @@ func (rc *ReputationContract) AddStake(
 		"amount":  amount,
 		"balance": stake.Balance,
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("StakeAdded", eventJSON)
+	emitEvent(ctx, "StakeAdded", eventPayload, stakeKey)
 
 	return nil
 }
 
 // GetStake retrieves an actor's stake information
-func (rc *ReputationContract) GetStake(
+func (sc *StakeContract) GetStake(
 	ctx contractapi.TransactionContextInterface,
 	actorID string,
 ) (*Stake, error) {
-	normalizedID := normalizeIdentity(actorID)
+	normalizedID := resolveIdentity(ctx, actorID)
 	return getOrInitStake(ctx, normalizedID)
 }
 
@@ -356,156 +482,421 @@ func (rc *ReputationContract) GetStake(
 // ============================================================================
 
 // SubmitRating allows an actor to rate another actor
-func (rc *ReputationContract) SubmitRating(
+func (rtc *RatingContract) SubmitRating(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+	valueStr string,
+	evidence string,
+	timestampStr string,
+) (string, error) {
+	// Get rater ID
+	normalizedRaterID, err := callerIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get rater ID: %v", err)
+	}
+
+	return submitRating(ctx, normalizedRaterID, "", actorID, dimension, valueStr, evidence, timestampStr, nil)
+}
+
+// SubmitRatingOnBehalf lets an integration identity submit a rating
+// attributed to an organization actor. The caller's certificate must carry a
+// canRateFor attribute naming that actor (comma-separated for several); the
+// caller is recorded on the rating as the submitter.
+func (rtc *RatingContract) SubmitRatingOnBehalf(
 	ctx contractapi.TransactionContextInterface,
+	onBehalfOf string,
 	actorID string,
 	dimension string,
 	valueStr string,
 	evidence string,
 	timestampStr string,
 ) (string, error) {
-	// Parse inputs
+	submitterID, err := callerIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get submitter ID: %v", err)
+	}
+
+	normalizedRaterID := resolveIdentity(ctx, onBehalfOf)
+
+	canRateFor, ok, err := ctx.GetClientIdentity().GetAttributeValue("canRateFor")
+	if err != nil {
+		return "", fmt.Errorf("failed to read canRateFor attribute: %v", err)
+	}
+	delegated := false
+	if ok {
+		for _, org := range strings.Split(canRateFor, ",") {
+			if resolveIdentity(ctx, strings.TrimSpace(org)) == normalizedRaterID {
+				delegated = true
+				break
+			}
+		}
+	}
+	if !delegated {
+		return "", fmt.Errorf("unauthorized: %s cannot rate on behalf of %s", submitterID, normalizedRaterID)
+	}
+
+	return submitRating(ctx, normalizedRaterID, submitterID, actorID, dimension, valueStr, evidence, timestampStr, nil)
+}
+
+// submitRating records a rating from raterID. submittedBy is set when the
+// rating was submitted by a delegated identity rather than the rater itself;
+// confidential is set when the value must stay out of world state.
+//
+// The submission runs in three stages: validateRating checks the inputs and
+// the parties, computeRating derives the weight and builds the record, and
+// writeRating produces the write set. Each record is written exactly once
+// and the reputation is updated exactly once per rating.
+func submitRating(
+	ctx contractapi.TransactionContextInterface,
+	normalizedRaterID string,
+	submittedBy string,
+	actorID string,
+	dimension string,
+	valueStr string,
+	evidence string,
+	timestampStr string,
+	confidential *confidentialRating,
+) (string, error) {
+	submission, err := validateRating(ctx, normalizedRaterID, actorID, dimension, valueStr, evidence, timestampStr)
+	if err != nil {
+		return "", err
+	}
+
+	if err := computeRating(ctx, submission, submittedBy); err != nil {
+		return "", err
+	}
+
+	if err := writeRating(ctx, submission, confidential); err != nil {
+		return "", err
+	}
+
+	return submission.rating.RatingID, nil
+}
+
+// ratingSubmission carries a rating through submitRating's stages
+type ratingSubmission struct {
+	config      *SystemConfig
+	raterID     string
+	actorID     string
+	dimension   string
+	value       float64
+	evidence    string
+	evidenceCID string // CIDv1 of IPFS evidence, "" otherwise
+	timestamp   int64
+	automated   bool    // submitted by an oracle, whatever its certificate
+	rating      *Rating // set by computeRating
+}
+
+// validateRating parses the submission and checks that the rater may rate
+// the actor in the dimension
+func validateRating(
+	ctx contractapi.TransactionContextInterface,
+	normalizedRaterID string,
+	actorID string,
+	dimension string,
+	valueStr string,
+	evidence string,
+	timestampStr string,
+) (*ratingSubmission, error) {
 	value, err := strconv.ParseFloat(valueStr, 64)
 	if err != nil || value < 0 || value > 1 {
-		return "", fmt.Errorf("invalid value: must be between 0 and 1")
+		return nil, fmt.Errorf("invalid value: must be between 0 and 1")
 	}
 
 	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
 	if err != nil {
-		return "", fmt.Errorf("invalid timestamp: %v", err)
+		return nil, fmt.Errorf("invalid timestamp: %v", err)
 	}
 
-	// Get rater ID
-	raterID, err := ctx.GetClientIdentity().GetID()
+	// IPFS evidence must be a well-formed CIDv1
+	evidenceCIDv1, err := evidenceCID(evidence)
 	if err != nil {
-		return "", fmt.Errorf("failed to get rater ID: %v", err)
+		return nil, err
 	}
 
-	// *** ADD DEBUG OUTPUT ***
-	fmt.Printf("=== SELF-RATING DEBUG ===\n")
-	fmt.Printf("Raw raterID from GetID(): %s\n", raterID)
-	fmt.Printf("Input actorID parameter: %s\n", actorID)
+	// Prevent self-rating, comparing normalized IDs
+	normalizedActorID := resolveIdentity(ctx, actorID)
+	if normalizedRaterID == normalizedActorID {
+		return nil, fmt.Errorf("self-rating is not allowed: rater %s cannot rate themselves", normalizedRaterID)
+	}
 
-	// *** FIX 3: Normalize both IDs for comparison ***
-	normalizedRaterID := normalizeIdentity(raterID)
-	normalizedActorID := normalizeIdentity(actorID)
+	// Fetch what the checks and updates below read in one round trip
+	if err := prefetchRatingState(ctx, normalizedRaterID, normalizedActorID, dimension); err != nil {
+		return nil, fmt.Errorf("failed to read rating state: %v", err)
+	}
 
-	// *** ADD DEBUG OUTPUT ***
-	fmt.Printf("Normalized raterID: %s\n", normalizedRaterID)
-	fmt.Printf("Normalized actorID: %s\n", normalizedActorID)
-	fmt.Printf("Are they equal? %v\n", normalizedRaterID == normalizedActorID)
-	fmt.Printf("=========================\n")
+	banned, err := isBanned(ctx, normalizedRaterID)
+	if err != nil {
+		return nil, err
+	}
+	if banned {
+		return nil, fmt.Errorf("rater is banned: %s", normalizedRaterID)
+	}
 
-	// CRITICAL: Prevent self-rating with normalized IDs
-	if normalizedRaterID == normalizedActorID {
-		return "", fmt.Errorf("self-rating is not allowed: rater %s cannot rate themselves", normalizedRaterID)
+	for _, id := range []string{normalizedRaterID, normalizedActorID} {
+		deactivated, err := isDeactivated(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if deactivated {
+			return nil, fmt.Errorf("actor is deactivated: %s", id)
+		}
 	}
 
-	// ... rest of function continues unchanged
 	// Validate dimension
 	config, err := getConfig(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if !config.ValidDimensions[dimension] {
-		return "", fmt.Errorf("invalid dimension: %s", dimension)
+		return nil, fmt.Errorf("invalid dimension: %s", dimension)
 	}
 
-	// *** FIX 4: Check rater has minimum stake using normalized ID ***
+	if err := checkEligibility(ctx, config, "SubmitRating"); err != nil {
+		return nil, err
+	}
+
+	if err := checkRatingRules(ctx, config, normalizedRaterID, normalizedActorID, dimension); err != nil {
+		return nil, err
+	}
+
+	// Check rater has minimum stake
 	raterStake, err := getOrInitStake(ctx, normalizedRaterID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get rater stake: %v", err)
+		return nil, fmt.Errorf("failed to get rater stake: %v", err)
 	}
 
 	if raterStake.Balance < config.MinStakeRequired {
-		return "", fmt.Errorf("insufficient stake: have %f, require %f", raterStake.Balance, config.MinStakeRequired)
-	}
+		return nil, fmt.Errorf("insufficient stake: have %f, require %f", raterStake.Balance, config.MinStakeRequired)
+	}
+
+	return &ratingSubmission{
+		config:      config,
+		raterID:     normalizedRaterID,
+		actorID:     normalizedActorID,
+		dimension:   dimension,
+		value:       value,
+		evidence:    evidence,
+		evidenceCID: evidenceCIDv1,
+		timestamp:   timestamp,
+	}, nil
+}
 
+// computeRating derives the rater's weight and builds the rating record
+func computeRating(
+	ctx contractapi.TransactionContextInterface,
+	submission *ratingSubmission,
+	submittedBy string,
+) error {
 	// Calculate rater weight based on METAREPUTATION
-	weight, err := rc.calculateRaterWeight(ctx, normalizedRaterID, dimension)
+	weight, err := calculateRaterWeight(ctx, submission.raterID, submission.dimension)
 	if err != nil {
-		return "", fmt.Errorf("failed to calculate rater weight: %v", err)
+		return fmt.Errorf("failed to calculate rater weight: %v", err)
 	}
 
-	// Generate rating ID
-	txID := ctx.GetStub().GetTxID()
-	ratingID := generateRatingID(normalizedRaterID, normalizedActorID, dimension, timestamp)
+	// Machine ratings are tagged and capped separately from human ones
+	source := ratingSourceHuman
+	automated := submission.automated
+	if !automated {
+		if automated, err = isServiceAccount(ctx); err != nil {
+			return err
+		}
+	}
+	if automated {
+		source = ratingSourceAutomated
+		weight = math.Min(weight, submission.config.ServiceMaxRaterWeight)
+	} else if err := checkRatingCooldown(ctx, submission); err != nil {
+		return err
+	}
 
-	// Create rating record (store normalized IDs)
-	rating := Rating{
+	// Applicants and actors on probation rate with capped weight
+	probationary, err := isProbationary(ctx, submission.raterID)
+	if err != nil {
+		return err
+	}
+	if probationary {
+		weight = math.Min(weight, submission.config.ProbationMaxRaterWeight)
+	}
+
+	ratingID := generateRatingID(submission.raterID, submission.actorID, submission.dimension, submission.timestamp)
+
+	submission.rating = &Rating{
 		RatingID:  ratingID,
-		RaterID:   normalizedRaterID,
-		ActorID:   normalizedActorID,
-		Dimension: dimension,
-		Value:     value,
+		RaterID:   submission.raterID,
+		ActorID:   submission.actorID,
+		Dimension: submission.dimension,
+		Value:     submission.value,
 		Weight:    weight,
-		Evidence:  evidence,
-		Timestamp: timestamp,
-		TxID:      txID,
+		Evidence:  submission.evidence,
+		Timestamp: submission.timestamp,
+		TxID:      ctx.GetStub().GetTxID(),
+
+		SubmittedBy: submittedBy,
+		Source:      source,
 	}
+	return nil
+}
 
-	// Store rating
-	ratingJSON, err := json.Marshal(rating)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal rating: %v", err)
+// writeRating stores the rating with its indexes and rater/actor pair
+// record, updates the actor's reputation and emits the rating's events
+func writeRating(
+	ctx contractapi.TransactionContextInterface,
+	submission *ratingSubmission,
+	confidential *confidentialRating,
+) error {
+	rating := submission.rating
+	correlateEvents(ctx, rating.RatingID)
+
+	// Confidential ratings keep only a salted hash of the value in world state
+	if confidential != nil {
+		if err := putConfidentialValue(ctx, confidential, rating); err != nil {
+			return err
+		}
 	}
 
-	err = ctx.GetStub().PutState(ratingID, ratingJSON)
+	// Within a challenge window the rating is stored without effect
+	pending, err := queuePendingRating(ctx, submission.config, rating)
 	if err != nil {
-		return "", fmt.Errorf("failed to store rating: %v", err)
+		return err
 	}
-// Store rating
-ratingJSON, err = json.Marshal(rating)
-if err != nil {
-    return "", fmt.Errorf("failed to marshal rating: %v", err)
-}
 
-err = ctx.GetStub().PutState(ratingID, ratingJSON)
-if err != nil {
-    return "", fmt.Errorf("failed to store rating: %v", err)
+	// Store rating
+	if err := putRating(ctx, rating); err != nil {
+		return err
+	}
+
+	if err := putRaterActorRecord(ctx, rating); err != nil {
+		return err
+	}
+	if err := indexRating(ctx, rating); err != nil {
+		return err
+	}
+	if err := recordActivity(ctx, ActivityEntry{Kind: "rating", ID: rating.RatingID, ActorID: rating.ActorID, Dimension: rating.Dimension}); err != nil {
+		return err
+	}
+	if err := recordRatingActivity(ctx, rating); err != nil {
+		return err
+	}
+	if err := updateMetrics(ctx, func(m *MetricsDay) { m.Ratings++ }); err != nil {
+		return err
+	}
+
+	// Update actor's reputation, bonds and probation
+	if !pending {
+		if err := applyRatingEffects(ctx, submission.config, rating, submission.value); err != nil {
+			return err
+		}
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"ratingId":  rating.RatingID,
+		"raterId":   rating.RaterID,
+		"actorId":   rating.ActorID,
+		"dimension": rating.Dimension,
+		"weight":    rating.Weight,
+		"timestamp": rating.Timestamp,
+		"source":    rating.Source,
+	}
+	if rating.Confidential {
+		eventPayload["confidential"] = true
+		eventPayload["valueHash"] = rating.ValueHash
+	} else {
+		eventPayload["value"] = submission.value
+	}
+	if rating.SubmittedBy != "" {
+		eventPayload["submittedBy"] = rating.SubmittedBy
+	}
+	if pending {
+		eventPayload["pendingUntil"] = rating.PendingUntil
+	}
+	emitEvent(ctx, "RatingSubmitted", eventPayload)
+
+	// Let pinning services keep IPFS evidence available
+	if submission.evidenceCID != "" && submission.config.EvidencePinRequests {
+		emitEvent(ctx, "EvidencePinRequested", map[string]interface{}{
+			"ratingId": rating.RatingID,
+			"cid":      submission.evidenceCID,
+			"actorId":  rating.ActorID,
+		})
+	}
+
+	return nil
 }
 
-// *** STORE THE RATER-ACTOR PAIR RECORD ***
-raterActorKey := fmt.Sprintf("RATER_ACTOR:%s:%s:%s", normalizedRaterID, normalizedActorID, dimension)
-raterActorRecord := map[string]interface{}{
-    "raterId":   normalizedRaterID,
-    "actorId":   normalizedActorID,
-    "dimension": dimension,
-    "ratingId":  ratingID,
-    "timestamp": timestamp,
+// putRaterActorRecord records the rater's latest rating of the actor in the
+// rating's dimension
+func putRaterActorRecord(ctx contractapi.TransactionContextInterface, rating *Rating) error {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+	raterActorRecord := map[string]interface{}{
+		"raterId":     rating.RaterID,
+		"actorId":     rating.ActorID,
+		"dimension":   rating.Dimension,
+		"ratingId":    rating.RatingID,
+		"timestamp":   rating.Timestamp,
+		"submittedAt": now,
+	}
+	raterActorJSON, err := marshalCanonical(raterActorRecord)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rater/actor record: %v", err)
+	}
+
+	raterActorKey := raterActorStateKey(rating.RaterID, rating.ActorID, rating.Dimension)
+	if err := ctx.GetStub().PutState(raterActorKey, raterActorJSON); err != nil {
+		return fmt.Errorf("failed to store rater/actor record: %v", err)
+	}
+	return nil
 }
-raterActorJSON, _ := json.Marshal(raterActorRecord)
-ctx.GetStub().PutState(raterActorKey, raterActorJSON)
 
-// Update actor's reputation
-err = rc.updateReputation(ctx, &rating)
-	// Update actor's reputation
-	err = rc.updateReputation(ctx, &rating)
+// prefetchRatingState batch-reads the committed records submitRating
+// consults: config, bans, offboarding, profiles, the rater/actor pair record,
+// the rater's stake and metareputation, and the actor's reputation
+func prefetchRatingState(
+	ctx contractapi.TransactionContextInterface,
+	raterID string,
+	actorID string,
+	dimension string,
+) error {
+	config, err := getConfig(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to update reputation: %v", err)
+		return err
 	}
 
-	// Emit event
-	eventPayload := map[string]interface{}{
-		"ratingId":  ratingID,
-		"raterId":   normalizedRaterID,
-		"actorId":   normalizedActorID,
-		"dimension": dimension,
-		"value":     value,
-		"weight":    weight,
-		"timestamp": timestamp,
-	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("RatingSubmitted", eventJSON)
-
-	return ratingID, nil
+	keys := []string{
+		banStateKey(raterID),
+		offboardingStateKey(raterID),
+		offboardingStateKey(actorID),
+		profileStateKey(raterID),
+		profileStateKey(actorID),
+		membershipStateKey(raterID),
+		membershipStateKey(actorID),
+		directoryListingKey(actorID),
+		raterActorStateKey(raterID, actorID, dimension),
+		stakeStateKey(raterID),
+		legacyStakeKey(raterID),
+		reputationStateKey(actorID, dimension),
+		legacyReputationKey(actorID, dimension),
+	}
+	if metaDimension, exists := config.MetaDimensions[dimension]; exists {
+		keys = append(keys,
+			reputationStateKey(raterID, metaDimension),
+			legacyReputationKey(raterID, metaDimension))
+	}
+
+	return prefetchState(ctx, keys...)
 }
 
-// updateReputation updates the actor's Beta distribution parameters
-func (rc *ReputationContract) updateReputation(
+// updateReputation updates the actor's Beta distribution parameters. value
+// is passed separately as confidential ratings do not carry it.
+func updateReputation(
 	ctx contractapi.TransactionContextInterface,
 	rating *Rating,
+	value float64,
 ) error {
 	config, err := getConfig(ctx)
 	if err != nil {
@@ -519,43 +910,39 @@ func (rc *ReputationContract) updateReputation(
 	}
 
 	// Update Beta parameters with weighted rating
-	if rating.Value >= 0.5 {
-		rep.Alpha += rating.Weight * rating.Value
-	} else {
-		rep.Beta += rating.Weight * (1.0 - rating.Value)
+	alpha, beta := ratingEvidence(rating.Weight, value)
+	rep.Alpha += alpha
+	rep.Beta += beta
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
 	}
 
 	rep.TotalEvents++
-	rep.LastTs = time.Now().Unix()
+	rep.LastTs = now
 
 	// Store updated reputation
-	repKey := fmt.Sprintf("REPUTATION:%s:%s", rating.ActorID, rating.Dimension)
-	repJSON, err := json.Marshal(rep)
-	if err != nil {
-		return fmt.Errorf("failed to marshal reputation: %v", err)
-	}
-
-	err = ctx.GetStub().PutState(repKey, repJSON)
-	if err != nil {
-		return fmt.Errorf("failed to store reputation: %v", err)
+	if err := putReputation(ctx, rep); err != nil {
+		return err
 	}
 
 	// Emit event
-	score := rep.Alpha / (rep.Alpha + rep.Beta)
+	score := reputationScore(rep)
 	eventPayload := map[string]interface{}{
 		"actorId":     rating.ActorID,
 		"dimension":   rating.Dimension,
 		"newScore":    score,
 		"totalEvents": rep.TotalEvents,
+		"ratingId":    rating.RatingID,
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("ReputationUpdated", eventJSON)
+	emitEvent(ctx, "ReputationUpdated", eventPayload, reputationStateKey(rating.ActorID, rating.Dimension))
 
 	return nil
 }
 
 // calculateRaterWeight computes the rater's influence based on METAREPUTATION
-func (rc *ReputationContract) calculateRaterWeight(
+func calculateRaterWeight(
 	ctx contractapi.TransactionContextInterface,
 	raterID string,
 	baseDimension string,
@@ -577,14 +964,24 @@ func (rc *ReputationContract) calculateRaterWeight(
 		return config.MinRaterWeight, err
 	}
 
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	// Apply dynamic time decay
-	effectiveRep := applyDynamicDecay(rep, config)
+	effectiveRep := applyDynamicDecayAt(rep, config, now)
+
+	return raterWeight(effectiveRep, config), nil
+}
 
+// raterWeight computes a rater's weight from their decayed METAREPUTATION
+func raterWeight(effectiveRep *Reputation, config *SystemConfig) float64 {
 	// Calculate metareputation score
-	metaScore := effectiveRep.Alpha / (effectiveRep.Alpha + effectiveRep.Beta)
+	metaScore := reputationScore(effectiveRep)
 
 	// Calculate confidence factor
-	totalEvents := effectiveRep.Alpha + effectiveRep.Beta
+	totalEvents := (effectiveRep.Alpha + effectiveRep.Beta).Float()
 	confidenceFactor := 1.0 + math.Sqrt(totalEvents/(totalEvents+10.0))
 
 	// Calculate weight
@@ -598,7 +995,7 @@ func (rc *ReputationContract) calculateRaterWeight(
 		weight = config.MaxRaterWeight
 	}
 
-	return weight, nil
+	return weight
 }
 
 // ============================================================================
@@ -606,27 +1003,31 @@ func (rc *ReputationContract) calculateRaterWeight(
 // ============================================================================
 
 // InitiateDispute allows challenging a rating
-func (rc *ReputationContract) InitiateDispute(
+func (dc *DisputeContract) InitiateDispute(
 	ctx contractapi.TransactionContextInterface,
 	ratingID string,
 	reason string,
 ) (string, error) {
-	initiatorID, err := ctx.GetClientIdentity().GetID()
+	normalizedInitiatorID, err := callerIdentity(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get initiator ID: %v", err)
 	}
 
-	normalizedInitiatorID := normalizeIdentity(initiatorID)
+	banned, err := isBanned(ctx, normalizedInitiatorID)
+	if err != nil {
+		return "", err
+	}
+	if banned {
+		return "", fmt.Errorf("initiator is banned: %s", normalizedInitiatorID)
+	}
 
 	// Load rating
-	ratingJSON, err := ctx.GetStub().GetState(ratingID)
-	if err != nil || ratingJSON == nil {
-		return "", fmt.Errorf("rating not found: %s", ratingID)
+	rating, err := getRating(ctx, ratingID)
+	if err != nil {
+		return "", err
 	}
-
-	var rating Rating
-	if err := json.Unmarshal(ratingJSON, &rating); err != nil {
-		return "", fmt.Errorf("failed to unmarshal rating: %v", err)
+	if rating == nil {
+		return "", fmt.Errorf("rating not found: %s", ratingID)
 	}
 
 	// Check initiator is the rated actor
@@ -640,6 +1041,10 @@ func (rc *ReputationContract) InitiateDispute(
 		return "", err
 	}
 
+	if err := checkEligibility(ctx, config, "InitiateDispute"); err != nil {
+		return "", err
+	}
+
 	stake, err := getOrInitStake(ctx, normalizedInitiatorID)
 	if err != nil {
 		return "", err
@@ -649,147 +1054,250 @@ func (rc *ReputationContract) InitiateDispute(
 		return "", fmt.Errorf("insufficient stake for dispute: %f required", config.DisputeCost)
 	}
 
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	// Lock dispute cost
 	stake.Balance -= config.DisputeCost
 	stake.Locked += config.DisputeCost
-	stake.UpdatedAt = time.Now().Unix()
+	stake.UpdatedAt = now
 
-	stakeKey := fmt.Sprintf("STAKE:%s", normalizedInitiatorID)
-	stakeJSON, _ := json.Marshal(stake)
-	ctx.GetStub().PutState(stakeKey, stakeJSON)
+	if err := putStake(ctx, stake); err != nil {
+		return "", err
+	}
 
 	// Create dispute
-	disputeID := generateDisputeID(ratingID, normalizedInitiatorID, time.Now().Unix())
-	dispute := Dispute{
+	dispute, err := openDispute(ctx, rating, normalizedInitiatorID, reason, "")
+	if err != nil {
+		return "", err
+	}
+
+	emitStakeMovement(ctx, stakeLockedEvent, stake, config.DisputeCost, dispute.DisputeID)
+	emitDisputeInitiated(ctx, dispute)
+
+	return dispute.DisputeID, nil
+}
+
+// openDispute files a pending dispute of a rating. bondID names the bond a
+// claim is filed for, "" for a dispute whose cost the initiator locked.
+func openDispute(
+	ctx contractapi.TransactionContextInterface,
+	rating *Rating,
+	initiatorID string,
+	reason string,
+	bondID string,
+) (*Dispute, error) {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	disputeID := generateDisputeID(rating.RatingID, initiatorID, now)
+	correlateEvents(ctx, disputeID, rating.RatingID)
+	dispute := &Dispute{
 		DisputeID:   disputeID,
-		RatingID:    ratingID,
-		InitiatorID: normalizedInitiatorID,
+		RatingID:    rating.RatingID,
+		InitiatorID: initiatorID,
 		RaterID:     rating.RaterID,
 		ActorID:     rating.ActorID,
 		Dimension:   rating.Dimension,
 		Reason:      reason,
 		Status:      "pending",
-		CreatedAt:   time.Now().Unix(),
+		BondID:      bondID,
+		CreatedAt:   now,
+		Deadline:    now + config.DisputeTimeout,
 	}
-
-	disputeJSON, err := json.Marshal(dispute)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal dispute: %v", err)
+	if bondID == "" {
+		dispute.LockedCost = config.DisputeCost
 	}
 
-	err = ctx.GetStub().PutState(disputeID, disputeJSON)
-	if err != nil {
-		return "", fmt.Errorf("failed to store dispute: %v", err)
+	if err := putDispute(ctx, dispute, ""); err != nil {
+		return nil, err
+	}
+	if err := updateMetrics(ctx, func(m *MetricsDay) { m.DisputesOpened++ }); err != nil {
+		return nil, err
 	}
 
-	// Emit event
+	return dispute, nil
+}
+
+// emitDisputeInitiated emits the DisputeInitiated event of a new dispute
+func emitDisputeInitiated(ctx contractapi.TransactionContextInterface, dispute *Dispute) {
 	eventPayload := map[string]interface{}{
-		"disputeId":   disputeID,
-		"ratingId":    ratingID,
-		"initiatorId": normalizedInitiatorID,
-		"reason":      reason,
+		"disputeId":   dispute.DisputeID,
+		"ratingId":    dispute.RatingID,
+		"initiatorId": dispute.InitiatorID,
+		"reason":      dispute.Reason,
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("DisputeInitiated", eventJSON)
-
-	return disputeID, nil
+	if dispute.BondID != "" {
+		eventPayload["bondId"] = dispute.BondID
+	}
+	emitEvent(ctx, "DisputeInitiated", eventPayload)
 }
 
 // ResolveDispute allows arbitrator to resolve a dispute
-func (rc *ReputationContract) ResolveDispute(
+func (dc *DisputeContract) ResolveDispute(
 	ctx contractapi.TransactionContextInterface,
 	disputeID string,
 	verdict string,
 	arbitratorNotes string,
 ) error {
-	// Validate verdict
-	if verdict != "upheld" && verdict != "overturned" {
-		return fmt.Errorf("verdict must be 'upheld' or 'overturned'")
-	}
-
 	// Check arbitrator role
 	if !isArbitrator(ctx) {
 		return fmt.Errorf("unauthorized: arbitrator role required")
 	}
 
 	// Load dispute
-	disputeJSON, err := ctx.GetStub().GetState(disputeID)
-	if err != nil || disputeJSON == nil {
-		return fmt.Errorf("dispute not found: %s", disputeID)
+	dispute, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return err
 	}
-
-	var dispute Dispute
-	if err := json.Unmarshal(disputeJSON, &dispute); err != nil {
-		return fmt.Errorf("failed to unmarshal dispute: %v", err)
+	if dispute == nil {
+		return fmt.Errorf("dispute not found: %s", disputeID)
 	}
 
 	if dispute.Status != "pending" {
 		return fmt.Errorf("dispute already resolved")
 	}
+	correlateEvents(ctx, dispute.DisputeID, dispute.RatingID)
 
 	// Get arbitrator ID
-	arbitratorID, _ := ctx.GetClientIdentity().GetID()
-	normalizedArbitratorID := normalizeIdentity(arbitratorID)
+	normalizedArbitratorID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get arbitrator ID: %v", err)
+	}
+	if arbitratorConflicted(dispute, normalizedArbitratorID) {
+		return fmt.Errorf("conflict of interest: arbitrator is a party to dispute %s", disputeID)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config.ArbitrationPanelSize > 0 {
+		return fmt.Errorf("disputes are decided by arbitration panels: use CastVerdict")
+	}
+
+	return resolveDispute(ctx, dispute, verdict, normalizedArbitratorID, arbitratorNotes)
+}
+
+// resolveDispute applies a verdict to a pending dispute: it updates the
+// rater's metareputation, reverses and slashes an overturned rating, and
+// settles the initiator's dispute cost or claimed bond
+func resolveDispute(
+	ctx contractapi.TransactionContextInterface,
+	dispute *Dispute,
+	verdict string,
+	arbitratorID string,
+	arbitratorNotes string,
+) error {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
 
 	// Update dispute record
 	dispute.Status = verdict
-	dispute.ArbitratorID = normalizedArbitratorID
+	dispute.ArbitratorID = arbitratorID
 	dispute.ArbitratorNotes = arbitratorNotes
-	dispute.ResolvedAt = time.Now().Unix()
+	dispute.ResolvedAt = now
 
 	// Determine if rater was correct
 	raterWasCorrect := (verdict == "upheld")
 
 	// Update METAREPUTATION
-	err = rc.updateMetaReputation(ctx, dispute.RaterID, dispute.Dimension, raterWasCorrect)
+	err = updateMetaReputation(ctx, dispute.RaterID, dispute.Dimension, raterWasCorrect)
 	if err != nil {
 		return fmt.Errorf("failed to update metareputation: %v", err)
 	}
 
-	// If overturned, reverse the rating's effect
+	// If overturned, reverse the rating's effect, or withhold it if it
+	// is still in its challenge window
 	if verdict == "overturned" {
-		err = rc.reverseRating(ctx, dispute.RatingID)
+		withheld, err := withholdPendingRating(ctx, dispute.RatingID)
 		if err != nil {
-			return fmt.Errorf("failed to reverse rating: %v", err)
+			return err
+		}
+		if !withheld {
+			if err := reverseRating(ctx, dispute.RatingID); err != nil {
+				return fmt.Errorf("failed to reverse rating: %v", err)
+			}
 		}
 
 		// Slash rater's stake
-		err = rc.slashStake(ctx, dispute.RaterID)
+		treasuryAmount, err := slashStake(ctx, dispute.RaterID, dispute.InitiatorID)
 		if err != nil {
 			return fmt.Errorf("failed to slash stake: %v", err)
 		}
+
+		// Compensate the rated actor out of the treasury's share
+		config, err := getConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if err := settleDispute(ctx, config, dispute, treasuryAmount); err != nil {
+			return err
+		}
 	}
 
-	// Return dispute cost to initiator
-	config, _ := getConfig(ctx)
-	stake, _ := getOrInitStake(ctx, dispute.InitiatorID)
-	stake.Locked -= config.DisputeCost
-	stake.Balance += config.DisputeCost
-	stake.UpdatedAt = time.Now().Unix()
+	// Return dispute cost to initiator, or settle the bond a claim was filed
+	// for: an upheld claim forfeits it
+	stake, err := getOrInitStake(ctx, dispute.InitiatorID)
+	if err != nil {
+		return err
+	}
+	if dispute.BondID != "" {
+		if err := settleClaimedBond(ctx, dispute, stake, raterWasCorrect); err != nil {
+			return err
+		}
+	} else {
+		stake.Locked -= dispute.LockedCost
+		stake.Balance += dispute.LockedCost
+	}
+	stake.UpdatedAt = now
 
-	stakeKey := fmt.Sprintf("STAKE:%s", dispute.InitiatorID)
-	stakeJSON, _ := json.Marshal(stake)
-	ctx.GetStub().PutState(stakeKey, stakeJSON)
+	if err := putStake(ctx, stake); err != nil {
+		return err
+	}
+	if dispute.BondID == "" {
+		emitStakeMovement(ctx, stakeRefundedEvent, stake, dispute.LockedCost, dispute.DisputeID)
+	}
 
 	// Store updated dispute
-	updatedDisputeJSON, _ := json.Marshal(dispute)
-	ctx.GetStub().PutState(disputeID, updatedDisputeJSON)
+	if err := putDispute(ctx, dispute, "pending"); err != nil {
+		return err
+	}
+	err = updateMetrics(ctx, func(m *MetricsDay) {
+		m.DisputesResolved++
+		if verdict == "overturned" {
+			m.DisputesOverturned++
+		}
+	})
+	if err != nil {
+		return err
+	}
 
 	// Emit event
 	eventPayload := map[string]interface{}{
-		"disputeId":       disputeID,
+		"disputeId":       dispute.DisputeID,
 		"verdict":         verdict,
 		"raterWasCorrect": raterWasCorrect,
 		"dimension":       dispute.Dimension,
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("DisputeResolved", eventJSON)
+	emitEvent(ctx, "DisputeResolved", eventPayload)
 
 	return nil
 }
 
 // updateMetaReputation updates rater's ability to rate others
-func (rc *ReputationContract) updateMetaReputation(
+func updateMetaReputation(
 	ctx contractapi.TransactionContextInterface,
 	raterID string,
 	baseDimension string,
@@ -814,42 +1322,45 @@ func (rc *ReputationContract) updateMetaReputation(
 
 	// Update based on dispute outcome
 	if wasCorrect {
-		rep.Alpha += 1.0 // Rater was right
+		rep.Alpha += fixedOne // Rater was right
 	} else {
-		rep.Beta += 1.0 // Rater was wrong
+		rep.Beta += fixedOne // Rater was wrong
 	}
 
-	rep.LastTs = time.Now().Unix()
-	rep.TotalEvents++
-
-	// Store updated metareputation
-	repKey := fmt.Sprintf("REPUTATION:%s:%s", raterID, metaDimension)
-	repJSON, err := json.Marshal(rep)
+	now, err := txUnixTime(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metareputation: %v", err)
+		return err
 	}
 
-	return ctx.GetStub().PutState(repKey, repJSON)
+	rep.LastTs = now
+	rep.TotalEvents++
+
+	// Store updated metareputation
+	return putReputation(ctx, rep)
 }
 
 // reverseRating undoes the effect of an overturned rating
-func (rc *ReputationContract) reverseRating(
+func reverseRating(
 	ctx contractapi.TransactionContextInterface,
 	ratingID string,
 ) error {
 	// Load rating
-	ratingJSON, err := ctx.GetStub().GetState(ratingID)
-	if err != nil || ratingJSON == nil {
-		return fmt.Errorf("rating not found: %s", ratingID)
+	rating, err := getRating(ctx, ratingID)
+	if err != nil {
+		return err
 	}
-
-	var rating Rating
-	if err := json.Unmarshal(ratingJSON, &rating); err != nil {
-		return fmt.Errorf("failed to unmarshal rating: %v", err)
+	if rating == nil {
+		return fmt.Errorf("rating not found: %s", ratingID)
 	}
 
 	config, _ := getConfig(ctx)
 
+	// Confidential values are read from the endorsing organization's collection
+	value, err := ratingValue(ctx, rating)
+	if err != nil {
+		return err
+	}
+
 	// Load actor's reputation
 	rep, err := getOrInitReputation(ctx, rating.ActorID, rating.Dimension, config)
 	if err != nil {
@@ -857,72 +1368,118 @@ func (rc *ReputationContract) reverseRating(
 	}
 
 	// Reverse the effect
-	if rating.Value >= 0.5 {
-		rep.Alpha -= rating.Weight * rating.Value
-	} else {
-		rep.Beta -= rating.Weight * (1.0 - rating.Value)
-	}
+	alpha, beta := ratingEvidence(rating.Weight, value)
+	rep.Alpha -= alpha
+	rep.Beta -= beta
 
 	// Ensure non-negative
-	if rep.Alpha < config.InitialAlpha {
-		rep.Alpha = config.InitialAlpha
+	if initialAlpha := fixedFromFloat(config.InitialAlpha); rep.Alpha < initialAlpha {
+		rep.Alpha = initialAlpha
 	}
-	if rep.Beta < config.InitialBeta {
-		rep.Beta = config.InitialBeta
+	if initialBeta := fixedFromFloat(config.InitialBeta); rep.Beta < initialBeta {
+		rep.Beta = initialBeta
 	}
 
 	rep.TotalEvents--
 
 	// Store updated reputation
-	repKey := fmt.Sprintf("REPUTATION:%s:%s", rating.ActorID, rating.Dimension)
-	repJSON, err := json.Marshal(rep)
-	if err != nil {
-		return fmt.Errorf("failed to marshal reputation: %v", err)
-	}
-
-	return ctx.GetStub().PutState(repKey, repJSON)
+	return putReputation(ctx, rep)
 }
 
-// slashStake penalizes rater for false rating
-func (rc *ReputationContract) slashStake(
+// slashStake penalizes rater for false rating. SlashPercentage is taken from
+// the free balance and from every withdrawal still unbonding, so stake
+// withdrawn after a bad rating remains answerable for it. SlashInitiatorShare
+// of the slash compensates the initiator of the dispute, credited to its
+// stake balance, and the rest flows into the treasury; the treasury's amount
+// is returned.
+func slashStake(
 	ctx contractapi.TransactionContextInterface,
 	raterID string,
-) error {
+	initiatorID string,
+) (float64, error) {
 	config, err := getConfig(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	stake, err := getOrInitStake(ctx, raterID)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return 0, err
 	}
 
 	slashAmount := stake.Balance * config.SlashPercentage
 	stake.Balance -= slashAmount
-	stake.UpdatedAt = time.Now().Unix()
 
-	stakeKey := fmt.Sprintf("STAKE:%s", raterID)
-	stakeJSON, err := json.Marshal(stake)
+	withdrawals, err := unbondingWithdrawals(ctx, raterID)
 	if err != nil {
-		return fmt.Errorf("failed to marshal stake: %v", err)
+		return 0, err
+	}
+	withdrawalSlash := 0.0
+	for _, withdrawal := range withdrawals {
+		cut := withdrawal.Amount * config.SlashPercentage
+		withdrawal.Amount -= cut
+		if err := putStakeWithdrawal(ctx, withdrawal); err != nil {
+			return 0, err
+		}
+		withdrawalSlash += cut
 	}
+	stake.Locked -= withdrawalSlash
+	slashAmount += withdrawalSlash
+	stake.UpdatedAt = now
 
-	err = ctx.GetStub().PutState(stakeKey, stakeJSON)
+	stakeKey := stakeStateKey(raterID)
+	if err := putStake(ctx, stake); err != nil {
+		return 0, err
+	}
+
+	// The initiator's share goes straight to its balance
+	initiatorShare := slashAmount * config.SlashInitiatorShare
+	if initiatorShare > 0 {
+		initiatorStake, err := getOrInitStake(ctx, initiatorID)
+		if err != nil {
+			return 0, err
+		}
+		initiatorStake.Balance += initiatorShare
+		initiatorStake.UpdatedAt = now
+		if err := putStake(ctx, initiatorStake); err != nil {
+			return 0, err
+		}
+	}
+
+	// The rest of the slashed funds flow into the treasury
+	treasuryShare := slashAmount - initiatorShare
+	err = recordTreasuryInflow(ctx, treasuryShare, raterID, "stake slash", stakeKey)
 	if err != nil {
-		return fmt.Errorf("failed to store stake: %v", err)
+		return 0, err
+	}
+	if err := updateMetrics(ctx, func(m *MetricsDay) { m.SlashedAmount += slashAmount }); err != nil {
+		return 0, err
 	}
 
-	// Emit event
-	eventPayload := map[string]interface{}{
-		"raterId":     raterID,
-		"slashAmount": slashAmount,
-		"newBalance":  stake.Balance,
+	// Whoever vouched for the rater shares the blame
+	if err := penalizeVouchers(ctx, raterID, "stake slash"); err != nil {
+		return 0, err
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("StakeSlashed", eventJSON)
 
-	return nil
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"raterId":         raterID,
+		"slashAmount":     slashAmount,
+		"withdrawalSlash": withdrawalSlash,
+		"newBalance":      stake.Balance,
+		"newLocked":       stake.Locked,
+		"initiatorId":     initiatorID,
+		"initiatorShare":  initiatorShare,
+		"treasuryShare":   treasuryShare,
+	}
+	emitEvent(ctx, "StakeSlashed", eventPayload, stakeKey, stakeStateKey(initiatorID))
+
+	return treasuryShare, nil
 }
 
 // ============================================================================
@@ -944,7 +1501,7 @@ func (rc *ReputationContract) GetReputation(
 		return nil, fmt.Errorf("invalid dimension: %s", dimension)
 	}
 
-	normalizedActorID := normalizeIdentity(actorID)
+	normalizedActorID := resolveIdentity(ctx, actorID)
 
 	// Load reputation
 	rep, err := getOrInitReputation(ctx, normalizedActorID, dimension, config)
@@ -952,148 +1509,395 @@ func (rc *ReputationContract) GetReputation(
 		return nil, err
 	}
 
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Apply dynamic decay
-	effectiveRep := applyDynamicDecay(rep, config)
+	effectiveRep := applyDynamicDecayAt(rep, config, now)
 
 	// Calculate score
-	score := effectiveRep.Alpha / (effectiveRep.Alpha + effectiveRep.Beta)
+	score := reputationScore(effectiveRep)
 
 	// Calculate Wilson confidence interval
-	ci := calculateWilsonCI(effectiveRep.Alpha, effectiveRep.Beta, 0.95)
+	ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+
+	suspended, err := isBanned(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := isVerified(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	archived, err := isDeactivated(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
 
 	result := map[string]interface{}{
 		"actorId":     normalizedActorID,
 		"dimension":   dimension,
 		"score":       score,
-		"alpha":       effectiveRep.Alpha,
-		"beta":        effectiveRep.Beta,
+		"alpha":       effectiveRep.Alpha.Float(),
+		"beta":        effectiveRep.Beta.Float(),
 		"ci_lower":    ci[0],
 		"ci_upper":    ci[1],
 		"totalEvents": rep.TotalEvents,
 		"lastUpdated": rep.LastTs,
+		"suspended":   suspended,
+		"verified":    verified,
+		"archived":    archived,
+	}
+
+	return result, nil
+}
+
+// GetReputationAllDimensions retrieves the decayed score and confidence
+// interval of every dimension (including meta-dimensions) an actor has a
+// reputation record in
+func (rc *ReputationContract) GetReputationAllDimensions(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (map[string]DimensionScore, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	// Namespaced records take precedence over legacy ones
+	prefixes := []string{namespacedKey("REPUTATION", normalizedActorID) + ":"}
+	if legacy := legacyReputationKey(normalizedActorID, ""); legacy != prefixes[0] {
+		prefixes = append(prefixes, legacy)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]DimensionScore)
+	for _, prefix := range prefixes {
+		resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reputation: %v", err)
+		}
+
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+
+			var rep Reputation
+			if err := json.Unmarshal(queryResponse.Value, &rep); err != nil {
+				continue
+			}
+			if rep.ActorID != normalizedActorID {
+				continue
+			}
+			if _, seen := scores[rep.Dimension]; seen {
+				continue
+			}
+
+			effectiveRep := applyDynamicDecayAt(&rep, config, now)
+			ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+
+			scores[rep.Dimension] = DimensionScore{
+				Score:       reputationScore(effectiveRep),
+				CILower:     ci[0],
+				CIUpper:     ci[1],
+				TotalEvents: rep.TotalEvents,
+				LastUpdated: rep.LastTs,
+			}
+		}
+		resultsIterator.Close()
+	}
+
+	return scores, nil
+}
+
+// maxBulkActors bounds the number of actors in one GetReputationsBulk call
+const maxBulkActors = 100
+
+// GetReputationsBulk retrieves the decayed score and confidence interval of
+// up to maxBulkActors actors in one dimension, keyed by normalized actor ID.
+// Actors without a record report the prior.
+func (rc *ReputationContract) GetReputationsBulk(
+	ctx contractapi.TransactionContextInterface,
+	actorIDs []string,
+	dimension string,
+) (map[string]DimensionScore, error) {
+	if len(actorIDs) == 0 {
+		return nil, fmt.Errorf("at least one actor ID is required")
+	}
+	if len(actorIDs) > maxBulkActors {
+		return nil, fmt.Errorf("at most %d actors per call", maxBulkActors)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.ValidDimensions[dimension] {
+		return nil, fmt.Errorf("invalid dimension: %s", dimension)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]DimensionScore)
+	for _, actorID := range actorIDs {
+		normalizedActorID := resolveIdentity(ctx, actorID)
+		if _, seen := scores[normalizedActorID]; seen {
+			continue
+		}
+
+		rep, err := getOrInitReputation(ctx, normalizedActorID, dimension, config)
+		if err != nil {
+			return nil, err
+		}
+
+		effectiveRep := applyDynamicDecayAt(rep, config, now)
+		ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+
+		scores[normalizedActorID] = DimensionScore{
+			Score:       reputationScore(effectiveRep),
+			CILower:     ci[0],
+			CIUpper:     ci[1],
+			TotalEvents: rep.TotalEvents,
+			LastUpdated: rep.LastTs,
+		}
+	}
+
+	return scores, nil
+}
+
+// GetRatingHistory retrieves a page of ratings for an actor in the given
+// order (field:direction over timestamp, value or weight; "" for newest
+// first), optionally bounded by rating value and weight (empty bounds are
+// ignored). Bounds are applied after paging, so a page may hold fewer than
+// pageSize ratings while more remain.
+func (rtc *RatingContract) GetRatingHistory(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+	minValueStr string,
+	maxValueStr string,
+	minWeightStr string,
+	maxWeightStr string,
+	sort string,
+	bookmark string,
+	pageSize int,
+) (*RatingPage, error) {
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseRatingFilter(minValueStr, maxValueStr, minWeightStr, maxWeightStr)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := parseRatingOrder(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	if order == orderTimestampDesc {
+		return indexedRatingsPage(ctx, ratingByActorIndex, []string{normalizedActorID, dimension}, bookmark, pageSize, filter.matches)
 	}
 
-	return result, nil
+	page, err := indexedRatingsPage(ctx, ratingByActorOrderIndex, []string{normalizedActorID, dimension, order}, bookmark, pageSize, filter.matches)
+	if err != nil {
+		return nil, err
+	}
+
+	// Counts are kept once per rating, under the default index
+	page.TotalCount, err = getIndexCount(ctx, ratingByActorIndex, []string{normalizedActorID, dimension})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
 }
 
-// GetRatingHistory retrieves all ratings for an actor
-func (rc *ReputationContract) GetRatingHistory(
+// GetPairHistory lists every rating a rater has given an actor, in one
+// dimension or, with an empty dimension, across all dimensions. Ratings are
+// grouped by dimension and newest first within each.
+func (rtc *RatingContract) GetPairHistory(
 	ctx contractapi.TransactionContextInterface,
+	raterID string,
 	actorID string,
 	dimension string,
 ) ([]Rating, error) {
-	normalizedActorID := normalizeIdentity(actorID)
-
-	// Construct CouchDB query
-	query := fmt.Sprintf(`{
-		"selector": {
-			"actorId": "%s",
-			"dimension": "%s"
-		},
-		"sort": [{"timestamp": "desc"}],
-		"limit": 100
-	}`, normalizedActorID, dimension)
-
-	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %v", err)
+	attributes := []string{resolveIdentity(ctx, raterID), resolveIdentity(ctx, actorID)}
+	if dimension != "" {
+		attributes = append(attributes, dimension)
 	}
-	defer resultsIterator.Close()
 
-	var ratings []Rating
-	for resultsIterator.HasNext() {
-		queryResponse, err := resultsIterator.Next()
-		if err != nil {
-			return nil, err
-		}
-
-		var rating Rating
-		if err := json.Unmarshal(queryResponse.Value, &rating); err != nil {
-			continue
-		}
-		ratings = append(ratings, rating)
+	ratings := []Rating{}
+	err := forEachIndexedRating(ctx, raterActorIndex, attributes, func(rating *Rating) (bool, error) {
+		ratings = append(ratings, *rating)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return ratings, nil
 }
 
-// GetDisputesByStatus retrieves disputes by status
-func (rc *ReputationContract) GetDisputesByStatus(
+// GetDisputesByStatus retrieves a page of disputes by status, newest first
+func (dc *DisputeContract) GetDisputesByStatus(
 	ctx contractapi.TransactionContextInterface,
 	status string,
-) ([]Dispute, error) {
-	query := fmt.Sprintf(`{
-		"selector": {
-			"status": "%s"
-		},
-		"sort": [{"createdAt": "desc"}],
-		"limit": 100
-	}`, status)
+	bookmark string,
+	pageSize int,
+) (*DisputePage, error) {
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDisputeStatus(status); err != nil {
+		return nil, err
+	}
 
-	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		disputeByStatusIndex, []string{status}, int32(pageSize), bookmark)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %v", err)
 	}
 	defer resultsIterator.Close()
 
-	var disputes []Dispute
+	totalCount, err := getIndexCount(ctx, disputeByStatusIndex, []string{status})
+	if err != nil {
+		return nil, err
+	}
+
+	disputes := []Dispute{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		var dispute Dispute
-		if err := json.Unmarshal(queryResponse.Value, &dispute); err != nil {
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 3 {
 			continue
 		}
-		disputes = append(disputes, dispute)
+
+		dispute, err := getDispute(ctx, parts[2])
+		if err != nil || dispute == nil {
+			continue
+		}
+		disputes = append(disputes, *dispute)
 	}
 
-	return disputes, nil
+	return &DisputePage{
+		Disputes:     disputes,
+		Bookmark:     metadata.Bookmark,
+		FetchedCount: metadata.FetchedRecordsCount,
+		TotalCount:   totalCount,
+	}, nil
 }
 
-// GetActorsByDimension retrieves actors with reputation above threshold
+// GetActorsByDimension retrieves a page of actors with reputation above
+// threshold. The score filter is applied after paging, so a page may hold
+// fewer than pageSize actors while more remain.
 func (rc *ReputationContract) GetActorsByDimension(
 	ctx contractapi.TransactionContextInterface,
 	dimension string,
 	minScoreStr string,
-) ([]map[string]interface{}, error) {
+	bookmark string,
+	pageSize int,
+) (*ActorScorePage, error) {
 	minScore, err := strconv.ParseFloat(minScoreStr, 64)
 	if err != nil {
 		return nil, fmt.Errorf("invalid minScore: %v", err)
 	}
+	pageSize, err = resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
 
-	query := fmt.Sprintf(`{
-		"selector": {
-			"dimension": "%s"
-		},
-		"limit": 1000
-	}`, dimension)
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
 
-	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		reputationByDimensionIndex, []string{dimension}, int32(pageSize), bookmark)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %v", err)
 	}
 	defer resultsIterator.Close()
 
-	config, _ := getConfig(ctx)
+	showUnlisted := canSeeUnlisted(ctx)
+
+	stats, err := getDimensionStats(ctx, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	var results []map[string]interface{}
+	results := []map[string]interface{}{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			continue
 		}
 
-		var rep Reputation
-		if err := json.Unmarshal(queryResponse.Value, &rep); err != nil {
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			continue
+		}
+
+		rep, err := getOrInitReputation(ctx, parts[1], dimension, config)
+		if err != nil {
 			continue
 		}
 
+		if !showUnlisted {
+			if listed, _ := isListed(ctx, rep.ActorID); !listed {
+				continue
+			}
+		}
+
 		// Apply dynamic decay and calculate score
-		effectiveRep := applyDynamicDecay(&rep, config)
-		score := effectiveRep.Alpha / (effectiveRep.Alpha + effectiveRep.Beta)
+		effectiveRep := applyDynamicDecayAt(rep, config, now)
+		score := reputationScore(effectiveRep)
+
+		suspended, _ := isBanned(ctx, rep.ActorID)
+		verified, _ := isVerified(ctx, rep.ActorID)
+		archived, _ := isDeactivated(ctx, rep.ActorID)
 
 		// Filter by minimum score
 		if score >= minScore {
@@ -1101,91 +1905,115 @@ func (rc *ReputationContract) GetActorsByDimension(
 				"actorId":   rep.ActorID,
 				"dimension": rep.Dimension,
 				"score":     score,
+				"suspended": suspended,
+				"verified":  verified,
+				"archived":  archived,
 			})
 		}
 	}
 
-	return results, nil
+	return &ActorScorePage{
+		Actors:       results,
+		Bookmark:     metadata.Bookmark,
+		FetchedCount: metadata.FetchedRecordsCount,
+		TotalCount:   stats.ActorCount,
+	}, nil
 }
 
-// GetRatingsByRater retrieves all ratings submitted by a rater
-func (rc *ReputationContract) GetRatingsByRater(
+// ListReputations pages through every stored reputation record in key
+// order, without decay, for reconciliation against off-chain copies
+func (rc *ReputationContract) ListReputations(
 	ctx contractapi.TransactionContextInterface,
-	raterID string,
-) ([]Rating, error) {
-	normalizedRaterID := normalizeIdentity(raterID)
-
-	query := fmt.Sprintf(`{
-		"selector": {
-			"raterId": "%s"
-		},
-		"sort": [{"timestamp": "desc"}],
-		"limit": 100
-	}`, normalizedRaterID)
+	bookmark string,
+	pageSize int,
+) (*ReputationPage, error) {
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
 
-	resultsIterator, err := ctx.GetStub().GetQueryResult(query)
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(
+		"REPUTATION:", "REPUTATION;", int32(pageSize), bookmark)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %v", err)
+		return nil, fmt.Errorf("failed to read reputations: %v", err)
 	}
 	defer resultsIterator.Close()
 
-	var ratings []Rating
+	totalCount, err := reputationRecordCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reputations := []Reputation{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
 			return nil, err
 		}
 
-		var rating Rating
-		if err := json.Unmarshal(queryResponse.Value, &rating); err != nil {
+		var rep Reputation
+		if err := json.Unmarshal(queryResponse.Value, &rep); err != nil {
 			continue
 		}
-		ratings = append(ratings, rating)
+		reputations = append(reputations, rep)
 	}
 
-	return ratings, nil
+	return &ReputationPage{
+		Reputations:  reputations,
+		Bookmark:     metadata.Bookmark,
+		FetchedCount: metadata.FetchedRecordsCount,
+		TotalCount:   totalCount,
+	}, nil
+}
+
+// GetRatingsByRater retrieves a page of ratings submitted by a rater,
+// newest first
+func (rtc *RatingContract) GetRatingsByRater(
+	ctx contractapi.TransactionContextInterface,
+	raterID string,
+	bookmark string,
+	pageSize int,
+) (*RatingPage, error) {
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedRaterID := resolveIdentity(ctx, raterID)
+
+	return indexedRatingsPage(ctx, ratingByRaterIndex, []string{normalizedRaterID}, bookmark, pageSize, nil)
 }
 
 // GetDispute retrieves a specific dispute
-func (rc *ReputationContract) GetDispute(
+func (dc *DisputeContract) GetDispute(
 	ctx contractapi.TransactionContextInterface,
 	disputeID string,
 ) (*Dispute, error) {
-	disputeJSON, err := ctx.GetStub().GetState(disputeID)
+	dispute, err := getDispute(ctx, disputeID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read dispute: %v", err)
+		return nil, err
 	}
-	if disputeJSON == nil {
+	if dispute == nil {
 		return nil, fmt.Errorf("dispute not found: %s", disputeID)
 	}
 
-	var dispute Dispute
-	if err := json.Unmarshal(disputeJSON, &dispute); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal dispute: %v", err)
-	}
-
-	return &dispute, nil
+	return dispute, nil
 }
 
 // GetRating retrieves a specific rating
-func (rc *ReputationContract) GetRating(
+func (rtc *RatingContract) GetRating(
 	ctx contractapi.TransactionContextInterface,
 	ratingID string,
 ) (*Rating, error) {
-	ratingJSON, err := ctx.GetStub().GetState(ratingID)
+	rating, err := getRating(ctx, ratingID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read rating: %v", err)
+		return nil, err
 	}
-	if ratingJSON == nil {
+	if rating == nil {
 		return nil, fmt.Errorf("rating not found: %s", ratingID)
 	}
 
-	var rating Rating
-	if err := json.Unmarshal(ratingJSON, &rating); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal rating: %v", err)
-	}
-
-	return &rating, nil
+	return rating, nil
 }
 
 // ============================================================================
@@ -1224,53 +2052,87 @@ func normalizeIdentity(identity string) string {
 	// If no x509 format, just return lowercase
 	return strings.ToLower(identity)
 }
+
+// defaultConfig returns the bootstrap system configuration
+func defaultConfig() *SystemConfig {
+	return &SystemConfig{
+		MinStakeRequired: 10000.0,
+		DisputeCost:      100.0,
+		SlashPercentage:  0.1,
+
+		DecayRate:      0.98,
+		DecayPeriod:    86400.0, // 1 day in seconds
+		InitialAlpha:   2.0,
+		InitialBeta:    2.0,
+		MinRaterWeight: 0.1,
+		MaxRaterWeight: 5.0,
+
+		ServiceMaxRaterWeight: 1.0,
+
+		ProbationEvents:         10,
+		ProbationMaxRaterWeight: 0.5,
+
+		TreasuryApprovals:   2,
+		SlashInitiatorShare: 0.5,
+		BanApprovals:        2,
+
+		AttestationWeight: 1.0,
+
+		VouchMinMetaScore: 0.7,
+		VouchPriorWeight:  0.5,
+		VouchPenalty:      2.0,
+
+		OffboardingWindow: 30 * 86400, // 30 days
+
+		UnbondingPeriod: 14 * 86400, // 14 days
+
+		DisputeTimeout: 30 * 86400, // 30 days
+
+		RatingCooldown: 86400, // 24 hours
+
+		IdentityMode:       identityModeCN,
+		TrustedCAApprovals: 2,
+
+		FraudWindow:          7 * 86400, // 7 days
+		FraudBurstRatings:    5,
+		FraudReciprocalScore: 0.8,
+		FraudThresholdMargin: 0.05,
+
+		ArchiveAge: 730 * 86400, // 2 years
+
+		DefaultPageSize: 100,
+		MaxPageSize:     1000,
+
+		ValidDimensions: map[string]bool{
+			"quality":    true,
+			"delivery":   true,
+			"compliance": true,
+			"warranty":   true,
+		},
+		MetaDimensions: map[string]string{
+			"quality":    "rating_quality",
+			"delivery":   "rating_delivery",
+			"compliance": "rating_compliance",
+			"warranty":   "rating_warranty",
+		},
+
+		Version: 1,
+	}
+}
+
 // getConfig retrieves system configuration
 // getConfig retrieves system configuration, initializing if needed
 func getConfig(ctx contractapi.TransactionContextInterface) (*SystemConfig, error) {
-	configJSON, err := ctx.GetStub().GetState("SYSTEM_CONFIG")
+	configJSON, err := cachedConfigJSON(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config: %v", err)
 	}
 
-	// AUTO-INITIALIZE if config doesn't exist
+	// Until InitConfig runs the defaults apply. They are not stored here:
+	// every transaction reads the config, so a read that also wrote it would
+	// conflict with all of them.
 	if configJSON == nil {
-		config := SystemConfig{
-			MinStakeRequired: 10000.0,
-			DisputeCost:      100.0,
-			SlashPercentage:  0.1,
-			DecayRate:        0.98,
-			DecayPeriod:      86400.0,
-			InitialAlpha:     2.0,
-			InitialBeta:      2.0,
-			MinRaterWeight:   0.1,
-			MaxRaterWeight:   5.0,
-			ValidDimensions: map[string]bool{
-				"quality":    true,
-				"delivery":   true,
-				"compliance": true,
-				"warranty":   true,
-			},
-			MetaDimensions: map[string]string{
-				"quality":    "rating_quality",
-				"delivery":   "rating_delivery",
-				"compliance": "rating_compliance",
-				"warranty":   "rating_warranty",
-			},
-			Version:     1,
-			LastUpdated: time.Now().Unix(),
-		}
-
-		configJSON, err = json.Marshal(config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal config: %v", err)
-		}
-
-		err = ctx.GetStub().PutState("SYSTEM_CONFIG", configJSON)
-		if err != nil {
-			return nil, fmt.Errorf("failed to auto-initialize config: %v", err)
-		}
-
-		return &config, nil
+		return defaultConfig(), nil
 	}
 
 	var config SystemConfig
@@ -1278,6 +2140,88 @@ func getConfig(ctx contractapi.TransactionContextInterface) (*SystemConfig, erro
 		return nil, fmt.Errorf("failed to unmarshal config: %v", err)
 	}
 
+	// Backfill parameters added after the config was first stored. Those
+	// for which zero is a valid setting are only backfilled when the stored
+	// config lacks them, so a zero set on purpose is kept.
+	if config.ServiceMaxRaterWeight == 0 {
+		config.ServiceMaxRaterWeight = defaultConfig().ServiceMaxRaterWeight
+	}
+	if config.ProbationEvents == 0 {
+		config.ProbationEvents = defaultConfig().ProbationEvents
+	}
+	if config.ProbationMaxRaterWeight == 0 {
+		config.ProbationMaxRaterWeight = defaultConfig().ProbationMaxRaterWeight
+	}
+	if config.TreasuryApprovals == 0 {
+		config.TreasuryApprovals = defaultConfig().TreasuryApprovals
+	}
+	if config.BanApprovals == 0 {
+		config.BanApprovals = defaultConfig().BanApprovals
+	}
+	if config.AttestationWeight == 0 {
+		config.AttestationWeight = defaultConfig().AttestationWeight
+	}
+	if config.VouchMinMetaScore == 0 {
+		config.VouchMinMetaScore = defaultConfig().VouchMinMetaScore
+	}
+	if config.VouchPriorWeight == 0 && !storedJSONHasKey(configJSON, "vouchPriorWeight") {
+		config.VouchPriorWeight = defaultConfig().VouchPriorWeight
+	}
+	if config.VouchPenalty == 0 && !storedJSONHasKey(configJSON, "vouchPenalty") {
+		config.VouchPenalty = defaultConfig().VouchPenalty
+	}
+	if config.OffboardingWindow == 0 && !storedJSONHasKey(configJSON, "offboardingWindow") {
+		config.OffboardingWindow = defaultConfig().OffboardingWindow
+	}
+	if config.UnbondingPeriod == 0 && !storedJSONHasKey(configJSON, "unbondingPeriod") {
+		config.UnbondingPeriod = defaultConfig().UnbondingPeriod
+	}
+	if config.DisputeTimeout == 0 && !storedJSONHasKey(configJSON, "disputeTimeout") {
+		config.DisputeTimeout = defaultConfig().DisputeTimeout
+	}
+	if config.IdentityMode == "" {
+		config.IdentityMode = identityModeCN
+	}
+	if config.TrustedCAApprovals == 0 {
+		config.TrustedCAApprovals = defaultConfig().TrustedCAApprovals
+	}
+	if config.SettlementChaincode != "" && config.SettlementFunction == "" {
+		config.SettlementFunction = defaultSettlementFunction
+	}
+	if config.FraudWindow == 0 {
+		config.FraudWindow = defaultConfig().FraudWindow
+	}
+	if config.FraudBurstRatings == 0 {
+		config.FraudBurstRatings = defaultConfig().FraudBurstRatings
+	}
+	if config.FraudReciprocalScore == 0 && !storedJSONHasKey(configJSON, "fraudReciprocalScore") {
+		config.FraudReciprocalScore = defaultConfig().FraudReciprocalScore
+	}
+	if config.FraudThresholdMargin == 0 {
+		config.FraudThresholdMargin = defaultConfig().FraudThresholdMargin
+	}
+	if config.ArchiveAge == 0 && !storedJSONHasKey(configJSON, "archiveAge") {
+		config.ArchiveAge = defaultConfig().ArchiveAge
+	}
+	if config.DefaultPageSize == 0 {
+		config.DefaultPageSize = defaultConfig().DefaultPageSize
+	}
+	if config.MaxPageSize == 0 {
+		config.MaxPageSize = defaultConfig().MaxPageSize
+	}
+	if config.SlashInitiatorShare == 0 && !storedJSONHasKey(configJSON, "slashInitiatorShare") {
+		config.SlashInitiatorShare = defaultConfig().SlashInitiatorShare
+	}
+
+	// Resolve ramped parameters against the transaction timestamp
+	if len(config.ParameterRamps) > 0 {
+		now, err := txUnixTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		applyParameterRamps(&config, now)
+	}
+
 	return &config, nil
 }
 // validateConfig validates system configuration
@@ -1291,6 +2235,9 @@ func validateConfig(config *SystemConfig) error {
 	if config.SlashPercentage < 0 || config.SlashPercentage > 1 {
 		return fmt.Errorf("slashPercentage must be between 0 and 1")
 	}
+	if config.SlashInitiatorShare < 0 || config.SlashInitiatorShare > 1 {
+		return fmt.Errorf("slashInitiatorShare must be between 0 and 1")
+	}
 	if config.DecayRate < 0 || config.DecayRate > 1 {
 		return fmt.Errorf("decayRate must be between 0 and 1")
 	}
@@ -1303,9 +2250,88 @@ func validateConfig(config *SystemConfig) error {
 	if config.MinRaterWeight < 0 || config.MaxRaterWeight < config.MinRaterWeight {
 		return fmt.Errorf("invalid rater weight bounds")
 	}
+	if config.ServiceMaxRaterWeight < 0 {
+		return fmt.Errorf("serviceMaxRaterWeight must be non-negative")
+	}
+	if config.ProbationEvents < 0 || config.ProbationMaxRaterWeight < 0 {
+		return fmt.Errorf("probationEvents and probationMaxRaterWeight must be non-negative")
+	}
 	if len(config.ValidDimensions) == 0 {
 		return fmt.Errorf("at least one valid dimension required")
 	}
+	if config.TreasuryApprovals < 1 {
+		return fmt.Errorf("treasuryApprovals must be at least 1")
+	}
+	if config.BanApprovals < 1 {
+		return fmt.Errorf("banApprovals must be at least 1")
+	}
+	if config.TrustedCAApprovals < 1 {
+		return fmt.Errorf("trustedCaApprovals must be at least 1")
+	}
+	if err := validateRatingRules(config); err != nil {
+		return err
+	}
+	if err := validateKeyEndorsement(config); err != nil {
+		return err
+	}
+	if config.AttestationWeight < 0 {
+		return fmt.Errorf("attestationWeight must be non-negative")
+	}
+	if err := validateSLARules(config); err != nil {
+		return err
+	}
+	for credentialType, dimension := range config.CredentialDimensions {
+		if !config.ValidDimensions[dimension] {
+			return fmt.Errorf("credential type %s maps to unknown dimension: %s", credentialType, dimension)
+		}
+	}
+	if config.VouchMinMetaScore < 0 || config.VouchMinMetaScore > 1 {
+		return fmt.Errorf("vouchMinMetaScore must be between 0 and 1")
+	}
+	if config.VouchPriorWeight < 0 || config.VouchPenalty < 0 {
+		return fmt.Errorf("vouchPriorWeight and vouchPenalty must be non-negative")
+	}
+	if config.OffboardingWindow < 0 {
+		return fmt.Errorf("offboardingWindow must be non-negative")
+	}
+	if config.UnbondingPeriod < 0 {
+		return fmt.Errorf("unbondingPeriod must be non-negative")
+	}
+	if config.DisputeTimeout < 0 {
+		return fmt.Errorf("disputeTimeout must be non-negative")
+	}
+	if config.ChallengeWindow < 0 {
+		return fmt.Errorf("challengeWindow must be non-negative")
+	}
+	if config.RatingCooldown < 0 {
+		return fmt.Errorf("ratingCooldown must be non-negative")
+	}
+	if config.ArbitrationPanelSize < 0 || (config.ArbitrationPanelSize > 0 && config.ArbitrationPanelSize%2 == 0) {
+		return fmt.Errorf("arbitrationPanelSize must be 0 or an odd number")
+	}
+	if !validIdentityModes[config.IdentityMode] {
+		return fmt.Errorf("identityMode must be one of cn, msp, hash")
+	}
+	if config.FraudWindow < 0 || config.FraudBurstRatings < 0 {
+		return fmt.Errorf("fraudWindow and fraudBurstRatings must be non-negative")
+	}
+	if config.FraudReciprocalScore < 0 || config.FraudReciprocalScore > 1 {
+		return fmt.Errorf("fraudReciprocalScore must be between 0 and 1")
+	}
+	if config.FraudThresholdMargin < 0 || config.FraudThresholdMargin > 1-bondReleaseValue {
+		return fmt.Errorf("fraudThresholdMargin must be between 0 and %g", 1-bondReleaseValue)
+	}
+	if config.ArchiveAge < 0 || (config.ArchiveAge > 0 && config.ArchiveAge < config.FraudWindow) {
+		return fmt.Errorf("archiveAge must be non-negative and at least fraudWindow")
+	}
+	if config.DefaultPageSize < 1 || config.MaxPageSize < config.DefaultPageSize {
+		return fmt.Errorf("defaultPageSize must be at least 1 and no larger than maxPageSize")
+	}
+	for function := range config.EligibilityRules {
+		if !gatedFunctions[function] {
+			return fmt.Errorf("function does not support eligibility rules: %s", function)
+		}
+	}
 
 	return nil
 }
@@ -1317,21 +2343,25 @@ func getOrInitReputation(
 	dimension string,
 	config *SystemConfig,
 ) (*Reputation, error) {
-	repKey := fmt.Sprintf("REPUTATION:%s:%s", actorID, dimension)
-	repJSON, err := ctx.GetStub().GetState(repKey)
+	repJSON, err := getStateWithLegacy(ctx, reputationStateKey(actorID, dimension), legacyReputationKey(actorID, dimension))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read reputation: %v", err)
 	}
 
 	if repJSON == nil {
+		now, err := txUnixTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+
 		// Initialize new reputation
 		return &Reputation{
 			ActorID:     actorID,
 			Dimension:   dimension,
-			Alpha:       config.InitialAlpha,
-			Beta:        config.InitialBeta,
+			Alpha:       fixedFromFloat(config.InitialAlpha),
+			Beta:        fixedFromFloat(config.InitialBeta),
 			TotalEvents: 0,
-			LastTs:      time.Now().Unix(),
+			LastTs:      now,
 		}, nil
 	}
 
@@ -1348,19 +2378,28 @@ func getOrInitStake(
 	ctx contractapi.TransactionContextInterface,
 	actorID string,
 ) (*Stake, error) {
-	stakeKey := fmt.Sprintf("STAKE:%s", actorID)
-	stakeJSON, err := ctx.GetStub().GetState(stakeKey)
+	if pending := pendingStake(ctx, actorID); pending != nil {
+		stake := *pending
+		return &stake, nil
+	}
+
+	stakeJSON, err := getStateWithLegacy(ctx, stakeStateKey(actorID), legacyStakeKey(actorID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read stake: %v", err)
 	}
 
 	if stakeJSON == nil {
+		now, err := txUnixTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+
 		// Initialize new stake
 		return &Stake{
 			ActorID:   actorID,
 			Balance:   0.0,
 			Locked:    0.0,
-			UpdatedAt: time.Now().Unix(),
+			UpdatedAt: now,
 		}, nil
 	}
 
@@ -1372,14 +2411,26 @@ func getOrInitStake(
 	return &stake, nil
 }
 
-// applyDynamicDecay applies variance-based time decay to reputation
-func applyDynamicDecay(rep *Reputation, config *SystemConfig) *Reputation {
-	now := time.Now().Unix()
+// putStake stores an actor's stake record
+func putStake(ctx contractapi.TransactionContextInterface, stake *Stake) error {
+	stakeJSON, err := marshalCanonical(stake)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stake: %v", err)
+	}
+	if err := ctx.GetStub().PutState(stakeStateKey(stake.ActorID), stakeJSON); err != nil {
+		return fmt.Errorf("failed to store stake: %v", err)
+	}
+	setPendingStake(ctx, stake)
+	return nil
+}
+
+// applyDynamicDecayAt applies dynamic decay as of the given Unix time
+func applyDynamicDecayAt(rep *Reputation, config *SystemConfig, now int64) *Reputation {
 	timeDelta := float64(now - rep.LastTs)
 
 	// Calculate Beta distribution variance
-	alpha := rep.Alpha
-	beta := rep.Beta
+	alpha := rep.Alpha.Float()
+	beta := rep.Beta.Float()
 	sum := alpha + beta
 	variance := (alpha * beta) / (sum * sum * (sum + 1))
 
@@ -1393,15 +2444,15 @@ func applyDynamicDecay(rep *Reputation, config *SystemConfig) *Reputation {
 	// Apply decay
 	decayFactor := math.Pow(adaptiveDecayRate, timeDelta/config.DecayPeriod)
 
-	effectiveAlpha := alpha * decayFactor
-	effectiveBeta := beta * decayFactor
+	effectiveAlpha := fixedFromFloat(alpha * decayFactor)
+	effectiveBeta := fixedFromFloat(beta * decayFactor)
 
 	// Prevent decay below initial values
-	if effectiveAlpha < config.InitialAlpha {
-		effectiveAlpha = config.InitialAlpha
+	if initialAlpha := fixedFromFloat(config.InitialAlpha); effectiveAlpha < initialAlpha {
+		effectiveAlpha = initialAlpha
 	}
-	if effectiveBeta < config.InitialBeta {
-		effectiveBeta = config.InitialBeta
+	if initialBeta := fixedFromFloat(config.InitialBeta); effectiveBeta < initialBeta {
+		effectiveBeta = initialBeta
 	}
 
 	return &Reputation{
@@ -1444,62 +2495,29 @@ func calculateWilsonCI(alpha, beta, confidence float64) [2]float64 {
 	return [2]float64{lower, upper}
 }
 
-// generateRatingID creates unique rating identifier
-func generateRatingID(raterID, actorID, dimension string, timestamp int64) string {
-	data := fmt.Sprintf("%s:%s:%s:%d", raterID, actorID, dimension, timestamp)
-	hash := sha256.Sum256([]byte(data))
-	return fmt.Sprintf("RATING:%x", hash[:16])
-}
-
-// generateDisputeID creates unique dispute identifier
-func generateDisputeID(ratingID, initiatorID string, timestamp int64) string {
-	data := fmt.Sprintf("%s:%s:%d", ratingID, initiatorID, timestamp)
-	hash := sha256.Sum256([]byte(data))
-	return fmt.Sprintf("DISPUTE:%x", hash[:16])
-}
 
 // isAdmin checks if caller has admin privileges
 func isAdmin(ctx contractapi.TransactionContextInterface) bool {
-	// Option 1: Check MSP attribute
-	val, ok, _ := ctx.GetClientIdentity().GetAttributeValue("admin")
-	if ok && val == "true" {
+	// Option 1: Check MSP attribute, scoped to the transaction's tenant
+	if roleAttributeGranted(ctx, "admin") {
 		return true
 	}
 
-	// Option 2: Check against admin list
-	adminListJSON, err := ctx.GetStub().GetState("ADMIN_LIST")
-	if err == nil && adminListJSON != nil {
-		var admins map[string]bool
-		if err := json.Unmarshal(adminListJSON, &admins); err == nil {
-			callerID, _ := ctx.GetClientIdentity().GetID()
-			normalizedCallerID := normalizeIdentity(callerID)
-			return admins[normalizedCallerID]
-		}
-	}
-
-	return false
+	// Option 2: Check against admin list, ignoring expired grants
+	normalizedCallerID, _ := callerIdentity(ctx)
+	return roleGranted(ctx, adminListKey, adminExpiryKey, normalizedCallerID)
 }
 
 // isArbitrator checks if caller has arbitrator privileges
 func isArbitrator(ctx contractapi.TransactionContextInterface) bool {
-	// Check MSP attribute
-	val, ok, _ := ctx.GetClientIdentity().GetAttributeValue("arbitrator")
-	if ok && val == "true" {
+	// Check MSP attribute, scoped to the transaction's tenant
+	if roleAttributeGranted(ctx, "arbitrator") {
 		return true
 	}
 
-	// Check against arbitrator list
-	arbitratorListJSON, err := ctx.GetStub().GetState("ARBITRATOR_LIST")
-	if err == nil && arbitratorListJSON != nil {
-		var arbitrators map[string]bool
-		if err := json.Unmarshal(arbitratorListJSON, &arbitrators); err == nil {
-			callerID, _ := ctx.GetClientIdentity().GetID()
-			normalizedCallerID := normalizeIdentity(callerID)
-			return arbitrators[normalizedCallerID]
-		}
-	}
-
-	return false
+	// Check against arbitrator list, ignoring expired grants
+	normalizedCallerID, _ := callerIdentity(ctx)
+	return roleGranted(ctx, arbitratorListKey, arbitratorExpiryKey, normalizedCallerID)
 }
 
 // ============================================================================
@@ -1507,7 +2525,7 @@ func isArbitrator(ctx contractapi.TransactionContextInterface) bool {
 // ============================================================================
 
 // AddAdmin adds a new administrator
-func (rc *ReputationContract) AddAdmin(
+func (gc *GovernanceContract) AddAdmin(
 	ctx contractapi.TransactionContextInterface,
 	newAdminID string,
 ) error {
@@ -1515,10 +2533,10 @@ func (rc *ReputationContract) AddAdmin(
 		return fmt.Errorf("unauthorized: only admin can add admins")
 	}
 
-	normalizedAdminID := normalizeIdentity(newAdminID)
+	normalizedAdminID := resolveIdentity(ctx, newAdminID)
 
 	// Load or initialize admin list
-	adminListJSON, err := ctx.GetStub().GetState("ADMIN_LIST")
+	adminListJSON, err := ctx.GetStub().GetState(adminListKey)
 	admins := make(map[string]bool)
 	if err == nil && adminListJSON != nil {
 		json.Unmarshal(adminListJSON, &admins)
@@ -1528,25 +2546,32 @@ func (rc *ReputationContract) AddAdmin(
 	admins[normalizedAdminID] = true
 
 	// Store updated list
-	updatedJSON, _ := json.Marshal(admins)
-	err = ctx.GetStub().PutState("ADMIN_LIST", updatedJSON)
+	updatedJSON, _ := marshalCanonical(admins)
+	err = ctx.GetStub().PutState(adminListKey, updatedJSON)
 	if err != nil {
 		return fmt.Errorf("failed to update admin list: %v", err)
 	}
+	if err := protectKey(ctx, adminListKey); err != nil {
+		return err
+	}
+
+	// A permanent grant replaces any temporary one
+	if err := clearRoleExpiry(ctx, adminExpiryKey, normalizedAdminID); err != nil {
+		return err
+	}
 
 	// Emit event
 	eventPayload := map[string]interface{}{
 		"adminId": normalizedAdminID,
 		"action":  "added",
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("AdminUpdated", eventJSON)
+	emitEvent(ctx, "AdminUpdated", eventPayload)
 
 	return nil
 }
 
 // RemoveAdmin removes an administrator
-func (rc *ReputationContract) RemoveAdmin(
+func (gc *GovernanceContract) RemoveAdmin(
 	ctx contractapi.TransactionContextInterface,
 	adminID string,
 ) error {
@@ -1554,10 +2579,10 @@ func (rc *ReputationContract) RemoveAdmin(
 		return fmt.Errorf("unauthorized: only admin can remove admins")
 	}
 
-	normalizedAdminID := normalizeIdentity(adminID)
+	normalizedAdminID := resolveIdentity(ctx, adminID)
 
 	// Load admin list
-	adminListJSON, err := ctx.GetStub().GetState("ADMIN_LIST")
+	adminListJSON, err := ctx.GetStub().GetState(adminListKey)
 	if err != nil || adminListJSON == nil {
 		return fmt.Errorf("admin list not found")
 	}
@@ -1574,25 +2599,28 @@ func (rc *ReputationContract) RemoveAdmin(
 	}
 
 	// Store updated list
-	updatedJSON, _ := json.Marshal(admins)
-	err = ctx.GetStub().PutState("ADMIN_LIST", updatedJSON)
+	updatedJSON, _ := marshalCanonical(admins)
+	err = ctx.GetStub().PutState(adminListKey, updatedJSON)
 	if err != nil {
 		return fmt.Errorf("failed to update admin list: %v", err)
 	}
 
+	if err := clearRoleExpiry(ctx, adminExpiryKey, normalizedAdminID); err != nil {
+		return err
+	}
+
 	// Emit event
 	eventPayload := map[string]interface{}{
 		"adminId": normalizedAdminID,
 		"action":  "removed",
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("AdminUpdated", eventJSON)
+	emitEvent(ctx, "AdminUpdated", eventPayload)
 
 	return nil
 }
 
 // AddArbitrator adds a new arbitrator
-func (rc *ReputationContract) AddArbitrator(
+func (gc *GovernanceContract) AddArbitrator(
 	ctx contractapi.TransactionContextInterface,
 	arbitratorID string,
 ) error {
@@ -1600,10 +2628,10 @@ func (rc *ReputationContract) AddArbitrator(
 		return fmt.Errorf("unauthorized: only admin can add arbitrators")
 	}
 
-	normalizedArbitratorID := normalizeIdentity(arbitratorID)
+	normalizedArbitratorID := resolveIdentity(ctx, arbitratorID)
 
 	// Load or initialize arbitrator list
-	arbitratorListJSON, err := ctx.GetStub().GetState("ARBITRATOR_LIST")
+	arbitratorListJSON, err := ctx.GetStub().GetState(arbitratorListKey)
 	arbitrators := make(map[string]bool)
 	if err == nil && arbitratorListJSON != nil {
 		json.Unmarshal(arbitratorListJSON, &arbitrators)
@@ -1613,25 +2641,32 @@ func (rc *ReputationContract) AddArbitrator(
 	arbitrators[normalizedArbitratorID] = true
 
 	// Store updated list
-	updatedJSON, _ := json.Marshal(arbitrators)
-	err = ctx.GetStub().PutState("ARBITRATOR_LIST", updatedJSON)
+	updatedJSON, _ := marshalCanonical(arbitrators)
+	err = ctx.GetStub().PutState(arbitratorListKey, updatedJSON)
 	if err != nil {
 		return fmt.Errorf("failed to update arbitrator list: %v", err)
 	}
+	if err := protectKey(ctx, arbitratorListKey); err != nil {
+		return err
+	}
+
+	// A permanent grant replaces any temporary one
+	if err := clearRoleExpiry(ctx, arbitratorExpiryKey, normalizedArbitratorID); err != nil {
+		return err
+	}
 
 	// Emit event
 	eventPayload := map[string]interface{}{
 		"arbitratorId": normalizedArbitratorID,
 		"action":       "added",
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("ArbitratorUpdated", eventJSON)
+	emitEvent(ctx, "ArbitratorUpdated", eventPayload)
 
 	return nil
 }
 
 // RemoveArbitrator removes an arbitrator
-func (rc *ReputationContract) RemoveArbitrator(
+func (gc *GovernanceContract) RemoveArbitrator(
 	ctx contractapi.TransactionContextInterface,
 	arbitratorID string,
 ) error {
@@ -1639,10 +2674,10 @@ func (rc *ReputationContract) RemoveArbitrator(
 		return fmt.Errorf("unauthorized: only admin can remove arbitrators")
 	}
 
-	normalizedArbitratorID := normalizeIdentity(arbitratorID)
+	normalizedArbitratorID := resolveIdentity(ctx, arbitratorID)
 
 	// Load arbitrator list
-	arbitratorListJSON, err := ctx.GetStub().GetState("ARBITRATOR_LIST")
+	arbitratorListJSON, err := ctx.GetStub().GetState(arbitratorListKey)
 	if err != nil || arbitratorListJSON == nil {
 		return fmt.Errorf("arbitrator list not found")
 	}
@@ -1654,39 +2689,47 @@ func (rc *ReputationContract) RemoveArbitrator(
 	delete(arbitrators, normalizedArbitratorID)
 
 	// Store updated list
-	updatedJSON, _ := json.Marshal(arbitrators)
-	err = ctx.GetStub().PutState("ARBITRATOR_LIST", updatedJSON)
+	updatedJSON, _ := marshalCanonical(arbitrators)
+	err = ctx.GetStub().PutState(arbitratorListKey, updatedJSON)
 	if err != nil {
 		return fmt.Errorf("failed to update arbitrator list: %v", err)
 	}
 
+	if err := clearRoleExpiry(ctx, arbitratorExpiryKey, normalizedArbitratorID); err != nil {
+		return err
+	}
+
 	// Emit event
 	eventPayload := map[string]interface{}{
 		"arbitratorId": normalizedArbitratorID,
 		"action":       "removed",
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	ctx.GetStub().SetEvent("ArbitratorUpdated", eventJSON)
+	emitEvent(ctx, "ArbitratorUpdated", eventPayload)
 
 	return nil
 }
 // ResetStake - TEST ONLY: Reset an actor's stake to zero
 // In production, remove this function or add proper access controls
-func (rc *ReputationContract) ResetStake(
+func (sc *StakeContract) ResetStake(
 	ctx contractapi.TransactionContextInterface,
 	actorID string,
 ) error {
-	normalizedID := normalizeIdentity(actorID)
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	normalizedID := resolveIdentity(ctx, actorID)
 	
 	stake := &Stake{
 		ActorID:   normalizedID,
 		Balance:   0,
 		Locked:    0,
-		UpdatedAt: time.Now().Unix(),
+		UpdatedAt: now,
 	}
 
-	stakeKey := fmt.Sprintf("STAKE:%s", normalizedID)
-	stakeJSON, err := json.Marshal(stake)
+	stakeKey := stakeStateKey(normalizedID)
+	stakeJSON, err := marshalCanonical(stake)
 	if err != nil {
 		return fmt.Errorf("failed to marshal stake: %v", err)
 	}
@@ -1698,7 +2741,7 @@ func (rc *ReputationContract) ResetStake(
 // ============================================================================
 
 func main() {
-	chaincode, err := contractapi.NewChaincode(&ReputationContract{})
+	chaincode, err := contractapi.NewChaincode(newContracts()...)
 	if err != nil {
 		fmt.Printf("Error creating reputation chaincode: %v\n", err)
 		return
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// NAMED CONTRACTS
+// ============================================================================
+
+// The chaincode registers one contract per area. Functions of the named
+// contracts are invoked as "<name>:<function>", e.g. "rating:SubmitRating";
+// ReputationContract is the default and its functions are called
+// unqualified. All contracts share the ReputationContext, and every
+// transaction selects its tenant and has its arguments pass
+// validateArguments first.
+const (
+	governanceContractName = "governance"
+	stakeContractName      = "stake"
+	ratingContractName     = "rating"
+	disputeContractName    = "dispute"
+)
+
+// GovernanceContract manages the config, roles, rating rules, bans and the
+// treasury. Every function except those in governanceOpenFunctions requires
+// the admin role.
+type GovernanceContract struct {
+	contractapi.Contract
+}
+
+// StakeContract manages stake deposits
+type StakeContract struct {
+	contractapi.Contract
+}
+
+// RatingContract submits and looks up ratings
+type RatingContract struct {
+	contractapi.Contract
+}
+
+// DisputeContract files, resolves and looks up disputes and detects fraud
+// patterns. ResolveDispute, CastVerdict and the fraud signal functions
+// require the arbitrator role.
+type DisputeContract struct {
+	contractapi.Contract
+}
+
+// governanceOpenFunctions are the governance functions callable without the
+// admin role: bootstrap, role pruning and read-only lookups
+var governanceOpenFunctions = map[string]bool{
+	"InitConfig":            true,
+	"GetConfig":             true,
+	"PruneExpiredRoles":     true,
+	"GetTreasury":           true,
+	"GetTreasuryWithdrawal": true,
+	"GetBan":                true,
+}
+
+// disputeArbitratorFunctions are the dispute functions reserved for
+// arbitrators
+var disputeArbitratorFunctions = map[string]bool{
+	"ResolveDispute":          true,
+	"CastVerdict":             true,
+	"ScanFraudSignals":        true,
+	"GetFraudSignal":          true,
+	"GetFraudSignalsForActor": true,
+}
+
+// newContracts returns the chaincode's contracts, default first
+func newContracts() []contractapi.ContractInterface {
+	reputation := &ReputationContract{}
+	reputation.TransactionContextHandler = new(ReputationContext)
+	reputation.BeforeTransaction = chainHooks(selectTenant, validateArguments)
+
+	governance := &GovernanceContract{}
+	governance.Name = governanceContractName
+	governance.TransactionContextHandler = new(ReputationContext)
+	governance.BeforeTransaction = chainHooks(
+		selectTenant,
+		validateArguments,
+		requireRole("admin", isAdmin, func(function string) bool {
+			return !governanceOpenFunctions[function]
+		}),
+	)
+
+	stake := &StakeContract{}
+	stake.Name = stakeContractName
+	stake.TransactionContextHandler = new(ReputationContext)
+	stake.BeforeTransaction = chainHooks(selectTenant, validateArguments)
+
+	rating := &RatingContract{}
+	rating.Name = ratingContractName
+	rating.TransactionContextHandler = new(ReputationContext)
+	rating.BeforeTransaction = chainHooks(selectTenant, validateArguments)
+
+	dispute := &DisputeContract{}
+	dispute.Name = disputeContractName
+	dispute.TransactionContextHandler = new(ReputationContext)
+	dispute.BeforeTransaction = chainHooks(
+		selectTenant,
+		validateArguments,
+		requireRole("arbitrator", isArbitrator, func(function string) bool {
+			return disputeArbitratorFunctions[function]
+		}),
+	)
+
+	return []contractapi.ContractInterface{reputation, governance, stake, rating, dispute}
+}
+
+// requireRole returns a before-transaction hook rejecting callers that fail
+// check when invoking a function for which gated returns true. Functions
+// keep their own checks; the hook makes the contract's boundary explicit.
+func requireRole(
+	role string,
+	check func(contractapi.TransactionContextInterface) bool,
+	gated func(function string) bool,
+) func(contractapi.TransactionContextInterface) error {
+	return func(ctx contractapi.TransactionContextInterface) error {
+		function, _ := ctx.GetStub().GetFunctionAndParameters()
+		function = function[strings.LastIndex(function, ":")+1:]
+
+		if gated(function) && !check(ctx) {
+			return fmt.Errorf("unauthorized: %s role required for %s", role, function)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// COUNT QUERIES
+// ============================================================================
+
+// CountRatings returns how many ratings an actor has received in a
+// dimension. With an empty sinceStr the maintained counter is returned;
+// otherwise only ratings timestamped at or after sinceStr (unix seconds)
+// are counted, from index keys alone.
+func (rtc *RatingContract) CountRatings(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+	sinceStr string,
+) (int, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return 0, err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+	attributes := []string{normalizedActorID, dimension}
+
+	if sinceStr == "" {
+		return getIndexCount(ctx, ratingByActorIndex, attributes)
+	}
+
+	since, err := strconv.ParseInt(sinceStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid since timestamp: %v", err)
+	}
+
+	return countIndexedSince(ctx, ratingByActorIndex, attributes, 2, since)
+}
+
+// CountDisputes returns how many disputes currently have a status
+func (dc *DisputeContract) CountDisputes(
+	ctx contractapi.TransactionContextInterface,
+	status string,
+) (int, error) {
+	if err := validateDisputeStatus(status); err != nil {
+		return 0, err
+	}
+
+	return getIndexCount(ctx, disputeByStatusIndex, []string{status})
+}
+
+// ============================================================================
+// INDEX COUNTS
+// ============================================================================
+
+// indexCountObjectType keys the entry counters kept for paged indexes. A
+// counter is addressed by the index name and the key attributes a paged
+// query filters on, e.g. INDEX_COUNT~RATING_BY_ACTOR~actorId~dimension.
+const indexCountObjectType = "INDEX_COUNT"
+
+// indexCountKey builds the state key of an index counter
+func indexCountKey(
+	ctx contractapi.TransactionContextInterface,
+	index string,
+	attributes []string,
+) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(indexCountObjectType, append([]string{index}, attributes...))
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s count key: %v", index, err)
+	}
+	return key, nil
+}
+
+// getIndexCount returns the number of index entries under the given
+// attributes, including changes made earlier in this transaction
+func getIndexCount(
+	ctx contractapi.TransactionContextInterface,
+	index string,
+	attributes []string,
+) (int, error) {
+	key, err := indexCountKey(ctx, index, attributes)
+	if err != nil {
+		return 0, err
+	}
+	if count, ok := pendingIndexCount(ctx, key); ok {
+		return count, nil
+	}
+
+	countBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s count: %v", index, err)
+	}
+	if countBytes == nil {
+		return 0, nil
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s count: %v", index, err)
+	}
+
+	return count, nil
+}
+
+// addIndexCount adjusts the entry count under the given attributes
+func addIndexCount(
+	ctx contractapi.TransactionContextInterface,
+	index string,
+	attributes []string,
+	delta int,
+) error {
+	count, err := getIndexCount(ctx, index, attributes)
+	if err != nil {
+		return err
+	}
+	count += delta
+	if count < 0 {
+		count = 0
+	}
+
+	key, err := indexCountKey(ctx, index, attributes)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, []byte(strconv.Itoa(count))); err != nil {
+		return fmt.Errorf("failed to store %s count: %v", index, err)
+	}
+
+	setPendingIndexCount(ctx, key, count)
+	return nil
+}
+
+// resetIndexCounts zeroes every counter of an index ahead of a full
+// RebuildIndexes pass
+func resetIndexCounts(ctx contractapi.TransactionContextInterface, index string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(indexCountObjectType, []string{index})
+	if err != nil {
+		return fmt.Errorf("failed to read %s counts: %v", index, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+			return fmt.Errorf("failed to reset %s count: %v", index, err)
+		}
+		setPendingIndexCount(ctx, queryResponse.Key, 0)
+	}
+
+	return nil
+}
+
+// reputationRecordCount totals the reputation records across all
+// dimensions from the maintained dimension statistics
+func reputationRecordCount(ctx contractapi.TransactionContextInterface) (int, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	dimensions := make(map[string]bool)
+	for dimension := range config.ValidDimensions {
+		dimensions[dimension] = true
+	}
+	for _, metaDimension := range config.MetaDimensions {
+		dimensions[metaDimension] = true
+	}
+
+	total := 0
+	for dimension := range dimensions {
+		stats, err := getDimensionStats(ctx, dimension)
+		if err != nil {
+			return 0, err
+		}
+		total += stats.ActorCount
+	}
+
+	return total, nil
+}
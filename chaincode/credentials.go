@@ -0,0 +1,376 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// CREDENTIAL DATA STRUCTURES
+// ============================================================================
+
+// CredentialIssuer is an organization trusted to sign credentials
+type CredentialIssuer struct {
+	IssuerID     string `json:"issuerId"`
+	PublicKeyPEM string `json:"publicKeyPem"`
+	Active       bool   `json:"active"`
+	RegisteredAt int64  `json:"registeredAt"`
+}
+
+// VerifiableCredential is a signed claim about an actor, modelled on the W3C
+// data model. Proof is a base64 signature (ECDSA P-256/SHA-256 in ASN.1, or
+// Ed25519) by the issuer over the JSON encoding of the credential with the
+// proof omitted.
+type VerifiableCredential struct {
+	ID             string            `json:"id"`
+	Type           string            `json:"type"` // e.g. ISO9001, AS9100, KYC
+	Issuer         string            `json:"issuer"`
+	Subject        string            `json:"credentialSubject"`
+	Claims         map[string]string `json:"claims,omitempty"`
+	IssuanceDate   int64             `json:"issuanceDate"`
+	ExpirationDate int64             `json:"expirationDate"`
+	Proof          string            `json:"proof,omitempty"`
+}
+
+// Attestation is an accepted credential stored against its subject
+type Attestation struct {
+	AttestationID string               `json:"attestationId"`
+	ActorID       string               `json:"actorId"`
+	Credential    VerifiableCredential `json:"credential"`
+	Dimension     string               `json:"dimension"` // dimension whose prior was raised, if any
+	AcceptedAt    int64                `json:"acceptedAt"`
+	TxID          string               `json:"txId"`
+}
+
+// ============================================================================
+// CREDENTIAL FUNCTIONS
+// ============================================================================
+
+// RegisterCredentialIssuer trusts an issuer's public key (PKIX PEM)
+func (rc *ReputationContract) RegisterCredentialIssuer(
+	ctx contractapi.TransactionContextInterface,
+	issuerID string,
+	publicKeyPEM string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	if _, err := parsePublicKeyPEM(publicKeyPEM); err != nil {
+		return fmt.Errorf("invalid issuer key: %v", err)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	issuer := CredentialIssuer{
+		IssuerID:     issuerID,
+		PublicKeyPEM: publicKeyPEM,
+		Active:       true,
+		RegisteredAt: now,
+	}
+
+	issuerJSON, err := marshalCanonical(issuer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal issuer: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(credentialIssuerKey(issuerID), issuerJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store issuer: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"issuerId": issuerID,
+		"action":   "registered",
+	}
+	emitEvent(ctx, "CredentialIssuerUpdated", eventPayload)
+
+	return nil
+}
+
+// RevokeCredentialIssuer stops accepting new credentials from an issuer.
+// Attestations already accepted are kept.
+func (rc *ReputationContract) RevokeCredentialIssuer(
+	ctx contractapi.TransactionContextInterface,
+	issuerID string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	issuer, err := getCredentialIssuer(ctx, issuerID)
+	if err != nil {
+		return err
+	}
+
+	issuer.Active = false
+
+	issuerJSON, err := marshalCanonical(issuer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal issuer: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(credentialIssuerKey(issuerID), issuerJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store issuer: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"issuerId": issuerID,
+		"action":   "revoked",
+	}
+	emitEvent(ctx, "CredentialIssuerUpdated", eventPayload)
+
+	return nil
+}
+
+// SubmitCredential verifies a credential against its registered issuer and
+// records it as an attestation for the subject. The credential type is added
+// to the subject's certifications, a KYC credential verifies the profile,
+// and types mapped in CredentialDimensions raise that dimension's prior.
+func (rc *ReputationContract) SubmitCredential(
+	ctx contractapi.TransactionContextInterface,
+	credentialJSON string,
+) (string, error) {
+	var credential VerifiableCredential
+	if err := json.Unmarshal([]byte(credentialJSON), &credential); err != nil {
+		return "", fmt.Errorf("invalid credential JSON: %v", err)
+	}
+	if credential.ID == "" || credential.Type == "" || credential.Subject == "" {
+		return "", fmt.Errorf("credential id, type and credentialSubject are required")
+	}
+
+	issuer, err := getCredentialIssuer(ctx, credential.Issuer)
+	if err != nil {
+		return "", err
+	}
+	if !issuer.Active {
+		return "", fmt.Errorf("issuer is revoked: %s", credential.Issuer)
+	}
+
+	if err := verifyCredentialProof(&credential, issuer); err != nil {
+		return "", err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	if credential.IssuanceDate > now {
+		return "", fmt.Errorf("credential is not yet valid")
+	}
+	if credential.ExpirationDate != 0 && credential.ExpirationDate <= now {
+		return "", fmt.Errorf("credential has expired")
+	}
+
+	subjectID := resolveIdentity(ctx, credential.Subject)
+
+	attestationID := attestationKey(subjectID, credential.Issuer, credential.ID)
+	existing, err := ctx.GetStub().GetState(attestationID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read attestation: %v", err)
+	}
+	if existing != nil {
+		return "", fmt.Errorf("credential already submitted: %s", credential.ID)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Feed the attestation into the subject's profile
+	profile, err := getOrInitProfile(ctx, subjectID)
+	if err != nil {
+		return "", err
+	}
+	var changed []string
+	if !containsString(profile.Certifications, credential.Type) {
+		profile.Certifications = append(profile.Certifications, credential.Type)
+		changed = append(changed, "certifications")
+	}
+	if credential.Type == "KYC" && !profile.Verified {
+		profile.Verified = true
+		profile.VerifiedBy = "issuer:" + credential.Issuer
+		profile.VerifiedAt = now
+		changed = append(changed, "verified")
+	}
+	if len(changed) > 0 {
+		if err := putProfile(ctx, profile, "issuer:"+credential.Issuer, changed, "ProfileUpdated"); err != nil {
+			return "", err
+		}
+	}
+
+	// Raise the prior of the dimension the credential speaks to
+	dimension := config.CredentialDimensions[credential.Type]
+	if dimension != "" && config.ValidDimensions[dimension] {
+		rep, err := getOrInitReputation(ctx, subjectID, dimension, config)
+		if err != nil {
+			return "", err
+		}
+		rep.Alpha += fixedFromFloat(config.AttestationWeight)
+
+		if err := putReputation(ctx, rep); err != nil {
+			return "", err
+		}
+	} else {
+		dimension = ""
+	}
+
+	attestation := Attestation{
+		AttestationID: attestationID,
+		ActorID:       subjectID,
+		Credential:    credential,
+		Dimension:     dimension,
+		AcceptedAt:    now,
+		TxID:          ctx.GetStub().GetTxID(),
+	}
+
+	attestationJSON, err := marshalCanonical(attestation)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal attestation: %v", err)
+	}
+	err = ctx.GetStub().PutState(attestationID, attestationJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to store attestation: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"attestationId":  attestationID,
+		"actorId":        subjectID,
+		"issuer":         credential.Issuer,
+		"credentialType": credential.Type,
+		"dimension":      dimension,
+	}
+	emitEvent(ctx, "AttestationAccepted", eventPayload)
+
+	return attestationID, nil
+}
+
+// GetAttestations lists the credentials accepted for an actor
+func (rc *ReputationContract) GetAttestations(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) ([]Attestation, error) {
+	prefix := attestationPrefix(resolveIdentity(ctx, actorID))
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestations: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var attestations []Attestation
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var attestation Attestation
+		if err := json.Unmarshal(queryResponse.Value, &attestation); err != nil {
+			continue
+		}
+		attestations = append(attestations, attestation)
+	}
+
+	return attestations, nil
+}
+
+// ============================================================================
+// CREDENTIAL HELPERS
+// ============================================================================
+
+// verifyCredentialProof checks the issuer's signature over the credential
+func verifyCredentialProof(credential *VerifiableCredential, issuer *CredentialIssuer) error {
+	signature, err := base64.StdEncoding.DecodeString(credential.Proof)
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("invalid credential proof encoding")
+	}
+
+	unsigned := *credential
+	unsigned.Proof = ""
+	payload, err := json.Marshal(unsigned)
+	if err != nil {
+		return fmt.Errorf("failed to marshal credential: %v", err)
+	}
+
+	publicKey, err := parsePublicKeyPEM(issuer.PublicKeyPEM)
+	if err != nil {
+		return fmt.Errorf("invalid issuer key: %v", err)
+	}
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		if ecdsa.VerifyASN1(key, digest[:], signature) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(key, payload, signature) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("credential proof does not verify against issuer %s", issuer.IssuerID)
+}
+
+// parsePublicKeyPEM decodes a PKIX public key and checks it is a supported type
+func parsePublicKeyPEM(publicKeyPEM string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM public key found")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return publicKey, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", publicKey)
+}
+
+// getCredentialIssuer loads a registered issuer
+func getCredentialIssuer(ctx contractapi.TransactionContextInterface, issuerID string) (*CredentialIssuer, error) {
+	issuerJSON, err := ctx.GetStub().GetState(credentialIssuerKey(issuerID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuer: %v", err)
+	}
+	if issuerJSON == nil {
+		return nil, fmt.Errorf("issuer not registered: %s", issuerID)
+	}
+
+	var issuer CredentialIssuer
+	if err := json.Unmarshal(issuerJSON, &issuer); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal issuer: %v", err)
+	}
+
+	return &issuer, nil
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// DISPUTE DEADLINES
+// ============================================================================
+
+// Every dispute carries a deadline SystemConfig.DisputeTimeout after it was
+// filed. Arbitrators can still decide it afterwards, but once the deadline
+// has passed anyone may expire it instead: the dispute is closed as expired
+// without a verdict, so no one's metareputation changes, and the initiator
+// is not made to pay for the missing decision: its locked dispute cost, or
+// the bond a claim held, is returned to its balance.
+// Disputes filed before deadlines existed expire DisputeTimeout after
+// creation under the current config.
+
+// disputeExpired is the status of a dispute closed at its deadline
+const disputeExpired = "expired"
+
+// ExpireDispute closes a pending dispute whose deadline has passed
+func (dc *DisputeContract) ExpireDispute(
+	ctx contractapi.TransactionContextInterface,
+	disputeID string,
+) error {
+	dispute, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return err
+	}
+	if dispute == nil {
+		return fmt.Errorf("dispute not found: %s", disputeID)
+	}
+	if dispute.Status != "pending" {
+		return fmt.Errorf("dispute already resolved")
+	}
+	correlateEvents(ctx, dispute.DisputeID, dispute.RatingID)
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+	deadline := disputeDeadline(dispute, config)
+	if now < deadline {
+		return fmt.Errorf("dispute open until %d", deadline)
+	}
+
+	dispute.Status = disputeExpired
+	dispute.ResolvedAt = now
+
+	// Unlike an upheld claim, an expired one releases the bond to the supplier
+	stake, err := getOrInitStake(ctx, dispute.InitiatorID)
+	if err != nil {
+		return err
+	}
+	if dispute.BondID != "" {
+		if err := settleClaimedBond(ctx, dispute, stake, false); err != nil {
+			return err
+		}
+	} else {
+		stake.Locked -= dispute.LockedCost
+		stake.Balance += dispute.LockedCost
+	}
+	stake.UpdatedAt = now
+	if err := putStake(ctx, stake); err != nil {
+		return err
+	}
+	if dispute.BondID == "" {
+		emitStakeMovement(ctx, stakeUnlockedEvent, stake, dispute.LockedCost, dispute.DisputeID)
+	}
+
+	if err := putDispute(ctx, dispute, "pending"); err != nil {
+		return err
+	}
+
+	eventPayload := map[string]interface{}{
+		"disputeId": dispute.DisputeID,
+		"ratingId":  dispute.RatingID,
+		"deadline":  deadline,
+	}
+	emitEvent(ctx, "DisputeExpired", eventPayload)
+
+	return nil
+}
+
+// disputeDeadline returns when a dispute can be expired
+func disputeDeadline(dispute *Dispute, config *SystemConfig) int64 {
+	if dispute.Deadline != 0 {
+		return dispute.Deadline
+	}
+	return dispute.CreatedAt + config.DisputeTimeout
+}
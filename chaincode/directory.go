@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// PUBLIC DIRECTORY
+// ============================================================================
+
+// Every actor with a profile or a reputation has a directory listing that
+// combines its public profile, its score in each dimension, a tier and its
+// badges, refreshed whenever the profile or one of the reputations is
+// written. Like the leaderboard, scores are as of each actor's last rating,
+// without decay. Listings are filed in the DIRECTORY index under
+// region~category~tier~actorId keys, where region is the profile's country
+// and the categories its capabilities. Each listing is filed under its own
+// values and under directoryAny for each attribute, so that any combination
+// of filters maps onto a partial key.
+
+const (
+	directoryIndex = "DIRECTORY"
+	directoryAny   = "*"
+)
+
+// Directory tiers, from the average lower bound of the 95% Wilson interval
+// over the dimensions an actor was rated in
+const (
+	tierUnrated = "unrated"
+	tierBronze  = "bronze"
+	tierSilver  = "silver" // average lower bound of at least tierSilverBound
+	tierGold    = "gold"   // average lower bound of at least tierGoldBound
+
+	tierSilverBound = 0.5
+	tierGoldBound   = 0.7
+)
+
+var validTiers = map[string]bool{tierUnrated: true, tierBronze: true, tierSilver: true, tierGold: true}
+
+// DirectoryListing is an actor's entry in the public directory
+type DirectoryListing struct {
+	ActorID        string                    `json:"actorId"`
+	DisplayName    string                    `json:"displayName"`
+	ActorType      string                    `json:"actorType"`
+	Organization   string                    `json:"organization"`
+	Region         string                    `json:"region"`     // the profile's country
+	Categories     []string                  `json:"categories"` // the profile's capabilities
+	Certifications []string                  `json:"certifications"`
+	Verified       bool                      `json:"verified"`
+	Scores         map[string]DimensionScore `json:"scores"` // by rated dimension, undecayed
+	Tier           string                    `json:"tier"`
+	Badges         []string                  `json:"badges"`
+	UpdatedAt      int64                     `json:"updatedAt"`
+}
+
+// DirectoryPage is one page of BrowseDirectory results. FetchedCount counts
+// the index entries read, which can exceed len(Listings) as listings hidden
+// from the caller are skipped.
+type DirectoryPage struct {
+	Listings     []DirectoryListing `json:"listings"`
+	Bookmark     string             `json:"bookmark"` // pass to the next call; "" when exhausted
+	FetchedCount int32              `json:"fetchedCount"`
+}
+
+// BrowseDirectory returns a page of directory listings, ordered by actor
+// ID, filtered by region (country code), category (capability,
+// case-insensitive) and tier; an empty filter matches every listing.
+// Banned and offboarding actors are left out, and unlisted or probationary
+// ones unless the caller is an admin or arbitrator.
+func (rc *ReputationContract) BrowseDirectory(
+	ctx contractapi.TransactionContextInterface,
+	region string,
+	category string,
+	tier string,
+	bookmark string,
+	pageSize int,
+) (*DirectoryPage, error) {
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	if tier != "" && !validTiers[tier] {
+		return nil, fmt.Errorf("invalid tier: %s", tier)
+	}
+
+	attributes := []string{
+		directoryAttribute(strings.ToUpper(strings.TrimSpace(region))),
+		directoryAttribute(strings.ToLower(strings.TrimSpace(category))),
+		directoryAttribute(tier),
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		directoryIndex, attributes, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", directoryIndex, err)
+	}
+	defer resultsIterator.Close()
+
+	showUnlisted := canSeeUnlisted(ctx)
+
+	page := &DirectoryPage{Listings: []DirectoryListing{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 4 {
+			continue
+		}
+		actorID := parts[3]
+
+		if visible, err := inDirectory(ctx, actorID, showUnlisted); err != nil || !visible {
+			continue
+		}
+		listing, err := getListing(ctx, actorID)
+		if err != nil || listing == nil {
+			continue
+		}
+		page.Listings = append(page.Listings, *listing)
+	}
+
+	page.FetchedCount = metadata.FetchedRecordsCount
+	if int(metadata.FetchedRecordsCount) == pageSize {
+		page.Bookmark = metadata.Bookmark
+	}
+
+	return page, nil
+}
+
+// GetDirectoryListing retrieves an actor's directory listing
+func (rc *ReputationContract) GetDirectoryListing(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*DirectoryListing, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	listing, err := getListing(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := inDirectory(ctx, normalizedActorID, canSeeUnlisted(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if listing == nil || !visible {
+		return nil, fmt.Errorf("no directory listing: %s", normalizedActorID)
+	}
+	return listing, nil
+}
+
+// ============================================================================
+// DIRECTORY HELPERS
+// ============================================================================
+
+// refreshListingProfile copies a profile's current fields into its actor's
+// listing
+func refreshListingProfile(ctx contractapi.TransactionContextInterface, profile *ActorProfile) error {
+	previous, err := getListing(ctx, profile.ActorID)
+	if err != nil {
+		return err
+	}
+
+	listing := &DirectoryListing{ActorID: profile.ActorID, Scores: map[string]DimensionScore{}}
+	if previous != nil {
+		listing = previous.clone()
+	}
+	listing.setProfile(profile)
+
+	return putListing(ctx, listing, previous)
+}
+
+// refreshListingScore copies a reputation's score into its actor's listing.
+// Meta-dimension reputations are not listed.
+func refreshListingScore(ctx contractapi.TransactionContextInterface, rep *Reputation) error {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.ValidDimensions[rep.Dimension] {
+		return nil
+	}
+
+	previous, err := getListing(ctx, rep.ActorID)
+	if err != nil {
+		return err
+	}
+
+	var listing *DirectoryListing
+	if previous != nil {
+		listing = previous.clone()
+	} else {
+		profile, err := getOrInitProfile(ctx, rep.ActorID)
+		if err != nil {
+			return err
+		}
+		listing = &DirectoryListing{ActorID: rep.ActorID, Scores: map[string]DimensionScore{}}
+		listing.setProfile(profile)
+	}
+
+	ci := calculateWilsonCI(rep.Alpha.Float(), rep.Beta.Float(), 0.95)
+	listing.Scores[rep.Dimension] = DimensionScore{
+		Score:       reputationScore(rep),
+		CILower:     ci[0],
+		CIUpper:     ci[1],
+		TotalEvents: rep.TotalEvents,
+		LastUpdated: rep.LastTs,
+	}
+
+	return putListing(ctx, listing, previous)
+}
+
+// setProfile copies the listed fields of a profile
+func (listing *DirectoryListing) setProfile(profile *ActorProfile) {
+	listing.DisplayName = profile.DisplayName
+	listing.ActorType = profile.ActorType
+	listing.Organization = profile.Organization
+	listing.Region = profile.Country
+	listing.Categories = append([]string{}, profile.Capabilities...)
+	listing.Certifications = append([]string{}, profile.Certifications...)
+	listing.Verified = profile.Verified
+}
+
+// clone returns a copy of a listing that shares no slices or maps with it
+func (listing *DirectoryListing) clone() *DirectoryListing {
+	copied := *listing
+	copied.Categories = append([]string{}, listing.Categories...)
+	copied.Certifications = append([]string{}, listing.Certifications...)
+	copied.Badges = append([]string{}, listing.Badges...)
+	copied.Scores = make(map[string]DimensionScore, len(listing.Scores))
+	for dimension, score := range listing.Scores {
+		copied.Scores[dimension] = score
+	}
+	return &copied
+}
+
+// putListing derives a listing's tier and badges, stores it and refiles it
+// in the directory index, removing the entries of previous, its stored
+// version if any
+func putListing(ctx contractapi.TransactionContextInterface, listing *DirectoryListing, previous *DirectoryListing) error {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	listing.Tier = listingTier(listing)
+	listing.Badges = actorBadges(&ActorSummary{
+		Profile: &ActorProfile{ActorType: listing.ActorType, Verified: listing.Verified},
+		Scores:  listing.Scores,
+	})
+	listing.UpdatedAt = now
+
+	listingJSON, err := marshalCanonical(listing)
+	if err != nil {
+		return fmt.Errorf("failed to marshal directory listing: %v", err)
+	}
+	if err := ctx.GetStub().PutState(directoryListingKey(listing.ActorID), listingJSON); err != nil {
+		return fmt.Errorf("failed to store directory listing: %v", err)
+	}
+	setPendingListing(ctx, listing)
+
+	current := directoryAttributes(listing)
+	var stale [][]string
+	if previous != nil {
+		stale = directoryAttributes(previous)
+	}
+	if strings.Join(flattenAttributes(stale), "\x00") == strings.Join(flattenAttributes(current), "\x00") {
+		return nil
+	}
+	for _, attributes := range stale {
+		if err := delIndexEntry(ctx, directoryIndex, attributes); err != nil {
+			return err
+		}
+	}
+	for _, attributes := range current {
+		if err := putIndexEntry(ctx, directoryIndex, attributes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listingTier ranks a listing by the average lower bound of its scores'
+// confidence intervals
+func listingTier(listing *DirectoryListing) string {
+	sum, rated := 0.0, 0
+	for _, score := range listing.Scores {
+		if score.TotalEvents > 0 {
+			sum += score.CILower
+			rated++
+		}
+	}
+	if rated == 0 {
+		return tierUnrated
+	}
+
+	switch average := sum / float64(rated); {
+	case average >= tierGoldBound:
+		return tierGold
+	case average >= tierSilverBound:
+		return tierSilver
+	default:
+		return tierBronze
+	}
+}
+
+// directoryAttributes returns every directory index key of a listing, in a
+// fixed order: each combination of its region, each category and its tier
+// with directoryAny
+func directoryAttributes(listing *DirectoryListing) [][]string {
+	regions := []string{directoryAny}
+	if listing.Region != "" {
+		regions = append(regions, directoryAttribute(listing.Region))
+	}
+
+	categorySet := map[string]bool{directoryAny: true}
+	for _, category := range listing.Categories {
+		categorySet[directoryAttribute(strings.ToLower(category))] = true
+	}
+	categories := make([]string, 0, len(categorySet))
+	for category := range categorySet {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	tiers := []string{directoryAny, listing.Tier}
+
+	keys := [][]string{}
+	for _, region := range regions {
+		for _, category := range categories {
+			for _, tier := range tiers {
+				keys = append(keys, []string{region, category, tier, listing.ActorID})
+			}
+		}
+	}
+	return keys
+}
+
+// directoryAttribute maps an empty filter or attribute to directoryAny
+func directoryAttribute(value string) string {
+	if value == "" {
+		return directoryAny
+	}
+	return value
+}
+
+// flattenAttributes joins index key attributes for comparison
+func flattenAttributes(keys [][]string) []string {
+	flattened := make([]string, len(keys))
+	for i, attributes := range keys {
+		flattened[i] = strings.Join(attributes, "\x00")
+	}
+	return flattened
+}
+
+// inDirectory reports whether an actor's listing is shown to the caller.
+// showUnlisted is canSeeUnlisted for the caller.
+func inDirectory(ctx contractapi.TransactionContextInterface, actorID string, showUnlisted bool) (bool, error) {
+	banned, err := isBanned(ctx, actorID)
+	if err != nil || banned {
+		return false, err
+	}
+	deactivated, err := isDeactivated(ctx, actorID)
+	if err != nil || deactivated {
+		return false, err
+	}
+	if showUnlisted {
+		return true, nil
+	}
+	return isListed(ctx, actorID)
+}
+
+// getListing loads an actor's directory listing, returning nil if none exists
+func getListing(ctx contractapi.TransactionContextInterface, actorID string) (*DirectoryListing, error) {
+	if pending := pendingListing(ctx, actorID); pending != nil {
+		return pending.clone(), nil
+	}
+
+	listingJSON, err := readState(ctx, directoryListingKey(actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory listing: %v", err)
+	}
+	if listingJSON == nil {
+		return nil, nil
+	}
+
+	var listing DirectoryListing
+	if err := json.Unmarshal(listingJSON, &listing); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal directory listing: %v", err)
+	}
+	if listing.Scores == nil {
+		listing.Scores = map[string]DimensionScore{}
+	}
+	return &listing, nil
+}
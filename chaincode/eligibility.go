@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// ELIGIBILITY GATES
+// ============================================================================
+
+// gatedFunctions lists the transactions that eligibility rules may apply to
+var gatedFunctions = map[string]bool{
+	"SubmitRating":    true,
+	"InitiateDispute": true,
+}
+
+// groupAttribute is the reserved rule attribute that requires membership
+// of the named group instead of a certificate attribute
+const groupAttribute = "group"
+
+// SetEligibilityRule requires callers of a gated function to carry a client
+// identity attribute with the given value, or with attribute "group", to be
+// a member of the given group. An empty value removes the rule.
+func (gc *GovernanceContract) SetEligibilityRule(
+	ctx contractapi.TransactionContextInterface,
+	function string,
+	attribute string,
+	value string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	if !gatedFunctions[function] {
+		return fmt.Errorf("function does not support eligibility rules: %s", function)
+	}
+	if attribute == "" {
+		return fmt.Errorf("attribute is required")
+	}
+	if attribute == groupAttribute && value != "" {
+		group, err := getGroup(ctx, value)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			return fmt.Errorf("group not found: %s", value)
+		}
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	if config.EligibilityRules == nil {
+		config.EligibilityRules = make(map[string]map[string]string)
+	}
+	if value == "" {
+		delete(config.EligibilityRules[function], attribute)
+		if len(config.EligibilityRules[function]) == 0 {
+			delete(config.EligibilityRules, function)
+		}
+	} else {
+		if config.EligibilityRules[function] == nil {
+			config.EligibilityRules[function] = make(map[string]string)
+		}
+		config.EligibilityRules[function][attribute] = value
+	}
+	config.Version++
+	config.LastUpdated = now
+
+	configJSON, err := marshalCanonical(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(configKey, configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update config: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"function":  function,
+		"attribute": attribute,
+		"value":     value,
+		"version":   config.Version,
+	}
+	emitEvent(ctx, "EligibilityRuleUpdated", eventPayload)
+
+	return nil
+}
+
+// checkEligibility verifies the caller carries every attribute required for
+// the given function
+func checkEligibility(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	function string,
+) error {
+	for attribute, required := range config.EligibilityRules[function] {
+		if attribute == groupAttribute {
+			callerID, err := callerIdentity(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get caller ID: %v", err)
+			}
+			member, err := isGroupMember(ctx, required, callerID)
+			if err != nil {
+				return err
+			}
+			if !member {
+				return fmt.Errorf("not eligible for %s: must be a member of group %s", function, required)
+			}
+			continue
+		}
+
+		val, ok, err := ctx.GetClientIdentity().GetAttributeValue(attribute)
+		if err != nil {
+			return fmt.Errorf("failed to read attribute %s: %v", attribute, err)
+		}
+		if !ok || val != required {
+			return fmt.Errorf("not eligible for %s: attribute %s must be %s", function, attribute, required)
+		}
+	}
+
+	return nil
+}
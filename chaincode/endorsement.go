@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"google.golang.org/protobuf/proto"
+)
+
+// ============================================================================
+// KEY-LEVEL ENDORSEMENT POLICIES
+// ============================================================================
+
+// When KeyEndorsementOrgs is configured, the config, the role lists and the
+// treasury records carry a state-based endorsement policy requiring peers of
+// KeyEndorsementQuorum of those organizations (all of them if 0) to endorse
+// any change. This is enforced by the peers' validation, on top of the
+// chaincode's own isAdmin checks, so a single organization cannot rewrite
+// these records even with a modified chaincode.
+//
+// A policy stays attached to a key until it is replaced. UpdateConfig
+// re-applies it to the fixed keys below; treasury records get it as they
+// are written.
+
+// sensitiveKeys are the fixed keys protected by the key-level policy,
+// including every treasury shard
+var sensitiveKeys = append([]string{
+	configKey,
+	adminListKey,
+	adminExpiryKey,
+	arbitratorListKey,
+	arbitratorExpiryKey,
+}, aggregateKeys(treasuryKey)...)
+
+// keyEndorsementPolicy returns the serialized policy for sensitive keys, or
+// nil if none is configured
+func keyEndorsementPolicy(config *SystemConfig) ([]byte, error) {
+	if len(config.KeyEndorsementOrgs) == 0 {
+		return nil, nil
+	}
+
+	// Sorted so that every endorser produces identical bytes
+	orgs := append([]string{}, config.KeyEndorsementOrgs...)
+	sort.Strings(orgs)
+
+	var principals []*msp.MSPPrincipal
+	var rules []*common.SignaturePolicy
+	for i, org := range orgs {
+		role, err := proto.Marshal(&msp.MSPRole{MspIdentifier: org, Role: msp.MSPRole_PEER})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal role of %s: %v", org, err)
+		}
+		principals = append(principals, &msp.MSPPrincipal{
+			PrincipalClassification: msp.MSPPrincipal_ROLE,
+			Principal:               role,
+		})
+		rules = append(rules, &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_SignedBy{SignedBy: int32(i)},
+		})
+	}
+
+	quorum := config.KeyEndorsementQuorum
+	if quorum == 0 {
+		quorum = len(orgs)
+	}
+
+	policy := &common.SignaturePolicyEnvelope{
+		Rule: &common.SignaturePolicy{
+			Type: &common.SignaturePolicy_NOutOf_{
+				NOutOf: &common.SignaturePolicy_NOutOf{N: int32(quorum), Rules: rules},
+			},
+		},
+		Identities: principals,
+	}
+
+	policyBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal endorsement policy: %v", err)
+	}
+
+	return policyBytes, nil
+}
+
+// protectKey attaches the configured policy to a key written in this
+// transaction. It does nothing if no policy is configured.
+func protectKey(ctx contractapi.TransactionContextInterface, key string) error {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	policy, err := keyEndorsementPolicy(config)
+	if err != nil || policy == nil {
+		return err
+	}
+
+	if err := ctx.GetStub().SetStateValidationParameter(key, policy); err != nil {
+		return fmt.Errorf("failed to set endorsement policy of %s: %v", key, err)
+	}
+
+	return nil
+}
+
+// applyKeyEndorsementPolicies sets, or clears if none is configured, the
+// policy of every existing sensitive key
+func applyKeyEndorsementPolicies(ctx contractapi.TransactionContextInterface, config *SystemConfig) error {
+	policy, err := keyEndorsementPolicy(config)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range sensitiveKeys {
+		value, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", key, err)
+		}
+		if value == nil && key != configKey {
+			continue
+		}
+		if err := ctx.GetStub().SetStateValidationParameter(key, policy); err != nil {
+			return fmt.Errorf("failed to set endorsement policy of %s: %v", key, err)
+		}
+	}
+
+	return nil
+}
+
+// validateKeyEndorsement checks the key-level policy parameters
+func validateKeyEndorsement(config *SystemConfig) error {
+	seen := make(map[string]bool)
+	for _, org := range config.KeyEndorsementOrgs {
+		if org == "" || seen[org] {
+			return fmt.Errorf("keyEndorsementOrgs must be distinct, non-empty MSP IDs")
+		}
+		seen[org] = true
+	}
+	if config.KeyEndorsementQuorum < 0 || config.KeyEndorsementQuorum > len(config.KeyEndorsementOrgs) {
+		return fmt.Errorf("keyEndorsementQuorum must be between 0 and the number of keyEndorsementOrgs")
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// EVENTS
+// ============================================================================
+
+// eventSchemaVersion is the version of the EventEnvelope layout. Payload
+// shapes are documented per event type in docs/events.md; adding a payload
+// field is compatible, removing or retyping one requires a version bump.
+const eventSchemaVersion = 1
+
+// EventEnvelope wraps every chaincode event payload
+type EventEnvelope struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	EventType     string      `json:"eventType"`
+	TxID          string      `json:"txId"`
+	Timestamp     int64       `json:"timestamp"` // transaction time, unix seconds
+	Payload       interface{} `json:"payload"`
+
+	// Correlation: the canonical ID of the identity that submitted the
+	// transaction, and the state keys of the records it acted on (such as
+	// the rating or dispute that caused a reputation update or a slash)
+	// followed by those the event itself changed
+	InitiatorID string   `json:"initiatorId,omitempty"`
+	Keys        []string `json:"keys,omitempty"`
+
+	// The tenant the transaction ran in, omitted for the default space
+	Tenant string `json:"tenant,omitempty"`
+
+	// Events emitted earlier in the same transaction, oldest first. Only
+	// the last event of a transaction reaches clients, so it carries the
+	// ones it replaced.
+	Preceding []EventEnvelope `json:"preceding,omitempty"`
+}
+
+// emitEvent sets the transaction's chaincode event, wrapping the payload in
+// the standard envelope. Fabric delivers one event per transaction, so a
+// later call replaces an earlier one; the replaced events travel in the new
+// envelope's Preceding list. keys are the state keys of records the event
+// changed; keys registered with correlateEvents are listed before them.
+func emitEvent(ctx contractapi.TransactionContextInterface, eventType string, payload interface{}, keys ...string) {
+	envelope := EventEnvelope{
+		SchemaVersion: eventSchemaVersion,
+		EventType:     eventType,
+		TxID:          ctx.GetStub().GetTxID(),
+		Payload:       payload,
+		Tenant:        currentTenant(ctx),
+	}
+	if now, err := txUnixTime(ctx); err == nil {
+		envelope.Timestamp = now
+	}
+	if initiatorID, err := callerIdentity(ctx); err == nil {
+		envelope.InitiatorID = initiatorID
+	}
+
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		envelope.Keys = appendUnique(append([]string{}, rctx.eventKeys...), keys...)
+		envelope.Preceding = rctx.events
+		rctx.events = append(append([]EventEnvelope{}, rctx.events...), EventEnvelope{
+			SchemaVersion: envelope.SchemaVersion,
+			EventType:     envelope.EventType,
+			TxID:          envelope.TxID,
+			Timestamp:     envelope.Timestamp,
+			Payload:       envelope.Payload,
+			InitiatorID:   envelope.InitiatorID,
+			Keys:          envelope.Keys,
+			Tenant:        envelope.Tenant,
+		})
+	} else {
+		envelope.Keys = appendUnique(nil, keys...)
+	}
+
+	envelopeJSON, _ := marshalCanonical(envelope)
+	ctx.GetStub().SetEvent(eventType, envelopeJSON)
+
+	journalEvent(ctx, envelope)
+}
+
+// correlateEvents registers the state keys of the records a transaction
+// acts on, so that every event it emits afterwards lists them
+func correlateEvents(ctx contractapi.TransactionContextInterface, keys ...string) {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		rctx.eventKeys = appendUnique(rctx.eventKeys, keys...)
+	}
+}
+
+// appendUnique appends the non-empty keys not already in list
+func appendUnique(list []string, keys ...string) []string {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		present := false
+		for _, existing := range list {
+			if existing == key {
+				present = true
+				break
+			}
+		}
+		if !present {
+			list = append(list, key)
+		}
+	}
+	return list
+}
+
+// ============================================================================
+// EVENT JOURNAL
+// ============================================================================
+
+// Every event is also written to world state under
+// EVENT_JOURNAL:<txNanos>:<txId>:<seq>, so consumers that missed events can
+// page through them by transaction time instead of replaying blocks from
+// genesis. Journal entries are only committed with their transaction, like
+// the delivered event itself.
+const eventJournalPrefix = "EVENT_JOURNAL"
+
+// JournalEntry is one journaled event. Sequence is the event's position
+// among the events its transaction emitted.
+type JournalEntry struct {
+	Sequence int           `json:"sequence"`
+	Event    EventEnvelope `json:"event"`
+}
+
+// EventPage is one page of GetEventsSince
+type EventPage struct {
+	Entries      []JournalEntry `json:"entries"`
+	Bookmark     string         `json:"bookmark"` // "" once the journal is exhausted
+	FetchedCount int32          `json:"fetchedCount"`
+}
+
+// GetEventsSince pages through journaled events from transactions at or
+// after sinceTs (unix seconds), ordered by transaction time. Transaction
+// times are set by clients and may commit out of order by a few seconds, so
+// a consumer catching up should start a little before the last event it saw
+// and skip entries it already has by txId and sequence.
+func (rc *ReputationContract) GetEventsSince(
+	ctx contractapi.TransactionContextInterface,
+	sinceTs int64,
+	bookmark string,
+	pageSize int,
+) (*EventPage, error) {
+	if sinceTs < 0 {
+		return nil, fmt.Errorf("sinceTs must not be negative")
+	}
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	startKey := fmt.Sprintf("%s:%019d", eventJournalPrefix, sinceTs*int64(time.Second))
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(
+		startKey, eventJournalPrefix+";", int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event journal: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	page := &EventPage{
+		Entries:      []JournalEntry{},
+		FetchedCount: metadata.FetchedRecordsCount,
+	}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry JournalEntry
+		if err := json.Unmarshal(queryResponse.Value, &entry); err != nil {
+			continue
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+
+	if int(metadata.FetchedRecordsCount) == pageSize {
+		page.Bookmark = metadata.Bookmark
+	}
+
+	return page, nil
+}
+
+// journalEvent stores an emitted event in the journal. Like SetEvent in
+// emitEvent, a failure here does not fail the transaction.
+func journalEvent(ctx contractapi.TransactionContextInterface, envelope EventEnvelope) {
+	var nanos int64
+	if now, err := txTime(ctx); err == nil {
+		nanos = now.UnixNano()
+	}
+
+	entry := JournalEntry{Sequence: len(envelope.Preceding), Event: envelope}
+	entry.Event.Preceding = nil
+
+	entryJSON, err := marshalCanonical(entry)
+	if err != nil {
+		return
+	}
+	key := fmt.Sprintf("%s:%019d:%s:%04d", eventJournalPrefix, nanos, envelope.TxID, entry.Sequence)
+	ctx.GetStub().PutState(key, entryJSON)
+}
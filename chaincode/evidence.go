@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// EVIDENCE LOOKUP
+// ============================================================================
+
+// GetRatingsByEvidenceHash returns every rating whose evidence is the given
+// document hash (hex SHA-1/SHA-256/SHA-384/SHA-512, optionally prefixed
+// "sha256:" etc.) or IPFS CID ("ipfs://<cid>"), newest first
+func (rtc *RatingContract) GetRatingsByEvidenceHash(
+	ctx contractapi.TransactionContextInterface,
+	hash string,
+) ([]Rating, error) {
+	normalizedHash, ok := evidenceHash(hash)
+	if !ok {
+		return nil, fmt.Errorf("invalid evidence hash: %s", hash)
+	}
+
+	ratings := []Rating{}
+	err := forEachIndexedRating(ctx, ratingByEvidenceIndex, []string{normalizedHash}, func(rating *Rating) (bool, error) {
+		ratings = append(ratings, *rating)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
+
+// ============================================================================
+// EVIDENCE HELPERS
+// ============================================================================
+
+// evidenceHash extracts the normalized document hash or IPFS CID from a
+// rating's evidence, reporting false if the evidence is neither. CIDs are
+// normalized to ipfs:// and their base32 form.
+func evidenceHash(evidence string) (string, bool) {
+	if cid, err := evidenceCID(evidence); err == nil && cid != "" {
+		return ipfsScheme + cid, true
+	}
+
+	hash := strings.ToLower(strings.TrimSpace(evidence))
+	if i := strings.Index(hash, ":"); i >= 0 && strings.HasPrefix(hash, "sha") {
+		hash = hash[i+1:]
+	}
+
+	switch len(hash) {
+	case 40, 64, 96, 128:
+	default:
+		return "", false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+
+	return hash, true
+}
+
+// indexRatingEvidence files a rating under its evidence hash, if it has one
+func indexRatingEvidence(ctx contractapi.TransactionContextInterface, rating *Rating) error {
+	hash, ok := evidenceHash(rating.Evidence)
+	if !ok {
+		return nil
+	}
+	return putIndexEntry(ctx, ratingByEvidenceIndex, []string{hash, invertedTimestamp(rating.Timestamp), rating.RatingID})
+}
+
+// unindexRatingEvidence removes a rating's evidence hash entry, e.g. when
+// the evidence is purged
+func unindexRatingEvidence(ctx contractapi.TransactionContextInterface, rating *Rating, evidence string) error {
+	hash, ok := evidenceHash(evidence)
+	if !ok {
+		return nil
+	}
+	return delIndexEntry(ctx, ratingByEvidenceIndex, []string{hash, invertedTimestamp(rating.Timestamp), rating.RatingID})
+}
+
+// ============================================================================
+// IPFS EVIDENCE
+// ============================================================================
+
+// Evidence of the form ipfs://<cid> refers to a document in IPFS. The CID
+// must be a CIDv1 in base32 ("b"), base58btc ("z") or base16 ("f")
+// multibase over a known multihash, so the reference stays tamper-evident:
+// the CID commits to the document's content.
+
+// ipfsScheme prefixes IPFS evidence
+const ipfsScheme = "ipfs://"
+
+// cidMultihashLengths maps supported multihash codes to their digest length
+var cidMultihashLengths = map[uint64]int{
+	0x12:   32, // sha2-256
+	0x13:   64, // sha2-512
+	0x16:   32, // sha3-256
+	0x14:   64, // sha3-512
+	0x1e:   32, // blake3
+	0xb220: 32, // blake2b-256
+}
+
+// base58Alphabet is the bitcoin base58 alphabet used by base58btc
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// evidenceCID validates IPFS evidence and returns its CID in canonical
+// base32 form. Evidence without the ipfs:// prefix yields "".
+func evidenceCID(evidence string) (string, error) {
+	evidence = strings.TrimSpace(evidence)
+	if !strings.HasPrefix(evidence, ipfsScheme) {
+		return "", nil
+	}
+	cid := strings.TrimPrefix(evidence, ipfsScheme)
+	if i := strings.IndexAny(cid, "/?#"); i >= 0 {
+		cid = cid[:i]
+	}
+	if cid == "" {
+		return "", fmt.Errorf("invalid IPFS evidence: missing CID")
+	}
+	if strings.HasPrefix(cid, "Qm") {
+		return "", fmt.Errorf("invalid IPFS evidence: CIDv0 %s is not accepted, use its CIDv1 form", cid)
+	}
+
+	raw, err := decodeMultibase(cid)
+	if err != nil {
+		return "", fmt.Errorf("invalid IPFS evidence %s: %v", cid, err)
+	}
+	if err := validateCIDv1(raw); err != nil {
+		return "", fmt.Errorf("invalid IPFS evidence %s: %v", cid, err)
+	}
+
+	return "b" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)), nil
+}
+
+// decodeMultibase decodes a base32, base58btc or base16 multibase string
+func decodeMultibase(encoded string) ([]byte, error) {
+	if len(encoded) < 2 {
+		return nil, fmt.Errorf("too short")
+	}
+
+	body := encoded[1:]
+	switch encoded[0] {
+	case 'b':
+		return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(body))
+	case 'z':
+		return decodeBase58(body)
+	case 'f':
+		return hex.DecodeString(body)
+	}
+	return nil, fmt.Errorf("unsupported multibase prefix %q", encoded[0])
+}
+
+// decodeBase58 decodes a base58btc string
+func decodeBase58(encoded string) ([]byte, error) {
+	value := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range encoded {
+		digit := strings.IndexRune(base58Alphabet, c)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	// Leading '1's encode leading zero bytes
+	zeros := 0
+	for zeros < len(encoded) && encoded[zeros] == '1' {
+		zeros++
+	}
+	return append(make([]byte, zeros), value.Bytes()...), nil
+}
+
+// validateCIDv1 checks a binary CID: version 1, a content codec, and a
+// multihash of a supported function with a digest of its exact length
+func validateCIDv1(raw []byte) error {
+	version, n := binary.Uvarint(raw)
+	if n <= 0 || version != 1 {
+		return fmt.Errorf("not a CIDv1")
+	}
+	raw = raw[n:]
+
+	if _, n = binary.Uvarint(raw); n <= 0 {
+		return fmt.Errorf("malformed codec")
+	}
+	raw = raw[n:]
+
+	code, n := binary.Uvarint(raw)
+	if n <= 0 {
+		return fmt.Errorf("malformed multihash")
+	}
+	raw = raw[n:]
+	expected, ok := cidMultihashLengths[code]
+	if !ok {
+		return fmt.Errorf("unsupported multihash 0x%x", code)
+	}
+
+	length, n := binary.Uvarint(raw)
+	if n <= 0 || length != uint64(expected) || len(raw[n:]) != expected {
+		return fmt.Errorf("digest length does not match multihash 0x%x", code)
+	}
+
+	return nil
+}
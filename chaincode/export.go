@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// PORTABLE REPUTATION PROOFS
+// ============================================================================
+
+// reputationProofFormat identifies the layout of ReputationProof
+const reputationProofFormat = "repcc-reputation-proof/1"
+
+// DimensionProof is one dimension of a reputation proof. StateHash is the
+// SHA-256 of the raw reputation record stored under StateKey, so a verifier
+// with ledger access can tie the scores to world state.
+type DimensionProof struct {
+	Dimension   string  `json:"dimension"`
+	Alpha       float64 `json:"alpha"`
+	Beta        float64 `json:"beta"`
+	Score       float64 `json:"score"`
+	CILower     float64 `json:"ciLower"`
+	CIUpper     float64 `json:"ciUpper"`
+	TotalEvents int     `json:"totalEvents"`
+	LastTs      int64   `json:"lastTs"`
+	StateKey    string  `json:"stateKey"`
+	StateHash   string  `json:"stateHash"`
+}
+
+// ReputationProof is a canonical snapshot of an actor's reputation. Every
+// field is derived from world state and the transaction timestamp, so all
+// endorsing peers return byte-identical payloads and the endorsed proposal
+// response can be presented to third parties as proof. Digest is the SHA-256
+// of the proof's JSON encoding with Digest empty.
+type ReputationProof struct {
+	Format        string           `json:"format"`
+	ChannelID     string           `json:"channelId"`
+	ActorID       string           `json:"actorId"`
+	ConfigVersion int              `json:"configVersion"`
+	AsOf          int64            `json:"asOf"`
+	Suspended     bool             `json:"suspended"`
+	Dimensions    []DimensionProof `json:"dimensions"`
+	Digest        string           `json:"digest,omitempty"`
+}
+
+// ExportReputationProof returns a canonical, endorsement-ready proof of an
+// actor's scores in every dimension they have been rated in
+func (rc *ReputationContract) ExportReputationProof(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*ReputationProof, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	suspended, err := isBanned(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := make([]string, 0, len(config.ValidDimensions))
+	for dimension := range config.ValidDimensions {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Strings(dimensions)
+
+	proof := &ReputationProof{
+		Format:        reputationProofFormat,
+		ChannelID:     ctx.GetStub().GetChannelID(),
+		ActorID:       normalizedActorID,
+		ConfigVersion: config.Version,
+		AsOf:          now,
+		Suspended:     suspended,
+		Dimensions:    []DimensionProof{},
+	}
+
+	for _, dimension := range dimensions {
+		stateKey := reputationStateKey(normalizedActorID, dimension)
+		repJSON, err := ctx.GetStub().GetState(stateKey)
+		if err == nil && repJSON == nil {
+			stateKey = legacyReputationKey(normalizedActorID, dimension)
+			repJSON, err = ctx.GetStub().GetState(stateKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reputation: %v", err)
+		}
+		if repJSON == nil {
+			continue
+		}
+
+		var rep Reputation
+		if err := json.Unmarshal(repJSON, &rep); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal reputation: %v", err)
+		}
+
+		effectiveRep := applyDynamicDecayAt(&rep, config, now)
+		ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+		stateHash := sha256.Sum256(repJSON)
+
+		proof.Dimensions = append(proof.Dimensions, DimensionProof{
+			Dimension:   dimension,
+			Alpha:       effectiveRep.Alpha.Float(),
+			Beta:        effectiveRep.Beta.Float(),
+			Score:       reputationScore(effectiveRep),
+			CILower:     ci[0],
+			CIUpper:     ci[1],
+			TotalEvents: rep.TotalEvents,
+			LastTs:      rep.LastTs,
+			StateKey:    stateKey,
+			StateHash:   hex.EncodeToString(stateHash[:]),
+		})
+	}
+
+	payload, err := json.Marshal(proof)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal proof: %v", err)
+	}
+	digest := sha256.Sum256(payload)
+	proof.Digest = hex.EncodeToString(digest[:])
+
+	return proof, nil
+}
+
+// ============================================================================
+// STATE EXPORT
+// ============================================================================
+
+// exportPageSize is the number of records returned per ExportState page
+const exportPageSize = 500
+
+// exportableRecordTypes lists the key prefixes ExportState can dump
+var exportableRecordTypes = map[string]bool{
+	"RATING":           true,
+	"REPUTATION":       true,
+	"DISPUTE":          true,
+	"STAKE":            true,
+	"STAKE_WITHDRAWAL": true,
+	"PROFILE":          true,
+	"BAN":              true,
+	"VOUCH":            true,
+	"ATTESTATION":      true,
+	"GROUP":            true,
+	"DIMENSION_STATS":  true,
+}
+
+// StateRecord is one exported key/value pair. Value is the stored JSON
+// document, unmodified.
+type StateRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// StatePage is one page of an ExportState dump
+type StatePage struct {
+	RecordType   string        `json:"recordType"`
+	Records      []StateRecord `json:"records"`
+	Bookmark     string        `json:"bookmark"` // "" once the record type is exhausted
+	FetchedCount int32         `json:"fetchedCount"`
+}
+
+// ExportState dumps one page of the raw records of a record type (e.g.
+// RATING, REPUTATION) in key order, for bulk loading into an analytics
+// warehouse. Start with an empty bookmark and repeat with the returned one
+// until it comes back empty.
+func (rc *ReputationContract) ExportState(
+	ctx contractapi.TransactionContextInterface,
+	prefix string,
+	bookmark string,
+) (*StatePage, error) {
+	if !isAdmin(ctx) {
+		return nil, fmt.Errorf("unauthorized: admin role required")
+	}
+	if !exportableRecordTypes[prefix] {
+		return nil, fmt.Errorf("record type cannot be exported: %s", prefix)
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(
+		prefix+":", prefix+";", exportPageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s records: %v", prefix, err)
+	}
+	defer resultsIterator.Close()
+
+	page := &StatePage{
+		RecordType:   prefix,
+		Records:      []StateRecord{},
+		FetchedCount: metadata.FetchedRecordsCount,
+	}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, StateRecord{
+			Key:   queryResponse.Key,
+			Value: string(queryResponse.Value),
+		})
+	}
+
+	if metadata.FetchedRecordsCount == exportPageSize {
+		page.Bookmark = metadata.Bookmark
+	}
+
+	return page, nil
+}
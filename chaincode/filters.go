@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// QUERY FILTERS
+// ============================================================================
+
+// ratingFilter bounds the value and weight of ratings returned by a query.
+// Bounds are inclusive.
+type ratingFilter struct {
+	minValue  float64
+	maxValue  float64
+	minWeight float64
+	maxWeight float64
+}
+
+// parseRatingFilter parses optional bounds; an empty string leaves that side
+// unbounded
+func parseRatingFilter(minValueStr, maxValueStr, minWeightStr, maxWeightStr string) (*ratingFilter, error) {
+	filter := &ratingFilter{
+		minValue:  math.Inf(-1),
+		maxValue:  math.Inf(1),
+		minWeight: math.Inf(-1),
+		maxWeight: math.Inf(1),
+	}
+
+	bounds := []struct {
+		name  string
+		value string
+		into  *float64
+	}{
+		{"minValue", minValueStr, &filter.minValue},
+		{"maxValue", maxValueStr, &filter.maxValue},
+		{"minWeight", minWeightStr, &filter.minWeight},
+		{"maxWeight", maxWeightStr, &filter.maxWeight},
+	}
+	for _, bound := range bounds {
+		if bound.value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(bound.value, 64)
+		if err != nil || math.IsNaN(parsed) {
+			return nil, fmt.Errorf("invalid %s: %s", bound.name, bound.value)
+		}
+		*bound.into = parsed
+	}
+
+	if filter.minValue > filter.maxValue {
+		return nil, fmt.Errorf("minValue exceeds maxValue")
+	}
+	if filter.minWeight > filter.maxWeight {
+		return nil, fmt.Errorf("minWeight exceeds maxWeight")
+	}
+
+	return filter, nil
+}
+
+// matches reports whether a rating lies within the filter's bounds.
+// Confidential ratings have no public value and fail any value bound.
+func (f *ratingFilter) matches(rating *Rating) bool {
+	if rating.Confidential && (!math.IsInf(f.minValue, -1) || !math.IsInf(f.maxValue, 1)) {
+		return false
+	}
+	return rating.Value >= f.minValue && rating.Value <= f.maxValue &&
+		rating.Weight >= f.minWeight && rating.Weight <= f.maxWeight
+}
+
+// ============================================================================
+// QUERY ORDERS
+// ============================================================================
+
+// Rating history orders. The default, newest first, is served by
+// RATING_BY_ACTOR; every other order has entries in RATING_BY_ACTOR_ORDER.
+const (
+	orderTimestampDesc = "timestamp:desc"
+	orderTimestampAsc  = "timestamp:asc"
+	orderValueAsc      = "value:asc"
+	orderValueDesc     = "value:desc"
+	orderWeightAsc     = "weight:asc"
+	orderWeightDesc    = "weight:desc"
+)
+
+// ratingOrders lists the orders kept in RATING_BY_ACTOR_ORDER
+var ratingOrders = []string{
+	orderTimestampAsc,
+	orderValueAsc,
+	orderValueDesc,
+	orderWeightAsc,
+	orderWeightDesc,
+}
+
+// parseRatingOrder validates a caller-supplied order; "" selects newest first
+func parseRatingOrder(order string) (string, error) {
+	if order == "" || order == orderTimestampDesc {
+		return orderTimestampDesc, nil
+	}
+	for _, indexed := range ratingOrders {
+		if order == indexed {
+			return order, nil
+		}
+	}
+	return "", fmt.Errorf("invalid sort %q: must be one of %s, %s, %s, %s, %s, %s", order,
+		orderTimestampDesc, orderTimestampAsc, orderValueAsc, orderValueDesc, orderWeightAsc, orderWeightDesc)
+}
+
+// ratingSortKey encodes the field a rating order sorts on so that ascending
+// key order is the requested order
+func ratingSortKey(rating *Rating, order string) string {
+	switch order {
+	case orderTimestampAsc:
+		return fmt.Sprintf("%020d", uint64(rating.Timestamp)^(1<<63))
+	case orderValueAsc:
+		return sortableFloat(rating.Value)
+	case orderValueDesc:
+		return sortableFloat(-rating.Value)
+	case orderWeightAsc:
+		return sortableFloat(rating.Weight)
+	case orderWeightDesc:
+		return sortableFloat(-rating.Weight)
+	}
+	return ""
+}
+
+// sortableFloat encodes a float so that lexical order matches numeric order
+func sortableFloat(f float64) string {
+	bits := math.Float64bits(f)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	return fmt.Sprintf("%016x", bits)
+}
+
+// ============================================================================
+// QUERY INPUT VALIDATION
+// ============================================================================
+
+// disputeStatuses lists every status a dispute can be filed under
+var disputeStatuses = []string{"pending", "upheld", "overturned", "withdrawn", disputeExpired}
+
+// validateDisputeStatus rejects statuses no dispute can have
+func validateDisputeStatus(status string) error {
+	for _, known := range disputeStatuses {
+		if status == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid status %q: must be one of %s", status, strings.Join(disputeStatuses, ", "))
+}
+
+// validateQueryDimension rejects dimensions that are neither configured nor
+// the meta-dimension of a configured dimension
+func validateQueryDimension(config *SystemConfig, dimension string) error {
+	if config.ValidDimensions[dimension] {
+		return nil
+	}
+	for _, metaDimension := range config.MetaDimensions {
+		if dimension == metaDimension {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid dimension: %s", dimension)
+}
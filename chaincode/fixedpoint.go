@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// ============================================================================
+// FIXED-POINT ARITHMETIC
+// ============================================================================
+
+// Reputation parameters and score aggregates are kept as Fixed, a count of
+// millionths, so that every peer accumulates and reverses them with exact
+// integer arithmetic. Floats enter only from configuration and ratings,
+// rounded to the nearest millionth by fixedFromFloat, and leave only in
+// query responses.
+
+// Fixed is a decimal with six fractional digits, held as millionths
+type Fixed int64
+
+const (
+	// fixedScale is the number of Fixed units in 1
+	fixedScale = 1000000
+
+	fixedOne Fixed = fixedScale
+)
+
+// fixedFromFloat rounds f to the nearest millionth
+func fixedFromFloat(f float64) Fixed {
+	return Fixed(math.Round(f * fixedScale))
+}
+
+// Float returns f as a float64, for query responses and non-ledger maths
+func (f Fixed) Float() float64 {
+	return float64(f) / fixedScale
+}
+
+// Mul returns f*g, rounded half away from zero
+func (f Fixed) Mul(g Fixed) Fixed {
+	product := new(big.Int).Mul(big.NewInt(int64(f)), big.NewInt(int64(g)))
+	return fixedQuo(product, big.NewInt(fixedScale))
+}
+
+// String formats f with exactly six decimals
+func (f Fixed) String() string {
+	sign := ""
+	units := int64(f)
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, units/fixedScale, units%fixedScale)
+}
+
+// fixedRatio returns num/den, rounded half away from zero. A zero
+// denominator yields zero.
+func fixedRatio(num Fixed, den Fixed) Fixed {
+	if den == 0 {
+		return 0
+	}
+	scaled := new(big.Int).Mul(big.NewInt(int64(num)), big.NewInt(fixedScale))
+	return fixedQuo(scaled, big.NewInt(int64(den)))
+}
+
+// fixedQuo divides num by den, rounding half away from zero
+func fixedQuo(num *big.Int, den *big.Int) Fixed {
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	twice := new(big.Int).Lsh(new(big.Int).Abs(remainder), 1)
+	if twice.Cmp(new(big.Int).Abs(den)) >= 0 {
+		if num.Sign()*den.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return Fixed(quotient.Int64())
+}
+
+// ============================================================================
+// REPUTATION MATHS
+// ============================================================================
+
+// ratingEvidence returns the alpha and beta increments of a rating. Update
+// and reversal both use it, so an overturned rating is undone exactly.
+func ratingEvidence(weight float64, value float64) (Fixed, Fixed) {
+	w := fixedFromFloat(weight)
+	v := fixedFromFloat(value)
+	if v >= fixedOne/2 {
+		return w.Mul(v), 0
+	}
+	return 0, w.Mul(fixedOne - v)
+}
+
+// scoreFixed returns a reputation's posterior mean alpha/(alpha+beta)
+func scoreFixed(rep *Reputation) Fixed {
+	return fixedRatio(rep.Alpha, rep.Alpha+rep.Beta)
+}
+
+// reputationScore returns a reputation's posterior mean as a float, for
+// query responses and ranking
+func reputationScore(rep *Reputation) float64 {
+	return float64(rep.Alpha) / float64(rep.Alpha+rep.Beta)
+}
+
+// ============================================================================
+// RECORDS WRITTEN BEFORE FIXED POINT
+// ============================================================================
+
+// UnmarshalJSON also accepts reputation records stored with float alpha and
+// beta, which remain in key history and, until UpgradeState migrates them,
+// in state
+func (rep *Reputation) UnmarshalJSON(data []byte) error {
+	type storedReputation Reputation
+	var record struct {
+		storedReputation
+		LegacyAlpha *float64 `json:"alpha"`
+		LegacyBeta  *float64 `json:"beta"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	*rep = Reputation(record.storedReputation)
+	if record.LegacyAlpha != nil {
+		rep.Alpha = fixedFromFloat(*record.LegacyAlpha)
+	}
+	if record.LegacyBeta != nil {
+		rep.Beta = fixedFromFloat(*record.LegacyBeta)
+	}
+	return nil
+}
+
+// UnmarshalJSON also accepts dimension statistics stored with a float score
+// sum
+func (stats *DimensionStats) UnmarshalJSON(data []byte) error {
+	type storedDimensionStats DimensionStats
+	var record struct {
+		storedDimensionStats
+		LegacyScoreSum *float64 `json:"scoreSum"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	*stats = DimensionStats(record.storedDimensionStats)
+	if record.LegacyScoreSum != nil {
+		stats.ScoreSum = fixedFromFloat(*record.LegacyScoreSum)
+	}
+	return nil
+}
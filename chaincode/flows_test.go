@@ -0,0 +1,785 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/chaincode/repcc/internal/chaincodetest"
+)
+
+// testLedger runs contract functions as transactions against a mock stub,
+// one minute apart
+type testLedger struct {
+	t          *testing.T
+	stub       *chaincodetest.MockStub
+	identities map[string]*chaincodetest.MockClientIdentity
+	now        time.Time
+	txs        int
+}
+
+func newTestLedger(t *testing.T) *testLedger {
+	return &testLedger{
+		t:          t,
+		stub:       chaincodetest.NewMockStub(),
+		identities: map[string]*chaincodetest.MockClientIdentity{},
+		now:        time.Unix(1700000000, 0),
+	}
+}
+
+// enroll issues an Org1MSP identity for name with the given attributes
+func (l *testLedger) enroll(name string, attrs map[string]string) {
+	l.t.Helper()
+	l.enrollIn("Org1MSP", name, attrs)
+}
+
+// enrollIn issues an identity of mspID for name with the given attributes
+func (l *testLedger) enrollIn(mspID string, name string, attrs map[string]string) {
+	l.t.Helper()
+	identity, err := chaincodetest.NewMockClientIdentity(mspID, name, attrs)
+	if err != nil {
+		l.t.Fatalf("failed to enroll %s: %v", name, err)
+	}
+	l.identities[name] = identity
+}
+
+// invoke runs fn as a transaction submitted by caller, committing its
+// writes if it succeeds and discarding them otherwise
+func (l *testLedger) invoke(caller string, fn func(ctx *ReputationContext) error) error {
+	l.t.Helper()
+	identity, ok := l.identities[caller]
+	if !ok {
+		l.t.Fatalf("%s is not enrolled", caller)
+	}
+
+	l.txs++
+	l.now = l.now.Add(time.Minute)
+	l.stub.Begin(fmt.Sprintf("tx%d", l.txs), l.now)
+	l.stub.WithIdentity(identity)
+
+	ctx := new(ReputationContext)
+	ctx.SetStub(l.stub)
+	ctx.SetClientIdentity(identity)
+
+	if err := fn(ctx); err != nil {
+		l.stub.Rollback()
+		return err
+	}
+	l.stub.Commit()
+	return nil
+}
+
+// mustInvoke is invoke failing the test on error
+func (l *testLedger) mustInvoke(caller string, fn func(ctx *ReputationContext) error) {
+	l.t.Helper()
+	if err := l.invoke(caller, fn); err != nil {
+		l.t.Fatalf("%s: %v", caller, err)
+	}
+}
+
+// config reads the committed config
+func (l *testLedger) config() *SystemConfig {
+	l.t.Helper()
+	var config *SystemConfig
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		var err error
+		config, err = getConfig(ctx)
+		return err
+	})
+	return config
+}
+
+// stake reads an actor's committed stake
+func (l *testLedger) stake(actorID string) *Stake {
+	l.t.Helper()
+	var stake *Stake
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		var err error
+		stake, err = (&StakeContract{}).GetStake(ctx, actorID)
+		return err
+	})
+	return stake
+}
+
+// treasury reads the committed treasury
+func (l *testLedger) treasury() *TreasuryView {
+	l.t.Helper()
+	var view *TreasuryView
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		var err error
+		view, err = (&GovernanceContract{}).GetTreasury(ctx)
+		return err
+	})
+	return view
+}
+
+func assertAmount(t *testing.T, what string, got float64, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s = %v, want %v", what, got, want)
+	}
+}
+
+func TestOverturnedRatingSlashesRater(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("arbitrator", nil)
+	l.enroll("rater", nil)
+	l.enroll("seller", nil)
+
+	governance := &GovernanceContract{}
+	stakes := &StakeContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.InitConfig(ctx) })
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.AddArbitrator(ctx, "arbitrator") })
+	l.mustInvoke("rater", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	l.mustInvoke("seller", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	config := l.config()
+
+	var ratingID string
+	l.mustInvoke("rater", func(ctx *ReputationContext) error {
+		var err error
+		ratingID, err = (&RatingContract{}).SubmitRating(ctx, "seller", "quality", "0.1", "item never arrived", "1700000100")
+		return err
+	})
+	if event := l.stub.LastEvent(); event.Name != "RatingSubmitted" {
+		t.Fatalf("last event = %s, want RatingSubmitted", event.Name)
+	}
+
+	var disputeID string
+	l.mustInvoke("seller", func(ctx *ReputationContext) error {
+		var err error
+		disputeID, err = (&DisputeContract{}).InitiateDispute(ctx, ratingID, "tracking shows delivery")
+		return err
+	})
+	if locked := l.stake("seller").Locked; locked != config.DisputeCost {
+		t.Errorf("seller locked = %v, want the dispute cost %v", locked, config.DisputeCost)
+	}
+
+	// Only an arbitrator decides
+	err := l.invoke("seller", func(ctx *ReputationContext) error {
+		return (&DisputeContract{}).ResolveDispute(ctx, disputeID, "overturned", "")
+	})
+	if err == nil {
+		t.Fatal("seller resolved their own dispute")
+	}
+	l.mustInvoke("arbitrator", func(ctx *ReputationContext) error {
+		return (&DisputeContract{}).ResolveDispute(ctx, disputeID, "overturned", "delivery confirmed")
+	})
+
+	var dispute *Dispute
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		var err error
+		dispute, err = (&DisputeContract{}).GetDispute(ctx, disputeID)
+		return err
+	})
+	if dispute.Status != "overturned" || dispute.ArbitratorID != "arbitrator" {
+		t.Errorf("dispute = %s by %s, want overturned by arbitrator", dispute.Status, dispute.ArbitratorID)
+	}
+
+	slashed := 15000 * config.SlashPercentage
+	initiatorShare := slashed * config.SlashInitiatorShare
+	rater := l.stake("rater")
+	assertAmount(t, "rater balance", rater.Balance, 15000-slashed)
+	seller := l.stake("seller")
+	assertAmount(t, "seller balance", seller.Balance, 15000+initiatorShare)
+	assertAmount(t, "seller locked", seller.Locked, 0)
+
+	treasury := l.treasury()
+	assertAmount(t, "treasury balance", treasury.Treasury.Balance, slashed-initiatorShare)
+	if len(treasury.History) != 1 || treasury.History[0].Counterpart != "rater" {
+		t.Errorf("treasury history = %+v, want one inflow from rater", treasury.History)
+	}
+}
+
+func TestTreasuryWithdrawalNeedsApprovals(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("admin2", map[string]string{"admin": "true"})
+	l.enroll("member", nil)
+
+	governance := &GovernanceContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.InitConfig(ctx) })
+	if approvals := l.config().TreasuryApprovals; approvals != 2 {
+		t.Fatalf("TreasuryApprovals = %d, the test assumes 2", approvals)
+	}
+
+	// Fund the treasury as a slash would
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		return recordTreasuryInflow(ctx, 500, "member", "stake slash", "STAKE:member")
+	})
+
+	err := l.invoke("member", func(ctx *ReputationContext) error {
+		_, err := governance.ProposeTreasuryWithdrawal(ctx, "member", "100", "grant")
+		return err
+	})
+	if err == nil {
+		t.Fatal("a non-admin proposed a withdrawal")
+	}
+
+	var withdrawalID string
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		var err error
+		withdrawalID, err = governance.ProposeTreasuryWithdrawal(ctx, "member", "100", "grant")
+		return err
+	})
+	assertAmount(t, "balance after proposal", l.treasury().Treasury.Balance, 500)
+
+	// The proposer's approval is already counted
+	err = l.invoke("admin", func(ctx *ReputationContext) error {
+		return governance.ApproveTreasuryWithdrawal(ctx, withdrawalID)
+	})
+	if err == nil {
+		t.Fatal("the proposer approved twice")
+	}
+
+	l.mustInvoke("admin2", func(ctx *ReputationContext) error {
+		return governance.ApproveTreasuryWithdrawal(ctx, withdrawalID)
+	})
+
+	treasury := l.treasury()
+	assertAmount(t, "balance after approval", treasury.Treasury.Balance, 400)
+	assertAmount(t, "total outflow", treasury.Treasury.TotalOutflow, 100)
+
+	var withdrawal *TreasuryWithdrawal
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		var err error
+		withdrawal, err = getTreasuryWithdrawal(ctx, withdrawalID)
+		return err
+	})
+	if withdrawal.Status != "executed" {
+		t.Errorf("withdrawal status = %s, want executed", withdrawal.Status)
+	}
+
+	// An executed withdrawal takes no further approvals
+	l.enroll("admin3", map[string]string{"admin": "true"})
+	err = l.invoke("admin3", func(ctx *ReputationContext) error {
+		return governance.ApproveTreasuryWithdrawal(ctx, withdrawalID)
+	})
+	if err == nil {
+		t.Fatal("an executed withdrawal was approved")
+	}
+}
+
+func TestMigrateActorRecordsMovesPairsAndRoles(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("rater", nil)
+	l.enroll("seller", nil)
+
+	governance := &GovernanceContract{}
+	stakes := &StakeContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.InitConfig(ctx) })
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.AddArbitrator(ctx, "rater") })
+	l.mustInvoke("rater", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	l.mustInvoke("seller", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	l.mustInvoke("rater", func(ctx *ReputationContext) error {
+		_, err := (&RatingContract{}).SubmitRating(ctx, "seller", "quality", "0.8", "", "1700000100")
+		return err
+	})
+	l.mustInvoke("seller", func(ctx *ReputationContext) error {
+		_, err := (&RatingContract{}).SubmitRating(ctx, "rater", "quality", "0.9", "", "1700000200")
+		return err
+	})
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		profile, err := getOrInitProfile(ctx, "rater")
+		if err != nil {
+			return err
+		}
+		return refreshListingProfile(ctx, profile)
+	})
+
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		return (&ReputationContract{}).MigrateActorRecords(ctx, "rater", "rater2")
+	})
+
+	assertAmount(t, "migrated balance", l.stake("rater2").Balance, 15000)
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		for key, want := range map[string]bool{
+			raterActorStateKey("rater", "seller", "quality"):  false,
+			raterActorStateKey("rater2", "seller", "quality"): true,
+			raterActorStateKey("seller", "rater", "quality"):  false,
+			raterActorStateKey("seller", "rater2", "quality"): true,
+		} {
+			value, err := ctx.GetStub().GetState(key)
+			if err != nil {
+				return err
+			}
+			if (value != nil) != want {
+				t.Errorf("%s present = %v, want %v", key, value != nil, want)
+			}
+		}
+
+		arbitrators, err := getRoleList(ctx, arbitratorListKey)
+		if err != nil {
+			return err
+		}
+		if arbitrators["rater"] || !arbitrators["rater2"] {
+			t.Errorf("arbitrators = %v, want rater2 in place of rater", arbitrators)
+		}
+
+		for id, want := range map[string]bool{"rater": false, "rater2": true} {
+			listing, err := getListing(ctx, id)
+			if err != nil {
+				return err
+			}
+			if (listing != nil) != want {
+				t.Errorf("%s listed = %v, want %v", id, listing != nil, want)
+			}
+		}
+		return nil
+	})
+}
+
+func TestOverturnedRatingSlashesUnbondingWithdrawal(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("arbitrator", nil)
+	l.enroll("rater", nil)
+	l.enroll("seller", nil)
+
+	governance := &GovernanceContract{}
+	stakes := &StakeContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.InitConfig(ctx) })
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.AddArbitrator(ctx, "arbitrator") })
+	l.mustInvoke("rater", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	l.mustInvoke("seller", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	config := l.config()
+
+	var ratingID string
+	l.mustInvoke("rater", func(ctx *ReputationContext) error {
+		var err error
+		ratingID, err = (&RatingContract{}).SubmitRating(ctx, "seller", "quality", "0.1", "item never arrived", "1700000100")
+		return err
+	})
+
+	// The rater takes most of its stake out right after rating
+	var withdrawalID string
+	l.mustInvoke("rater", func(ctx *ReputationContext) error {
+		var err error
+		withdrawalID, err = stakes.WithdrawStake(ctx, "10000")
+		return err
+	})
+
+	var disputeID string
+	l.mustInvoke("seller", func(ctx *ReputationContext) error {
+		var err error
+		disputeID, err = (&DisputeContract{}).InitiateDispute(ctx, ratingID, "tracking shows delivery")
+		return err
+	})
+	l.mustInvoke("arbitrator", func(ctx *ReputationContext) error {
+		return (&DisputeContract{}).ResolveDispute(ctx, disputeID, "overturned", "delivery confirmed")
+	})
+
+	kept := 1 - config.SlashPercentage
+	rater := l.stake("rater")
+	assertAmount(t, "rater balance", rater.Balance, 5000*kept)
+	assertAmount(t, "rater locked", rater.Locked, 10000*kept)
+
+	var withdrawal *StakeWithdrawal
+	l.mustInvoke("rater", func(ctx *ReputationContext) error {
+		var err error
+		withdrawal, err = stakes.GetStakeWithdrawal(ctx, withdrawalID)
+		return err
+	})
+	assertAmount(t, "withdrawal amount", withdrawal.Amount, 10000*kept)
+
+	slashed := 15000 * config.SlashPercentage
+	initiatorShare := slashed * config.SlashInitiatorShare
+	assertAmount(t, "treasury balance", l.treasury().Treasury.Balance, slashed-initiatorShare)
+}
+
+func TestResolvedDisputeRefundsTheCostItLocked(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("arbitrator", nil)
+	l.enroll("rater", nil)
+	l.enroll("seller", nil)
+
+	governance := &GovernanceContract{}
+	stakes := &StakeContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.InitConfig(ctx) })
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.AddArbitrator(ctx, "arbitrator") })
+	l.mustInvoke("rater", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	l.mustInvoke("seller", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	lockedCost := l.config().DisputeCost
+
+	var ratingID string
+	l.mustInvoke("rater", func(ctx *ReputationContext) error {
+		var err error
+		ratingID, err = (&RatingContract{}).SubmitRating(ctx, "seller", "quality", "0.1", "item never arrived", "1700000100")
+		return err
+	})
+	var disputeID string
+	l.mustInvoke("seller", func(ctx *ReputationContext) error {
+		var err error
+		disputeID, err = (&DisputeContract{}).InitiateDispute(ctx, ratingID, "tracking shows delivery")
+		return err
+	})
+
+	// The cost is raised while the dispute is pending
+	values := l.configValues()
+	values["disputeCost"] = lockedCost * 3
+	l.putConfigValues(values)
+
+	l.mustInvoke("arbitrator", func(ctx *ReputationContext) error {
+		return (&DisputeContract{}).ResolveDispute(ctx, disputeID, "upheld", "rating stands")
+	})
+
+	seller := l.stake("seller")
+	assertAmount(t, "seller locked", seller.Locked, 0)
+	assertAmount(t, "seller balance", seller.Balance, 15000)
+}
+
+// configValues reads the committed config as its stored JSON fields
+func (l *testLedger) configValues() map[string]interface{} {
+	l.t.Helper()
+	configJSON, err := json.Marshal(l.config())
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(configJSON, &values); err != nil {
+		l.t.Fatal(err)
+	}
+	return values
+}
+
+// putConfigValues stores values as the config, bypassing validation
+func (l *testLedger) putConfigValues(values map[string]interface{}) {
+	l.t.Helper()
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		configJSON, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		return ctx.GetStub().PutState(configKey, configJSON)
+	})
+}
+
+func TestConfigKeepsZeroParameters(t *testing.T) {
+	// Parameters for which zero is a valid setting
+	zeroable := []string{"slashInitiatorShare", "unbondingPeriod", "disputeTimeout", "archiveAge", "offboardingWindow", "vouchPriorWeight", "vouchPenalty", "fraudReciprocalScore"}
+
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	governance := &GovernanceContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.InitConfig(ctx) })
+	defaults := l.configValues()
+
+	values := l.configValues()
+	for _, key := range zeroable {
+		values[key] = 0
+	}
+	configJSON, _ := json.Marshal(values)
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		return governance.UpdateConfig(ctx, string(configJSON))
+	})
+	updated := l.configValues()
+	for _, key := range zeroable {
+		if updated[key] != 0.0 {
+			t.Errorf("%s = %v after setting 0", key, updated[key])
+		}
+	}
+
+	// A config stored before a parameter existed takes its default
+	for _, key := range zeroable {
+		delete(updated, key)
+	}
+	l.putConfigValues(updated)
+	backfilled := l.configValues()
+	for _, key := range zeroable {
+		if backfilled[key] != defaults[key] {
+			t.Errorf("%s = %v when unset, want the default %v", key, backfilled[key], defaults[key])
+		}
+	}
+}
+
+func TestMigratedProbationaryActorStaysOnProbation(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("newbie", nil)
+	l.enroll("newbie2", nil)
+
+	governance := &GovernanceContract{}
+	stakes := &StakeContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.InitConfig(ctx) })
+	l.mustInvoke("newbie", func(ctx *ReputationContext) error {
+		return (&ReputationContract{}).ApplyForMembership(ctx, "supplier of widgets")
+	})
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		return governance.ReviewApplication(ctx, "newbie", "approve", "")
+	})
+	l.mustInvoke("newbie", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	var withdrawalID string
+	l.mustInvoke("newbie", func(ctx *ReputationContext) error {
+		var err error
+		withdrawalID, err = stakes.WithdrawStake(ctx, "5000")
+		return err
+	})
+
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		return (&ReputationContract{}).MigrateActorRecords(ctx, "newbie", "newbie2")
+	})
+
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		for id, want := range map[string]bool{"newbie": false, "newbie2": true} {
+			probationary, err := isProbationary(ctx, id)
+			if err != nil {
+				return err
+			}
+			if probationary != want {
+				t.Errorf("%s probationary = %v, want %v", id, probationary, want)
+			}
+		}
+		return nil
+	})
+
+	// The migrated actor claims the withdrawal it started under the old ID
+	l.now = l.now.Add(time.Duration(l.config().UnbondingPeriod) * time.Second)
+	l.mustInvoke("newbie2", func(ctx *ReputationContext) error {
+		_, err := stakes.ClaimWithdrawal(ctx, withdrawalID)
+		return err
+	})
+	stake := l.stake("newbie2")
+	assertAmount(t, "balance after claim", stake.Balance, 10000)
+	assertAmount(t, "locked after claim", stake.Locked, 0)
+}
+
+// testCA issues certificates outside the mock identities, such as the old
+// certificate of a rotation
+type testCA struct {
+	t    *testing.T
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+	pem  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "rotation-ca"},
+		NotBefore:             time.Unix(0, 0),
+		NotAfter:              time.Unix(4000000000, 0),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{t: t, key: key, cert: cert, pem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+// issue returns a PEM certificate for commonName and its key
+func (ca *testCA) issue(commonName string) (string, *ecdsa.PrivateKey) {
+	ca.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(4000000000, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), key
+}
+
+// rotate rotates the actor of oldCertPEM onto caller, signing the challenge
+// with oldKey
+func (l *testLedger) rotate(caller string, oldID string, oldCertPEM string, oldKey *ecdsa.PrivateKey) error {
+	l.t.Helper()
+	return l.invoke(caller, func(ctx *ReputationContext) error {
+		rc := &ReputationContract{}
+		challenge, err := rc.GetRotationChallenge(ctx, oldID)
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256([]byte(challenge))
+		signature, err := ecdsa.SignASN1(rand.Reader, oldKey, digest[:])
+		if err != nil {
+			return err
+		}
+		return rc.RotateIdentity(ctx, oldCertPEM, base64.StdEncoding.EncodeToString(signature))
+	})
+}
+
+func TestRotationNeedsTheActorsOwnMSP(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("admin2", map[string]string{"admin": "true"})
+	l.enroll("mallory", nil)
+	l.enroll("carol", nil)
+	l.enroll("carol2", nil)
+	l.enrollIn("Org2MSP", "victim", nil)
+
+	rc := &ReputationContract{}
+	stakes := &StakeContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return (&GovernanceContract{}).InitConfig(ctx) })
+	l.mustInvoke("victim", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	l.mustInvoke("carol", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+
+	ca := newTestCA(t)
+	err := l.invoke("admin", func(ctx *ReputationContext) error { return rc.RegisterTrustedCA(ctx, "Org2MSP", ca.pem) })
+	if err == nil {
+		t.Fatal("an Org1MSP admin registered a CA for Org2MSP")
+	}
+
+	// One approval does not register the CA
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return rc.RegisterTrustedCA(ctx, "Org1MSP", ca.pem) })
+	carolCert, carolKey := ca.issue("carol")
+	if err := l.rotate("carol2", "carol", carolCert, carolKey); err == nil {
+		t.Fatal("rotated with a CA approved by one admin")
+	}
+	l.mustInvoke("admin2", func(ctx *ReputationContext) error { return rc.RegisterTrustedCA(ctx, "Org1MSP", ca.pem) })
+
+	// The CA can mint a certificate with any CN, but not take over an actor
+	// of another MSP
+	victimCert, victimKey := ca.issue("victim")
+	if err := l.rotate("mallory", "victim", victimCert, victimKey); err == nil {
+		t.Fatal("an Org1MSP certificate took over an Org2MSP actor")
+	}
+	assertAmount(t, "victim balance", l.stake("victim").Balance, 15000)
+
+	if err := l.rotate("carol2", "carol", carolCert, carolKey); err != nil {
+		t.Fatalf("rotation within the actor's MSP: %v", err)
+	}
+	assertAmount(t, "rotated balance", l.stake("carol2").Balance, 15000)
+}
+
+func TestOffboardingWaitsForLockedStake(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("leaver", nil)
+
+	stakes := &StakeContract{}
+	reputation := &ReputationContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return (&GovernanceContract{}).InitConfig(ctx) })
+	l.mustInvoke("leaver", func(ctx *ReputationContext) error { return stakes.AddStake(ctx, "15000") })
+	var withdrawalID string
+	l.mustInvoke("leaver", func(ctx *ReputationContext) error {
+		var err error
+		withdrawalID, err = stakes.WithdrawStake(ctx, "5000")
+		return err
+	})
+	l.mustInvoke("leaver", func(ctx *ReputationContext) error {
+		return reputation.DeactivateActor(ctx, "leaver", "leaving the consortium")
+	})
+
+	// The withdrawal still unbonding keeps part of the stake locked
+	config := l.config()
+	l.now = l.now.Add(time.Duration(config.OffboardingWindow) * time.Second)
+	err := l.invoke("leaver", func(ctx *ReputationContext) error {
+		_, err := reputation.CompleteOffboarding(ctx, "leaver")
+		return err
+	})
+	if err == nil {
+		t.Fatal("offboarding completed with stake still locked")
+	}
+
+	l.now = l.now.Add(time.Duration(config.UnbondingPeriod) * time.Second)
+	l.mustInvoke("leaver", func(ctx *ReputationContext) error {
+		_, err := stakes.ClaimWithdrawal(ctx, withdrawalID)
+		return err
+	})
+	var withdrawn float64
+	l.mustInvoke("leaver", func(ctx *ReputationContext) error {
+		var err error
+		withdrawn, err = reputation.CompleteOffboarding(ctx, "leaver")
+		return err
+	})
+	assertAmount(t, "withdrawn", withdrawn, 10000)
+
+	stake := l.stake("leaver")
+	assertAmount(t, "balance", stake.Balance, 0)
+	assertAmount(t, "locked", stake.Locked, 0)
+
+	var envelope EventEnvelope
+	if err := json.Unmarshal(l.stub.LastEvent().Payload, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if len(envelope.Preceding) != 1 || envelope.Preceding[0].EventType != stakeReleasedEvent {
+		t.Errorf("events before ActorOffboarded = %+v, want one %s", envelope.Preceding, stakeReleasedEvent)
+	}
+}
+
+func TestPendingBanIsWithdrawnByItsProposer(t *testing.T) {
+	l := newTestLedger(t)
+	l.enroll("admin", map[string]string{"admin": "true"})
+	l.enroll("admin2", map[string]string{"admin": "true"})
+	l.enroll("admin3", map[string]string{"admin": "true"})
+	l.enroll("mallory", nil)
+
+	governance := &GovernanceContract{}
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.InitConfig(ctx) })
+	if approvals := l.config().BanApprovals; approvals != 2 {
+		t.Fatalf("BanApprovals = %d, the test assumes 2", approvals)
+	}
+	ban := func() *Ban {
+		var ban *Ban
+		l.mustInvoke("admin", func(ctx *ReputationContext) error {
+			var err error
+			ban, err = governance.GetBan(ctx, "mallory")
+			return err
+		})
+		return ban
+	}
+
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		return governance.BanActor(ctx, "mallory", "wash trading")
+	})
+
+	// Another admin alone cannot withdraw the proposal
+	l.mustInvoke("admin2", func(ctx *ReputationContext) error { return governance.UnbanActor(ctx, "mallory") })
+	if status := ban().Status; status != "pending" {
+		t.Fatalf("ban status after one approval = %s, want pending", status)
+	}
+	if event := l.stub.LastEvent(); event.Name != "ActorUnbanApproved" {
+		t.Errorf("last event = %s, want ActorUnbanApproved", event.Name)
+	}
+
+	l.mustInvoke("admin", func(ctx *ReputationContext) error { return governance.UnbanActor(ctx, "mallory") })
+	if status := ban().Status; status != "lifted" {
+		t.Errorf("ban status after the proposer withdrew = %s, want lifted", status)
+	}
+	if event := l.stub.LastEvent(); event.Name != "BanWithdrawn" {
+		t.Errorf("last event = %s, want BanWithdrawn", event.Name)
+	}
+
+	// Without the proposer, withdrawing takes the unban quorum
+	l.mustInvoke("admin", func(ctx *ReputationContext) error {
+		return governance.BanActor(ctx, "mallory", "wash trading")
+	})
+	l.mustInvoke("admin2", func(ctx *ReputationContext) error { return governance.UnbanActor(ctx, "mallory") })
+	l.mustInvoke("admin3", func(ctx *ReputationContext) error { return governance.UnbanActor(ctx, "mallory") })
+	if status := ban().Status; status != "lifted" {
+		t.Errorf("ban status after the quorum withdrew = %s, want lifted", status)
+	}
+	if event := l.stub.LastEvent(); event.Name != "BanWithdrawn" {
+		t.Errorf("last event = %s, want BanWithdrawn", event.Name)
+	}
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// FRAUD-PATTERN DETECTION
+// ============================================================================
+
+// ScanFraudSignals works through the event journal in transaction time
+// order, a page per call, matching each event against known fraud patterns:
+//
+//   - burst_before_bond: a supplier received fraudBurstRatings or more
+//     ratings in a bond's dimension within fraudWindow before posting it
+//   - reciprocal_high_ratings: a rater gave a high rating (at least
+//     fraudReciprocalScore) to an actor that had given it a high rating
+//     within fraudWindow before
+//   - near_dispute_threshold: a buyer's rating released a bond with a value
+//     less than fraudThresholdMargin above bondReleaseValue, just clearing
+//     the value that would have put it in dispute
+//
+// Each match is stored as a FraudSignal for the arbitrators to review and
+// emitted as a FraudSignalRaised event. A signal's ID is derived from its
+// pattern and the record that completed it, so scanning an event again never
+// raises a signal twice. The scan stays fraudScanSettle behind the
+// transaction time, since transaction times are set by clients and
+// transactions may commit a little out of order.
+
+// Fraud patterns
+const (
+	fraudBurstBeforeBond       = "burst_before_bond"
+	fraudReciprocalHighRatings = "reciprocal_high_ratings"
+	fraudNearDisputeThreshold  = "near_dispute_threshold"
+)
+
+// Fraud signal severities
+const (
+	fraudSeverityLow    = "low"
+	fraudSeverityMedium = "medium"
+	fraudSeverityHigh   = "high"
+)
+
+const (
+	fraudScanSettle         = 5 * 60 // seconds the scan stays behind the transaction time
+	maxFraudSignalRecordIDs = 100    // record IDs kept per signal
+)
+
+// fraudSignalByActorIndex files signals by the actor they concern, newest
+// first: actorId~invDetectedAt~signalId
+const fraudSignalByActorIndex = "FRAUD_SIGNAL_BY_ACTOR"
+
+// FraudSignal is one detected occurrence of a fraud pattern
+type FraudSignal struct {
+	SignalID       string   `json:"signalId"`
+	Pattern        string   `json:"pattern"`        // burst_before_bond, reciprocal_high_ratings, near_dispute_threshold
+	Severity       string   `json:"severity"`       // low, medium, high
+	ActorID        string   `json:"actorId"`        // the actor whose reputation the pattern inflates
+	CounterpartyID string   `json:"counterpartyId"` // the buyer or rater involved
+	Dimension      string   `json:"dimension"`
+	Count          int      `json:"count"`     // ratings making up the pattern
+	RecordIDs      []string `json:"recordIds"` // the bond and ratings involved, at most maxFraudSignalRecordIDs
+	EventTxID      string   `json:"eventTxId"` // transaction of the event that completed the pattern
+	DetectedAt     int64    `json:"detectedAt"`
+}
+
+// FraudScan is the journal position of ScanFraudSignals
+type FraudScan struct {
+	LastKey   string `json:"lastKey"` // journal key of the last event scanned
+	ScannedAt int64  `json:"scannedAt"`
+}
+
+// FraudScanResult reports one page of ScanFraudSignals
+type FraudScanResult struct {
+	Scanned int           `json:"scanned"` // journaled events scanned
+	Signals []FraudSignal `json:"signals"` // signals raised
+	Done    bool          `json:"done"`    // the scan caught up with the settled journal
+}
+
+// ScanFraudSignals scans the next page of journaled events for fraud
+// patterns; repeat until done. Progress is kept on the ledger.
+func (dc *DisputeContract) ScanFraudSignals(
+	ctx contractapi.TransactionContextInterface,
+	pageSize int,
+) (*FraudScanResult, error) {
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := getFraudScan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	startKey := eventJournalPrefix + ":"
+	if scan.LastKey != "" {
+		startKey = scan.LastKey + "\x00"
+	}
+	endKey := fmt.Sprintf("%s:%019d", eventJournalPrefix, (now-fraudScanSettle)*int64(time.Second))
+
+	result := &FraudScanResult{Signals: []FraudSignal{}, Done: true}
+	if startKey < endKey {
+		resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, endKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event journal: %v", err)
+		}
+		defer resultsIterator.Close()
+
+		for resultsIterator.HasNext() {
+			if result.Scanned == pageSize {
+				result.Done = false
+				break
+			}
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				return nil, err
+			}
+			scan.LastKey = queryResponse.Key
+			result.Scanned++
+
+			var entry JournalEntry
+			if err := json.Unmarshal(queryResponse.Value, &entry); err != nil {
+				continue
+			}
+			signal, err := detectFraud(ctx, config, &entry.Event)
+			if err != nil {
+				return nil, err
+			}
+			if signal == nil {
+				continue
+			}
+			raised, err := raiseFraudSignal(ctx, signal, now)
+			if err != nil {
+				return nil, err
+			}
+			if raised {
+				result.Signals = append(result.Signals, *signal)
+			}
+		}
+	}
+
+	if result.Scanned > 0 {
+		scan.ScannedAt = now
+		if err := putFraudScan(ctx, scan); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// GetFraudSignal retrieves a fraud signal
+func (dc *DisputeContract) GetFraudSignal(
+	ctx contractapi.TransactionContextInterface,
+	signalID string,
+) (*FraudSignal, error) {
+	signal, err := getFraudSignal(ctx, signalID)
+	if err != nil {
+		return nil, err
+	}
+	if signal == nil {
+		return nil, fmt.Errorf("fraud signal not found: %s", signalID)
+	}
+	return signal, nil
+}
+
+// GetFraudSignalsForActor lists the fraud signals raised about an actor,
+// newest first
+func (dc *DisputeContract) GetFraudSignalsForActor(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) ([]FraudSignal, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(fraudSignalByActorIndex, []string{normalizedActorID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", fraudSignalByActorIndex, err)
+	}
+	defer resultsIterator.Close()
+
+	signals := []FraudSignal{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 3 {
+			continue
+		}
+		signal, err := getFraudSignal(ctx, parts[2])
+		if err != nil || signal == nil {
+			continue
+		}
+		signals = append(signals, *signal)
+	}
+
+	return signals, nil
+}
+
+// ============================================================================
+// FRAUD PATTERNS
+// ============================================================================
+
+// detectFraud matches a journaled event against the fraud patterns,
+// returning the signal it completes or nil
+func detectFraud(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	event *EventEnvelope,
+) (*FraudSignal, error) {
+	var signal *FraudSignal
+	var err error
+
+	switch event.EventType {
+	case "RatingSubmitted":
+		var rating struct {
+			RatingID  string   `json:"ratingId"`
+			RaterID   string   `json:"raterId"`
+			ActorID   string   `json:"actorId"`
+			Dimension string   `json:"dimension"`
+			Value     *float64 `json:"value"` // absent for confidential ratings
+			Timestamp int64    `json:"timestamp"`
+		}
+		if decodeEventPayload(event, &rating) != nil || rating.Value == nil || *rating.Value < config.FraudReciprocalScore {
+			return nil, nil
+		}
+		signal, err = detectReciprocalRatings(ctx, config, rating.RatingID, rating.RaterID, rating.ActorID, rating.Dimension, rating.Timestamp)
+
+	case "BondPosted":
+		var bond Bond
+		if decodeEventPayload(event, &bond) != nil {
+			return nil, nil
+		}
+		signal, err = detectRatingBurst(ctx, config, &bond)
+
+	case "BondReleased":
+		var bond Bond
+		if decodeEventPayload(event, &bond) != nil {
+			return nil, nil
+		}
+		signal, err = detectNearThresholdRelease(ctx, config, &bond)
+	}
+
+	if err != nil || signal == nil {
+		return nil, err
+	}
+	signal.EventTxID = event.TxID
+	return signal, nil
+}
+
+// detectRatingBurst matches burst_before_bond: the ratings a supplier
+// received in the bond's dimension within the fraud window before posting it
+func detectRatingBurst(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	bond *Bond,
+) (*FraudSignal, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ratingByActorIndex, []string{bond.SupplierID, bond.Dimension})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", ratingByActorIndex, err)
+	}
+	defer resultsIterator.Close()
+
+	since := bond.CreatedAt - config.FraudWindow
+	ratingIDs := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 4 {
+			continue
+		}
+		ts := timestampFromInverted(parts[2])
+		if ts > bond.CreatedAt {
+			continue
+		}
+		if ts < since {
+			break // newest first
+		}
+		ratingIDs = append(ratingIDs, parts[3])
+	}
+
+	if len(ratingIDs) < config.FraudBurstRatings {
+		return nil, nil
+	}
+	severity := fraudSeverityMedium
+	if len(ratingIDs) >= 2*config.FraudBurstRatings {
+		severity = fraudSeverityHigh
+	}
+
+	return newFraudSignal(fraudBurstBeforeBond, severity, bond.SupplierID, bond.BuyerID, bond.Dimension,
+		bond.BondID, ratingIDs), nil
+}
+
+// detectReciprocalRatings matches reciprocal_high_ratings: the high ratings
+// the actor gave the rater, in any dimension, within the fraud window before
+// the rater's high rating ratingID. Only earlier ratings count, so the
+// signal is raised by the rating that completes a pair.
+func detectReciprocalRatings(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	ratingID string,
+	raterID string,
+	actorID string,
+	dimension string,
+	ts int64,
+) (*FraudSignal, error) {
+	ratingIDs := []string{}
+	err := forEachIndexedRating(ctx, raterActorIndex, []string{actorID, raterID}, func(rating *Rating) (bool, error) {
+		if !rating.Confidential && rating.Value >= config.FraudReciprocalScore &&
+			rating.Timestamp <= ts && rating.Timestamp >= ts-config.FraudWindow {
+			ratingIDs = append(ratingIDs, rating.RatingID)
+		}
+		return true, nil
+	})
+	if err != nil || len(ratingIDs) == 0 {
+		return nil, err
+	}
+
+	severity := fraudSeverityLow
+	switch {
+	case len(ratingIDs) >= 3:
+		severity = fraudSeverityHigh
+	case len(ratingIDs) == 2:
+		severity = fraudSeverityMedium
+	}
+
+	return newFraudSignal(fraudReciprocalHighRatings, severity, actorID, raterID, dimension, ratingID, ratingIDs), nil
+}
+
+// detectNearThresholdRelease matches near_dispute_threshold: a bond
+// released by its buyer's rating, rather than by an overturned dispute,
+// whose value only just reached bondReleaseValue
+func detectNearThresholdRelease(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	bond *Bond,
+) (*FraudSignal, error) {
+	if bond.Status != bondReleased || bond.RatingID == "" || bond.DisputeID != "" {
+		return nil, nil
+	}
+
+	rating, err := getRating(ctx, bond.RatingID)
+	if err != nil || rating == nil || rating.Confidential {
+		return nil, err
+	}
+	if rating.Value < bondReleaseValue || rating.Value >= bondReleaseValue+config.FraudThresholdMargin {
+		return nil, nil
+	}
+
+	return newFraudSignal(fraudNearDisputeThreshold, fraudSeverityMedium, bond.SupplierID, bond.BuyerID, bond.Dimension,
+		bond.BondID, []string{rating.RatingID}), nil
+}
+
+// ============================================================================
+// FRAUD SIGNAL HELPERS
+// ============================================================================
+
+// newFraudSignal builds a signal completed by the record triggerID. The
+// trigger leads the record IDs, followed by the ratings making up the
+// pattern.
+func newFraudSignal(
+	pattern string,
+	severity string,
+	actorID string,
+	counterpartyID string,
+	dimension string,
+	triggerID string,
+	ratingIDs []string,
+) *FraudSignal {
+	hash := sha256.Sum256([]byte(pattern + ":" + triggerID))
+
+	recordIDs := appendUnique([]string{triggerID}, ratingIDs...)
+	if len(recordIDs) > maxFraudSignalRecordIDs {
+		recordIDs = recordIDs[:maxFraudSignalRecordIDs]
+	}
+
+	return &FraudSignal{
+		SignalID:       fmt.Sprintf("%s%x", fraudSignalIDPrefix, hash[:16]),
+		Pattern:        pattern,
+		Severity:       severity,
+		ActorID:        actorID,
+		CounterpartyID: counterpartyID,
+		Dimension:      dimension,
+		Count:          len(ratingIDs),
+		RecordIDs:      recordIDs,
+	}
+}
+
+// raiseFraudSignal stores and announces a signal, returning false if it was
+// raised before
+func raiseFraudSignal(ctx contractapi.TransactionContextInterface, signal *FraudSignal, now int64) (bool, error) {
+	existing, err := getFraudSignal(ctx, signal.SignalID)
+	if err != nil || existing != nil {
+		return false, err
+	}
+
+	signal.DetectedAt = now
+	signalJSON, err := marshalCanonical(signal)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal fraud signal: %v", err)
+	}
+	if err := ctx.GetStub().PutState(signal.SignalID, signalJSON); err != nil {
+		return false, fmt.Errorf("failed to store fraud signal: %v", err)
+	}
+	if err := putIndexEntry(ctx, fraudSignalByActorIndex, []string{signal.ActorID, invertedTimestamp(now), signal.SignalID}); err != nil {
+		return false, err
+	}
+
+	emitEvent(ctx, "FraudSignalRaised", signal, signal.SignalID)
+	return true, nil
+}
+
+// decodeEventPayload decodes a journaled event's payload into v
+func decodeEventPayload(event *EventEnvelope, v interface{}) error {
+	payloadJSON, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payloadJSON, v)
+}
+
+// getFraudSignal loads a fraud signal, returning nil if it does not exist
+func getFraudSignal(ctx contractapi.TransactionContextInterface, signalID string) (*FraudSignal, error) {
+	if err := validateRecordID(signalID, fraudSignalIDPrefix); err != nil {
+		return nil, err
+	}
+
+	signalJSON, err := ctx.GetStub().GetState(signalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fraud signal: %v", err)
+	}
+	if signalJSON == nil {
+		return nil, nil
+	}
+
+	var signal FraudSignal
+	if err := json.Unmarshal(signalJSON, &signal); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fraud signal: %v", err)
+	}
+	return &signal, nil
+}
+
+// getFraudScan loads the fraud scan position, empty before the first scan
+func getFraudScan(ctx contractapi.TransactionContextInterface) (*FraudScan, error) {
+	scanJSON, err := ctx.GetStub().GetState(fraudScanKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fraud scan: %v", err)
+	}
+
+	var scan FraudScan
+	if scanJSON != nil {
+		if err := json.Unmarshal(scanJSON, &scan); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal fraud scan: %v", err)
+		}
+	}
+	return &scan, nil
+}
+
+// putFraudScan stores the fraud scan position
+func putFraudScan(ctx contractapi.TransactionContextInterface, scan *FraudScan) error {
+	scanJSON, err := marshalCanonical(scan)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fraud scan: %v", err)
+	}
+	if err := ctx.GetStub().PutState(fraudScanKey, scanJSON); err != nil {
+		return fmt.Errorf("failed to store fraud scan: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// GROUP DATA STRUCTURES
+// ============================================================================
+
+// Group is a sub-community of actors, e.g. a consortium's automotive
+// suppliers. Membership is indexed under GROUP_MEMBER composite keys.
+type Group struct {
+	GroupID     string   `json:"groupId"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Admins      []string `json:"admins"` // group admins may manage members
+	CreatedBy   string   `json:"createdBy"`
+	CreatedAt   int64    `json:"createdAt"`
+}
+
+// ============================================================================
+// GROUP FUNCTIONS
+// ============================================================================
+
+// CreateGroup creates a group with an initial group admin
+func (rc *ReputationContract) CreateGroup(
+	ctx contractapi.TransactionContextInterface,
+	groupID string,
+	name string,
+	description string,
+	groupAdminID string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	if groupID == "" || name == "" {
+		return fmt.Errorf("group ID and name are required")
+	}
+
+	existing, err := getGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("group already exists: %s", groupID)
+	}
+
+	adminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	group := &Group{
+		GroupID:     groupID,
+		Name:        name,
+		Description: description,
+		Admins:      []string{},
+		CreatedBy:   adminID,
+		CreatedAt:   now,
+	}
+	if groupAdminID != "" {
+		group.Admins = append(group.Admins, resolveIdentity(ctx, groupAdminID))
+	}
+
+	return putGroup(ctx, group, "GroupCreated")
+}
+
+// SetGroupAdmin grants or revokes group admin rights for an actor
+func (rc *ReputationContract) SetGroupAdmin(
+	ctx contractapi.TransactionContextInterface,
+	groupID string,
+	actorID string,
+	isGroupAdmin bool,
+) error {
+	group, err := getManagedGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	admins := []string{}
+	for _, admin := range group.Admins {
+		if admin != normalizedActorID {
+			admins = append(admins, admin)
+		}
+	}
+	if isGroupAdmin {
+		admins = append(admins, normalizedActorID)
+	}
+	group.Admins = admins
+
+	return putGroup(ctx, group, "GroupUpdated")
+}
+
+// AddGroupMember adds an actor to a group
+func (rc *ReputationContract) AddGroupMember(
+	ctx contractapi.TransactionContextInterface,
+	groupID string,
+	actorID string,
+) error {
+	if _, err := getManagedGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	memberKey, err := ctx.GetStub().CreateCompositeKey("GROUP_MEMBER", []string{groupID, normalizedActorID})
+	if err != nil {
+		return fmt.Errorf("failed to create member key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(memberKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to store group member: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"groupId": groupID,
+		"actorId": normalizedActorID,
+		"action":  "added",
+	}
+	emitEvent(ctx, "GroupMembershipChanged", eventPayload, groupStateKey(groupID))
+
+	return nil
+}
+
+// RemoveGroupMember removes an actor from a group
+func (rc *ReputationContract) RemoveGroupMember(
+	ctx contractapi.TransactionContextInterface,
+	groupID string,
+	actorID string,
+) error {
+	if _, err := getManagedGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	member, err := isGroupMember(ctx, groupID, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return fmt.Errorf("%s is not a member of group %s", normalizedActorID, groupID)
+	}
+
+	memberKey, err := ctx.GetStub().CreateCompositeKey("GROUP_MEMBER", []string{groupID, normalizedActorID})
+	if err != nil {
+		return fmt.Errorf("failed to create member key: %v", err)
+	}
+	if err := ctx.GetStub().DelState(memberKey); err != nil {
+		return fmt.Errorf("failed to delete group member: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"groupId": groupID,
+		"actorId": normalizedActorID,
+		"action":  "removed",
+	}
+	emitEvent(ctx, "GroupMembershipChanged", eventPayload, groupStateKey(groupID))
+
+	return nil
+}
+
+// GetGroup retrieves a group record
+func (rc *ReputationContract) GetGroup(
+	ctx contractapi.TransactionContextInterface,
+	groupID string,
+) (*Group, error) {
+	group, err := getGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("group not found: %s", groupID)
+	}
+
+	return group, nil
+}
+
+// GetGroupMembers lists the actors in a group
+func (rc *ReputationContract) GetGroupMembers(
+	ctx contractapi.TransactionContextInterface,
+	groupID string,
+) ([]string, error) {
+	return getGroupMembers(ctx, groupID)
+}
+
+// GetGroupTopActors ranks a group's members by score in a dimension,
+// optionally restricted to one actor type, e.g. the top suppliers within
+// the automotive group. Members with no ratings in the dimension are skipped.
+func (rc *ReputationContract) GetGroupTopActors(
+	ctx contractapi.TransactionContextInterface,
+	groupID string,
+	dimension string,
+	actorType string,
+	limitStr string,
+) ([]map[string]interface{}, error) {
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		return nil, fmt.Errorf("invalid limit: must be a positive integer")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !config.ValidDimensions[dimension] {
+		return nil, fmt.Errorf("invalid dimension: %s", dimension)
+	}
+
+	members, err := getGroupMembers(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	showUnlisted := canSeeUnlisted(ctx)
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]interface{}{}
+	for _, actorID := range members {
+		profile, err := getOrInitProfile(ctx, actorID)
+		if err != nil {
+			continue
+		}
+		if actorType != "" && profile.ActorType != actorType {
+			continue
+		}
+		if profile.Unlisted && !showUnlisted {
+			continue
+		}
+
+		rep, err := getOrInitReputation(ctx, actorID, dimension, config)
+		if err != nil || rep.TotalEvents == 0 {
+			continue
+		}
+
+		effectiveRep := applyDynamicDecayAt(rep, config, now)
+		score := reputationScore(effectiveRep)
+
+		results = append(results, map[string]interface{}{
+			"actorId":     actorID,
+			"dimension":   dimension,
+			"score":       score,
+			"totalEvents": rep.TotalEvents,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i]["score"].(float64) > results[j]["score"].(float64)
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// ============================================================================
+// GROUP HELPERS
+// ============================================================================
+
+// getManagedGroup loads a group the caller may manage, i.e. the caller is a
+// system admin or one of the group's admins
+func getManagedGroup(ctx contractapi.TransactionContextInterface, groupID string) (*Group, error) {
+	group, err := getGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("group not found: %s", groupID)
+	}
+
+	if isAdmin(ctx) {
+		return group, nil
+	}
+
+	callerID, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller ID: %v", err)
+	}
+	if !containsString(group.Admins, callerID) {
+		return nil, fmt.Errorf("unauthorized: group admin role required")
+	}
+
+	return group, nil
+}
+
+// isGroupMember reports whether an actor (normalized ID) belongs to a group
+func isGroupMember(ctx contractapi.TransactionContextInterface, groupID string, actorID string) (bool, error) {
+	memberKey, err := ctx.GetStub().CreateCompositeKey("GROUP_MEMBER", []string{groupID, actorID})
+	if err != nil {
+		return false, fmt.Errorf("failed to create member key: %v", err)
+	}
+
+	value, err := ctx.GetStub().GetState(memberKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read group member: %v", err)
+	}
+
+	return value != nil, nil
+}
+
+// getGroupMembers lists the normalized IDs of a group's members
+func getGroupMembers(ctx contractapi.TransactionContextInterface, groupID string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("GROUP_MEMBER", []string{groupID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read group members: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	members := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			continue
+		}
+		members = append(members, parts[1])
+	}
+
+	return members, nil
+}
+
+// getGroup loads a group, returning nil if it does not exist
+func getGroup(ctx contractapi.TransactionContextInterface, groupID string) (*Group, error) {
+	groupJSON, err := ctx.GetStub().GetState(groupStateKey(groupID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read group: %v", err)
+	}
+	if groupJSON == nil {
+		return nil, nil
+	}
+
+	var group Group
+	if err := json.Unmarshal(groupJSON, &group); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal group: %v", err)
+	}
+
+	return &group, nil
+}
+
+// putGroup stores a group and emits the given event
+func putGroup(ctx contractapi.TransactionContextInterface, group *Group, eventName string) error {
+	groupJSON, err := marshalCanonical(group)
+	if err != nil {
+		return fmt.Errorf("failed to marshal group: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(groupStateKey(group.GroupID), groupJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store group: %v", err)
+	}
+
+	// Emit event
+	emitEvent(ctx, eventName, group, groupStateKey(group.GroupID))
+
+	return nil
+}
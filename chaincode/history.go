@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// LEDGER HISTORY
+// ============================================================================
+
+// historyKeyPrefixes lists the record kinds whose ledger history is exposed
+var historyKeyPrefixes = []string{"REPUTATION:", "STAKE:", "DISPUTE:", "RATING:"}
+
+// RecordVersion is one historical value of a state key
+type RecordVersion struct {
+	TxID      string                 `json:"txId"`
+	Timestamp int64                  `json:"timestamp"`
+	IsDelete  bool                   `json:"isDelete"`
+	Value     map[string]interface{} `json:"value"` // nil for deletions
+}
+
+// GetRecordHistory returns every committed version of a reputation, stake,
+// dispute or rating key, oldest first, with the writing transaction's ID and
+// timestamp. It requires the peer history database to be enabled.
+func (rc *ReputationContract) GetRecordHistory(
+	ctx contractapi.TransactionContextInterface,
+	key string,
+) ([]RecordVersion, error) {
+	allowed := false
+	for _, prefix := range historyKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("history is only available for keys starting with %s", strings.Join(historyKeyPrefixes, ", "))
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	versions := []RecordVersion{}
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		version := RecordVersion{
+			TxID:     modification.TxId,
+			IsDelete: modification.IsDelete,
+		}
+		if modification.Timestamp != nil {
+			version.Timestamp = modification.Timestamp.GetSeconds()
+		}
+		if !modification.IsDelete {
+			if err := json.Unmarshal(modification.Value, &version.Value); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal version %s: %v", modification.TxId, err)
+			}
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// ReputationDelta is the change in an actor's reputation over an interval
+type ReputationDelta struct {
+	ActorID    string  `json:"actorId"`
+	Dimension  string  `json:"dimension"`
+	FromTs     int64   `json:"fromTs"`
+	ToTs       int64   `json:"toTs"`
+	FromScore  float64 `json:"fromScore"`
+	ToScore    float64 `json:"toScore"`
+	ScoreDelta float64 `json:"scoreDelta"`
+	Ratings    int     `json:"ratings"`  // ratings received in the interval
+	Disputes   int     `json:"disputes"` // disputes over the actor's ratings filed in the interval
+}
+
+// GetReputationDelta compares an actor's decayed score at fromTs and toTs
+// (unix seconds), reconstructed from the reputation record's ledger
+// history, and counts the ratings and disputes that arrived in between
+func (rc *ReputationContract) GetReputationDelta(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+	fromTsStr string,
+	toTsStr string,
+) (*ReputationDelta, error) {
+	fromTs, err := strconv.ParseInt(fromTsStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fromTs: %v", err)
+	}
+	toTs, err := strconv.ParseInt(toTsStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid toTs: %v", err)
+	}
+	if fromTs > toTs {
+		return nil, fmt.Errorf("fromTs must not be after toTs")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	versions, err := reputationVersions(ctx, normalizedActorID, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := &ReputationDelta{
+		ActorID:   normalizedActorID,
+		Dimension: dimension,
+		FromTs:    fromTs,
+		ToTs:      toTs,
+		FromScore: scoreAsOf(versions, normalizedActorID, dimension, config, fromTs),
+		ToScore:   scoreAsOf(versions, normalizedActorID, dimension, config, toTs),
+	}
+	delta.ScoreDelta = delta.ToScore - delta.FromScore
+
+	err = forEachIndexedRating(ctx, ratingByActorIndex, []string{normalizedActorID, dimension}, func(rating *Rating) (bool, error) {
+		if rating.Timestamp < fromTs {
+			return false, nil
+		}
+		if rating.Timestamp <= toTs {
+			delta.Ratings++
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, status := range disputeStatuses {
+		disputes, err := disputesWithStatus(ctx, status, func(dispute *Dispute) bool {
+			return dispute.ActorID == normalizedActorID && dispute.Dimension == dimension &&
+				dispute.CreatedAt >= fromTs && dispute.CreatedAt <= toTs
+		})
+		if err != nil {
+			return nil, err
+		}
+		delta.Disputes += len(disputes)
+	}
+
+	return delta, nil
+}
+
+// ReputationChange is one committed write of an actor's reputation record
+type ReputationChange struct {
+	TxID        string  `json:"txId"`
+	Timestamp   int64   `json:"timestamp"`
+	IsDelete    bool    `json:"isDelete"` // the record was deleted and the prior applies again
+	Alpha       float64 `json:"alpha"`
+	Beta        float64 `json:"beta"`
+	Score       float64 `json:"score"` // undecayed, as of the write
+	TotalEvents int     `json:"totalEvents"`
+	AlphaDelta  float64 `json:"alphaDelta"`
+	BetaDelta   float64 `json:"betaDelta"`
+	ScoreDelta  float64 `json:"scoreDelta"`
+}
+
+// GetReputationHistory returns every committed write of an actor's
+// reputation in a dimension, oldest first, with the alpha, beta and score it
+// left and their change from the previous write, starting from the prior.
+// It requires the peer history database to be enabled.
+func (rc *ReputationContract) GetReputationHistory(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+) ([]ReputationChange, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	versions, err := reputationVersions(ctx, normalizedActorID, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	prior := &Reputation{
+		ActorID:   normalizedActorID,
+		Dimension: dimension,
+		Alpha:     fixedFromFloat(config.InitialAlpha),
+		Beta:      fixedFromFloat(config.InitialBeta),
+	}
+	previous := prior
+	changes := make([]ReputationChange, 0, len(versions))
+	for _, version := range versions {
+		rep := version.rep
+		if rep == nil {
+			rep = prior
+		}
+		changes = append(changes, ReputationChange{
+			TxID:        version.txID,
+			Timestamp:   version.committedAt,
+			IsDelete:    version.rep == nil,
+			Alpha:       rep.Alpha.Float(),
+			Beta:        rep.Beta.Float(),
+			Score:       reputationScore(rep),
+			TotalEvents: rep.TotalEvents,
+			AlphaDelta:  (rep.Alpha - previous.Alpha).Float(),
+			BetaDelta:   (rep.Beta - previous.Beta).Float(),
+			ScoreDelta:  reputationScore(rep) - reputationScore(previous),
+		})
+		previous = rep
+	}
+
+	return changes, nil
+}
+
+// reputationVersion is a reputation record as committed at a point in time
+type reputationVersion struct {
+	txID        string
+	committedAt int64
+	rep         *Reputation // nil if the record was deleted
+}
+
+// reputationVersions returns the committed versions of an actor's
+// reputation record, oldest first
+func reputationVersions(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+) ([]reputationVersion, error) {
+	keys := []string{reputationStateKey(actorID, dimension)}
+	if legacy := legacyReputationKey(actorID, dimension); legacy != keys[0] {
+		keys = append(keys, legacy)
+	}
+
+	versions := []reputationVersion{}
+	for _, key := range keys {
+		resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %v", err)
+		}
+
+		// History comes newest first; collect it oldest first so writes
+		// committed in the same second keep their order through the sort
+		keyVersions := []reputationVersion{}
+		for resultsIterator.HasNext() {
+			modification, err := resultsIterator.Next()
+			if err != nil {
+				resultsIterator.Close()
+				return nil, err
+			}
+
+			version := reputationVersion{txID: modification.TxId, committedAt: modification.Timestamp.GetSeconds()}
+			if !modification.IsDelete {
+				var rep Reputation
+				if err := json.Unmarshal(modification.Value, &rep); err != nil {
+					continue
+				}
+				version.rep = &rep
+			}
+			keyVersions = append([]reputationVersion{version}, keyVersions...)
+		}
+		resultsIterator.Close()
+		versions = append(versions, keyVersions...)
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].committedAt < versions[j].committedAt
+	})
+
+	return versions, nil
+}
+
+// scoreAsOf returns the decayed score of the version in effect at ts, or the
+// prior if the record did not exist yet
+func scoreAsOf(
+	versions []reputationVersion,
+	actorID string,
+	dimension string,
+	config *SystemConfig,
+	ts int64,
+) float64 {
+	rep := &Reputation{
+		ActorID:   actorID,
+		Dimension: dimension,
+		Alpha:     fixedFromFloat(config.InitialAlpha),
+		Beta:      fixedFromFloat(config.InitialBeta),
+		LastTs:    ts,
+	}
+	for _, version := range versions {
+		if version.committedAt > ts {
+			break
+		}
+		if version.rep != nil {
+			rep = version.rep
+		}
+	}
+
+	effectiveRep := applyDynamicDecayAt(rep, config, ts)
+	return reputationScore(effectiveRep)
+}
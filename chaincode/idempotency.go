@@ -0,0 +1,137 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// IDEMPOTENT RATING SUBMISSION
+// ============================================================================
+
+// maxIdempotencyKeyLength bounds client-supplied idempotency keys
+const maxIdempotencyKeyLength = 128
+
+// IdempotencyRecord remembers the rating created under a rater's
+// idempotency key, so that a retried submission returns it instead of
+// rating again
+type IdempotencyRecord struct {
+	RaterID     string `json:"raterId"`
+	Key         string `json:"key"`
+	RatingID    string `json:"ratingId"`
+	RequestHash string `json:"requestHash"` // of the submission's arguments
+	CreatedAt   int64  `json:"createdAt"`
+}
+
+// SubmitRatingIdempotent is SubmitRating with a client-supplied idempotency
+// key, such as a nonce or order reference. The first submission under a key
+// records the rating ID; a retry with the same key and arguments returns that
+// ID without rating again, and reusing the key for a different rating is
+// rejected. Concurrent retries read the same dedupe record, so at most one
+// of them commits.
+func (rtc *RatingContract) SubmitRatingIdempotent(
+	ctx contractapi.TransactionContextInterface,
+	idempotencyKey string,
+	actorID string,
+	dimension string,
+	valueStr string,
+	evidence string,
+	timestampStr string,
+) (string, error) {
+	normalizedRaterID, err := callerIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get rater ID: %v", err)
+	}
+
+	requestHash := ratingRequestHash(resolveIdentity(ctx, actorID), dimension, valueStr, evidence, timestampStr)
+
+	record, err := getIdempotencyRecord(ctx, normalizedRaterID, idempotencyKey)
+	if err != nil {
+		return "", err
+	}
+	if record != nil {
+		if record.RequestHash != requestHash {
+			return "", fmt.Errorf("idempotency key %s was already used for a different rating", idempotencyKey)
+		}
+		return record.RatingID, nil
+	}
+
+	ratingID, err := submitRating(ctx, normalizedRaterID, "", actorID, dimension, valueStr, evidence, timestampStr, nil)
+	if err != nil {
+		return "", err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	record = &IdempotencyRecord{
+		RaterID:     normalizedRaterID,
+		Key:         idempotencyKey,
+		RatingID:    ratingID,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+	}
+	if err := putIdempotencyRecord(ctx, record); err != nil {
+		return "", err
+	}
+
+	return ratingID, nil
+}
+
+// ============================================================================
+// IDEMPOTENCY HELPERS
+// ============================================================================
+
+// idempotencyStateKey returns the state key of a rater's idempotency key,
+// namespaced by the rater
+func idempotencyStateKey(raterID string, key string) string {
+	return namespacedKey("IDEMPOTENCY", raterID, key)
+}
+
+// ratingRequestHash fingerprints the arguments of a rating submission
+func ratingRequestHash(actorID, dimension, valueStr, evidence, timestampStr string) string {
+	data := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", actorID, dimension, valueStr, evidence, timestampStr)
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+// getIdempotencyRecord loads the record of a rater's idempotency key,
+// returning nil if the key is unused
+func getIdempotencyRecord(
+	ctx contractapi.TransactionContextInterface,
+	raterID string,
+	key string,
+) (*IdempotencyRecord, error) {
+	recordJSON, err := ctx.GetStub().GetState(idempotencyStateKey(raterID, key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency record: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+
+	var record IdempotencyRecord
+	if err := json.Unmarshal(recordJSON, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %v", err)
+	}
+	return &record, nil
+}
+
+// putIdempotencyRecord stores the record of a rater's idempotency key
+func putIdempotencyRecord(ctx contractapi.TransactionContextInterface, record *IdempotencyRecord) error {
+	recordJSON, err := marshalCanonical(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(idempotencyStateKey(record.RaterID, record.Key), recordJSON); err != nil {
+		return fmt.Errorf("failed to store idempotency record: %v", err)
+	}
+	return nil
+}
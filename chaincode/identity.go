@@ -0,0 +1,727 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// IDENTITY MODES
+// ============================================================================
+
+// Identity modes control how a caller's certificate maps to an actor ID
+const (
+	identityModeCN   = "cn"   // lowercase subject CN (legacy)
+	identityModeMSP  = "msp"  // lowercase "<mspid>/<cn>"
+	identityModeHash = "hash" // hex SHA-256 of "<mspid>::<fabric id>"
+)
+
+// validIdentityModes lists the accepted SystemConfig.IdentityMode values
+var validIdentityModes = map[string]bool{
+	identityModeCN:   true,
+	identityModeMSP:  true,
+	identityModeHash: true,
+}
+
+// MigrateActorRecords moves an actor's stake, reputation, profile and
+// profile history, ban, offboarding, membership, activity, purge and
+// directory records, its rater/actor pair records, vouches, unbonding stake
+// withdrawals, posted bonds and admin and arbitrator grants from a legacy ID
+// to the ID it has under the current identity mode, refiling their index
+// entries. Rating and dispute documents keep the IDs they were written
+// with, and so do their index entries: archiving, purging and dispute
+// settlement derive index keys from the documents, so entries moved to the
+// new ID would be left behind when the documents change. For the same
+// reason actors with pending disputes cannot be migrated until the disputes
+// are settled.
+func (rc *ReputationContract) MigrateActorRecords(
+	ctx contractapi.TransactionContextInterface,
+	legacyID string,
+	newID string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	normalizedLegacyID := normalizeIdentity(legacyID)
+	normalizedNewID := normalizeIdentity(newID)
+	if normalizedLegacyID == normalizedNewID {
+		return fmt.Errorf("legacy and new IDs are identical")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	moved, err := moveActorRecords(ctx, config, normalizedLegacyID, normalizedNewID)
+	if err != nil {
+		return err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"legacyId": normalizedLegacyID,
+		"newId":    normalizedNewID,
+		"moved":    moved,
+	}
+	emitEvent(ctx, "ActorRecordsMigrated", eventPayload)
+
+	return nil
+}
+
+// ============================================================================
+// IDENTITY HELPERS
+// ============================================================================
+
+// callerIdentity returns the calling client's canonical actor ID, following
+// any identity link
+func callerIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
+	return cachedCallerIdentity(ctx, func() (string, error) {
+		id, err := certificateIdentity(ctx)
+		if err != nil {
+			return "", err
+		}
+		return canonicalIdentity(ctx, id), nil
+	})
+}
+
+// resolveIdentity normalizes a caller-supplied actor ID and follows any
+// identity link to the canonical actor
+func resolveIdentity(ctx contractapi.TransactionContextInterface, identity string) string {
+	return canonicalIdentity(ctx, normalizeIdentity(identity))
+}
+
+// canonicalIdentity maps a normalized ID to its canonical actor, or returns
+// it unchanged if it is not a linked alias
+func canonicalIdentity(ctx contractapi.TransactionContextInterface, id string) string {
+	link, err := getIdentityLink(ctx, id)
+	if err != nil || link == nil || link.Status != "active" {
+		return id
+	}
+	return link.CanonicalID
+}
+
+// certificateIdentity returns the actor ID of the calling certificate under
+// the configured identity mode, without following identity links
+func certificateIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
+	id, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", err
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return identityForMode(config.IdentityMode, mspID, id), nil
+}
+
+// identityForMode derives an actor ID from a Fabric client ID (the base64
+// "x509::subject::issuer" string) and its MSP
+func identityForMode(mode string, mspID string, fabricID string) string {
+	switch mode {
+	case identityModeMSP:
+		return strings.ToLower(mspID) + "/" + normalizeIdentity(fabricID)
+	case identityModeHash:
+		hash := sha256.Sum256([]byte(mspID + "::" + fabricID))
+		return fmt.Sprintf("%x", hash)
+	}
+
+	return normalizeIdentity(fabricID)
+}
+
+// actorRecordKeys lists, for each per-actor record, the keys the record of
+// oldID may be stored under (preferred first) and the key it moves to
+func actorRecordKeys(config *SystemConfig, oldID string, newID string) []actorRecordMove {
+	moves := []actorRecordMove{
+		{[]string{stakeStateKey(oldID), legacyStakeKey(oldID)}, stakeStateKey(newID), ""},
+	}
+	for _, key := range []func(string) string{
+		profileStateKey, banStateKey, offboardingStateKey, activityStateKey, purgeStateKey,
+	} {
+		moves = append(moves, actorRecordMove{[]string{key(oldID)}, key(newID), ""})
+	}
+
+	dimensions := []string{}
+	for dimension := range config.ValidDimensions {
+		dimensions = append(dimensions, dimension)
+	}
+	for _, metaDimension := range config.MetaDimensions {
+		dimensions = append(dimensions, metaDimension)
+	}
+	sort.Strings(dimensions)
+	for _, dimension := range dimensions {
+		moves = append(moves, actorRecordMove{
+			[]string{reputationStateKey(oldID, dimension), legacyReputationKey(oldID, dimension)},
+			reputationStateKey(newID, dimension),
+			dimension,
+		})
+	}
+
+	return moves
+}
+
+// actorRecordMove describes one record to move between actor IDs
+type actorRecordMove struct {
+	from      []string
+	to        string
+	dimension string // set for reputation records, which are also indexed
+}
+
+// moveActorRecords moves every per-actor record of oldID to newID and
+// returns the keys that received a record. Actors with pending disputes are
+// refused: their settlement moves locked stake by the IDs the disputes were
+// filed under.
+func moveActorRecords(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	oldID string,
+	newID string,
+) ([]string, error) {
+	pending, err := disputesWithStatus(ctx, "pending", func(dispute *Dispute) bool {
+		return dispute.InitiatorID == oldID || dispute.RaterID == oldID || dispute.ActorID == oldID
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("actor has pending disputes: %s", oldID)
+	}
+
+	var moved []string
+	for _, move := range actorRecordKeys(config, oldID, newID) {
+		ok, err := moveActorRecord(ctx, move.from, move.to, newID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		moved = append(moved, move.to)
+
+		if move.dimension != "" {
+			if err := delIndexEntry(ctx, reputationByDimensionIndex, []string{move.dimension, oldID}); err != nil {
+				return nil, err
+			}
+			if err := indexReputation(ctx, newID, move.dimension); err != nil {
+				return nil, err
+			}
+
+			// Reads do not observe this transaction's writes, so the
+			// committed record is still found under the old ID
+			oldRep, err := getStoredReputation(ctx, oldID, move.dimension)
+			if err != nil {
+				return nil, err
+			}
+			if oldRep != nil {
+				newRep := *oldRep
+				newRep.ActorID = newID
+				if err := updateLeaderboard(ctx, oldRep, &newRep); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	keys, err := moveRaterActorRecords(ctx, oldID, newID)
+	if err != nil {
+		return nil, err
+	}
+	moved = append(moved, keys...)
+
+	listingMoved, err := moveDirectoryListing(ctx, oldID, newID)
+	if err != nil {
+		return nil, err
+	}
+	if listingMoved {
+		moved = append(moved, directoryListingKey(newID))
+	}
+
+	membershipMoved, err := moveMembership(ctx, oldID, newID)
+	if err != nil {
+		return nil, err
+	}
+	if membershipMoved {
+		moved = append(moved, membershipStateKey(newID))
+	}
+
+	for _, moveRecords := range []func(contractapi.TransactionContextInterface, string, string) ([]string, error){
+		moveProfileHistory, moveVouches, moveUnbondingWithdrawals, movePostedBonds,
+	} {
+		keys, err := moveRecords(ctx, oldID, newID)
+		if err != nil {
+			return nil, err
+		}
+		moved = append(moved, keys...)
+	}
+
+	for _, role := range [][2]string{{adminListKey, adminExpiryKey}, {arbitratorListKey, arbitratorExpiryKey}} {
+		granted, err := moveRoleGrant(ctx, role[0], role[1], oldID, newID)
+		if err != nil {
+			return nil, err
+		}
+		if granted {
+			moved = append(moved, role[0])
+		}
+	}
+	return moved, nil
+}
+
+// moveProfileHistory moves oldID's profile versions to newID
+func moveProfileHistory(ctx contractapi.TransactionContextInterface, oldID string, newID string) ([]string, error) {
+	oldPrefix := profileHistoryPrefix(oldID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(oldPrefix, oldPrefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var moved []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var change ProfileChange
+		if err := json.Unmarshal(queryResponse.Value, &change); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %v", queryResponse.Key, err)
+		}
+		change.ActorID = newID
+		if change.Profile != nil {
+			change.Profile.ActorID = newID
+		}
+
+		newKey := profileHistoryKey(newID, change.Version)
+		changeJSON, err := marshalCanonical(change)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %v", newKey, err)
+		}
+		if err := ctx.GetStub().PutState(newKey, changeJSON); err != nil {
+			return nil, fmt.Errorf("failed to store %s: %v", newKey, err)
+		}
+		if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %v", queryResponse.Key, err)
+		}
+		moved = append(moved, newKey)
+	}
+	return moved, nil
+}
+
+// moveVouches moves the vouches recorded for oldID, and those oldID gave,
+// to newID. Given vouches are filed under the vouched actor, so all vouches
+// are scanned for them.
+func moveVouches(ctx contractapi.TransactionContextInterface, oldID string, newID string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange(vouchKeyPrefix, vouchKeyPrefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vouches: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var moved []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vouch Vouch
+		if err := json.Unmarshal(queryResponse.Value, &vouch); err != nil {
+			continue
+		}
+		if vouch.ActorID != oldID && vouch.VoucherID != oldID {
+			continue
+		}
+		if vouch.ActorID == oldID {
+			vouch.ActorID = newID
+		}
+		if vouch.VoucherID == oldID {
+			vouch.VoucherID = newID
+		}
+
+		newKey := vouchStateKey(vouch.ActorID, vouch.Dimension, vouch.VoucherID)
+		existing, err := ctx.GetStub().GetState(newKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", newKey, err)
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("target record already exists: %s", newKey)
+		}
+		vouchJSON, err := marshalCanonical(vouch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %v", newKey, err)
+		}
+		if err := ctx.GetStub().PutState(newKey, vouchJSON); err != nil {
+			return nil, fmt.Errorf("failed to store %s: %v", newKey, err)
+		}
+		if err := ctx.GetStub().DelState(queryResponse.Key); err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %v", queryResponse.Key, err)
+		}
+		moved = append(moved, newKey)
+	}
+	return moved, nil
+}
+
+// moveUnbondingWithdrawals hands oldID's unbonding stake withdrawals to
+// newID, so the actor can still claim them. Withdrawal IDs do not change.
+func moveUnbondingWithdrawals(ctx contractapi.TransactionContextInterface, oldID string, newID string) ([]string, error) {
+	withdrawals, err := unbondingWithdrawals(ctx, oldID)
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	for _, withdrawal := range withdrawals {
+		if err := delIndexEntry(ctx, stakeWithdrawalByActorIndex, stakeWithdrawalActorAttributes(withdrawal)); err != nil {
+			return nil, err
+		}
+		withdrawal.ActorID = newID
+		if err := putStakeWithdrawal(ctx, withdrawal); err != nil {
+			return nil, err
+		}
+		if err := putIndexEntry(ctx, stakeWithdrawalByActorIndex, stakeWithdrawalActorAttributes(withdrawal)); err != nil {
+			return nil, err
+		}
+		moved = append(moved, withdrawal.WithdrawalID)
+	}
+	return moved, nil
+}
+
+// movePostedBonds hands the posted bonds oldID supplied or is the buyer of
+// to newID. Only posted bonds are indexed, so the index is scanned whole;
+// bond IDs do not change.
+func movePostedBonds(ctx contractapi.TransactionContextInterface, oldID string, newID string) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(bondByPairIndex, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", bondByPairIndex, err)
+	}
+	defer resultsIterator.Close()
+
+	var bonds []*Bond
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) < 2 || (parts[0] != oldID && parts[1] != oldID) {
+			continue
+		}
+		bond, err := getBond(ctx, parts[len(parts)-1])
+		if err != nil {
+			return nil, err
+		}
+		if bond != nil && bond.Status == bondPosted {
+			bonds = append(bonds, bond)
+		}
+	}
+
+	var moved []string
+	for _, bond := range bonds {
+		if err := delIndexEntry(ctx, bondByPairIndex, bondPairAttributes(bond)); err != nil {
+			return nil, err
+		}
+		if bond.SupplierID == oldID {
+			bond.SupplierID = newID
+		}
+		if bond.BuyerID == oldID {
+			bond.BuyerID = newID
+		}
+		if err := putBond(ctx, bond); err != nil {
+			return nil, err
+		}
+		if err := putIndexEntry(ctx, bondByPairIndex, bondPairAttributes(bond)); err != nil {
+			return nil, err
+		}
+		moved = append(moved, bond.BondID)
+	}
+	return moved, nil
+}
+
+// moveRaterActorRecords moves the rater/actor pair records of the ratings
+// oldID submitted and received to newID. Pairs it rated are found under its
+// key prefix, pairs that rated it through its ratings.
+func moveRaterActorRecords(
+	ctx contractapi.TransactionContextInterface,
+	oldID string,
+	newID string,
+) ([]string, error) {
+	type pairMove struct{ from, to string }
+	var moves []pairMove
+
+	prefix := namespacedKey("RATER_ACTOR", oldID) + ":"
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rater/actor records: %v", err)
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var pair struct {
+			RaterID   string `json:"raterId"`
+			ActorID   string `json:"actorId"`
+			Dimension string `json:"dimension"`
+		}
+		if err := json.Unmarshal(queryResponse.Value, &pair); err != nil || pair.RaterID != oldID {
+			continue
+		}
+		moves = append(moves, pairMove{queryResponse.Key, raterActorStateKey(newID, pair.ActorID, pair.Dimension)})
+	}
+
+	raters := map[string]bool{}
+	err = forEachIndexedRating(ctx, ratingByActorIndex, []string{oldID}, func(rating *Rating) (bool, error) {
+		if rating.RaterID != oldID {
+			raters[rating.RaterID+"\x00"+rating.Dimension] = true
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]string, 0, len(raters))
+	for pair := range raters {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	for _, pair := range pairs {
+		raterID, dimension, _ := strings.Cut(pair, "\x00")
+		moves = append(moves, pairMove{
+			raterActorStateKey(raterID, oldID, dimension),
+			raterActorStateKey(raterID, newID, dimension),
+		})
+	}
+
+	var moved []string
+	for _, move := range moves {
+		ok, err := moveRaterActorRecord(ctx, move.from, move.to, oldID, newID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			moved = append(moved, move.to)
+		}
+	}
+	return moved, nil
+}
+
+// moveRaterActorRecord rewrites a pair record at newKey with oldID replaced
+// by newID, reporting false if there was no record
+func moveRaterActorRecord(
+	ctx contractapi.TransactionContextInterface,
+	oldKey string,
+	newKey string,
+	oldID string,
+	newID string,
+) (bool, error) {
+	recordJSON, err := ctx.GetStub().GetState(oldKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %v", oldKey, err)
+	}
+	if recordJSON == nil {
+		return false, nil
+	}
+	existing, err := ctx.GetStub().GetState(newKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %v", newKey, err)
+	}
+	if existing != nil {
+		return false, fmt.Errorf("target record already exists: %s", newKey)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(recordJSON, &record); err != nil {
+		return false, fmt.Errorf("failed to unmarshal %s: %v", oldKey, err)
+	}
+	for _, field := range []string{"raterId", "actorId"} {
+		if record[field] == oldID {
+			record[field] = newID
+		}
+	}
+
+	updatedJSON, err := marshalCanonical(record)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal %s: %v", newKey, err)
+	}
+	if err := ctx.GetStub().PutState(newKey, updatedJSON); err != nil {
+		return false, fmt.Errorf("failed to store %s: %v", newKey, err)
+	}
+	if err := ctx.GetStub().DelState(oldKey); err != nil {
+		return false, fmt.Errorf("failed to delete %s: %v", oldKey, err)
+	}
+	return true, nil
+}
+
+// moveDirectoryListing refiles oldID's directory listing under newID,
+// reporting false if it had none
+func moveDirectoryListing(ctx contractapi.TransactionContextInterface, oldID string, newID string) (bool, error) {
+	listing, err := getListing(ctx, oldID)
+	if err != nil || listing == nil {
+		return false, err
+	}
+	existing, err := getListing(ctx, newID)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil {
+		return false, fmt.Errorf("target record already exists: %s", directoryListingKey(newID))
+	}
+
+	for _, attributes := range directoryAttributes(listing) {
+		if err := delIndexEntry(ctx, directoryIndex, attributes); err != nil {
+			return false, err
+		}
+	}
+	if err := ctx.GetStub().DelState(directoryListingKey(oldID)); err != nil {
+		return false, fmt.Errorf("failed to delete directory listing: %v", err)
+	}
+
+	moved := listing.clone()
+	moved.ActorID = newID
+	if err := putListing(ctx, moved, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// moveMembership refiles oldID's membership, and with it any probation,
+// under newID, reporting false if it had none
+func moveMembership(ctx contractapi.TransactionContextInterface, oldID string, newID string) (bool, error) {
+	membership, err := getMembership(ctx, oldID)
+	if err != nil || membership == nil {
+		return false, err
+	}
+	existing, err := getMembership(ctx, newID)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil {
+		return false, fmt.Errorf("target record already exists: %s", membershipStateKey(newID))
+	}
+
+	if err := delIndexEntry(ctx, membershipByStatusIndex, membershipStatusAttributes(membership)); err != nil {
+		return false, err
+	}
+	if err := ctx.GetStub().DelState(membershipStateKey(oldID)); err != nil {
+		return false, fmt.Errorf("failed to delete membership: %v", err)
+	}
+
+	membership.ActorID = newID
+	if err := putMembership(ctx, membership, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// moveRoleGrant transfers oldID's grant in a role list, with its expiry, to
+// newID, reporting false if oldID held no grant
+func moveRoleGrant(
+	ctx contractapi.TransactionContextInterface,
+	listKey string,
+	expiryKey string,
+	oldID string,
+	newID string,
+) (bool, error) {
+	list, err := getRoleList(ctx, listKey)
+	if err != nil {
+		return false, err
+	}
+	if !list[oldID] {
+		return false, nil
+	}
+	delete(list, oldID)
+	list[newID] = true
+
+	listJSON, _ := marshalCanonical(list)
+	if err := ctx.GetStub().PutState(listKey, listJSON); err != nil {
+		return false, fmt.Errorf("failed to update role list: %v", err)
+	}
+
+	expiries, err := getRoleExpiries(ctx, expiryKey)
+	if err != nil {
+		return false, err
+	}
+	if expiresAt, exists := expiries[oldID]; exists {
+		delete(expiries, oldID)
+		expiries[newID] = expiresAt
+		expiriesJSON, _ := marshalCanonical(expiries)
+		if err := ctx.GetStub().PutState(expiryKey, expiriesJSON); err != nil {
+			return false, fmt.Errorf("failed to update role expiries: %v", err)
+		}
+	}
+	return true, nil
+}
+
+// moveActorRecord rewrites the first record found under oldKeys at newKey
+// with its actorId updated, and clears every old key. It reports false if
+// there was nothing to move.
+func moveActorRecord(
+	ctx contractapi.TransactionContextInterface,
+	oldKeys []string,
+	newKey string,
+	newID string,
+) (bool, error) {
+	var oldKey string
+	var recordJSON []byte
+	for _, key := range oldKeys {
+		value, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %v", key, err)
+		}
+		if value != nil {
+			oldKey, recordJSON = key, value
+			break
+		}
+	}
+	if recordJSON == nil {
+		return false, nil
+	}
+
+	existing, err := ctx.GetStub().GetState(newKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %v", newKey, err)
+	}
+	if existing != nil {
+		return false, fmt.Errorf("target record already exists: %s", newKey)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(recordJSON, &record); err != nil {
+		return false, fmt.Errorf("failed to unmarshal %s: %v", oldKey, err)
+	}
+	record["actorId"] = newID
+
+	updatedJSON, err := marshalCanonical(record)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal %s: %v", newKey, err)
+	}
+
+	if err := ctx.GetStub().PutState(newKey, updatedJSON); err != nil {
+		return false, fmt.Errorf("failed to store %s: %v", newKey, err)
+	}
+	for _, key := range oldKeys {
+		if key == newKey {
+			continue
+		}
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return false, fmt.Errorf("failed to delete %s: %v", key, err)
+		}
+	}
+
+	return true, nil
+}
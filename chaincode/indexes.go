@@ -0,0 +1,581 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// COMPOSITE-KEY INDEXES
+// ============================================================================
+
+// List queries are served from composite-key indexes rather than CouchDB
+// rich queries, so the chaincode runs on LevelDB peers as well. Timestamps
+// in index keys are inverted so that ascending key order is newest first.
+const (
+	ratingByActorIndex         = "RATING_BY_ACTOR"         // actorId~dimension~invTimestamp~ratingId
+	ratingByActorOrderIndex    = "RATING_BY_ACTOR_ORDER"   // actorId~dimension~order~sortKey~ratingId
+	ratingByRaterIndex         = "RATING_BY_RATER"         // raterId~invTimestamp~ratingId
+	raterActorIndex            = "RATER_ACTOR"             // raterId~actorId~dimension~invTimestamp~ratingId
+	ratingByEvidenceIndex      = "RATING_BY_EVIDENCE"      // evidenceHash~invTimestamp~ratingId
+	disputeByStatusIndex       = "DISPUTE_BY_STATUS"       // status~invCreatedAt~disputeId
+	disputeByRatingIndex       = "DISPUTE_BY_RATING"       // ratingId~disputeId
+	reputationByDimensionIndex = "REPUTATION_BY_DIMENSION" // dimension~actorId
+	aliasOfIndex               = "ALIAS_OF"                // canonicalId~aliasId
+
+	// raterDisputeCounts has counts only, no entries: the disputes filed
+	// against each rater's ratings, by current status
+	raterDisputeCounts = "RATER_DISPUTES" // raterId~status
+)
+
+// IndexRebuildPage reports one page of RebuildIndexes
+type IndexRebuildPage struct {
+	Indexed  int    `json:"indexed"`
+	Bookmark string `json:"bookmark"` // "" once the prefix is exhausted
+}
+
+// RebuildIndexes indexes one page of records of the given kind (RATING,
+// DISPUTE or REPUTATION). It backfills records written before the indexes
+// existed and is safe to repeat; a REPUTATION pass also builds the directory
+// listings of rated actors. Index counts and dimension statistics are
+// recomputed by a full pass of their kind: the first page (empty bookmark)
+// resets them.
+func (rc *ReputationContract) RebuildIndexes(
+	ctx contractapi.TransactionContextInterface,
+	kind string,
+	bookmark string,
+	pageSize int,
+) (*IndexRebuildPage, error) {
+	if !isAdmin(ctx) {
+		return nil, fmt.Errorf("unauthorized: admin role required")
+	}
+	if kind != "RATING" && kind != "DISPUTE" && kind != "REPUTATION" {
+		return nil, fmt.Errorf("kind must be RATING, DISPUTE or REPUTATION")
+	}
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(
+		kind+":", kind+";", int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s records: %v", kind, err)
+	}
+	defer resultsIterator.Close()
+
+	if kind == "RATING" && bookmark == "" {
+		if err := resetIndexCounts(ctx, ratingByActorIndex); err != nil {
+			return nil, err
+		}
+		if err := resetIndexCounts(ctx, ratingByRaterIndex); err != nil {
+			return nil, err
+		}
+	}
+	if kind == "DISPUTE" && bookmark == "" {
+		if err := resetIndexCounts(ctx, disputeByStatusIndex); err != nil {
+			return nil, err
+		}
+		if err := resetIndexCounts(ctx, raterDisputeCounts); err != nil {
+			return nil, err
+		}
+	}
+	if kind == "REPUTATION" && bookmark == "" {
+		config, err := getConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		dimensions := []string{}
+		for dimension := range config.ValidDimensions {
+			dimensions = append(dimensions, dimension)
+		}
+		for _, metaDimension := range config.MetaDimensions {
+			dimensions = append(dimensions, metaDimension)
+		}
+		for _, dimension := range dimensions {
+			if err := resetDimensionStats(ctx, dimension); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	page := &IndexRebuildPage{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case "RATING":
+			var rating Rating
+			if err := json.Unmarshal(queryResponse.Value, &rating); err != nil || rating.RatingID == "" {
+				continue
+			}
+			err = indexRating(ctx, &rating)
+		case "DISPUTE":
+			var dispute Dispute
+			if err := json.Unmarshal(queryResponse.Value, &dispute); err != nil || dispute.DisputeID == "" {
+				continue
+			}
+			err = indexDispute(ctx, &dispute, "")
+		case "REPUTATION":
+			var rep Reputation
+			if err := json.Unmarshal(queryResponse.Value, &rep); err != nil || rep.ActorID == "" {
+				continue
+			}
+			err = indexReputation(ctx, rep.ActorID, rep.Dimension)
+			if err == nil {
+				err = updateLeaderboard(ctx, nil, &rep)
+			}
+			if err == nil {
+				err = updateDimensionStats(ctx, nil, &rep)
+			}
+			if err == nil {
+				err = refreshListingScore(ctx, &rep)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		page.Indexed++
+	}
+
+	if int(metadata.FetchedRecordsCount) == pageSize {
+		page.Bookmark = metadata.Bookmark
+	}
+
+	return page, nil
+}
+
+// ============================================================================
+// INDEX WRITERS
+// ============================================================================
+
+// invertedTimestamp encodes a timestamp so that later times sort first
+func invertedTimestamp(ts int64) string {
+	return fmt.Sprintf("%019d", math.MaxInt64-ts)
+}
+
+// timestampFromInverted decodes an invertedTimestamp key attribute
+func timestampFromInverted(attribute string) int64 {
+	inverted, err := strconv.ParseInt(attribute, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return math.MaxInt64 - inverted
+}
+
+// putIndexEntry stores an empty-valued composite index key
+func putIndexEntry(ctx contractapi.TransactionContextInterface, index string, attributes []string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(index, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create %s key: %v", index, err)
+	}
+	if err := ctx.GetStub().PutState(key, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to store %s entry: %v", index, err)
+	}
+	return nil
+}
+
+// delIndexEntry removes a composite index key
+func delIndexEntry(ctx contractapi.TransactionContextInterface, index string, attributes []string) error {
+	key, err := ctx.GetStub().CreateCompositeKey(index, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to create %s key: %v", index, err)
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("failed to delete %s entry: %v", index, err)
+	}
+	return nil
+}
+
+// indexRating adds a rating to the by-actor (in every order), by-rater,
+// rater~actor pair and evidence indexes and counts it. It is called once
+// per rating.
+func indexRating(ctx contractapi.TransactionContextInterface, rating *Rating) error {
+	ts := invertedTimestamp(rating.Timestamp)
+	if err := putIndexEntry(ctx, ratingByActorIndex, []string{rating.ActorID, rating.Dimension, ts, rating.RatingID}); err != nil {
+		return err
+	}
+	for _, order := range ratingOrders {
+		attributes := []string{rating.ActorID, rating.Dimension, order, ratingSortKey(rating, order), rating.RatingID}
+		if err := putIndexEntry(ctx, ratingByActorOrderIndex, attributes); err != nil {
+			return err
+		}
+	}
+	if err := putIndexEntry(ctx, raterActorIndex, []string{rating.RaterID, rating.ActorID, rating.Dimension, ts, rating.RatingID}); err != nil {
+		return err
+	}
+	if err := putIndexEntry(ctx, ratingByRaterIndex, []string{rating.RaterID, ts, rating.RatingID}); err != nil {
+		return err
+	}
+	if err := indexRatingEvidence(ctx, rating); err != nil {
+		return err
+	}
+
+	if err := addIndexCount(ctx, ratingByActorIndex, []string{rating.ActorID, rating.Dimension}, 1); err != nil {
+		return err
+	}
+	return addIndexCount(ctx, ratingByRaterIndex, []string{rating.RaterID}, 1)
+}
+
+// unindexRating removes a rating from every index indexRating added it to
+// and uncounts it
+func unindexRating(ctx contractapi.TransactionContextInterface, rating *Rating) error {
+	ts := invertedTimestamp(rating.Timestamp)
+	if err := delIndexEntry(ctx, ratingByActorIndex, []string{rating.ActorID, rating.Dimension, ts, rating.RatingID}); err != nil {
+		return err
+	}
+	for _, order := range ratingOrders {
+		attributes := []string{rating.ActorID, rating.Dimension, order, ratingSortKey(rating, order), rating.RatingID}
+		if err := delIndexEntry(ctx, ratingByActorOrderIndex, attributes); err != nil {
+			return err
+		}
+	}
+	if err := delIndexEntry(ctx, raterActorIndex, []string{rating.RaterID, rating.ActorID, rating.Dimension, ts, rating.RatingID}); err != nil {
+		return err
+	}
+	if err := delIndexEntry(ctx, ratingByRaterIndex, []string{rating.RaterID, ts, rating.RatingID}); err != nil {
+		return err
+	}
+	if err := unindexRatingEvidence(ctx, rating, rating.Evidence); err != nil {
+		return err
+	}
+
+	if err := addIndexCount(ctx, ratingByActorIndex, []string{rating.ActorID, rating.Dimension}, -1); err != nil {
+		return err
+	}
+	return addIndexCount(ctx, ratingByRaterIndex, []string{rating.RaterID}, -1)
+}
+
+// indexDispute files a dispute under its rating and its current status,
+// removing the entry for previousStatus if the status changed, and keeps the
+// status counts
+func indexDispute(ctx contractapi.TransactionContextInterface, dispute *Dispute, previousStatus string) error {
+	if previousStatus == "" {
+		if err := putIndexEntry(ctx, disputeByRatingIndex, []string{dispute.RatingID, dispute.DisputeID}); err != nil {
+			return err
+		}
+	}
+
+	ts := invertedTimestamp(dispute.CreatedAt)
+	if previousStatus == dispute.Status {
+		return putIndexEntry(ctx, disputeByStatusIndex, []string{dispute.Status, ts, dispute.DisputeID})
+	}
+
+	if previousStatus != "" {
+		if err := delIndexEntry(ctx, disputeByStatusIndex, []string{previousStatus, ts, dispute.DisputeID}); err != nil {
+			return err
+		}
+		if err := addIndexCount(ctx, disputeByStatusIndex, []string{previousStatus}, -1); err != nil {
+			return err
+		}
+		if err := addIndexCount(ctx, raterDisputeCounts, []string{dispute.RaterID, previousStatus}, -1); err != nil {
+			return err
+		}
+	}
+	if err := putIndexEntry(ctx, disputeByStatusIndex, []string{dispute.Status, ts, dispute.DisputeID}); err != nil {
+		return err
+	}
+	if err := addIndexCount(ctx, disputeByStatusIndex, []string{dispute.Status}, 1); err != nil {
+		return err
+	}
+	return addIndexCount(ctx, raterDisputeCounts, []string{dispute.RaterID, dispute.Status}, 1)
+}
+
+// indexReputation records that an actor has a reputation in a dimension
+func indexReputation(ctx contractapi.TransactionContextInterface, actorID string, dimension string) error {
+	return putIndexEntry(ctx, reputationByDimensionIndex, []string{dimension, actorID})
+}
+
+// putDispute stores a dispute, keeps the status index and the parties'
+// activity records current and adds the change to the recent activity feed.
+// previousStatus is "" for a new dispute.
+func putDispute(ctx contractapi.TransactionContextInterface, dispute *Dispute, previousStatus string) error {
+	disputeJSON, err := marshalCanonical(dispute)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dispute: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(dispute.DisputeID, disputeJSON); err != nil {
+		return fmt.Errorf("failed to store dispute: %v", err)
+	}
+
+	if err := indexDispute(ctx, dispute, previousStatus); err != nil {
+		return err
+	}
+	if err := recordDisputeActivity(ctx, dispute, previousStatus); err != nil {
+		return err
+	}
+
+	return recordActivity(ctx, ActivityEntry{
+		Kind:      "dispute",
+		ID:        dispute.DisputeID,
+		ActorID:   dispute.ActorID,
+		Dimension: dispute.Dimension,
+		Status:    dispute.Status,
+	})
+}
+
+// putReputation stores a reputation record, indexes its dimension and
+// updates its leaderboard entry and directory listing
+func putReputation(ctx contractapi.TransactionContextInterface, rep *Reputation) error {
+	previous := pendingReputation(ctx, rep.ActorID, rep.Dimension)
+	if previous == nil {
+		var err error
+		if previous, err = getStoredReputation(ctx, rep.ActorID, rep.Dimension); err != nil {
+			return err
+		}
+	}
+
+	repJSON, err := marshalCanonical(rep)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reputation: %v", err)
+	}
+
+	if err := ctx.GetStub().PutState(reputationStateKey(rep.ActorID, rep.Dimension), repJSON); err != nil {
+		return fmt.Errorf("failed to store reputation: %v", err)
+	}
+
+	setPendingReputation(ctx, rep)
+
+	if err := indexReputation(ctx, rep.ActorID, rep.Dimension); err != nil {
+		return err
+	}
+	if err := updateDimensionStats(ctx, previous, rep); err != nil {
+		return err
+	}
+	if err := updateLeaderboard(ctx, previous, rep); err != nil {
+		return err
+	}
+	return refreshListingScore(ctx, rep)
+}
+
+// ============================================================================
+// INDEX READERS
+// ============================================================================
+
+// indexedRatingsPage loads one page of ratings from a rating index, keeping
+// those that satisfy match (nil keeps every rating)
+func indexedRatingsPage(
+	ctx contractapi.TransactionContextInterface,
+	index string,
+	attributes []string,
+	bookmark string,
+	pageSize int,
+	match func(rating *Rating) bool,
+) (*RatingPage, error) {
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		index, attributes, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", index, err)
+	}
+	defer resultsIterator.Close()
+
+	totalCount, err := getIndexCount(ctx, index, attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings := []Rating{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) == 0 {
+			continue
+		}
+
+		rating, err := getRating(ctx, parts[len(parts)-1])
+		if err != nil || rating == nil {
+			continue
+		}
+		if match != nil && !match(rating) {
+			continue
+		}
+		ratings = append(ratings, *rating)
+	}
+
+	return &RatingPage{
+		Ratings:      ratings,
+		Bookmark:     metadata.Bookmark,
+		FetchedCount: metadata.FetchedRecordsCount,
+		TotalCount:   totalCount,
+	}, nil
+}
+
+// forEachIndexedRating visits the ratings in a rating index, newest first
+// within each key prefix, until visit returns false
+func forEachIndexedRating(
+	ctx contractapi.TransactionContextInterface,
+	index string,
+	attributes []string,
+	visit func(rating *Rating) (bool, error),
+) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, attributes)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", index, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) == 0 {
+			continue
+		}
+
+		rating, err := getRating(ctx, parts[len(parts)-1])
+		if err != nil || rating == nil {
+			continue
+		}
+
+		more, err := visit(rating)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// countIndexedSince counts entries of a timestamped index under the given
+// attributes whose timestamp is at least since. tsPosition is the position
+// of the inverted timestamp among the key attributes.
+func countIndexedSince(
+	ctx contractapi.TransactionContextInterface,
+	index string,
+	attributes []string,
+	tsPosition int,
+	since int64,
+) (int, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, attributes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", index, err)
+	}
+	defer resultsIterator.Close()
+
+	count := 0
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) <= tsPosition {
+			continue
+		}
+		if timestampFromInverted(parts[tsPosition]) >= since {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// disputesWithStatus loads the disputes filed under a status that satisfy
+// match (nil matches every dispute), newest first
+func disputesWithStatus(
+	ctx contractapi.TransactionContextInterface,
+	status string,
+	match func(dispute *Dispute) bool,
+) ([]Dispute, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(disputeByStatusIndex, []string{status})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", disputeByStatusIndex, err)
+	}
+	defer resultsIterator.Close()
+
+	disputes := []Dispute{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 3 {
+			continue
+		}
+
+		dispute, err := getDispute(ctx, parts[2])
+		if err != nil || dispute == nil || dispute.Status != status {
+			continue
+		}
+		if match == nil || match(dispute) {
+			disputes = append(disputes, *dispute)
+		}
+	}
+
+	return disputes, nil
+}
+
+// getRating loads a rating, returning nil if it does not exist
+func getRating(ctx contractapi.TransactionContextInterface, ratingID string) (*Rating, error) {
+	if err := validateRecordID(ratingID, ratingIDPrefix); err != nil {
+		return nil, err
+	}
+
+	ratingJSON, err := ctx.GetStub().GetState(ratingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rating: %v", err)
+	}
+	if ratingJSON == nil {
+		return nil, nil
+	}
+
+	var rating Rating
+	if err := json.Unmarshal(ratingJSON, &rating); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rating: %v", err)
+	}
+
+	return &rating, nil
+}
+
+// getDispute loads a dispute, returning nil if it does not exist
+func getDispute(ctx contractapi.TransactionContextInterface, disputeID string) (*Dispute, error) {
+	if err := validateRecordID(disputeID, disputeIDPrefix); err != nil {
+		return nil, err
+	}
+
+	disputeJSON, err := ctx.GetStub().GetState(disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dispute: %v", err)
+	}
+	if disputeJSON == nil {
+		return nil, nil
+	}
+
+	var dispute Dispute
+	if err := json.Unmarshal(disputeJSON, &dispute); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dispute: %v", err)
+	}
+
+	// Disputes filed before the locked cost was recorded are refunded the
+	// current cost, as they always were
+	if dispute.BondID == "" && !storedJSONHasKey(disputeJSON, "lockedCost") {
+		config, err := getConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		dispute.LockedCost = config.DisputeCost
+	}
+
+	return &dispute, nil
+}
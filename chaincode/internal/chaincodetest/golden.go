@@ -0,0 +1,107 @@
+package chaincodetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ============================================================================
+// GOLDEN STATE
+// ============================================================================
+
+// updateGoldenEnv names the environment variable that makes AssertGolden
+// rewrite golden files instead of comparing against them
+const updateGoldenEnv = "UPDATE_GOLDEN"
+
+// Snapshot renders committed state as a map from readable key to value.
+// Composite keys are written "OBJECTTYPE~attr~attr"; JSON values are kept
+// as JSON and other values become strings. Keys starting with any of
+// ignorePrefixes (in readable form) are left out, e.g. the event journal
+// when a test does not fix transaction times.
+func (s *MockStub) Snapshot(ignorePrefixes ...string) map[string]json.RawMessage {
+	snapshot := make(map[string]json.RawMessage, len(s.State))
+	for key, value := range s.State {
+		readable := ReadableKey(key)
+		if hasAnyPrefix(readable, ignorePrefixes) {
+			continue
+		}
+		if json.Valid(value) {
+			snapshot[readable] = json.RawMessage(value)
+		} else {
+			quoted, _ := json.Marshal(string(value))
+			snapshot[readable] = quoted
+		}
+	}
+	return snapshot
+}
+
+// AssertGolden compares the stub's committed state with the JSON golden file
+// at path, failing t with both renderings if they differ. With
+// UPDATE_GOLDEN=1 set, the file is written from the current state instead.
+func AssertGolden(t testing.TB, s *MockStub, path string, ignorePrefixes ...string) {
+	t.Helper()
+
+	actual, err := json.MarshalIndent(s.Snapshot(ignorePrefixes...), "", "  ")
+	if err != nil {
+		t.Fatalf("failed to render state: %v", err)
+	}
+	actual = append(actual, '\n')
+
+	if os.Getenv(updateGoldenEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with %s=1 to create it): %v", updateGoldenEnv, err)
+	}
+	if !bytes.Equal(normalizeGolden(expected), normalizeGolden(actual)) {
+		t.Fatalf("state differs from %s\n--- expected\n%s\n--- actual\n%s", path, expected, actual)
+	}
+}
+
+// ReadableKey renders a composite key as "OBJECTTYPE~attr~attr"; simple keys
+// are returned unchanged
+func ReadableKey(key string) string {
+	if !strings.HasPrefix(key, compositeKeyNamespace) {
+		return key
+	}
+	return strings.Join(strings.Split(strings.TrimSuffix(key[1:], "\x00"), "\x00"), "~")
+}
+
+// normalizeGolden re-indents a golden rendering so that hand-edited files
+// compare by content
+func normalizeGolden(data []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return data
+	}
+	normalized, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return data
+	}
+	return normalized
+}
+
+// hasAnyPrefix reports whether s starts with one of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package chaincodetest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	stub := NewMockStub()
+	key, _ := stub.CreateCompositeKey("INDEX", []string{"alice", "1"})
+	stub.PutState(key, []byte{0})
+	stub.PutState("config", []byte(`{"version":1}`))
+	stub.PutState("JOURNAL:1", []byte("event"))
+	stub.PutState("note", []byte("plain text"))
+	stub.Commit()
+
+	snapshot := stub.Snapshot("JOURNAL:")
+	if len(snapshot) != 3 {
+		t.Fatalf("snapshot has %d keys, want 3: %v", len(snapshot), snapshot)
+	}
+	if string(snapshot["config"]) != `{"version":1}` {
+		t.Errorf("JSON value = %s", snapshot["config"])
+	}
+	if string(snapshot["note"]) != `"plain text"` {
+		t.Errorf("text value = %s", snapshot["note"])
+	}
+	if _, ok := snapshot["INDEX~alice~1"]; !ok {
+		t.Error("composite key is not readable")
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	stub := NewMockStub()
+	stub.PutState("config", []byte(`{"version":1,"name":"x"}`))
+	stub.Commit()
+
+	// Golden files compare by content, not layout
+	path := filepath.Join(t.TempDir(), "state.json")
+	golden := "{\"config\": {\"name\": \"x\", \"version\": 1}}"
+	if err := os.WriteFile(path, []byte(golden), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	AssertGolden(t, stub, path)
+
+	// UPDATE_GOLDEN rewrites the file from state
+	t.Setenv(updateGoldenEnv, "1")
+	updated := filepath.Join(t.TempDir(), "new", "state.json")
+	AssertGolden(t, stub, updated)
+	if _, err := os.Stat(updated); err != nil {
+		t.Errorf("golden file was not written: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package chaincodetest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/attrmgr"
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/cid"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"google.golang.org/protobuf/proto"
+)
+
+// ============================================================================
+// CLIENT IDENTITIES
+// ============================================================================
+
+// MockClientIdentity is a cid.ClientIdentity backed by a real certificate,
+// so that it reports the same ID and attributes whether the chaincode reads
+// it directly or through the stub's creator. Fields may be changed after
+// construction; Creator and Cert are not regenerated.
+type MockClientIdentity struct {
+	MSPID      string
+	ID         string // base64 "x509::<subject>::<issuer>", as the cid package returns it
+	Attributes map[string]string
+	Cert       *x509.Certificate
+	Creator    []byte // serialized identity, for MockStub.Creator
+}
+
+// NewMockClientIdentity issues a client certificate for commonName from a
+// CA of mspID, carrying attrs as Fabric CA attributes
+func NewMockClientIdentity(mspID string, commonName string, attrs map[string]string) (*MockClientIdentity, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca." + strings.ToLower(mspID), Organization: []string{mspID}},
+		NotBefore:             time.Unix(0, 0),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate client key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName, OrganizationalUnit: []string{"client"}},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if len(attrs) > 0 {
+		if err := attrmgr.New().AddAttributesToCert(&attrmgr.Attributes{Attrs: attrs}, template); err != nil {
+			return nil, fmt.Errorf("failed to add attributes: %v", err)
+		}
+		// CreateCertificate only writes ExtraExtensions
+		template.ExtraExtensions = template.Extensions
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client certificate: %v", err)
+	}
+
+	creator, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize identity: %v", err)
+	}
+
+	// Derive the ID exactly as the chaincode's own cid lookup would
+	clientID, err := cid.New(creatorStub(creator))
+	if err != nil {
+		return nil, err
+	}
+	id, err := clientID.GetID()
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &MockClientIdentity{
+		MSPID:      mspID,
+		ID:         id,
+		Attributes: map[string]string{},
+		Cert:       cert,
+		Creator:    creator,
+	}
+	for name, value := range attrs {
+		identity.Attributes[name] = value
+	}
+	return identity, nil
+}
+
+// GetID returns the identity's ID
+func (m *MockClientIdentity) GetID() (string, error) {
+	return m.ID, nil
+}
+
+// GetMSPID returns the identity's MSP
+func (m *MockClientIdentity) GetMSPID() (string, error) {
+	return m.MSPID, nil
+}
+
+// GetAttributeValue returns an attribute and whether it is set
+func (m *MockClientIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := m.Attributes[attrName]
+	return value, found, nil
+}
+
+// AssertAttributeValue checks that an attribute has a value
+func (m *MockClientIdentity) AssertAttributeValue(attrName, attrValue string) error {
+	value, found := m.Attributes[attrName]
+	if !found {
+		return fmt.Errorf("attribute '%s' was not found", attrName)
+	}
+	if value != attrValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", attrName, value, attrValue)
+	}
+	return nil
+}
+
+// GetX509Certificate returns the identity's certificate
+func (m *MockClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return m.Cert, nil
+}
+
+// creatorStub serves a serialized identity to cid.New
+type creatorStub []byte
+
+// GetCreator returns the serialized identity
+func (c creatorStub) GetCreator() ([]byte, error) {
+	return c, nil
+}
+
+var _ cid.ClientIdentity = (*MockClientIdentity)(nil)
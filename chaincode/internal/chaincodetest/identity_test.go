@@ -0,0 +1,61 @@
+package chaincodetest
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/pkg/cid"
+)
+
+func TestIdentityMatchesCreator(t *testing.T) {
+	identity, err := NewMockClientIdentity("Org1MSP", "alice", map[string]string{"admin": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := NewMockStub().WithIdentity(identity)
+
+	// The chaincode reads the submitter through cid from the creator
+	fromCreator, err := cid.New(stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := fromCreator.GetID()
+	if id != identity.ID {
+		t.Errorf("creator ID = %s, identity ID = %s", id, identity.ID)
+	}
+	mspID, _ := fromCreator.GetMSPID()
+	if mspID != "Org1MSP" {
+		t.Errorf("creator MSP = %s", mspID)
+	}
+	if value, found, _ := fromCreator.GetAttributeValue("admin"); !found || value != "true" {
+		t.Errorf("creator admin attribute = %q, %v", value, found)
+	}
+
+	cert, _ := identity.GetX509Certificate()
+	if cert.Subject.CommonName != "alice" {
+		t.Errorf("certificate CN = %s", cert.Subject.CommonName)
+	}
+}
+
+func TestIdentityAttributes(t *testing.T) {
+	identity, err := NewMockClientIdentity("Org2MSP", "bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found, _ := identity.GetAttributeValue("admin"); found {
+		t.Error("identity without attributes has admin")
+	}
+	if err := identity.AssertAttributeValue("admin", "true"); err == nil {
+		t.Error("AssertAttributeValue passed for a missing attribute")
+	}
+
+	// Attributes may be changed after issue for checks that bypass the
+	// certificate
+	identity.Attributes["admin"] = "false"
+	if err := identity.AssertAttributeValue("admin", "true"); err == nil {
+		t.Error("AssertAttributeValue passed for a different value")
+	}
+	if err := identity.AssertAttributeValue("admin", "false"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,98 @@
+package chaincodetest
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// ============================================================================
+// QUERY ITERATORS
+// ============================================================================
+
+// stateIterator iterates a fixed list of keys, reading values from data
+type stateIterator struct {
+	data   map[string][]byte
+	keys   []string
+	closed bool
+}
+
+// newIterator returns an iterator over keys, which must be present in data
+func newIterator(data map[string][]byte, keys []string) *stateIterator {
+	return &stateIterator{data: data, keys: keys}
+}
+
+// HasNext reports whether another result is available
+func (it *stateIterator) HasNext() bool {
+	return !it.closed && len(it.keys) > 0
+}
+
+// Next returns the next key and value
+func (it *stateIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("iterator is exhausted")
+	}
+	key := it.keys[0]
+	it.keys = it.keys[1:]
+	return &queryresult.KV{Key: key, Value: it.data[key]}, nil
+}
+
+// Close ends the iteration
+func (it *stateIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// paginate returns an iterator over the page of sorted keys starting at
+// bookmark, which is the first key of the page. The returned bookmark is the
+// first key of the next page, or "" on the last page.
+func paginate(
+	data map[string][]byte,
+	keys []string,
+	pageSize int32,
+	bookmark string,
+) (*stateIterator, *peer.QueryResponseMetadata) {
+	start := 0
+	if bookmark != "" {
+		start = sort.SearchStrings(keys, bookmark)
+	}
+	end := len(keys)
+	if pageSize > 0 && start+int(pageSize) < end {
+		end = start + int(pageSize)
+	}
+
+	metadata := &peer.QueryResponseMetadata{FetchedRecordsCount: int32(end - start)}
+	if end < len(keys) {
+		metadata.Bookmark = keys[end]
+	}
+	return newIterator(data, keys[start:end]), metadata
+}
+
+// historyIterator iterates the modifications of a key
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+	closed        bool
+}
+
+// HasNext reports whether another modification is available
+func (it *historyIterator) HasNext() bool {
+	return !it.closed && len(it.modifications) > 0
+}
+
+// Next returns the next modification
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("iterator is exhausted")
+	}
+	modification := it.modifications[0]
+	it.modifications = it.modifications[1:]
+	return modification, nil
+}
+
+// Close ends the iteration
+func (it *historyIterator) Close() error {
+	it.closed = true
+	return nil
+}
@@ -0,0 +1,668 @@
+// Package chaincodetest provides an in-memory stub and client identity for
+// unit testing contract functions without a Fabric network, and helpers to
+// compare world state against golden files.
+//
+// A test binds a stub and an identity to the chaincode's transaction
+// context, runs the function inside a transaction and commits it:
+//
+//	stub := chaincodetest.NewMockStub()
+//	alice, _ := chaincodetest.NewMockClientIdentity("Org1MSP", "alice", nil)
+//	ctx := new(ReputationContext)
+//	ctx.SetStub(stub)
+//	ctx.SetClientIdentity(alice)
+//
+//	stub.Begin("tx1", time.Unix(1700000000, 0))
+//	err := contract.AddStake(ctx, "15000")
+//	stub.Commit()
+//
+// Like a peer, the stub does not let a transaction read its own writes:
+// reads see committed state until Commit.
+package chaincodetest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Key layout used by the shim for composite keys and open-ended ranges
+const (
+	compositeKeyNamespace = "\x00"
+	emptyKeySubstitute    = "\x01"
+	maxUnicodeRune        = string(utf8.MaxRune)
+)
+
+// Event is a chaincode event set by a committed transaction
+type Event struct {
+	TxID    string
+	Name    string
+	Payload []byte
+}
+
+// QueryHandler answers a rich query with the keys of the matching records,
+// in result order. The stub reads their values from committed state.
+type QueryHandler func(query string) ([]string, error)
+
+// ChaincodeHandler answers an InvokeChaincode call
+type ChaincodeHandler func(args [][]byte) *peer.Response
+
+// MockStub is an in-memory shim.ChaincodeStubInterface
+type MockStub struct {
+	// Transaction proposal, set by Begin and the With* helpers
+	TxID      string
+	ChannelID string
+	Args      [][]byte
+	Transient map[string][]byte
+	Creator   []byte
+	Timestamp time.Time
+
+	// Committed world state and private data
+	State       map[string][]byte
+	PrivateData map[string]map[string][]byte
+
+	// Events of committed transactions, oldest first
+	Events []Event
+
+	// Rich query and cross-chaincode handlers
+	Queries    map[string][]string // exact query string to result keys
+	QueryFunc  QueryHandler        // consulted for queries not in Queries
+	Chaincodes map[string]ChaincodeHandler
+
+	history           map[string][]*queryresult.KeyModification
+	validation        map[string][]byte
+	privateValidation map[string]map[string][]byte
+	writes            map[string][]byte // nil value deletes
+	privateWrites     map[string]map[string][]byte
+	validationWrites  map[string][]byte
+	pendingEvent      *Event
+}
+
+// NewMockStub returns a stub with empty state on channel "mychannel"
+func NewMockStub() *MockStub {
+	stub := &MockStub{
+		ChannelID:         "mychannel",
+		Transient:         map[string][]byte{},
+		State:             map[string][]byte{},
+		PrivateData:       map[string]map[string][]byte{},
+		Queries:           map[string][]string{},
+		Chaincodes:        map[string]ChaincodeHandler{},
+		history:           map[string][]*queryresult.KeyModification{},
+		validation:        map[string][]byte{},
+		privateValidation: map[string]map[string][]byte{},
+	}
+	stub.Begin("tx0", time.Unix(0, 0))
+	return stub
+}
+
+// ============================================================================
+// TRANSACTIONS
+// ============================================================================
+
+// Begin starts a transaction, discarding any uncommitted writes. Args,
+// Transient and Creator are kept, so set them after Begin when they differ
+// between transactions.
+func (s *MockStub) Begin(txID string, timestamp time.Time) {
+	s.TxID = txID
+	s.Timestamp = timestamp
+	s.writes = map[string][]byte{}
+	s.privateWrites = map[string]map[string][]byte{}
+	s.validationWrites = map[string][]byte{}
+	s.pendingEvent = nil
+}
+
+// WithFunction sets the invoked function and its string arguments, as read
+// by GetFunctionAndParameters
+func (s *MockStub) WithFunction(function string, params ...string) *MockStub {
+	s.Args = [][]byte{[]byte(function)}
+	for _, param := range params {
+		s.Args = append(s.Args, []byte(param))
+	}
+	return s
+}
+
+// WithTransient sets the transient map
+func (s *MockStub) WithTransient(transient map[string][]byte) *MockStub {
+	s.Transient = transient
+	return s
+}
+
+// WithIdentity makes identity the submitter of the following transactions
+func (s *MockStub) WithIdentity(identity *MockClientIdentity) *MockStub {
+	s.Creator = identity.Creator
+	return s
+}
+
+// Commit applies the transaction's writes, records their history and its
+// event
+func (s *MockStub) Commit() {
+	timestamp := timestamppb.New(s.Timestamp)
+
+	for _, key := range sortedKeys(s.writes) {
+		value := s.writes[key]
+		if value == nil {
+			delete(s.State, key)
+		} else {
+			s.State[key] = value
+		}
+		s.history[key] = append(s.history[key], &queryresult.KeyModification{
+			TxId:      s.TxID,
+			Value:     value,
+			Timestamp: timestamp,
+			IsDelete:  value == nil,
+		})
+	}
+
+	for collection, writes := range s.privateWrites {
+		if s.PrivateData[collection] == nil {
+			s.PrivateData[collection] = map[string][]byte{}
+		}
+		for key, value := range writes {
+			if value == nil {
+				delete(s.PrivateData[collection], key)
+			} else {
+				s.PrivateData[collection][key] = value
+			}
+		}
+	}
+
+	for key, ep := range s.validationWrites {
+		s.validation[key] = ep
+	}
+
+	if s.pendingEvent != nil {
+		s.Events = append(s.Events, *s.pendingEvent)
+	}
+
+	s.Begin(s.TxID, s.Timestamp)
+}
+
+// Rollback discards the transaction's writes and event
+func (s *MockStub) Rollback() {
+	s.Begin(s.TxID, s.Timestamp)
+}
+
+// Writes returns the transaction's uncommitted writes; deleted keys map to
+// nil
+func (s *MockStub) Writes() map[string][]byte {
+	writes := make(map[string][]byte, len(s.writes))
+	for key, value := range s.writes {
+		writes[key] = value
+	}
+	return writes
+}
+
+// LastEvent returns the most recent committed event, or nil
+func (s *MockStub) LastEvent() *Event {
+	if len(s.Events) == 0 {
+		return nil
+	}
+	return &s.Events[len(s.Events)-1]
+}
+
+// SetPeerMSPID sets the MSP reported by shim.GetMSPID, which reads the
+// peer's environment
+func SetPeerMSPID(mspID string) error {
+	return os.Setenv("CORE_PEER_LOCALMSPID", mspID)
+}
+
+// ============================================================================
+// PROPOSAL
+// ============================================================================
+
+// GetArgs returns the invocation arguments
+func (s *MockStub) GetArgs() [][]byte {
+	return s.Args
+}
+
+// GetStringArgs returns the invocation arguments as strings
+func (s *MockStub) GetStringArgs() []string {
+	args := make([]string, len(s.Args))
+	for i, arg := range s.Args {
+		args[i] = string(arg)
+	}
+	return args
+}
+
+// GetFunctionAndParameters splits the arguments into function and parameters
+func (s *MockStub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+// GetArgsSlice returns the arguments concatenated
+func (s *MockStub) GetArgsSlice() ([]byte, error) {
+	var slice []byte
+	for _, arg := range s.Args {
+		slice = append(slice, arg...)
+	}
+	return slice, nil
+}
+
+// GetTxID returns the current transaction ID
+func (s *MockStub) GetTxID() string {
+	return s.TxID
+}
+
+// GetChannelID returns the channel name
+func (s *MockStub) GetChannelID() string {
+	return s.ChannelID
+}
+
+// GetCreator returns the serialized submitter identity
+func (s *MockStub) GetCreator() ([]byte, error) {
+	return s.Creator, nil
+}
+
+// GetTransient returns the transient map
+func (s *MockStub) GetTransient() (map[string][]byte, error) {
+	return s.Transient, nil
+}
+
+// GetBinding returns a hash of the transaction ID and creator
+func (s *MockStub) GetBinding() ([]byte, error) {
+	hash := sha256.Sum256(append([]byte(s.TxID), s.Creator...))
+	return hash[:], nil
+}
+
+// GetDecorations returns no decorations
+func (s *MockStub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+// GetSignedProposal is not available without a network
+func (s *MockStub) GetSignedProposal() (*peer.SignedProposal, error) {
+	return nil, fmt.Errorf("signed proposals are not available in the mock stub")
+}
+
+// GetTxTimestamp returns the transaction timestamp set by Begin
+func (s *MockStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.Timestamp), nil
+}
+
+// SetEvent sets the transaction's event, replacing any earlier one
+func (s *MockStub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return fmt.Errorf("event name can not be empty string")
+	}
+	s.pendingEvent = &Event{TxID: s.TxID, Name: name, Payload: payload}
+	return nil
+}
+
+// InvokeChaincode calls the handler registered under chaincodeName
+func (s *MockStub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) *peer.Response {
+	handler, ok := s.Chaincodes[chaincodeName]
+	if !ok {
+		return &peer.Response{Status: shim.ERROR, Message: fmt.Sprintf("chaincode %s is not registered", chaincodeName)}
+	}
+	return handler(args)
+}
+
+// ============================================================================
+// WORLD STATE
+// ============================================================================
+
+// GetState returns the committed value of key
+func (s *MockStub) GetState(key string) ([]byte, error) {
+	return s.State[key], nil
+}
+
+// GetMultipleStates returns the committed values of keys
+func (s *MockStub) GetMultipleStates(keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = s.State[key]
+	}
+	return values, nil
+}
+
+// PutState writes key in the current transaction
+func (s *MockStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	s.writes[key] = value
+	return nil
+}
+
+// DelState deletes key in the current transaction
+func (s *MockStub) DelState(key string) error {
+	s.writes[key] = nil
+	return nil
+}
+
+// SetStateValidationParameter sets a key-level endorsement policy
+func (s *MockStub) SetStateValidationParameter(key string, ep []byte) error {
+	s.validationWrites[key] = ep
+	return nil
+}
+
+// GetStateValidationParameter returns a key's committed endorsement policy
+func (s *MockStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.validation[key], nil
+}
+
+// GetStateByRange iterates committed simple keys in [startKey, endKey)
+func (s *MockStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	return newIterator(s.State, s.rangeKeys(startKey, endKey)), nil
+}
+
+// GetStateByRangeWithPagination iterates a page of committed simple keys
+func (s *MockStub) GetStateByRangeWithPagination(
+	startKey, endKey string,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	iterator, metadata := paginate(s.State, s.rangeKeys(startKey, endKey), pageSize, bookmark)
+	return iterator, metadata, nil
+}
+
+// GetStateByPartialCompositeKey iterates committed composite keys with a
+// prefix
+func (s *MockStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator(s.State, s.rangeKeys(prefix, prefix+maxUnicodeRune)), nil
+}
+
+// GetStateByPartialCompositeKeyWithPagination iterates a page of committed
+// composite keys with a prefix
+func (s *MockStub) GetStateByPartialCompositeKeyWithPagination(
+	objectType string,
+	keys []string,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	iterator, metadata := paginate(s.State, s.rangeKeys(prefix, prefix+maxUnicodeRune), pageSize, bookmark)
+	return iterator, metadata, nil
+}
+
+// GetAllStatesCompositeKeyWithPagination iterates a page of every committed
+// composite key
+func (s *MockStub) GetAllStatesCompositeKeyWithPagination(
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	keys := s.rangeKeys(compositeKeyNamespace, compositeKeyNamespace+maxUnicodeRune)
+	iterator, metadata := paginate(s.State, keys, pageSize, bookmark)
+	return iterator, metadata, nil
+}
+
+// CreateCompositeKey builds a composite key the way the shim does
+func (s *MockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	if err := validateCompositeKeyAttribute(objectType); err != nil {
+		return "", err
+	}
+	key := compositeKeyNamespace + objectType + "\x00"
+	for _, attribute := range attributes {
+		if err := validateCompositeKeyAttribute(attribute); err != nil {
+			return "", err
+		}
+		key += attribute + "\x00"
+	}
+	return key, nil
+}
+
+// SplitCompositeKey splits a composite key into its object type and
+// attributes
+func (s *MockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	components := strings.Split(compositeKey[1:], "\x00")
+	if len(components) < 2 {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	return components[0], components[1 : len(components)-1], nil
+}
+
+// GetQueryResult answers a rich query from Queries or QueryFunc
+func (s *MockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	keys, err := s.queryKeys(query)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator(s.State, keys), nil
+}
+
+// GetQueryResultWithPagination answers a page of a rich query. Bookmarks
+// are offsets into the result.
+func (s *MockStub) GetQueryResultWithPagination(
+	query string,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	keys, err := s.queryKeys(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	offset := 0
+	if bookmark != "" {
+		if _, err := fmt.Sscanf(bookmark, "%d", &offset); err != nil || offset < 0 || offset > len(keys) {
+			return nil, nil, fmt.Errorf("invalid bookmark %q", bookmark)
+		}
+	}
+	end := len(keys)
+	if pageSize > 0 && offset+int(pageSize) < end {
+		end = offset + int(pageSize)
+	}
+
+	metadata := &peer.QueryResponseMetadata{FetchedRecordsCount: int32(end - offset)}
+	if end < len(keys) {
+		metadata.Bookmark = fmt.Sprintf("%d", end)
+	}
+	return newIterator(s.State, keys[offset:end]), metadata, nil
+}
+
+// GetHistoryForKey returns the committed modifications of key, newest first
+func (s *MockStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	modifications := s.history[key]
+	reversed := make([]*queryresult.KeyModification, len(modifications))
+	for i, modification := range modifications {
+		reversed[len(modifications)-1-i] = modification
+	}
+	return &historyIterator{modifications: reversed}, nil
+}
+
+// StartWriteBatch is a no-op; writes are always batched until Commit
+func (s *MockStub) StartWriteBatch() {}
+
+// FinishWriteBatch is a no-op; writes are always batched until Commit
+func (s *MockStub) FinishWriteBatch() error {
+	return nil
+}
+
+// ============================================================================
+// PRIVATE DATA
+// ============================================================================
+
+// GetPrivateData returns the committed value of key in a collection
+func (s *MockStub) GetPrivateData(collection, key string) ([]byte, error) {
+	return s.PrivateData[collection][key], nil
+}
+
+// GetMultiplePrivateData returns the committed values of keys in a
+// collection
+func (s *MockStub) GetMultiplePrivateData(collection string, keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = s.PrivateData[collection][key]
+	}
+	return values, nil
+}
+
+// GetPrivateDataHash returns the SHA-256 of a committed private value
+func (s *MockStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	value := s.PrivateData[collection][key]
+	if value == nil {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+// PutPrivateData writes key in a collection in the current transaction
+func (s *MockStub) PutPrivateData(collection string, key string, value []byte) error {
+	if collection == "" {
+		return fmt.Errorf("collection must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	s.privateWrite(collection)[key] = value
+	return nil
+}
+
+// DelPrivateData deletes key from a collection in the current transaction
+func (s *MockStub) DelPrivateData(collection, key string) error {
+	s.privateWrite(collection)[key] = nil
+	return nil
+}
+
+// PurgePrivateData deletes key from a collection; the mock keeps no private
+// history, so purging is the same as deleting
+func (s *MockStub) PurgePrivateData(collection, key string) error {
+	return s.DelPrivateData(collection, key)
+}
+
+// SetPrivateDataValidationParameter sets a private key's endorsement policy
+func (s *MockStub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	if s.privateValidation[collection] == nil {
+		s.privateValidation[collection] = map[string][]byte{}
+	}
+	s.privateValidation[collection][key] = ep
+	return nil
+}
+
+// GetPrivateDataValidationParameter returns a private key's endorsement
+// policy
+func (s *MockStub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	return s.privateValidation[collection][key], nil
+}
+
+// GetPrivateDataByRange iterates committed keys of a collection in
+// [startKey, endKey)
+func (s *MockStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	data := s.PrivateData[collection]
+	return newIterator(data, keysInRange(data, startKey, endKey)), nil
+}
+
+// GetPrivateDataByPartialCompositeKey iterates committed composite keys of a
+// collection with a prefix
+func (s *MockStub) GetPrivateDataByPartialCompositeKey(
+	collection, objectType string,
+	keys []string,
+) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	data := s.PrivateData[collection]
+	return newIterator(data, keysInRange(data, prefix, prefix+maxUnicodeRune)), nil
+}
+
+// GetPrivateDataQueryResult answers a rich query against a collection from
+// Queries or QueryFunc
+func (s *MockStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	keys, err := s.queryKeys(query)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator(s.PrivateData[collection], keys), nil
+}
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+// rangeKeys returns the committed keys in [startKey, endKey), sorted
+func (s *MockStub) rangeKeys(startKey, endKey string) []string {
+	return keysInRange(s.State, startKey, endKey)
+}
+
+// queryKeys resolves a rich query to result keys
+func (s *MockStub) queryKeys(query string) ([]string, error) {
+	if keys, ok := s.Queries[query]; ok {
+		return keys, nil
+	}
+	if s.QueryFunc != nil {
+		return s.QueryFunc(query)
+	}
+	return nil, fmt.Errorf("no result registered for query %s", query)
+}
+
+// privateWrite returns the write set of a collection
+func (s *MockStub) privateWrite(collection string) map[string][]byte {
+	if s.privateWrites[collection] == nil {
+		s.privateWrites[collection] = map[string][]byte{}
+	}
+	return s.privateWrites[collection]
+}
+
+// keysInRange returns the keys of data in [startKey, endKey), sorted. An
+// empty endKey is unbounded.
+func keysInRange(data map[string][]byte, startKey, endKey string) []string {
+	var keys []string
+	for key := range data {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedKeys returns the keys of a map, sorted
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateCompositeKeyAttribute rejects the characters the shim reserves
+func validateCompositeKeyAttribute(attribute string) error {
+	if !utf8.ValidString(attribute) {
+		return fmt.Errorf("not a valid utf8 string: [%x]", attribute)
+	}
+	for index, r := range attribute {
+		if r == 0 || r == utf8.MaxRune {
+			return fmt.Errorf("input contains unicode %#U starting at position [%d]", r, index)
+		}
+	}
+	return nil
+}
+
+var _ shim.ChaincodeStubInterface = (*MockStub)(nil)
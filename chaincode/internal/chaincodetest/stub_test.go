@@ -0,0 +1,267 @@
+package chaincodetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+)
+
+// collectKeys drains an iterator into its keys
+func collectKeys(t *testing.T, iterator shim.StateQueryIteratorInterface) []string {
+	t.Helper()
+	defer iterator.Close()
+	var keys []string
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		keys = append(keys, kv.Key)
+	}
+	return keys
+}
+
+func TestWritesAreVisibleAfterCommit(t *testing.T) {
+	stub := NewMockStub()
+	stub.Begin("tx1", time.Unix(100, 0))
+	if err := stub.PutState("a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, _ := stub.GetState("a"); value != nil {
+		t.Errorf("read own write %q before commit", value)
+	}
+	if writes := stub.Writes(); string(writes["a"]) != "1" {
+		t.Errorf("Writes = %v, want a=1", writes)
+	}
+
+	stub.Commit()
+	if value, _ := stub.GetState("a"); string(value) != "1" {
+		t.Errorf("GetState after commit = %q, want 1", value)
+	}
+	if len(stub.Writes()) != 0 {
+		t.Error("writes remain after commit")
+	}
+}
+
+func TestRollbackDiscardsWritesAndEvent(t *testing.T) {
+	stub := NewMockStub()
+	stub.Begin("tx1", time.Unix(100, 0))
+	stub.PutState("a", []byte("1"))
+	stub.SetEvent("Written", nil)
+	stub.Rollback()
+	stub.Commit()
+
+	if value, _ := stub.GetState("a"); value != nil {
+		t.Errorf("rolled back write committed: %q", value)
+	}
+	if event := stub.LastEvent(); event != nil {
+		t.Errorf("rolled back event committed: %s", event.Name)
+	}
+}
+
+func TestCommitRecordsLastEvent(t *testing.T) {
+	stub := NewMockStub()
+	stub.Begin("tx1", time.Unix(100, 0))
+	stub.SetEvent("First", []byte("1"))
+	stub.SetEvent("Second", []byte("2"))
+	if stub.LastEvent() != nil {
+		t.Error("event visible before commit")
+	}
+	stub.Commit()
+
+	if len(stub.Events) != 1 {
+		t.Fatalf("Events = %d, want 1", len(stub.Events))
+	}
+	if event := stub.LastEvent(); event.Name != "Second" || event.TxID != "tx1" || string(event.Payload) != "2" {
+		t.Errorf("LastEvent = %+v, want Second from tx1", event)
+	}
+	if err := stub.SetEvent("", nil); err == nil {
+		t.Error("SetEvent accepted an empty name")
+	}
+}
+
+func TestDeleteAndHistory(t *testing.T) {
+	stub := NewMockStub()
+	stub.Begin("tx1", time.Unix(100, 0))
+	stub.PutState("a", []byte("1"))
+	stub.Commit()
+	stub.Begin("tx2", time.Unix(200, 0))
+	stub.DelState("a")
+	stub.Commit()
+
+	if value, _ := stub.GetState("a"); value != nil {
+		t.Errorf("deleted key reads %q", value)
+	}
+
+	iterator, err := stub.GetHistoryForKey("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var txIDs []string
+	for iterator.HasNext() {
+		modification, _ := iterator.Next()
+		txIDs = append(txIDs, modification.TxId)
+		if modification.IsDelete != (modification.TxId == "tx2") {
+			t.Errorf("%s IsDelete = %v", modification.TxId, modification.IsDelete)
+		}
+	}
+	if !reflect.DeepEqual(txIDs, []string{"tx2", "tx1"}) {
+		t.Errorf("history = %v, want newest first", txIDs)
+	}
+}
+
+func TestRangeAndPagination(t *testing.T) {
+	stub := NewMockStub()
+	for _, key := range []string{"k3", "k1", "k2", "k4", "x1"} {
+		stub.PutState(key, []byte(key))
+	}
+	stub.Commit()
+
+	iterator, _ := stub.GetStateByRange("k", "k~")
+	if keys := collectKeys(t, iterator); !reflect.DeepEqual(keys, []string{"k1", "k2", "k3", "k4"}) {
+		t.Errorf("range = %v", keys)
+	}
+
+	var pages [][]string
+	bookmark := ""
+	for {
+		iterator, metadata, err := stub.GetStateByRangeWithPagination("k", "k~", 3, bookmark)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := collectKeys(t, iterator)
+		if int(metadata.FetchedRecordsCount) != len(page) {
+			t.Errorf("FetchedRecordsCount = %d for %d keys", metadata.FetchedRecordsCount, len(page))
+		}
+		pages = append(pages, page)
+		if bookmark = metadata.Bookmark; bookmark == "" {
+			break
+		}
+	}
+	if !reflect.DeepEqual(pages, [][]string{{"k1", "k2", "k3"}, {"k4"}}) {
+		t.Errorf("pages = %v", pages)
+	}
+}
+
+func TestCompositeKeys(t *testing.T) {
+	stub := NewMockStub()
+	for _, attrs := range [][]string{{"alice", "2"}, {"alice", "1"}, {"bob", "1"}} {
+		key, err := stub.CreateCompositeKey("RATING", attrs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stub.PutState(key, []byte("x"))
+	}
+	stub.PutState("RATING", []byte("simple"))
+	stub.Commit()
+
+	iterator, _ := stub.GetStateByPartialCompositeKey("RATING", []string{"alice"})
+	keys := collectKeys(t, iterator)
+	if len(keys) != 2 {
+		t.Fatalf("partial key matched %d keys, want 2", len(keys))
+	}
+	objectType, attrs, err := stub.SplitCompositeKey(keys[0])
+	if err != nil || objectType != "RATING" || !reflect.DeepEqual(attrs, []string{"alice", "1"}) {
+		t.Errorf("SplitCompositeKey = %s %v %v", objectType, attrs, err)
+	}
+	if readable := ReadableKey(keys[1]); readable != "RATING~alice~2" {
+		t.Errorf("ReadableKey = %q", readable)
+	}
+
+	// Simple range queries skip composite keys
+	iterator, _ = stub.GetStateByRange("", "")
+	if keys := collectKeys(t, iterator); !reflect.DeepEqual(keys, []string{"RATING"}) {
+		t.Errorf("open range = %v, want only the simple key", keys)
+	}
+
+	if _, err := stub.CreateCompositeKey("RATING", []string{"a\x00b"}); err == nil {
+		t.Error("CreateCompositeKey accepted a NUL attribute")
+	}
+}
+
+func TestPrivateData(t *testing.T) {
+	stub := NewMockStub()
+	stub.PutPrivateData("secrets", "a", []byte("1"))
+	if value, _ := stub.GetPrivateData("secrets", "a"); value != nil {
+		t.Error("private write visible before commit")
+	}
+	stub.Commit()
+
+	hash, _ := stub.GetPrivateDataHash("secrets", "a")
+	if len(hash) != 32 {
+		t.Errorf("hash has %d bytes, want 32", len(hash))
+	}
+	if value, _ := stub.GetState("a"); value != nil {
+		t.Error("private data leaked into world state")
+	}
+
+	stub.PurgePrivateData("secrets", "a")
+	stub.Commit()
+	if value, _ := stub.GetPrivateData("secrets", "a"); value != nil {
+		t.Errorf("purged value reads %q", value)
+	}
+	if err := stub.PutPrivateData("", "a", []byte("1")); err == nil {
+		t.Error("PutPrivateData accepted an empty collection")
+	}
+}
+
+func TestRichQueries(t *testing.T) {
+	stub := NewMockStub()
+	stub.PutState("a", []byte("1"))
+	stub.PutState("b", []byte("2"))
+	stub.Commit()
+
+	stub.Queries[`{"selector":{}}`] = []string{"b", "a"}
+	iterator, err := stub.GetQueryResult(`{"selector":{}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := collectKeys(t, iterator); !reflect.DeepEqual(keys, []string{"b", "a"}) {
+		t.Errorf("query = %v, want registered order", keys)
+	}
+
+	iterator, metadata, err := stub.GetQueryResultWithPagination(`{"selector":{}}`, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := collectKeys(t, iterator); !reflect.DeepEqual(keys, []string{"b"}) || metadata.Bookmark != "1" {
+		t.Errorf("first page = %v, bookmark %q", keys, metadata.Bookmark)
+	}
+
+	if _, err := stub.GetQueryResult(`{"selector":{"x":1}}`); err == nil {
+		t.Error("unregistered query succeeded")
+	}
+	stub.QueryFunc = func(query string) ([]string, error) { return []string{"a"}, nil }
+	iterator, _ = stub.GetQueryResult(`{"selector":{"x":1}}`)
+	if keys := collectKeys(t, iterator); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Errorf("QueryFunc result = %v", keys)
+	}
+}
+
+func TestProposalAccessors(t *testing.T) {
+	stub := NewMockStub()
+	stub.Begin("tx9", time.Unix(1700000000, 0))
+	stub.WithFunction("rating:SubmitRating", "alice", "quality").
+		WithTransient(map[string][]byte{"salt": []byte("s")})
+
+	function, params := stub.GetFunctionAndParameters()
+	if function != "rating:SubmitRating" || !reflect.DeepEqual(params, []string{"alice", "quality"}) {
+		t.Errorf("GetFunctionAndParameters = %s %v", function, params)
+	}
+	if transient, _ := stub.GetTransient(); string(transient["salt"]) != "s" {
+		t.Errorf("transient = %v", transient)
+	}
+	if timestamp, _ := stub.GetTxTimestamp(); timestamp.GetSeconds() != 1700000000 {
+		t.Errorf("timestamp = %v", timestamp)
+	}
+	if stub.GetTxID() != "tx9" || stub.GetChannelID() != "mychannel" {
+		t.Errorf("tx %s on %s", stub.GetTxID(), stub.GetChannelID())
+	}
+
+	if response := stub.InvokeChaincode("settlement", nil, ""); response.Status != shim.ERROR {
+		t.Errorf("unregistered chaincode returned status %d", response.Status)
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// CROSS-CHAINCODE QUERIES
+// ============================================================================
+
+// GetScore, CheckThreshold and GetWeight form the interface other chaincodes
+// on the channel call through InvokeChaincode. Their payloads are small,
+// carry interopVersion, and are computed as of the transaction timestamp
+// rather than the peer clock, so every endorsing peer returns the same
+// bytes. Fields may be added within a version; renaming or removing one
+// requires a version bump.
+const interopVersion = 1
+
+// InteropScore is the GetScore response
+type InteropScore struct {
+	V         int     `json:"v"`
+	ActorID   string  `json:"actorId"`
+	Dimension string  `json:"dimension"`
+	Score     float64 `json:"score"`
+	CILower   float64 `json:"ciLower"`
+	Events    int     `json:"events"`
+	AsOf      int64   `json:"asOf"`
+}
+
+// InteropThreshold is the CheckThreshold response
+type InteropThreshold struct {
+	V         int     `json:"v"`
+	ActorID   string  `json:"actorId"`
+	Dimension string  `json:"dimension"`
+	MinScore  float64 `json:"minScore"`
+	Score     float64 `json:"score"`
+	Suspended bool    `json:"suspended"`
+	Pass      bool    `json:"pass"`
+	AsOf      int64   `json:"asOf"`
+}
+
+// InteropWeight is the GetWeight response
+type InteropWeight struct {
+	V         int     `json:"v"`
+	RaterID   string  `json:"raterId"`
+	Dimension string  `json:"dimension"`
+	Weight    float64 `json:"weight"`
+	AsOf      int64   `json:"asOf"`
+}
+
+// GetScore returns an actor's decayed score in a dimension
+func (rc *ReputationContract) GetScore(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+) (*InteropScore, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+	rep, err := getOrInitReputation(ctx, normalizedActorID, dimension, config)
+	if err != nil {
+		return nil, err
+	}
+
+	effectiveRep := applyDynamicDecayAt(rep, config, now)
+	ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+
+	return &InteropScore{
+		V:         interopVersion,
+		ActorID:   normalizedActorID,
+		Dimension: dimension,
+		Score:     reputationScore(effectiveRep),
+		CILower:   ci[0],
+		Events:    rep.TotalEvents,
+		AsOf:      now,
+	}, nil
+}
+
+// CheckThreshold reports whether an actor's decayed score in a dimension is
+// at least minScore. Suspended actors never pass, so callers can gate on
+// Pass alone.
+func (rc *ReputationContract) CheckThreshold(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+	minScoreStr string,
+) (*InteropThreshold, error) {
+	minScore, err := strconv.ParseFloat(minScoreStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minScore: %v", err)
+	}
+
+	score, err := rc.GetScore(ctx, actorID, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	suspended, err := isBanned(ctx, score.ActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InteropThreshold{
+		V:         interopVersion,
+		ActorID:   score.ActorID,
+		Dimension: dimension,
+		MinScore:  minScore,
+		Score:     score.Score,
+		Suspended: suspended,
+		Pass:      !suspended && score.Score >= minScore,
+		AsOf:      score.AsOf,
+	}, nil
+}
+
+// GetWeight returns the weight a rater's ratings in a base dimension carry,
+// derived from their METAREPUTATION in the matching meta-dimension
+func (rc *ReputationContract) GetWeight(
+	ctx contractapi.TransactionContextInterface,
+	raterID string,
+	dimension string,
+) (*InteropWeight, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metaDimension, exists := config.MetaDimensions[dimension]
+	if !exists {
+		return nil, fmt.Errorf("no meta-dimension for %s", dimension)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedRaterID := resolveIdentity(ctx, raterID)
+	rep, err := getOrInitReputation(ctx, normalizedRaterID, metaDimension, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InteropWeight{
+		V:         interopVersion,
+		RaterID:   normalizedRaterID,
+		Dimension: dimension,
+		Weight:    raterWeight(applyDynamicDecayAt(rep, config, now), config),
+		AsOf:      now,
+	}, nil
+}
+
+// ============================================================================
+// ACCESS DECISIONS
+// ============================================================================
+
+// Access decision checks, reported in InteropDecision.Check
+const (
+	accessCheckReputation = "reputation"
+	accessCheckStake      = "stake"
+)
+
+// Access denial codes, reported in InteropDenial.Code
+const (
+	denialSuspended   = "suspended"
+	denialDeactivated = "deactivated"
+	denialScore       = "score_below_minimum"
+	denialEvents      = "events_below_minimum"
+	denialStake       = "stake_below_minimum"
+)
+
+// InteropDenial is one reason an access decision denied
+type InteropDenial struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// InteropDecision is the RequireReputation and RequireStake response.
+// Requirements that were not checked are left zero.
+type InteropDecision struct {
+	V           int             `json:"v"`
+	Check       string          `json:"check"` // reputation, stake
+	ActorID     string          `json:"actorId"`
+	Dimension   string          `json:"dimension,omitempty"`
+	MinScore    float64         `json:"minScore,omitempty"`
+	MinEvents   int             `json:"minEvents,omitempty"`
+	MinStake    float64         `json:"minStake,omitempty"`
+	Allowed     bool            `json:"allowed"`
+	Reasons     []InteropDenial `json:"reasons"` // empty when allowed
+	RequestedBy string          `json:"requestedBy"`
+	AsOf        int64           `json:"asOf"`
+}
+
+// RequireReputation decides whether an actor may perform an operation gated
+// on a decayed score of at least minScore over at least minEvents events in
+// a dimension, e.g. only suppliers with delivery >= 0.8 may bid. A denial is
+// a normal response listing every unmet requirement, so the calling
+// chaincode can relay the reasons; suspended and deactivated actors are
+// always denied. The decision is emitted as an AccessDecision event, which
+// Fabric delivers only when this chaincode is invoked directly rather than
+// through InvokeChaincode.
+func (rc *ReputationContract) RequireReputation(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+	minScoreStr string,
+	minEventsStr string,
+) (*InteropDecision, error) {
+	minScore, err := strconv.ParseFloat(minScoreStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minScore: %v", err)
+	}
+	minEvents, err := strconv.Atoi(minEventsStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minEvents: %v", err)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
+
+	decision, err := newAccessDecision(ctx, accessCheckReputation, actorID)
+	if err != nil {
+		return nil, err
+	}
+	decision.Dimension = dimension
+	decision.MinScore = minScore
+	decision.MinEvents = minEvents
+
+	rep, err := getOrInitReputation(ctx, decision.ActorID, dimension, config)
+	if err != nil {
+		return nil, err
+	}
+
+	effectiveRep := applyDynamicDecayAt(rep, config, decision.AsOf)
+	if score := scoreFixed(effectiveRep); score < fixedFromFloat(minScore) {
+		decision.deny(denialScore, fmt.Sprintf("%s score %s is below %s", dimension, score, fixedFromFloat(minScore)))
+	}
+	if rep.TotalEvents < minEvents {
+		decision.deny(denialEvents, fmt.Sprintf("%d %s events are fewer than %d", rep.TotalEvents, dimension, minEvents))
+	}
+
+	return decideAccess(ctx, decision, reputationStateKey(decision.ActorID, dimension))
+}
+
+// RequireStake decides whether an actor may perform an operation gated on
+// an unlocked stake balance of at least minStake. Denials and events are as
+// for RequireReputation.
+func (rc *ReputationContract) RequireStake(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	minStakeStr string,
+) (*InteropDecision, error) {
+	minStake, err := strconv.ParseFloat(minStakeStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minStake: %v", err)
+	}
+
+	decision, err := newAccessDecision(ctx, accessCheckStake, actorID)
+	if err != nil {
+		return nil, err
+	}
+	decision.MinStake = minStake
+
+	stake, err := getOrInitStake(ctx, decision.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	if stake.Balance < minStake {
+		decision.deny(denialStake, fmt.Sprintf("stake balance %f is below %f", stake.Balance, minStake))
+	}
+
+	return decideAccess(ctx, decision, stakeStateKey(decision.ActorID))
+}
+
+// newAccessDecision starts a decision about an actor, denying suspended and
+// deactivated actors
+func newAccessDecision(
+	ctx contractapi.TransactionContextInterface,
+	check string,
+	actorID string,
+) (*InteropDecision, error) {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requestedBy, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller ID: %v", err)
+	}
+
+	decision := &InteropDecision{
+		V:           interopVersion,
+		Check:       check,
+		ActorID:     resolveIdentity(ctx, actorID),
+		Reasons:     []InteropDenial{},
+		RequestedBy: requestedBy,
+		AsOf:        now,
+	}
+
+	suspended, err := isBanned(ctx, decision.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	if suspended {
+		decision.deny(denialSuspended, "actor is suspended")
+	}
+	deactivated, err := isDeactivated(ctx, decision.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	if deactivated {
+		decision.deny(denialDeactivated, "actor is offboarding")
+	}
+
+	return decision, nil
+}
+
+// deny records an unmet requirement
+func (d *InteropDecision) deny(code string, message string) {
+	d.Reasons = append(d.Reasons, InteropDenial{Code: code, Message: message})
+}
+
+// decideAccess settles the decision and emits it
+func decideAccess(
+	ctx contractapi.TransactionContextInterface,
+	decision *InteropDecision,
+	keys ...string,
+) (*InteropDecision, error) {
+	decision.Allowed = len(decision.Reasons) == 0
+	emitEvent(ctx, "AccessDecision", decision, keys...)
+	return decision, nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// STATE KEY NAMESPACING
+// ============================================================================
+
+// Stake, reputation and rater/actor pair keys carry the actor's MSP
+// namespace, so that identically named users of different organizations
+// (identified as "<mspid>/<name>" under the msp identity mode) can never
+// address each other's records. Unqualified IDs keep the original key
+// layout. Records written before namespacing are still read through the
+// legacy key until they are next written.
+
+// actorNamespace returns the MSP qualifier of an actor ID, or "" if the ID
+// is unqualified
+func actorNamespace(actorID string) string {
+	if i := strings.Index(actorID, "/"); i > 0 {
+		return actorID[:i]
+	}
+	return ""
+}
+
+// namespacedKey builds "<prefix>:<namespace>:<parts...>", omitting the
+// namespace for unqualified IDs
+func namespacedKey(prefix string, actorID string, parts ...string) string {
+	key := prefix + ":"
+	if ns := actorNamespace(actorID); ns != "" {
+		key += ns + ":"
+	}
+	key += actorID
+	for _, part := range parts {
+		key += ":" + part
+	}
+	return key
+}
+
+// stakeKey returns the state key of an actor's stake
+func stakeStateKey(actorID string) string {
+	return namespacedKey("STAKE", actorID)
+}
+
+// reputationKey returns the state key of an actor's reputation in a dimension
+func reputationStateKey(actorID string, dimension string) string {
+	return namespacedKey("REPUTATION", actorID, dimension)
+}
+
+// raterActorKey returns the state key recording that a rater rated an actor
+// in a dimension, namespaced by the rater
+func raterActorStateKey(raterID string, actorID string, dimension string) string {
+	return namespacedKey("RATER_ACTOR", raterID, actorID, dimension)
+}
+
+// legacyStakeKey returns the pre-namespacing stake key
+func legacyStakeKey(actorID string) string {
+	return fmt.Sprintf("STAKE:%s", actorID)
+}
+
+// legacyReputationKey returns the pre-namespacing reputation key
+func legacyReputationKey(actorID string, dimension string) string {
+	return fmt.Sprintf("REPUTATION:%s:%s", actorID, dimension)
+}
+
+// getStateWithLegacy reads key, falling back to legacyKey if key is unset
+func getStateWithLegacy(
+	ctx contractapi.TransactionContextInterface,
+	key string,
+	legacyKey string,
+) ([]byte, error) {
+	value, err := readState(ctx, key)
+	if err != nil || value != nil || key == legacyKey {
+		return value, err
+	}
+	return readState(ctx, legacyKey)
+}
+
+// ============================================================================
+// KEY CONSTRUCTION
+// ============================================================================
+
+// Every state and private data key is built below, so that each record type
+// owns one prefix and no two accessors can address the same key. Caller
+// supplied record IDs are checked against their type's prefix before use.
+
+// Singleton keys
+const (
+	configKey      = "SYSTEM_CONFIG"
+	treasuryKey    = "TREASURY"
+	fraudScanKey   = "FRAUD_SCAN"
+	archiveScanKey = "ARCHIVE_SCAN"
+)
+
+// Object types of composite keys that hold records rather than index
+// entries
+const (
+	trustedCAObjectType         = "TRUSTED_CA"          // mspId~fingerprint, holding the CA certificate PEM
+	trustedCAProposalObjectType = "TRUSTED_CA_PROPOSAL" // mspId~fingerprint
+)
+
+// Prefixes of record IDs that callers pass back in
+const (
+	ratingIDPrefix          = "RATING:"
+	disputeIDPrefix         = "DISPUTE:"
+	withdrawalIDPrefix      = "TREASURY_WITHDRAWAL:"
+	stakeWithdrawalIDPrefix = "STAKE_WITHDRAWAL:"
+	bondIDPrefix            = "BOND:"
+	fraudSignalIDPrefix     = "FRAUD_SIGNAL:"
+)
+
+// generateRatingID creates unique rating identifier
+func generateRatingID(raterID, actorID, dimension string, timestamp int64) string {
+	data := fmt.Sprintf("%s:%s:%s:%d", raterID, actorID, dimension, timestamp)
+	hash := sha256.Sum256([]byte(data))
+	return fmt.Sprintf("%s%x", ratingIDPrefix, hash[:16])
+}
+
+// generateDisputeID creates unique dispute identifier
+func generateDisputeID(ratingID, initiatorID string, timestamp int64) string {
+	data := fmt.Sprintf("%s:%s:%d", ratingID, initiatorID, timestamp)
+	hash := sha256.Sum256([]byte(data))
+	return fmt.Sprintf("%s%x", disputeIDPrefix, hash[:16])
+}
+
+// newWithdrawalID returns the ID of the treasury withdrawal proposed by txID
+func newWithdrawalID(txID string) string {
+	return withdrawalIDPrefix + txID
+}
+
+// newStakeWithdrawalID returns the ID of the stake withdrawal requested by
+// txID
+func newStakeWithdrawalID(txID string) string {
+	return stakeWithdrawalIDPrefix + txID
+}
+
+// banStateKey returns the key of an actor's ban record
+func banStateKey(actorID string) string {
+	return fmt.Sprintf("BAN:%s", actorID)
+}
+
+// offboardingStateKey returns the key of an actor's offboarding record
+func offboardingStateKey(actorID string) string {
+	return fmt.Sprintf("OFFBOARDING:%s", actorID)
+}
+
+// membershipStateKey returns the key of an actor's membership record
+func membershipStateKey(actorID string) string {
+	return fmt.Sprintf("MEMBERSHIP:%s", actorID)
+}
+
+// profileStateKey returns the key of an actor's public profile
+func profileStateKey(actorID string) string {
+	return fmt.Sprintf("PROFILE:%s", actorID)
+}
+
+// directoryListingKey returns the key of an actor's directory listing
+func directoryListingKey(actorID string) string {
+	return fmt.Sprintf("DIRECTORY:%s", actorID)
+}
+
+// profileHistoryPrefix returns the key prefix of an actor's profile versions
+func profileHistoryPrefix(actorID string) string {
+	return fmt.Sprintf("PROFILE_HISTORY:%s:", actorID)
+}
+
+// profileHistoryKey returns the key of one version of an actor's profile
+func profileHistoryKey(actorID string, version int) string {
+	return fmt.Sprintf("%s%010d", profileHistoryPrefix(actorID), version)
+}
+
+// activityStateKey returns the key of an actor's activity summary
+func activityStateKey(actorID string) string {
+	return fmt.Sprintf("ACTIVITY:%s", actorID)
+}
+
+// dimensionStatsKey returns the key of a dimension's statistics
+func dimensionStatsKey(dimension string) string {
+	return fmt.Sprintf("DIMENSION_STATS:%s", dimension)
+}
+
+// metricsKey returns the key of one day's usage metrics
+func metricsKey(day string) string {
+	return fmt.Sprintf("METRICS:%s", day)
+}
+
+// credentialIssuerKey returns the key of a registered credential issuer
+func credentialIssuerKey(issuerID string) string {
+	return fmt.Sprintf("CREDENTIAL_ISSUER:%s", issuerID)
+}
+
+// attestationPrefix returns the key prefix of a subject's attestations
+func attestationPrefix(subjectID string) string {
+	return fmt.Sprintf("ATTESTATION:%s:", subjectID)
+}
+
+// attestationKey returns the key of a credential's attestation, derived from
+// its issuer and credential ID
+func attestationKey(subjectID string, issuer string, credentialID string) string {
+	idHash := sha256.Sum256([]byte(issuer + ":" + credentialID))
+	return fmt.Sprintf("%s%x", attestationPrefix(subjectID), idHash[:16])
+}
+
+// monitoringServiceKey returns the key of a registered monitoring service
+func monitoringServiceKey(serviceID string) string {
+	return fmt.Sprintf("MONITORING_SERVICE:%s", serviceID)
+}
+
+// slaMeasurementKey returns the key of a monitoring service's measurements
+// of an order, derived from both
+func slaMeasurementKey(serviceID string, orderID string) string {
+	idHash := sha256.Sum256([]byte(serviceID + ":" + orderID))
+	return fmt.Sprintf("SLA_MEASUREMENT:%x", idHash[:16])
+}
+
+// groupStateKey returns the key of an actor group
+func groupStateKey(groupID string) string {
+	return fmt.Sprintf("GROUP:%s", groupID)
+}
+
+// aliasStateKey returns the key of an alias's link to its primary identity
+func aliasStateKey(aliasID string) string {
+	return fmt.Sprintf("ALIAS:%s", aliasID)
+}
+
+// vouchKeyPrefix is the key prefix of all vouches
+const vouchKeyPrefix = "VOUCH:"
+
+// vouchPrefix returns the key prefix of the vouches for an actor
+func vouchPrefix(actorID string) string {
+	return fmt.Sprintf("%s%s:", vouchKeyPrefix, actorID)
+}
+
+// vouchStateKey returns the key of a voucher's vouch for an actor
+func vouchStateKey(actorID string, dimension string, voucherID string) string {
+	return fmt.Sprintf("%s%s:%s", vouchPrefix(actorID), dimension, voucherID)
+}
+
+// recomputeStateKey returns the key of the cursor of a running
+// recomputation of an actor's reputation in a dimension
+func recomputeStateKey(actorID string, dimension string) string {
+	return namespacedKey("REPUTATION_RECOMPUTE", actorID, dimension)
+}
+
+// ratingArchivePrefix returns the key prefix of an actor's rating archives
+// in a dimension
+func ratingArchivePrefix(actorID string, dimension string) string {
+	return namespacedKey("RATING_ARCHIVE", actorID, dimension) + ":"
+}
+
+// ratingArchiveKey returns the key of the archive of an actor's ratings in a
+// dimension over one period
+func ratingArchiveKey(actorID string, dimension string, period string) string {
+	return ratingArchivePrefix(actorID, dimension) + period
+}
+
+// purgeStateKey returns the key of an actor's personal data purge record
+func purgeStateKey(actorID string) string {
+	return fmt.Sprintf("PURGE:%s", actorID)
+}
+
+// pendingRatingPrefix is the key prefix of the queue of ratings in their
+// challenge window
+const pendingRatingPrefix = "PENDING_RATING:"
+
+// pendingRatingKey returns the queue key of a rating in its challenge
+// window, ordered by the window's end
+func pendingRatingKey(pendingUntil int64, ratingID string) string {
+	return fmt.Sprintf("%s%020d:%s", pendingRatingPrefix, pendingUntil, ratingID)
+}
+
+// treasuryEntryKey returns the ledger entry key of a treasury movement,
+// ordered by time
+func treasuryEntryKey(now int64, txID string, direction string) string {
+	return fmt.Sprintf("TREASURY_ENTRY:%020d:%s:%s", now, txID, direction)
+}
+
+// confidentialValueStateKey returns the private data key of a rating's value
+func confidentialValueStateKey(ratingID string) string {
+	return fmt.Sprintf("CONFIDENTIAL_RATING:%s", ratingID)
+}
+
+// personalProfileKey returns the private data key of a personal profile field
+func personalProfileKey(actorID string, field string) string {
+	return fmt.Sprintf("PERSONAL:%s:profile:%s", actorID, field)
+}
+
+// personalHistoryKey returns the private data key of one profile version's field
+func personalHistoryKey(actorID string, version int, field string) string {
+	return fmt.Sprintf("PERSONAL:%s:history:%010d:%s", actorID, version, field)
+}
+
+// personalRatingKey returns the private data key of a rating's evidence text
+func personalRatingKey(actorID string, ratingID string) string {
+	return fmt.Sprintf("PERSONAL:%s:rating:%s", actorID, ratingID)
+}
+
+// ============================================================================
+// RECORD ID VALIDATION
+// ============================================================================
+
+// recordKind names the record type owning an ID prefix, for messages
+func recordKind(prefix string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSuffix(prefix, ":")), "_", " ")
+}
+
+// validateRecordID checks that a caller-supplied ID addresses a record of the
+// type owning prefix, so that an accessor can never read another type's
+// record. Generated rating, dispute, bond and fraud signal IDs end in 32
+// lowercase hex digits.
+func validateRecordID(id string, prefix string) error {
+	kind := recordKind(prefix)
+	suffix := strings.TrimPrefix(id, prefix)
+	if suffix == id || suffix == "" {
+		return fmt.Errorf("invalid %s ID: %q", kind, id)
+	}
+
+	switch prefix {
+	case ratingIDPrefix, disputeIDPrefix, bondIDPrefix, fraudSignalIDPrefix:
+		if len(suffix) != 32 || strings.Trim(suffix, "0123456789abcdef") != "" {
+			return fmt.Errorf("invalid %s ID: %q", kind, id)
+		}
+	default:
+		if strings.ContainsAny(suffix, ":\x00") {
+			return fmt.Errorf("invalid %s ID: %q", kind, id)
+		}
+	}
+	return nil
+}
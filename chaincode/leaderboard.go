@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// LEADERBOARD
+// ============================================================================
+
+// The leaderboard index holds one LEADERBOARD~dimension~rank~actorId key per
+// reputation record, where rank encodes the undecayed posterior mean so that
+// ascending key order is highest score first. It is updated whenever a
+// reputation record is written.
+const leaderboardIndex = "LEADERBOARD"
+
+// GetTopActors returns a page of actors ranked by score in a dimension,
+// highest first. Ranking uses the score as of each actor's last update;
+// the returned scores and confidence intervals have decay applied.
+func (rc *ReputationContract) GetTopActors(
+	ctx contractapi.TransactionContextInterface,
+	dimension string,
+	n int,
+	bookmark string,
+) (*ActorScorePage, error) {
+	n, err := resolvePageSize(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		leaderboardIndex, []string{dimension}, int32(n), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leaderboard: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	showUnlisted := canSeeUnlisted(ctx)
+
+	stats, err := getDimensionStats(ctx, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]interface{}{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 3 {
+			continue
+		}
+		actorID := parts[2]
+
+		if !showUnlisted {
+			if listed, _ := isListed(ctx, actorID); !listed {
+				continue
+			}
+		}
+
+		rep, err := getOrInitReputation(ctx, actorID, dimension, config)
+		if err != nil {
+			continue
+		}
+
+		effectiveRep := applyDynamicDecayAt(rep, config, now)
+		ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+
+		suspended, _ := isBanned(ctx, actorID)
+
+		results = append(results, map[string]interface{}{
+			"rank":        len(results) + 1,
+			"actorId":     actorID,
+			"dimension":   dimension,
+			"score":       reputationScore(effectiveRep),
+			"ci_lower":    ci[0],
+			"ci_upper":    ci[1],
+			"totalEvents": rep.TotalEvents,
+			"suspended":   suspended,
+		})
+	}
+
+	return &ActorScorePage{
+		Actors:       results,
+		Bookmark:     metadata.Bookmark,
+		FetchedCount: metadata.FetchedRecordsCount,
+		TotalCount:   stats.ActorCount,
+	}, nil
+}
+
+// Ranking statistics accepted by GetRankedActors
+const (
+	rankByMean        = "mean"         // posterior mean
+	rankByWilsonLower = "wilson_lower" // lower bound of the 95% Wilson interval
+	rankByBayesShrunk = "bayes_shrunk" // posterior shrunk toward the dimension mean
+)
+
+// GetRankedActors ranks the actors of a dimension by a confidence-adjusted
+// statistic of their decayed reputation, highest first. method is mean,
+// wilson_lower (penalizes thin evidence) or bayes_shrunk (pulls thin
+// evidence toward the dimension average). At most maxPageSize actors are
+// returned.
+func (rc *ReputationContract) GetRankedActors(
+	ctx contractapi.TransactionContextInterface,
+	dimension string,
+	method string,
+) ([]map[string]interface{}, error) {
+	if method != rankByMean && method != rankByWilsonLower && method != rankByBayesShrunk {
+		return nil, fmt.Errorf("invalid method: must be %s, %s or %s", rankByMean, rankByWilsonLower, rankByBayesShrunk)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
+
+	stats, err := getDimensionStats(ctx, dimension)
+	if err != nil {
+		return nil, err
+	}
+	dimensionMean := config.InitialAlpha / (config.InitialAlpha + config.InitialBeta)
+	if stats.ActorCount > 0 {
+		dimensionMean = stats.ScoreSum.Float() / float64(stats.ActorCount)
+	}
+	priorWeight := config.InitialAlpha + config.InitialBeta
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(reputationByDimensionIndex, []string{dimension})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	showUnlisted := canSeeUnlisted(ctx)
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]interface{}{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			continue
+		}
+		actorID := parts[1]
+
+		if !showUnlisted {
+			if listed, _ := isListed(ctx, actorID); !listed {
+				continue
+			}
+		}
+
+		rep, err := getStoredReputation(ctx, actorID, dimension)
+		if err != nil || rep == nil {
+			continue
+		}
+
+		effectiveRep := applyDynamicDecayAt(rep, config, now)
+		mean := reputationScore(effectiveRep)
+		ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+
+		rankScore := mean
+		switch method {
+		case rankByWilsonLower:
+			rankScore = ci[0]
+		case rankByBayesShrunk:
+			rankScore = (effectiveRep.Alpha.Float() + priorWeight*dimensionMean) /
+				((effectiveRep.Alpha + effectiveRep.Beta).Float() + priorWeight)
+		}
+
+		results = append(results, map[string]interface{}{
+			"actorId":     actorID,
+			"dimension":   dimension,
+			"rankScore":   rankScore,
+			"score":       mean,
+			"ci_lower":    ci[0],
+			"ci_upper":    ci[1],
+			"totalEvents": rep.TotalEvents,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i]["rankScore"].(float64) > results[j]["rankScore"].(float64)
+	})
+	if len(results) > config.MaxPageSize {
+		results = results[:config.MaxPageSize]
+	}
+	for i, result := range results {
+		result["rank"] = i + 1
+	}
+
+	return results, nil
+}
+
+// ============================================================================
+// LEADERBOARD HELPERS
+// ============================================================================
+
+// leaderboardRank encodes a reputation's posterior mean so that higher
+// scores sort first
+func leaderboardRank(rep *Reputation) string {
+	return scoreRank(reputationScore(rep))
+}
+
+// scoreRank encodes a posterior mean so that higher scores sort first
+func scoreRank(score float64) string {
+	return fmt.Sprintf("%010d", int64((1.0-score)*1e9))
+}
+
+// updateLeaderboard moves an actor's leaderboard entry from its previous
+// rank (nil if the record is new) to its current one
+func updateLeaderboard(
+	ctx contractapi.TransactionContextInterface,
+	previous *Reputation,
+	current *Reputation,
+) error {
+	if previous != nil {
+		oldAttributes := []string{previous.Dimension, leaderboardRank(previous), previous.ActorID}
+		if err := delIndexEntry(ctx, leaderboardIndex, oldAttributes); err != nil {
+			return err
+		}
+	}
+	if current != nil {
+		newAttributes := []string{current.Dimension, leaderboardRank(current), current.ActorID}
+		if err := putIndexEntry(ctx, leaderboardIndex, newAttributes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getStoredReputation loads the stored reputation record, returning nil if
+// none exists
+func getStoredReputation(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+) (*Reputation, error) {
+	repJSON, err := getStateWithLegacy(ctx, reputationStateKey(actorID, dimension), legacyReputationKey(actorID, dimension))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reputation: %v", err)
+	}
+	if repJSON == nil {
+		return nil, nil
+	}
+
+	var rep Reputation
+	if err := json.Unmarshal(repJSON, &rep); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reputation: %v", err)
+	}
+
+	return &rep, nil
+}
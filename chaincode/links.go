@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// IDENTITY LINKS
+// ============================================================================
+
+// IdentityLink maps an additional certificate identity onto a canonical actor.
+// A link is proposed by the canonical actor and becomes active once the alias
+// certificate confirms it, so neither side can claim the other unilaterally.
+type IdentityLink struct {
+	AliasID     string `json:"aliasId"`
+	CanonicalID string `json:"canonicalId"`
+	Status      string `json:"status"` // pending, active
+	CreatedAt   int64  `json:"createdAt"`
+	ConfirmedAt int64  `json:"confirmedAt"`
+}
+
+// LinkIdentity proposes that aliasID act as the caller's actor identity
+func (rc *ReputationContract) LinkIdentity(
+	ctx contractapi.TransactionContextInterface,
+	aliasID string,
+) error {
+	canonicalID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get actor ID: %v", err)
+	}
+
+	normalizedAliasID := normalizeIdentity(aliasID)
+	if normalizedAliasID == canonicalID {
+		return fmt.Errorf("cannot link an identity to itself")
+	}
+
+	existing, err := getIdentityLink(ctx, normalizedAliasID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Status == "active" {
+		return fmt.Errorf("identity already linked: %s", normalizedAliasID)
+	}
+
+	// Aliases cannot themselves carry aliases
+	if hasAliases, err := identityHasAliases(ctx, normalizedAliasID); err != nil {
+		return err
+	} else if hasAliases {
+		return fmt.Errorf("identity is canonical for other aliases: %s", normalizedAliasID)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	link := &IdentityLink{
+		AliasID:     normalizedAliasID,
+		CanonicalID: canonicalID,
+		Status:      "pending",
+		CreatedAt:   now,
+	}
+
+	return putIdentityLink(ctx, link, "IdentityLinkProposed")
+}
+
+// ConfirmIdentityLink is called from the alias certificate to accept a link
+// proposed by canonicalID
+func (rc *ReputationContract) ConfirmIdentityLink(
+	ctx contractapi.TransactionContextInterface,
+	canonicalID string,
+) error {
+	aliasID, err := certificateIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get alias ID: %v", err)
+	}
+
+	link, err := getIdentityLink(ctx, aliasID)
+	if err != nil {
+		return err
+	}
+	if link == nil || link.Status != "pending" || link.CanonicalID != normalizeIdentity(canonicalID) {
+		return fmt.Errorf("no pending link from %s to %s", aliasID, normalizeIdentity(canonicalID))
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	link.Status = "active"
+	link.ConfirmedAt = now
+
+	if err := putIdentityLink(ctx, link, "IdentityLinked"); err != nil {
+		return err
+	}
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(aliasOfIndex, []string{link.CanonicalID, link.AliasID})
+	if err != nil {
+		return fmt.Errorf("failed to create alias index key: %v", err)
+	}
+	return ctx.GetStub().PutState(indexKey, []byte{0x00})
+}
+
+// UnlinkIdentity removes a pending or active link. It may be called by the
+// canonical actor, the alias certificate itself, or an admin.
+func (rc *ReputationContract) UnlinkIdentity(
+	ctx contractapi.TransactionContextInterface,
+	aliasID string,
+) error {
+	normalizedAliasID := normalizeIdentity(aliasID)
+
+	link, err := getIdentityLink(ctx, normalizedAliasID)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return fmt.Errorf("identity is not linked: %s", normalizedAliasID)
+	}
+
+	certID, err := certificateIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get caller ID: %v", err)
+	}
+	callerID := canonicalIdentity(ctx, certID)
+	if callerID != link.CanonicalID && certID != link.AliasID && !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: only the linked actor or an admin can unlink")
+	}
+
+	if err := ctx.GetStub().DelState(aliasStateKey(normalizedAliasID)); err != nil {
+		return fmt.Errorf("failed to delete identity link: %v", err)
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(aliasOfIndex, []string{link.CanonicalID, link.AliasID})
+	if err != nil {
+		return fmt.Errorf("failed to create alias index key: %v", err)
+	}
+	if err := ctx.GetStub().DelState(indexKey); err != nil {
+		return fmt.Errorf("failed to delete alias index: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"aliasId":     link.AliasID,
+		"canonicalId": link.CanonicalID,
+	}
+	emitEvent(ctx, "IdentityUnlinked", eventPayload)
+
+	return nil
+}
+
+// GetIdentityAliases lists the active aliases of an actor
+func (rc *ReputationContract) GetIdentityAliases(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) ([]string, error) {
+	canonicalID := resolveIdentity(ctx, actorID)
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(aliasOfIndex, []string{canonicalID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aliases: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	aliases := []string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			continue
+		}
+		aliases = append(aliases, parts[1])
+	}
+
+	return aliases, nil
+}
+
+// ============================================================================
+// LINK HELPERS
+// ============================================================================
+
+// getIdentityLink loads the link for an alias, returning nil if none exists
+func getIdentityLink(ctx contractapi.TransactionContextInterface, aliasID string) (*IdentityLink, error) {
+	linkJSON, err := ctx.GetStub().GetState(aliasStateKey(aliasID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity link: %v", err)
+	}
+	if linkJSON == nil {
+		return nil, nil
+	}
+
+	var link IdentityLink
+	if err := json.Unmarshal(linkJSON, &link); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal identity link: %v", err)
+	}
+
+	return &link, nil
+}
+
+// putIdentityLink stores a link and emits the given event
+func putIdentityLink(ctx contractapi.TransactionContextInterface, link *IdentityLink, eventName string) error {
+	linkJSON, err := marshalCanonical(link)
+	if err != nil {
+		return fmt.Errorf("failed to marshal identity link: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(aliasStateKey(link.AliasID), linkJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store identity link: %v", err)
+	}
+
+	// Emit event
+	emitEvent(ctx, eventName, link)
+
+	return nil
+}
+
+// identityHasAliases reports whether any active alias points at the actor
+func identityHasAliases(ctx contractapi.TransactionContextInterface, canonicalID string) (bool, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(aliasOfIndex, []string{canonicalID})
+	if err != nil {
+		return false, fmt.Errorf("failed to read aliases: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	return resultsIterator.HasNext(), nil
+}
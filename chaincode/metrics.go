@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// OPERATIONAL METRICS
+// ============================================================================
+
+// metricsDayFormat names a UTC day in METRICS keys, so keys sort by date
+const metricsDayFormat = "2006-01-02"
+
+// MetricsDay holds one UTC day's operational counters, updated by the
+// transactions they count and stored as shards METRICS:<day>:<shard> (see
+// aggregateShards)
+type MetricsDay struct {
+	Day                string  `json:"day"`
+	Ratings            int     `json:"ratings"`
+	DisputesOpened     int     `json:"disputesOpened"`
+	DisputesResolved   int     `json:"disputesResolved"`
+	DisputesOverturned int     `json:"disputesOverturned"`
+	SlashedAmount      float64 `json:"slashedAmount"`
+	SettledAmount      float64 `json:"settledAmount"`
+}
+
+// MetricsReport is the GetMetrics response: the days in range that saw
+// activity, oldest first, and their sum
+type MetricsReport struct {
+	From   string       `json:"from"`
+	To     string       `json:"to"`
+	Days   []MetricsDay `json:"days"`
+	Totals MetricsDay   `json:"totals"`
+}
+
+// GetMetrics returns the daily operational counters between two UTC days
+// (YYYY-MM-DD, inclusive). Empty bounds are open, so GetMetrics("", "")
+// reports all-time totals.
+func (rc *ReputationContract) GetMetrics(
+	ctx contractapi.TransactionContextInterface,
+	fromDay string,
+	toDay string,
+) (*MetricsReport, error) {
+	for _, day := range []string{fromDay, toDay} {
+		if day == "" {
+			continue
+		}
+		if _, err := time.Parse(metricsDayFormat, day); err != nil {
+			return nil, fmt.Errorf("invalid day %q: expected YYYY-MM-DD", day)
+		}
+	}
+
+	// "~" sorts after every digit, closing the range past the last day
+	endKey := "METRICS:~"
+	if toDay != "" {
+		endKey = metricsKey(toDay) + "~"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(metricsKey(fromDay), endKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	report := &MetricsReport{From: fromDay, To: toDay, Days: []MetricsDay{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var share MetricsDay
+		if err := json.Unmarshal(queryResponse.Value, &share); err != nil {
+			continue
+		}
+
+		// A day's shards sort together, right after its unsharded key
+		if last := len(report.Days) - 1; last >= 0 && report.Days[last].Day == share.Day {
+			report.Days[last].add(&share)
+		} else {
+			report.Days = append(report.Days, share)
+		}
+		report.Totals.add(&share)
+	}
+
+	return report, nil
+}
+
+// add accumulates another day's or shard's counters
+func (m *MetricsDay) add(share *MetricsDay) {
+	m.Ratings += share.Ratings
+	m.DisputesOpened += share.DisputesOpened
+	m.DisputesResolved += share.DisputesResolved
+	m.DisputesOverturned += share.DisputesOverturned
+	m.SlashedAmount += share.SlashedAmount
+	m.SettledAmount += share.SettledAmount
+}
+
+// updateMetrics applies update to this transaction's shard of the current
+// day's counters. Days are taken from the transaction timestamp, so every
+// endorser updates the same record.
+func updateMetrics(ctx contractapi.TransactionContextInterface, update func(*MetricsDay)) error {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+	day := time.Unix(now, 0).UTC().Format(metricsDayFormat)
+
+	shardKey := aggregateShardKey(metricsKey(day), aggregateShard(ctx))
+
+	metrics := pendingMetricsDay(ctx, day)
+	if metrics == nil {
+		metricsJSON, err := ctx.GetStub().GetState(shardKey)
+		if err != nil {
+			return fmt.Errorf("failed to read metrics: %v", err)
+		}
+
+		metrics = &MetricsDay{Day: day}
+		if metricsJSON != nil {
+			if err := json.Unmarshal(metricsJSON, metrics); err != nil {
+				return fmt.Errorf("failed to unmarshal metrics: %v", err)
+			}
+		}
+	}
+
+	update(metrics)
+
+	metricsJSON, err := marshalCanonical(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics: %v", err)
+	}
+	if err := ctx.GetStub().PutState(shardKey, metricsJSON); err != nil {
+		return fmt.Errorf("failed to store metrics: %v", err)
+	}
+
+	setPendingMetricsDay(ctx, metrics)
+	return nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// OFFBOARDING DATA STRUCTURES
+// ============================================================================
+
+// Offboarding tracks an actor leaving the consortium
+type Offboarding struct {
+	ActorID               string   `json:"actorId"`
+	Status                string   `json:"status"` // deactivated, completed
+	Reason                string   `json:"reason"`
+	RequestedBy           string   `json:"requestedBy"`
+	CancelledDisputes     []string `json:"cancelledDisputes"`
+	DeactivatedAt         int64    `json:"deactivatedAt"`
+	WithdrawalAvailableAt int64    `json:"withdrawalAvailableAt"`
+	WithdrawnAmount       float64  `json:"withdrawnAmount"`
+	CompletedAt           int64    `json:"completedAt"`
+}
+
+// ============================================================================
+// OFFBOARDING FUNCTIONS
+// ============================================================================
+
+// DeactivateActor starts offboarding: the actor can no longer rate or be
+// rated, disputes it opened are withdrawn with their cost refunded, and its
+// stake becomes withdrawable once the dispute window has passed. Either the
+// actor itself or an admin may call it.
+func (rc *ReputationContract) DeactivateActor(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	reason string,
+) error {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	callerID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get caller ID: %v", err)
+	}
+	if callerID != normalizedActorID && !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: only the actor or an admin can deactivate")
+	}
+
+	existing, err := getOffboarding(ctx, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("actor already offboarded: %s", normalizedActorID)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	cancelled, err := withdrawInitiatedDisputes(ctx, normalizedActorID)
+	if err != nil {
+		return err
+	}
+
+	offboarding := &Offboarding{
+		ActorID:               normalizedActorID,
+		Status:                "deactivated",
+		Reason:                reason,
+		RequestedBy:           callerID,
+		CancelledDisputes:     cancelled,
+		DeactivatedAt:         now,
+		WithdrawalAvailableAt: now + config.OffboardingWindow,
+	}
+
+	if err := putOffboarding(ctx, offboarding); err != nil {
+		return err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"actorId":               normalizedActorID,
+		"reason":                reason,
+		"cancelledDisputes":     cancelled,
+		"withdrawalAvailableAt": offboarding.WithdrawalAvailableAt,
+	}
+	emitEvent(ctx, "ActorDeactivated", eventPayload, offboardingStateKey(normalizedActorID))
+
+	return nil
+}
+
+// CompleteOffboarding releases a deactivated actor's stake once the dispute
+// window has passed, no disputes against its ratings remain pending and none
+// of its stake is still locked in unbonding withdrawals or posted bonds
+func (rc *ReputationContract) CompleteOffboarding(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (float64, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	callerID, err := callerIdentity(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get caller ID: %v", err)
+	}
+	if callerID != normalizedActorID && !isAdmin(ctx) {
+		return 0, fmt.Errorf("unauthorized: only the actor or an admin can complete offboarding")
+	}
+
+	offboarding, err := getOffboarding(ctx, normalizedActorID)
+	if err != nil {
+		return 0, err
+	}
+	if offboarding == nil || offboarding.Status != "deactivated" {
+		return 0, fmt.Errorf("actor is not awaiting offboarding: %s", normalizedActorID)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if now < offboarding.WithdrawalAvailableAt {
+		return 0, fmt.Errorf("dispute window open until %d", offboarding.WithdrawalAvailableAt)
+	}
+
+	pending, err := disputesWithStatus(ctx, "pending", func(dispute *Dispute) bool {
+		return dispute.RaterID == normalizedActorID
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(pending) > 0 {
+		return 0, fmt.Errorf("disputes against the actor's ratings are still pending")
+	}
+
+	stake, err := getOrInitStake(ctx, normalizedActorID)
+	if err != nil {
+		return 0, err
+	}
+	if stake.Locked > 0 {
+		return 0, fmt.Errorf("stake of %f is still locked: claim unbonding withdrawals and settle posted bonds first", stake.Locked)
+	}
+
+	withdrawn := stake.Balance
+	stake.Balance = 0
+	stake.UpdatedAt = now
+
+	if err := putStake(ctx, stake); err != nil {
+		return 0, err
+	}
+	emitStakeMovement(ctx, stakeReleasedEvent, stake, withdrawn, "")
+
+	offboarding.Status = "completed"
+	offboarding.WithdrawnAmount = withdrawn
+	offboarding.CompletedAt = now
+
+	if err := putOffboarding(ctx, offboarding); err != nil {
+		return 0, err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"actorId": normalizedActorID,
+		"amount":  withdrawn,
+	}
+	emitEvent(ctx, "ActorOffboarded", eventPayload, offboardingStateKey(normalizedActorID))
+
+	return withdrawn, nil
+}
+
+// GetOffboarding retrieves an actor's offboarding record
+func (rc *ReputationContract) GetOffboarding(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*Offboarding, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	offboarding, err := getOffboarding(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+	if offboarding == nil {
+		return nil, fmt.Errorf("no offboarding record for %s", normalizedActorID)
+	}
+
+	return offboarding, nil
+}
+
+// ============================================================================
+// OFFBOARDING HELPERS
+// ============================================================================
+
+// withdrawInitiatedDisputes closes the actor's own pending disputes,
+// refunding their locked cost or forfeiting the bonds they claimed
+func withdrawInitiatedDisputes(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) ([]string, error) {
+	disputes, err := disputesWithStatus(ctx, "pending", func(dispute *Dispute) bool {
+		return dispute.InitiatorID == actorID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled := []string{}
+	if len(disputes) == 0 {
+		return cancelled, nil
+	}
+
+	stake, err := getOrInitStake(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dispute := range disputes {
+		dispute.Status = "withdrawn"
+		dispute.ResolvedAt = now
+
+		if err := putDispute(ctx, &dispute, "pending"); err != nil {
+			return nil, err
+		}
+
+		// Abandoning a bond claim lets the rating stand
+		if dispute.BondID != "" {
+			if err := settleClaimedBond(ctx, &dispute, stake, true); err != nil {
+				return nil, err
+			}
+		} else {
+			stake.Locked -= dispute.LockedCost
+			stake.Balance += dispute.LockedCost
+			emitStakeMovement(ctx, stakeUnlockedEvent, stake, dispute.LockedCost, dispute.DisputeID)
+		}
+		cancelled = append(cancelled, dispute.DisputeID)
+	}
+	stake.UpdatedAt = now
+
+	if err := putStake(ctx, stake); err != nil {
+		return nil, err
+	}
+
+	return cancelled, nil
+}
+
+// isDeactivated reports whether an actor has started or completed offboarding
+func isDeactivated(ctx contractapi.TransactionContextInterface, actorID string) (bool, error) {
+	offboarding, err := getOffboarding(ctx, actorID)
+	if err != nil {
+		return false, err
+	}
+	return offboarding != nil, nil
+}
+
+// getOffboarding loads an actor's offboarding record, returning nil if none exists
+func getOffboarding(ctx contractapi.TransactionContextInterface, actorID string) (*Offboarding, error) {
+	offboardingJSON, err := readState(ctx, offboardingStateKey(actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offboarding: %v", err)
+	}
+	if offboardingJSON == nil {
+		return nil, nil
+	}
+
+	var offboarding Offboarding
+	if err := json.Unmarshal(offboardingJSON, &offboarding); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal offboarding: %v", err)
+	}
+
+	return &offboarding, nil
+}
+
+// putOffboarding stores an actor's offboarding record
+func putOffboarding(ctx contractapi.TransactionContextInterface, offboarding *Offboarding) error {
+	offboardingJSON, err := marshalCanonical(offboarding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offboarding: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(offboardingStateKey(offboarding.ActorID), offboardingJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store offboarding: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// ONBOARDING DATA STRUCTURES
+// ============================================================================
+
+// A new supplier applies for membership and an admin reviews the
+// application. An approved applicant starts on probation: its ratings of
+// others carry at most probationMaxRaterWeight and it is left out of public
+// listing queries, as are pending and rejected applicants. Each clean event
+// - a rating received with a value of at least cleanRatingValue - counts
+// towards graduation, and after probationEvents of them the supplier
+// graduates to full membership automatically. Actors that never applied are
+// unaffected.
+
+// Membership statuses
+const (
+	membershipApplied   = "applied"
+	membershipProbation = "probation"
+	membershipMember    = "member"
+	membershipRejected  = "rejected"
+)
+
+// cleanRatingValue is the lowest rating value that counts as a clean event
+const cleanRatingValue = 0.5
+
+// membershipByStatusIndex files memberships by status, oldest application
+// first: status~appliedAt~actorId
+const membershipByStatusIndex = "MEMBERSHIP_BY_STATUS"
+
+// Membership tracks an actor's application and probation
+type Membership struct {
+	ActorID     string `json:"actorId"`
+	Status      string `json:"status"`      // applied, probation, member, rejected
+	Application string `json:"application"` // the applicant's statement, such as registration details and references
+	AppliedAt   int64  `json:"appliedAt"`
+	ReviewedBy  string `json:"reviewedBy"`
+	ReviewNotes string `json:"reviewNotes"`
+	ReviewedAt  int64  `json:"reviewedAt"`
+	CleanEvents int    `json:"cleanEvents"` // clean events received on probation
+	GraduatedAt int64  `json:"graduatedAt"`
+}
+
+// ============================================================================
+// ONBOARDING FUNCTIONS
+// ============================================================================
+
+// ApplyForMembership files the caller's membership application. A rejected
+// applicant may apply again.
+func (rc *ReputationContract) ApplyForMembership(
+	ctx contractapi.TransactionContextInterface,
+	application string,
+) error {
+	normalizedID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get actor ID: %v", err)
+	}
+
+	banned, err := isBanned(ctx, normalizedID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return fmt.Errorf("actor is banned: %s", normalizedID)
+	}
+	deactivated, err := isDeactivated(ctx, normalizedID)
+	if err != nil {
+		return err
+	}
+	if deactivated {
+		return fmt.Errorf("actor is deactivated: %s", normalizedID)
+	}
+
+	existing, err := getMembership(ctx, normalizedID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Status != membershipRejected {
+		return fmt.Errorf("membership already %s: %s", existing.Status, normalizedID)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	membership := &Membership{
+		ActorID:     normalizedID,
+		Status:      membershipApplied,
+		Application: application,
+		AppliedAt:   now,
+	}
+	if err := putMembership(ctx, membership, existing); err != nil {
+		return err
+	}
+
+	emitEvent(ctx, "MembershipApplied", map[string]interface{}{
+		"actorId":     normalizedID,
+		"application": application,
+	}, membershipStateKey(normalizedID))
+
+	return nil
+}
+
+// ReviewApplication approves or rejects a pending membership application.
+// Approval starts the applicant's probation.
+func (gc *GovernanceContract) ReviewApplication(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	decision string,
+	notes string,
+) error {
+	if decision != "approve" && decision != "reject" {
+		return fmt.Errorf("decision must be 'approve' or 'reject'")
+	}
+
+	normalizedAdminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	previous, err := getMembership(ctx, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	if previous == nil || previous.Status != membershipApplied {
+		return fmt.Errorf("no pending application: %s", normalizedActorID)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	membership := *previous
+	membership.Status = membershipRejected
+	if decision == "approve" {
+		membership.Status = membershipProbation
+	}
+	membership.ReviewedBy = normalizedAdminID
+	membership.ReviewNotes = notes
+	membership.ReviewedAt = now
+	if err := putMembership(ctx, &membership, previous); err != nil {
+		return err
+	}
+
+	emitEvent(ctx, "ApplicationReviewed", map[string]interface{}{
+		"actorId":    normalizedActorID,
+		"decision":   decision,
+		"status":     membership.Status,
+		"reviewerId": normalizedAdminID,
+		"notes":      notes,
+	}, membershipStateKey(normalizedActorID))
+
+	return nil
+}
+
+// GetMembership retrieves an actor's membership record
+func (rc *ReputationContract) GetMembership(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*Membership, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	membership, err := getMembership(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, fmt.Errorf("no membership record: %s", normalizedActorID)
+	}
+	return membership, nil
+}
+
+// GetMembershipsByStatus lists the memberships with a status, oldest
+// application first; "applied" is the review queue
+func (gc *GovernanceContract) GetMembershipsByStatus(
+	ctx contractapi.TransactionContextInterface,
+	status string,
+) ([]Membership, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(membershipByStatusIndex, []string{status})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", membershipByStatusIndex, err)
+	}
+	defer resultsIterator.Close()
+
+	memberships := []Membership{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 3 {
+			continue
+		}
+		membership, err := getMembership(ctx, parts[2])
+		if err != nil || membership == nil || membership.Status != status {
+			continue
+		}
+		memberships = append(memberships, *membership)
+	}
+
+	return memberships, nil
+}
+
+// ============================================================================
+// PROBATION HELPERS
+// ============================================================================
+
+// recordProbationEvent counts a clean rating towards the rated actor's
+// probation, graduating it once it has probationEvents of them
+func recordProbationEvent(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	rating *Rating,
+	value float64,
+) error {
+	if value < cleanRatingValue {
+		return nil
+	}
+
+	previous, err := getMembership(ctx, rating.ActorID)
+	if err != nil || previous == nil || previous.Status != membershipProbation {
+		return err
+	}
+
+	membership := *previous
+	membership.CleanEvents++
+	graduated := membership.CleanEvents >= config.ProbationEvents
+	if graduated {
+		now, err := txUnixTime(ctx)
+		if err != nil {
+			return err
+		}
+		membership.Status = membershipMember
+		membership.GraduatedAt = now
+	}
+	if err := putMembership(ctx, &membership, previous); err != nil {
+		return err
+	}
+
+	if graduated {
+		emitEvent(ctx, "MemberGraduated", map[string]interface{}{
+			"actorId":     membership.ActorID,
+			"cleanEvents": membership.CleanEvents,
+		}, membershipStateKey(membership.ActorID))
+	}
+	return nil
+}
+
+// isProbationary reports whether an actor applied for membership and has not
+// graduated
+func isProbationary(ctx contractapi.TransactionContextInterface, actorID string) (bool, error) {
+	membership, err := getMembership(ctx, actorID)
+	if err != nil {
+		return false, err
+	}
+	return membership != nil && membership.Status != membershipMember, nil
+}
+
+// getMembership loads an actor's membership record, returning nil if none exists
+func getMembership(ctx contractapi.TransactionContextInterface, actorID string) (*Membership, error) {
+	if pending := pendingMembership(ctx, actorID); pending != nil {
+		membership := *pending
+		return &membership, nil
+	}
+
+	membershipJSON, err := readState(ctx, membershipStateKey(actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read membership: %v", err)
+	}
+	if membershipJSON == nil {
+		return nil, nil
+	}
+
+	var membership Membership
+	if err := json.Unmarshal(membershipJSON, &membership); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal membership: %v", err)
+	}
+
+	return &membership, nil
+}
+
+// putMembership stores a membership record and refiles it under its status,
+// removing the entry of previous, its stored version if any
+func putMembership(ctx contractapi.TransactionContextInterface, membership *Membership, previous *Membership) error {
+	membershipJSON, err := marshalCanonical(membership)
+	if err != nil {
+		return fmt.Errorf("failed to marshal membership: %v", err)
+	}
+	if err := ctx.GetStub().PutState(membershipStateKey(membership.ActorID), membershipJSON); err != nil {
+		return fmt.Errorf("failed to store membership: %v", err)
+	}
+	setPendingMembership(ctx, membership)
+
+	if previous != nil {
+		if previous.Status == membership.Status && previous.AppliedAt == membership.AppliedAt {
+			return nil
+		}
+		if err := delIndexEntry(ctx, membershipByStatusIndex, membershipStatusAttributes(previous)); err != nil {
+			return err
+		}
+	}
+	return putIndexEntry(ctx, membershipByStatusIndex, membershipStatusAttributes(membership))
+}
+
+// membershipStatusAttributes returns a membership's membershipByStatusIndex
+// key attributes
+func membershipStatusAttributes(membership *Membership) []string {
+	return []string{membership.Status, fmt.Sprintf("%020d", membership.AppliedAt), membership.ActorID}
+}
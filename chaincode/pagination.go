@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// PAGINATION
+// ============================================================================
+
+// RatingPage is one page of a rating query. FetchedCount counts the index
+// entries read and TotalCount every rating under the query's key, both
+// before value and weight filters.
+type RatingPage struct {
+	Ratings      []Rating `json:"ratings"`
+	Bookmark     string   `json:"bookmark"` // pass to the next call to continue
+	FetchedCount int32    `json:"fetchedCount"`
+	TotalCount   int      `json:"totalCount"`
+}
+
+// DisputePage is one page of a dispute query
+type DisputePage struct {
+	Disputes     []Dispute `json:"disputes"`
+	Bookmark     string    `json:"bookmark"`
+	FetchedCount int32     `json:"fetchedCount"`
+	TotalCount   int       `json:"totalCount"`
+}
+
+// ActorScorePage is one page of an actor score query. FetchedCount counts
+// the records read, which can exceed len(Actors) when results are filtered;
+// TotalCount likewise counts every actor rated in the dimension, before
+// score and visibility filters.
+type ActorScorePage struct {
+	Actors       []map[string]interface{} `json:"actors"`
+	Bookmark     string                   `json:"bookmark"`
+	FetchedCount int32                    `json:"fetchedCount"`
+	TotalCount   int                      `json:"totalCount"`
+}
+
+// ReputationPage is one page of raw reputation records
+type ReputationPage struct {
+	Reputations  []Reputation `json:"reputations"`
+	Bookmark     string       `json:"bookmark"`
+	FetchedCount int32        `json:"fetchedCount"`
+	TotalCount   int          `json:"totalCount"`
+}
+
+// resolvePageSize checks a caller-supplied page size against the configured
+// maximum. Zero selects the configured default.
+func resolvePageSize(ctx contractapi.TransactionContextInterface, pageSize int) (int, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if pageSize == 0 {
+		return config.DefaultPageSize, nil
+	}
+	if pageSize < 0 || pageSize > config.MaxPageSize {
+		return 0, fmt.Errorf("pageSize must be between 1 and %d, or 0 for the default of %d", config.MaxPageSize, config.DefaultPageSize)
+	}
+
+	return pageSize, nil
+}
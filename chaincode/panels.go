@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// ARBITRATION PANELS
+// ============================================================================
+
+// With SystemConfig.ArbitrationPanelSize set, no arbitrator decides a
+// dispute alone. An admin assigns each pending dispute a panel of that many
+// listed arbitrators who are not parties to it; each member casts one
+// verdict with CastVerdict, and the dispute resolves as soon as one verdict
+// has a majority of the panel. The votes are kept on the dispute, and its
+// arbitratorId is the member whose vote decided it. A panel can be replaced
+// until its first vote.
+
+// PanelVerdict is one panel member's vote on a dispute
+type PanelVerdict struct {
+	ArbitratorID string `json:"arbitratorId"`
+	Verdict      string `json:"verdict"` // upheld, overturned
+	Notes        string `json:"notes"`
+	CastAt       int64  `json:"castAt"`
+}
+
+// AssignArbitrationPanel assigns a pending dispute its panel, given as a JSON
+// array of arbitrator IDs. Requires the admin role.
+func (dc *DisputeContract) AssignArbitrationPanel(
+	ctx contractapi.TransactionContextInterface,
+	disputeID string,
+	arbitratorIDsJSON string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	var arbitratorIDs []string
+	if err := json.Unmarshal([]byte(arbitratorIDsJSON), &arbitratorIDs); err != nil {
+		return fmt.Errorf("invalid arbitrator IDs JSON: %v", err)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config.ArbitrationPanelSize == 0 {
+		return fmt.Errorf("arbitration panels are disabled: arbitrationPanelSize is 0")
+	}
+	if len(arbitratorIDs) != config.ArbitrationPanelSize {
+		return fmt.Errorf("panel must have %d arbitrators, got %d", config.ArbitrationPanelSize, len(arbitratorIDs))
+	}
+
+	dispute, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return err
+	}
+	if dispute == nil {
+		return fmt.Errorf("dispute not found: %s", disputeID)
+	}
+	if dispute.Status != "pending" {
+		return fmt.Errorf("dispute already resolved")
+	}
+	if len(dispute.Votes) > 0 {
+		return fmt.Errorf("panel of dispute %s has started voting", disputeID)
+	}
+
+	panel := make([]string, len(arbitratorIDs))
+	assigned := map[string]bool{}
+	for i, arbitratorID := range arbitratorIDs {
+		normalizedArbitratorID := resolveIdentity(ctx, arbitratorID)
+		if assigned[normalizedArbitratorID] {
+			return fmt.Errorf("arbitrator %s is listed twice", normalizedArbitratorID)
+		}
+		if !roleGranted(ctx, arbitratorListKey, arbitratorExpiryKey, normalizedArbitratorID) {
+			return fmt.Errorf("not a listed arbitrator: %s", normalizedArbitratorID)
+		}
+		if arbitratorConflicted(dispute, normalizedArbitratorID) {
+			return fmt.Errorf("conflict of interest: arbitrator %s is a party to dispute %s", normalizedArbitratorID, disputeID)
+		}
+		assigned[normalizedArbitratorID] = true
+		panel[i] = normalizedArbitratorID
+	}
+
+	dispute.Panel = panel
+	if err := putDispute(ctx, dispute, dispute.Status); err != nil {
+		return err
+	}
+
+	eventPayload := map[string]interface{}{
+		"disputeId": dispute.DisputeID,
+		"panel":     panel,
+	}
+	emitEvent(ctx, "ArbitrationPanelAssigned", eventPayload, dispute.DisputeID)
+
+	return nil
+}
+
+// CastVerdict records the caller's vote on a dispute whose panel it sits on,
+// resolving the dispute once a verdict has a majority. It returns the
+// dispute as updated.
+func (dc *DisputeContract) CastVerdict(
+	ctx contractapi.TransactionContextInterface,
+	disputeID string,
+	verdict string,
+	notes string,
+) (*Dispute, error) {
+	if !isArbitrator(ctx) {
+		return nil, fmt.Errorf("unauthorized: arbitrator role required")
+	}
+
+	dispute, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if dispute == nil {
+		return nil, fmt.Errorf("dispute not found: %s", disputeID)
+	}
+	if dispute.Status != "pending" {
+		return nil, fmt.Errorf("dispute already resolved")
+	}
+	correlateEvents(ctx, dispute.DisputeID, dispute.RatingID)
+
+	normalizedArbitratorID, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get arbitrator ID: %v", err)
+	}
+	if !onPanel(dispute, normalizedArbitratorID) {
+		return nil, fmt.Errorf("unauthorized: %s is not on the panel of dispute %s", normalizedArbitratorID, disputeID)
+	}
+	for _, vote := range dispute.Votes {
+		if vote.ArbitratorID == normalizedArbitratorID {
+			return nil, fmt.Errorf("arbitrator %s already voted on dispute %s", normalizedArbitratorID, disputeID)
+		}
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dispute.Votes = append(dispute.Votes, PanelVerdict{
+		ArbitratorID: normalizedArbitratorID,
+		Verdict:      verdict,
+		Notes:        notes,
+		CastAt:       now,
+	})
+
+	votes := 0
+	for _, vote := range dispute.Votes {
+		if vote.Verdict == verdict {
+			votes++
+		}
+	}
+
+	eventPayload := map[string]interface{}{
+		"disputeId":    dispute.DisputeID,
+		"arbitratorId": normalizedArbitratorID,
+		"verdict":      verdict,
+		"votes":        votes,
+		"panelSize":    len(dispute.Panel),
+	}
+	emitEvent(ctx, "VerdictCast", eventPayload)
+
+	if votes > len(dispute.Panel)/2 {
+		if err := resolveDispute(ctx, dispute, verdict, normalizedArbitratorID, notes); err != nil {
+			return nil, err
+		}
+		return dispute, nil
+	}
+
+	if err := putDispute(ctx, dispute, dispute.Status); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// onPanel reports whether an arbitrator sits on a dispute's panel
+func onPanel(dispute *Dispute, arbitratorID string) bool {
+	for _, member := range dispute.Panel {
+		if member == arbitratorID {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitingVerdict reports whether an arbitrator can act on a pending
+// dispute: decide it alone, or vote on it as a panel member who has not
+// voted yet
+func awaitingVerdict(dispute *Dispute, arbitratorID string, panelSize int) bool {
+	if arbitratorConflicted(dispute, arbitratorID) {
+		return false
+	}
+	if panelSize == 0 {
+		return true
+	}
+	if !onPanel(dispute, arbitratorID) {
+		return false
+	}
+	for _, vote := range dispute.Votes {
+		if vote.ArbitratorID == arbitratorID {
+			return false
+		}
+	}
+	return true
+}
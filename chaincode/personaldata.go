@@ -0,0 +1,391 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// PERSONAL DATA PURGE
+// ============================================================================
+
+// Personal data is purged in two steps. RequestPersonalDataPurge moves the
+// actor's personal profile fields and the evidence text of ratings by or
+// about the actor into personalDataCollection, leaving salted-hash
+// tombstones in world state. The salts derive from a secret the caller
+// passes in the transient map, so they are only ever recorded in the
+// private copies. PurgePersonalData then purges the private copies, after
+// which the tombstones can no longer be linked to the data. Rating values,
+// weights and reputation parameters are left untouched.
+
+// personalDataCollection is the private data collection holding personal
+// data awaiting purge (see collections_config.json)
+const personalDataCollection = "personalDataCollection"
+
+// tombstonePrefix marks a field whose value was moved out of world state
+const tombstonePrefix = "purged:"
+
+// purgeSecretKey is the transient field holding the secret the tombstone
+// salts derive from. It is at least minConfidentialSaltLength characters,
+// like a confidential rating's salt.
+const purgeSecretKey = "salt"
+
+// PurgeRequest tracks an actor's personal data purge
+type PurgeRequest struct {
+	ActorID     string   `json:"actorId"`
+	Status      string   `json:"status"` // moved, purged
+	RequestedBy string   `json:"requestedBy"`
+	RequestedAt int64    `json:"requestedAt"`
+	PurgedBy    string   `json:"purgedBy"`
+	PurgedAt    int64    `json:"purgedAt"`
+	PrivateKeys []string `json:"privateKeys"`
+}
+
+// personalRecord is a private copy of a value replaced by a tombstone
+type personalRecord struct {
+	Salt  string `json:"salt"`
+	Value string `json:"value"`
+}
+
+// personalField is one personal profile field, named as in the profile JSON
+type personalField struct {
+	name  string
+	value *string
+}
+
+// personalFields lists a profile's personal fields in a fixed order, so the
+// keys a purge records are the same on every endorser
+func personalFields(profile *ActorProfile) []personalField {
+	return []personalField{
+		{"displayName", &profile.DisplayName},
+		{"organization", &profile.Organization},
+		{"website", &profile.Website},
+	}
+}
+
+// RequestPersonalDataPurge moves an actor's personal data into the private
+// collection and tombstones it in world state. It may be called by the actor
+// or an admin.
+func (rc *ReputationContract) RequestPersonalDataPurge(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) error {
+	callerID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get caller ID: %v", err)
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+	if callerID != normalizedActorID && !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: only the actor or an admin may request a purge")
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to read transient data: %v", err)
+	}
+	secret := transient[purgeSecretKey]
+	if len(secret) < minConfidentialSaltLength {
+		return fmt.Errorf("a secret must be passed in the transient map under %q and be at least %d characters", purgeSecretKey, minConfidentialSaltLength)
+	}
+
+	purge, err := getPurgeRequest(ctx, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	if purge == nil {
+		purge = &PurgeRequest{ActorID: normalizedActorID}
+	}
+	purge.Status = "moved"
+	purge.RequestedBy = callerID
+	purge.RequestedAt = now
+
+	// Profile fields
+	profile, err := getOrInitProfile(ctx, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	var changed []string
+	for _, field := range personalFields(profile) {
+		key := personalProfileKey(normalizedActorID, field.name)
+		moved, err := tombstoneValue(ctx, secret, key, field.value)
+		if err != nil {
+			return err
+		}
+		if moved {
+			changed = append(changed, field.name)
+			purge.PrivateKeys = append(purge.PrivateKeys, key)
+		}
+	}
+	if len(changed) > 0 || !profile.Purged {
+		profile.Purged = true
+		if err := putProfile(ctx, profile, callerID, append(changed, "purged"), "ProfileUpdated"); err != nil {
+			return err
+		}
+	}
+
+	// Earlier profile versions carry the same fields
+	keys, err := tombstoneProfileHistory(ctx, secret, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	purge.PrivateKeys = append(purge.PrivateKeys, keys...)
+
+	// Rating evidence
+	keys, err = tombstoneRatingEvidence(ctx, secret, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	purge.PrivateKeys = append(purge.PrivateKeys, keys...)
+
+	if err := putPurgeRequest(ctx, purge); err != nil {
+		return err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"actorId":     normalizedActorID,
+		"requestedBy": callerID,
+		"records":     len(purge.PrivateKeys),
+	}
+	emitEvent(ctx, "PersonalDataMoved", eventPayload)
+
+	return nil
+}
+
+// PurgePersonalData purges the private copies recorded by a purge request
+func (rc *ReputationContract) PurgePersonalData(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	adminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	purge, err := getPurgeRequest(ctx, normalizedActorID)
+	if err != nil {
+		return err
+	}
+	if purge == nil || purge.Status != "moved" {
+		return fmt.Errorf("no pending purge request for %s", normalizedActorID)
+	}
+
+	for _, key := range purge.PrivateKeys {
+		if err := ctx.GetStub().PurgePrivateData(personalDataCollection, key); err != nil {
+			return fmt.Errorf("failed to purge %s: %v", key, err)
+		}
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	purge.Status = "purged"
+	purge.PurgedBy = adminID
+	purge.PurgedAt = now
+	purge.PrivateKeys = []string{}
+
+	if err := putPurgeRequest(ctx, purge); err != nil {
+		return err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"actorId":  normalizedActorID,
+		"purgedBy": adminID,
+	}
+	emitEvent(ctx, "PersonalDataPurged", eventPayload)
+
+	return nil
+}
+
+// GetPurgeRequest retrieves an actor's purge request
+func (rc *ReputationContract) GetPurgeRequest(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*PurgeRequest, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	purge, err := getPurgeRequest(ctx, normalizedActorID)
+	if err != nil {
+		return nil, err
+	}
+	if purge == nil {
+		return nil, fmt.Errorf("no purge request for %s", normalizedActorID)
+	}
+
+	return purge, nil
+}
+
+// ============================================================================
+// PURGE HELPERS
+// ============================================================================
+
+// tombstoneValue moves a non-empty, not yet tombstoned value to the private
+// collection under key and replaces it with a salted hash. The salt is the
+// HMAC of key under the caller's secret and is only kept privately, so the
+// tombstone is unlinkable once purged.
+func tombstoneValue(ctx contractapi.TransactionContextInterface, secret []byte, key string, value *string) (bool, error) {
+	if *value == "" || strings.HasPrefix(*value, tombstonePrefix) {
+		return false, nil
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(key))
+	record := personalRecord{
+		Salt:  hex.EncodeToString(mac.Sum(nil)),
+		Value: *value,
+	}
+
+	recordJSON, err := marshalCanonical(record)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal personal record: %v", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(personalDataCollection, key, recordJSON); err != nil {
+		return false, fmt.Errorf("failed to store personal record: %v", err)
+	}
+
+	hash := sha256.Sum256([]byte(record.Salt + record.Value))
+	*value = tombstonePrefix + hex.EncodeToString(hash[:])
+
+	return true, nil
+}
+
+// tombstoneProfileHistory tombstones the personal fields of every stored
+// profile version
+func tombstoneProfileHistory(ctx contractapi.TransactionContextInterface, secret []byte, actorID string) ([]string, error) {
+	prefix := profileHistoryPrefix(actorID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var keys []string
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var change ProfileChange
+		if err := json.Unmarshal(queryResponse.Value, &change); err != nil || change.Profile == nil {
+			continue
+		}
+
+		updated := false
+		for _, field := range personalFields(change.Profile) {
+			key := personalHistoryKey(actorID, change.Version, field.name)
+			moved, err := tombstoneValue(ctx, secret, key, field.value)
+			if err != nil {
+				return nil, err
+			}
+			if moved {
+				updated = true
+				keys = append(keys, key)
+			}
+		}
+		if !updated {
+			continue
+		}
+
+		changeJSON, err := marshalCanonical(change)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal profile change: %v", err)
+		}
+		if err := ctx.GetStub().PutState(queryResponse.Key, changeJSON); err != nil {
+			return nil, fmt.Errorf("failed to store profile change: %v", err)
+		}
+	}
+
+	return keys, nil
+}
+
+// tombstoneRatingEvidence tombstones the evidence text of ratings submitted
+// by or about an actor
+func tombstoneRatingEvidence(ctx contractapi.TransactionContextInterface, secret []byte, actorID string) ([]string, error) {
+	var keys []string
+	tombstone := func(rating *Rating) (bool, error) {
+		key := personalRatingKey(actorID, rating.RatingID)
+		evidence := rating.Evidence
+		moved, err := tombstoneValue(ctx, secret, key, &rating.Evidence)
+		if err != nil || !moved {
+			return err == nil, err
+		}
+		keys = append(keys, key)
+
+		if err := unindexRatingEvidence(ctx, rating, evidence); err != nil {
+			return false, err
+		}
+
+		ratingJSON, err := marshalCanonical(rating)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal rating: %v", err)
+		}
+		if err := ctx.GetStub().PutState(rating.RatingID, ratingJSON); err != nil {
+			return false, fmt.Errorf("failed to store rating: %v", err)
+		}
+		return true, nil
+	}
+
+	if err := forEachIndexedRating(ctx, ratingByRaterIndex, []string{actorID}, tombstone); err != nil {
+		return nil, err
+	}
+	if err := forEachIndexedRating(ctx, ratingByActorIndex, []string{actorID}, tombstone); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// getPurgeRequest loads an actor's purge request, returning nil if none exists
+func getPurgeRequest(ctx contractapi.TransactionContextInterface, actorID string) (*PurgeRequest, error) {
+	purgeJSON, err := ctx.GetStub().GetState(purgeStateKey(actorID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read purge request: %v", err)
+	}
+	if purgeJSON == nil {
+		return nil, nil
+	}
+
+	var purge PurgeRequest
+	if err := json.Unmarshal(purgeJSON, &purge); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal purge request: %v", err)
+	}
+
+	return &purge, nil
+}
+
+// putPurgeRequest stores an actor's purge request
+func putPurgeRequest(ctx contractapi.TransactionContextInterface, purge *PurgeRequest) error {
+	purgeJSON, err := marshalCanonical(purge)
+	if err != nil {
+		return fmt.Errorf("failed to marshal purge request: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(purgeStateKey(purge.ActorID), purgeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store purge request: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// PROFILE DATA STRUCTURES
+// ============================================================================
+
+// ActorProfile holds an actor's self-declared details and KYC status
+type ActorProfile struct {
+	ActorID        string   `json:"actorId"`
+	ActorType      string   `json:"actorType"` // buyer, supplier, auditor, logistics
+	DisplayName    string   `json:"displayName"`
+	Organization   string   `json:"organization"`
+	Country        string   `json:"country"`
+	Website        string   `json:"website"`
+	Capabilities   []string `json:"capabilities"`
+	Certifications []string `json:"certifications"`
+	Verified       bool     `json:"verified"`
+	VerifiedBy     string   `json:"verifiedBy"`
+	VerifiedAt     int64    `json:"verifiedAt"`
+	Unlisted       bool     `json:"unlisted"` // hidden from public listing queries
+	Purged         bool     `json:"purged"`   // personal fields replaced by tombstones
+	Version        int      `json:"version"`
+	UpdatedAt      int64    `json:"updatedAt"`
+}
+
+// ProfileChange records one versioned change to a profile
+type ProfileChange struct {
+	ActorID       string        `json:"actorId"`
+	Version       int           `json:"version"`
+	ChangedBy     string        `json:"changedBy"`
+	ChangedFields []string      `json:"changedFields"`
+	Profile       *ActorProfile `json:"profile"`
+	Timestamp     int64         `json:"timestamp"`
+	TxID          string        `json:"txId"`
+}
+
+// Profile field limits
+const (
+	maxProfileTextLength = 128
+	maxProfileListItems  = 32
+)
+
+// ============================================================================
+// PROFILE FUNCTIONS
+// ============================================================================
+
+// UpdateActorProfile replaces the caller's profile fields. Any change to a
+// verified profile clears the verification until an admin re-verifies it.
+func (rc *ReputationContract) UpdateActorProfile(
+	ctx contractapi.TransactionContextInterface,
+	profileJSON string,
+) error {
+	normalizedID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get actor ID: %v", err)
+	}
+
+	var update ActorProfile
+	if err := json.Unmarshal([]byte(profileJSON), &update); err != nil {
+		return fmt.Errorf("invalid profile JSON: %v", err)
+	}
+	if err := validateProfile(&update); err != nil {
+		return fmt.Errorf("invalid profile: %v", err)
+	}
+
+	profile, err := getOrInitProfile(ctx, normalizedID)
+	if err != nil {
+		return err
+	}
+
+	changed := profileChangedFields(profile, &update)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	profile.DisplayName = update.DisplayName
+	profile.Organization = update.Organization
+	profile.Country = update.Country
+	profile.Website = update.Website
+	profile.Capabilities = update.Capabilities
+	profile.Certifications = update.Certifications
+	if profile.Verified {
+		profile.Verified = false
+		profile.VerifiedBy = ""
+		profile.VerifiedAt = 0
+		changed = append(changed, "verified")
+	}
+
+	return putProfile(ctx, profile, normalizedID, changed, "ProfileUpdated")
+}
+
+// VerifyActor marks (or unmarks) an actor's profile as KYC-verified
+func (rc *ReputationContract) VerifyActor(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	verified bool,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	normalizedAdminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+
+	profile, err := getOrInitProfile(ctx, resolveIdentity(ctx, actorID))
+	if err != nil {
+		return err
+	}
+	if profile.Verified == verified {
+		return nil
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	profile.Verified = verified
+	if verified {
+		profile.VerifiedBy = normalizedAdminID
+		profile.VerifiedAt = now
+	} else {
+		profile.VerifiedBy = ""
+		profile.VerifiedAt = 0
+	}
+
+	return putProfile(ctx, profile, normalizedAdminID, []string{"verified"}, "ActorVerified")
+}
+
+// SetListingVisibility lets the caller opt out of (or back into) public
+// listing queries such as GetActorsByDimension. Unlisted actors remain
+// visible to admins, arbitrators and direct lookups.
+func (rc *ReputationContract) SetListingVisibility(
+	ctx contractapi.TransactionContextInterface,
+	listed bool,
+) error {
+	normalizedID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get actor ID: %v", err)
+	}
+
+	profile, err := getOrInitProfile(ctx, normalizedID)
+	if err != nil {
+		return err
+	}
+	if profile.Unlisted == !listed {
+		return nil
+	}
+
+	profile.Unlisted = !listed
+
+	return putProfile(ctx, profile, normalizedID, []string{"unlisted"}, "ProfileUpdated")
+}
+
+// GetActorProfile retrieves an actor's current profile
+func (rc *ReputationContract) GetActorProfile(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*ActorProfile, error) {
+	return getOrInitProfile(ctx, resolveIdentity(ctx, actorID))
+}
+
+// GetProfileHistory retrieves every recorded version of an actor's profile,
+// oldest first
+func (rc *ReputationContract) GetProfileHistory(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) ([]ProfileChange, error) {
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	prefix := profileHistoryPrefix(normalizedActorID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var history []ProfileChange
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var change ProfileChange
+		if err := json.Unmarshal(queryResponse.Value, &change); err != nil {
+			continue
+		}
+		history = append(history, change)
+	}
+
+	return history, nil
+}
+
+// ============================================================================
+// PROFILE HELPERS
+// ============================================================================
+
+// validateProfile checks the caller-supplied profile fields
+func validateProfile(profile *ActorProfile) error {
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+	profile.Organization = strings.TrimSpace(profile.Organization)
+	profile.Country = strings.ToUpper(strings.TrimSpace(profile.Country))
+	profile.Website = strings.TrimSpace(profile.Website)
+
+	if profile.DisplayName == "" {
+		return fmt.Errorf("displayName is required")
+	}
+	for field, value := range map[string]string{
+		"displayName":  profile.DisplayName,
+		"organization": profile.Organization,
+		"website":      profile.Website,
+	} {
+		if len(value) > maxProfileTextLength {
+			return fmt.Errorf("%s must be at most %d characters", field, maxProfileTextLength)
+		}
+	}
+
+	if profile.Country != "" {
+		if len(profile.Country) != 2 || strings.Trim(profile.Country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return fmt.Errorf("country must be an ISO 3166-1 alpha-2 code")
+		}
+	}
+
+	if profile.Website != "" {
+		u, err := url.Parse(profile.Website)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("website must be an http(s) URL")
+		}
+	}
+
+	for field, list := range map[string][]string{
+		"capabilities":   profile.Capabilities,
+		"certifications": profile.Certifications,
+	} {
+		if len(list) > maxProfileListItems {
+			return fmt.Errorf("%s must have at most %d entries", field, maxProfileListItems)
+		}
+		for i, item := range list {
+			item = strings.TrimSpace(item)
+			if item == "" || len(item) > maxProfileTextLength {
+				return fmt.Errorf("%s entries must be 1-%d characters", field, maxProfileTextLength)
+			}
+			list[i] = item
+		}
+	}
+
+	return nil
+}
+
+// profileChangedFields lists the editable fields that differ between profiles
+func profileChangedFields(current, update *ActorProfile) []string {
+	var changed []string
+	if current.DisplayName != update.DisplayName {
+		changed = append(changed, "displayName")
+	}
+	if current.Organization != update.Organization {
+		changed = append(changed, "organization")
+	}
+	if current.Country != update.Country {
+		changed = append(changed, "country")
+	}
+	if current.Website != update.Website {
+		changed = append(changed, "website")
+	}
+	if strings.Join(current.Capabilities, "\x00") != strings.Join(update.Capabilities, "\x00") {
+		changed = append(changed, "capabilities")
+	}
+	if strings.Join(current.Certifications, "\x00") != strings.Join(update.Certifications, "\x00") {
+		changed = append(changed, "certifications")
+	}
+	return changed
+}
+
+// putProfile bumps the profile version, stores it, appends a history record
+// and emits the given event
+func putProfile(
+	ctx contractapi.TransactionContextInterface,
+	profile *ActorProfile,
+	changedBy string,
+	changedFields []string,
+	eventName string,
+) error {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	profile.Version++
+	profile.UpdatedAt = now
+
+	profileJSON, err := marshalCanonical(profile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %v", err)
+	}
+
+	profileKey := profileStateKey(profile.ActorID)
+	previousJSON, err := ctx.GetStub().GetState(profileKey)
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %v", err)
+	}
+	var previous *ActorProfile
+	if previousJSON != nil {
+		previous = &ActorProfile{}
+		if err := json.Unmarshal(previousJSON, previous); err != nil {
+			return fmt.Errorf("failed to unmarshal profile: %v", err)
+		}
+	}
+
+	err = ctx.GetStub().PutState(profileKey, profileJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store profile: %v", err)
+	}
+
+	if err := reindexProfile(ctx, previous, profile); err != nil {
+		return err
+	}
+	if err := refreshListingProfile(ctx, profile); err != nil {
+		return err
+	}
+
+	change := ProfileChange{
+		ActorID:       profile.ActorID,
+		Version:       profile.Version,
+		ChangedBy:     changedBy,
+		ChangedFields: changedFields,
+		Profile:       profile,
+		Timestamp:     now,
+		TxID:          ctx.GetStub().GetTxID(),
+	}
+
+	changeJSON, err := marshalCanonical(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile change: %v", err)
+	}
+
+	historyKey := profileHistoryKey(profile.ActorID, profile.Version)
+	err = ctx.GetStub().PutState(historyKey, changeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store profile change: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"actorId":       profile.ActorID,
+		"version":       profile.Version,
+		"changedBy":     changedBy,
+		"changedFields": changedFields,
+		"verified":      profile.Verified,
+	}
+	emitEvent(ctx, eventName, eventPayload, profileStateKey(profile.ActorID))
+
+	return nil
+}
+
+// getOrInitProfile loads or initializes an actor's profile
+func getOrInitProfile(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*ActorProfile, error) {
+	profileKey := profileStateKey(actorID)
+	profileJSON, err := readState(ctx, profileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %v", err)
+	}
+
+	if profileJSON == nil {
+		return &ActorProfile{ActorID: actorID}, nil
+	}
+
+	var profile ActorProfile
+	if err := json.Unmarshal(profileJSON, &profile); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profile: %v", err)
+	}
+
+	return &profile, nil
+}
+
+// isListed reports whether an actor appears in public listing queries.
+// Applicants and actors on probation do not.
+func isListed(ctx contractapi.TransactionContextInterface, actorID string) (bool, error) {
+	profile, err := getOrInitProfile(ctx, actorID)
+	if err != nil {
+		return false, err
+	}
+	if profile.Unlisted {
+		return false, nil
+	}
+	probationary, err := isProbationary(ctx, actorID)
+	if err != nil {
+		return false, err
+	}
+	return !probationary, nil
+}
+
+// canSeeUnlisted reports whether the caller may see unlisted actors in
+// listing queries
+func canSeeUnlisted(ctx contractapi.TransactionContextInterface) bool {
+	return isAdmin(ctx) || isArbitrator(ctx)
+}
+
+// isVerified reports whether an actor's profile is KYC-verified
+func isVerified(ctx contractapi.TransactionContextInterface, actorID string) (bool, error) {
+	profile, err := getOrInitProfile(ctx, actorID)
+	if err != nil {
+		return false, err
+	}
+	return profile.Verified, nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// PARAMETER RAMPS
+// ============================================================================
+
+// ParameterRamp moves a numeric config parameter linearly from StartValue
+// to EndValue between StartTs and EndTs. Once a ramp ends, getConfig writes
+// its end value into the parameter and drops it, and the next config write
+// stores the result; setting the parameter directly also drops its ramp.
+type ParameterRamp struct {
+	Parameter  string  `json:"parameter"`
+	StartValue float64 `json:"startValue"`
+	EndValue   float64 `json:"endValue"`
+	StartTs    int64   `json:"startTs"`
+	EndTs      int64   `json:"endTs"`
+}
+
+// ScheduleParameterRamp schedules a linear change of a numeric parameter.
+// The ramp starts from the parameter's current effective value at startTs,
+// which must not be in the past, and reaches endValue after durationStr
+// seconds.
+func (gc *GovernanceContract) ScheduleParameterRamp(
+	ctx contractapi.TransactionContextInterface,
+	parameter string,
+	endValueStr string,
+	startTsStr string,
+	durationStr string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	endValue, err := strconv.ParseFloat(endValueStr, 64)
+	if err != nil {
+		return fmt.Errorf("invalid end value: %v", err)
+	}
+
+	startTs, err := strconv.ParseInt(startTsStr, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid start timestamp: %v", err)
+	}
+
+	duration, err := strconv.ParseInt(durationStr, 10, 64)
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("invalid duration: must be positive number of seconds")
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+	if startTs < now {
+		return fmt.Errorf("invalid start timestamp: %d is in the past", startTs)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := rampableParameter(config, parameter)
+	if current == nil {
+		return fmt.Errorf("parameter cannot be ramped: %s", parameter)
+	}
+
+	// Reject targets the config would not accept
+	target := *config
+	*rampableParameter(&target, parameter) = endValue
+	if err := validateConfig(&target); err != nil {
+		return fmt.Errorf("invalid ramp target: %v", err)
+	}
+
+	ramp := &ParameterRamp{
+		Parameter:  parameter,
+		StartValue: *current,
+		EndValue:   endValue,
+		StartTs:    startTs,
+		EndTs:      startTs + duration,
+	}
+
+	if config.ParameterRamps == nil {
+		config.ParameterRamps = make(map[string]*ParameterRamp)
+	}
+	config.ParameterRamps[parameter] = ramp
+	config.Version++
+	config.LastUpdated = now
+
+	configJSON, err := marshalCanonical(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(configKey, configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update config: %v", err)
+	}
+
+	// Emit event
+	emitEvent(ctx, "ParameterRampScheduled", ramp)
+
+	return nil
+}
+
+// CancelParameterRamp stops a ramp, freezing the parameter at its current
+// effective value
+func (gc *GovernanceContract) CancelParameterRamp(
+	ctx contractapi.TransactionContextInterface,
+	parameter string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := config.ParameterRamps[parameter]; !exists {
+		return fmt.Errorf("no ramp scheduled for %s", parameter)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// getConfig already resolved the effective value into the parameter
+	delete(config.ParameterRamps, parameter)
+	config.Version++
+	config.LastUpdated = now
+
+	configJSON, err := marshalCanonical(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(configKey, configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to update config: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"parameter": parameter,
+		"value":     *rampableParameter(config, parameter),
+	}
+	emitEvent(ctx, "ParameterRampCancelled", eventPayload)
+
+	return nil
+}
+
+// ============================================================================
+// RAMP HELPERS
+// ============================================================================
+
+// rampableParameter returns a pointer to the named numeric config parameter,
+// or nil if the parameter cannot be ramped
+func rampableParameter(config *SystemConfig, parameter string) *float64 {
+	switch parameter {
+	case "minStakeRequired":
+		return &config.MinStakeRequired
+	case "disputeCost":
+		return &config.DisputeCost
+	case "slashPercentage":
+		return &config.SlashPercentage
+	case "decayRate":
+		return &config.DecayRate
+	case "decayPeriod":
+		return &config.DecayPeriod
+	case "minRaterWeight":
+		return &config.MinRaterWeight
+	case "maxRaterWeight":
+		return &config.MaxRaterWeight
+	}
+	return nil
+}
+
+// applyParameterRamps overwrites ramped parameters with their effective
+// value at the given timestamp and drops the ramps that have ended
+func applyParameterRamps(config *SystemConfig, now int64) {
+	for parameter, ramp := range config.ParameterRamps {
+		if value := rampableParameter(config, parameter); value != nil {
+			*value = rampValue(ramp, now)
+		}
+		if now >= ramp.EndTs {
+			delete(config.ParameterRamps, parameter)
+		}
+	}
+}
+
+// dropOverriddenRamps drops the ramps of parameters a config update sets to
+// a value other than their current effective one, so the ramp does not
+// overwrite it
+func dropOverriddenRamps(current *SystemConfig, updated *SystemConfig) {
+	for parameter := range updated.ParameterRamps {
+		value := rampableParameter(updated, parameter)
+		if value == nil || *value != *rampableParameter(current, parameter) {
+			delete(updated.ParameterRamps, parameter)
+		}
+	}
+}
+
+// rampValue linearly interpolates a ramp at the given timestamp
+func rampValue(ramp *ParameterRamp, now int64) float64 {
+	if now <= ramp.StartTs {
+		return ramp.StartValue
+	}
+	if now >= ramp.EndTs {
+		return ramp.EndValue
+	}
+
+	progress := float64(now-ramp.StartTs) / float64(ramp.EndTs-ramp.StartTs)
+	return ramp.StartValue + (ramp.EndValue-ramp.StartValue)*progress
+}
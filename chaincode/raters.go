@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// RATER ANALYTICS
+// ============================================================================
+
+// RaterAccuracy summarizes how a rater's ratings have held up under dispute
+type RaterAccuracy struct {
+	RaterID          string                    `json:"raterId"`
+	RatingsSubmitted int                       `json:"ratingsSubmitted"`
+	DisputesReceived int                       `json:"disputesReceived"`
+	DisputesByStatus map[string]int            `json:"disputesByStatus"`
+	OverturnRate     float64                   `json:"overturnRate"`   // overturned / resolved, 0 if none resolved
+	MetaReputation   map[string]DimensionScore `json:"metaReputation"` // by base dimension
+}
+
+// GetRaterAccuracy returns the number of ratings a rater submitted, the
+// disputes filed against them and how many were overturned, and their
+// current metareputation in every dimension. Counts come from counters
+// maintained as ratings and disputes are written.
+func (rc *ReputationContract) GetRaterAccuracy(
+	ctx contractapi.TransactionContextInterface,
+	raterID string,
+) (*RaterAccuracy, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedRaterID := resolveIdentity(ctx, raterID)
+
+	accuracy := &RaterAccuracy{
+		RaterID:          normalizedRaterID,
+		DisputesByStatus: make(map[string]int),
+		MetaReputation:   make(map[string]DimensionScore),
+	}
+
+	if accuracy.RatingsSubmitted, err = getIndexCount(ctx, ratingByRaterIndex, []string{normalizedRaterID}); err != nil {
+		return nil, err
+	}
+
+	for _, status := range disputeStatuses {
+		count, err := getIndexCount(ctx, raterDisputeCounts, []string{normalizedRaterID, status})
+		if err != nil {
+			return nil, err
+		}
+		accuracy.DisputesByStatus[status] = count
+		accuracy.DisputesReceived += count
+	}
+	if resolved := accuracy.DisputesByStatus["upheld"] + accuracy.DisputesByStatus["overturned"]; resolved > 0 {
+		accuracy.OverturnRate = float64(accuracy.DisputesByStatus["overturned"]) / float64(resolved)
+	}
+
+	baseDimensions := []string{}
+	for baseDimension := range config.MetaDimensions {
+		baseDimensions = append(baseDimensions, baseDimension)
+	}
+	sort.Strings(baseDimensions)
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, baseDimension := range baseDimensions {
+		rep, err := getOrInitReputation(ctx, normalizedRaterID, config.MetaDimensions[baseDimension], config)
+		if err != nil {
+			return nil, err
+		}
+
+		effectiveRep := applyDynamicDecayAt(rep, config, now)
+		ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+
+		accuracy.MetaReputation[baseDimension] = DimensionScore{
+			Score:       reputationScore(effectiveRep),
+			CILower:     ci[0],
+			CIUpper:     ci[1],
+			TotalEvents: rep.TotalEvents,
+			LastUpdated: rep.LastTs,
+		}
+	}
+
+	return accuracy, nil
+}
+
+// RaterReport is the periodic review document for a rater
+type RaterReport struct {
+	RaterID  string         `json:"raterId"`
+	Since    int64          `json:"since"`
+	AsOf     int64          `json:"asOf"`
+	Accuracy *RaterAccuracy `json:"accuracy"` // all-time counts and current metareputation
+	Ratings  []Rating       `json:"ratings"`  // submitted since Since, newest first
+	Disputes []Dispute      `json:"disputes"` // filed against the rater since Since, newest first
+}
+
+// GetRaterReport assembles a rater's accuracy summary, metareputation in
+// every meta-dimension, and the ratings they submitted and disputes filed
+// against them since sinceStr (unix seconds), for periodic rater reviews
+func (rc *ReputationContract) GetRaterReport(
+	ctx contractapi.TransactionContextInterface,
+	raterID string,
+	sinceStr string,
+) (*RaterReport, error) {
+	since, err := strconv.ParseInt(sinceStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid since timestamp: %v", err)
+	}
+
+	accuracy, err := rc.GetRaterAccuracy(ctx, raterID)
+	if err != nil {
+		return nil, err
+	}
+
+	asOf, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RaterReport{
+		RaterID:  accuracy.RaterID,
+		Since:    since,
+		AsOf:     asOf,
+		Accuracy: accuracy,
+		Ratings:  []Rating{},
+		Disputes: []Dispute{},
+	}
+
+	err = forEachIndexedRating(ctx, ratingByRaterIndex, []string{accuracy.RaterID}, func(rating *Rating) (bool, error) {
+		if rating.Timestamp < since {
+			return false, nil
+		}
+		report.Ratings = append(report.Ratings, *rating)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, status := range disputeStatuses {
+		disputes, err := disputesWithStatus(ctx, status, func(dispute *Dispute) bool {
+			return dispute.RaterID == accuracy.RaterID && dispute.CreatedAt >= since
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.Disputes = append(report.Disputes, disputes...)
+	}
+	sort.SliceStable(report.Disputes, func(i, j int) bool {
+		return report.Disputes[i].CreatedAt > report.Disputes[j].CreatedAt
+	})
+
+	return report, nil
+}
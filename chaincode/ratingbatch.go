@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// BATCH RATING SUBMISSION
+// ============================================================================
+
+// High-volume raters submit up to maxBatchRatings ratings in one
+// transaction. Each rating goes through the same checks and updates as
+// SubmitRating, in the order given, and the batch applies atomically: if
+// any rating is rejected the transaction fails and none is stored. A batch
+// rates each actor at most once per dimension. The config is read from the
+// ledger once per transaction (see cachedConfigJSON), so the batch shares
+// that read.
+
+// maxBatchRatings bounds the number of ratings in one SubmitRatingsBatch call
+const maxBatchRatings = 100
+
+// BatchRating is one rating of a SubmitRatingsBatch call
+type BatchRating struct {
+	ActorID   string  `json:"actorId"`
+	Dimension string  `json:"dimension"`
+	Value     float64 `json:"value"`
+	Evidence  string  `json:"evidence"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// SubmitRatingsBatch submits a JSON array of ratings by the caller and
+// returns their rating IDs in the same order
+func (rtc *RatingContract) SubmitRatingsBatch(
+	ctx contractapi.TransactionContextInterface,
+	ratingsJSON string,
+) ([]string, error) {
+	normalizedRaterID, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rater ID: %v", err)
+	}
+
+	var ratings []BatchRating
+	if err := json.Unmarshal([]byte(ratingsJSON), &ratings); err != nil {
+		return nil, fmt.Errorf("invalid ratings JSON: %v", err)
+	}
+	if len(ratings) == 0 {
+		return nil, fmt.Errorf("no ratings to submit")
+	}
+	if len(ratings) > maxBatchRatings {
+		return nil, fmt.Errorf("at most %d ratings per call", maxBatchRatings)
+	}
+
+	ratingIDs := make([]string, len(ratings))
+	submitted := map[string]int{}
+	for i, entry := range ratings {
+		// Each rating passes the argument checks of SubmitRating
+		args := []string{
+			entry.ActorID, entry.Dimension, strconv.FormatFloat(entry.Value, 'f', -1, 64),
+			entry.Evidence, strconv.FormatInt(entry.Timestamp, 10),
+		}
+		for j, arg := range transactionArguments["SubmitRating"] {
+			if message := checkText(args[j], maxArgumentLength); message != "" {
+				return nil, fmt.Errorf("rating %d: %s %s", i, arg.name, message)
+			}
+			if message := arg.check(args[j]); message != "" {
+				return nil, fmt.Errorf("rating %d: %s %s", i, arg.name, message)
+			}
+		}
+
+		submission, err := validateRating(ctx, normalizedRaterID, args[0], args[1], args[2], args[3], args[4])
+		if err != nil {
+			return nil, fmt.Errorf("rating %d: %v", i, err)
+		}
+
+		// Checks such as rating rules and bond settlement read committed
+		// state, which a second rating of the pair in this batch would not see
+		pair := submission.actorID + "~" + submission.dimension
+		if previous, exists := submitted[pair]; exists {
+			return nil, fmt.Errorf("rating %d: rates the same actor and dimension as rating %d", i, previous)
+		}
+		submitted[pair] = i
+
+		if err := computeRating(ctx, submission, ""); err != nil {
+			return nil, fmt.Errorf("rating %d: %v", i, err)
+		}
+		if err := writeRating(ctx, submission, nil); err != nil {
+			return nil, fmt.Errorf("rating %d: %v", i, err)
+		}
+		ratingIDs[i] = submission.rating.RatingID
+	}
+
+	emitEvent(ctx, "RatingsSubmitted", map[string]interface{}{
+		"raterId":   normalizedRaterID,
+		"count":     len(ratingIDs),
+		"ratingIds": ratingIDs,
+	})
+
+	return ratingIDs, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// RATING COOLDOWN
+// ============================================================================
+
+// A rater may rate an actor in a dimension at most once per
+// SystemConfig.RatingCooldown seconds, measured in transaction time against
+// the rater/actor pair record of its previous rating, so repeated ratings
+// cannot flood a score. A cooldown of 0 disables the check. Automated ratings from monitoring services and
+// service accounts are exempt, as they rate per order or measurement.
+// Pair records written before the cooldown existed carry no submission
+// time and do not hold back the next rating, and configs stored before it
+// leave it disabled until updated.
+
+// checkRatingCooldown rejects a rating of a rater/actor pair rated within
+// the cooldown
+func checkRatingCooldown(ctx contractapi.TransactionContextInterface, submission *ratingSubmission) error {
+	if submission.config.RatingCooldown == 0 {
+		return nil
+	}
+
+	pairJSON, err := readState(ctx, raterActorStateKey(submission.raterID, submission.actorID, submission.dimension))
+	if err != nil {
+		return fmt.Errorf("failed to read rater/actor record: %v", err)
+	}
+	if pairJSON == nil {
+		return nil
+	}
+
+	var pair struct {
+		SubmittedAt int64 `json:"submittedAt"`
+	}
+	if err := json.Unmarshal(pairJSON, &pair); err != nil || pair.SubmittedAt == 0 {
+		return nil
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+	if next := pair.SubmittedAt + submission.config.RatingCooldown; now < next {
+		return fmt.Errorf(
+			"rating cooldown: %s already rated %s on %s at %d; next rating allowed from %d",
+			submission.raterID, submission.actorID, submission.dimension, pair.SubmittedAt, next,
+		)
+	}
+	return nil
+}
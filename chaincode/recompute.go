@@ -0,0 +1,376 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// REPUTATION RECOMPUTATION
+// ============================================================================
+
+// A reputation record is the dimension's prior plus the evidence of every
+// rating of the actor that was applied and not overturned, the accepted
+// credentials' attestation weight and the vouches received.
+// RecomputeReputation rebuilds it from those records, taking archived
+// ratings from their archives, so scores skewed by an update applied twice
+// or by a reversal clamped at the prior can be audited and repaired. The
+// replay spans several transactions; its running sums are kept in a cursor
+// between batches.
+
+// ReputationRecompute is the cursor of a running recomputation
+type ReputationRecompute struct {
+	ActorID   string `json:"actorId"`
+	Dimension string `json:"dimension"`
+	Bookmark  string `json:"bookmark"`    // position within the actor's ratings
+	Alpha     Fixed  `json:"alphaMicros"` // rating evidence replayed so far
+	Beta      Fixed  `json:"betaMicros"`
+	Events    int    `json:"events"`   // ratings replayed so far
+	Reversed  int    `json:"reversed"` // overturned ratings skipped so far
+	Baseline  string `json:"baseline"` // sha256 of the stored reputation when the run started
+	StartedAt int64  `json:"startedAt"`
+}
+
+// ReputationRecomputeResult reports one batch of RecomputeReputation
+type ReputationRecomputeResult struct {
+	ActorID    string      `json:"actorId"`
+	Dimension  string      `json:"dimension"`
+	Scanned    int         `json:"scanned"`   // ratings read in this batch
+	Replayed   int         `json:"replayed"`  // ratings replayed so far
+	Reversed   int         `json:"reversed"`  // overturned ratings skipped so far
+	Restarted  bool        `json:"restarted"` // the reputation changed during the run, which starts over
+	Done       bool        `json:"done"`
+	Changed    bool        `json:"changed"`              // the stored reputation differed and was replaced
+	Previous   *Reputation `json:"previous,omitempty"`   // once done, the stored reputation before the run
+	Recomputed *Reputation `json:"recomputed,omitempty"` // once done, the rebuilt reputation
+}
+
+// RecomputeReputation replays one batch of up to pageSize of an actor's
+// ratings in a dimension. The call is repeated until done is true; the last
+// batch adds attestations and vouches to the replayed evidence and replaces
+// the stored reputation if it differs. Priors and the attestation weight are
+// taken from the current config. If the reputation is written by another
+// transaction during the run, the final batch starts the run over instead.
+// Metareputation is built from dispute outcomes, not ratings, and cannot be
+// recomputed.
+func (gc *GovernanceContract) RecomputeReputation(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+	pageSize int,
+) (*ReputationRecomputeResult, error) {
+	if !isAdmin(ctx) {
+		return nil, fmt.Errorf("unauthorized: admin role required")
+	}
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !config.ValidDimensions[dimension] {
+		return nil, fmt.Errorf("invalid dimension: %s", dimension)
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+	correlateEvents(ctx, reputationStateKey(normalizedActorID, dimension))
+
+	stored, baseline, err := storedReputationBaseline(ctx, normalizedActorID, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := getReputationRecompute(ctx, normalizedActorID, dimension)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil {
+		now, err := txUnixTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &ReputationRecompute{
+			ActorID:   normalizedActorID,
+			Dimension: dimension,
+			Baseline:  baseline,
+			StartedAt: now,
+		}
+	}
+
+	result := &ReputationRecomputeResult{ActorID: normalizedActorID, Dimension: dimension}
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		ratingByActorIndex, []string{normalizedActorID, dimension}, int32(pageSize), cursor.Bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ratings: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		result.Scanned++
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) == 0 {
+			continue
+		}
+		rating, err := getRating(ctx, parts[len(parts)-1])
+		if err != nil || rating == nil || rating.PendingUntil != 0 {
+			continue
+		}
+
+		overturned, err := ratingOverturned(ctx, rating.RatingID)
+		if err != nil {
+			return nil, err
+		}
+		if overturned {
+			cursor.Reversed++
+			continue
+		}
+
+		value, err := ratingValue(ctx, rating)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value of %s: %v", rating.RatingID, err)
+		}
+		alpha, beta := ratingEvidence(rating.Weight, value)
+		cursor.Alpha += alpha
+		cursor.Beta += beta
+		cursor.Events++
+	}
+
+	result.Replayed = cursor.Events
+	result.Reversed = cursor.Reversed
+
+	if int(metadata.FetchedRecordsCount) == pageSize {
+		cursor.Bookmark = metadata.Bookmark
+		if err := putReputationRecompute(ctx, cursor); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// Every rating is replayed
+	if err := ctx.GetStub().DelState(recomputeStateKey(normalizedActorID, dimension)); err != nil {
+		return nil, fmt.Errorf("failed to clear recompute cursor: %v", err)
+	}
+	if cursor.Baseline != baseline {
+		result.Restarted = true
+		return result, nil
+	}
+
+	recomputed, err := recomputedReputation(ctx, config, cursor, stored)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Done = true
+	result.Previous = stored
+	result.Recomputed = recomputed
+
+	// An actor without a stored reputation has the bare prior
+	current := stored
+	if current == nil {
+		current = &Reputation{Alpha: fixedFromFloat(config.InitialAlpha), Beta: fixedFromFloat(config.InitialBeta)}
+	}
+	result.Changed = current.Alpha != recomputed.Alpha ||
+		current.Beta != recomputed.Beta ||
+		current.TotalEvents != recomputed.TotalEvents
+	if !result.Changed {
+		return result, nil
+	}
+
+	if err := putReputation(ctx, recomputed); err != nil {
+		return nil, err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"actorId":     normalizedActorID,
+		"dimension":   dimension,
+		"alpha":       recomputed.Alpha.String(),
+		"beta":        recomputed.Beta.String(),
+		"totalEvents": recomputed.TotalEvents,
+		"reversed":    cursor.Reversed,
+	}
+	if stored != nil {
+		eventPayload["previousAlpha"] = stored.Alpha.String()
+		eventPayload["previousBeta"] = stored.Beta.String()
+		eventPayload["previousTotalEvents"] = stored.TotalEvents
+	}
+	emitEvent(ctx, "ReputationRecomputed", eventPayload)
+
+	return result, nil
+}
+
+// ============================================================================
+// RECOMPUTATION HELPERS
+// ============================================================================
+
+// recomputedReputation adds the prior, rating archives, attestations and
+// vouches to the replayed rating evidence
+func recomputedReputation(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	cursor *ReputationRecompute,
+	stored *Reputation,
+) (*Reputation, error) {
+	rep := &Reputation{
+		ActorID:     cursor.ActorID,
+		Dimension:   cursor.Dimension,
+		Alpha:       fixedFromFloat(config.InitialAlpha) + cursor.Alpha,
+		Beta:        fixedFromFloat(config.InitialBeta) + cursor.Beta,
+		TotalEvents: cursor.Events,
+		LastTs:      cursor.StartedAt,
+	}
+	if stored != nil {
+		rep.LastTs = stored.LastTs
+	}
+
+	archives, err := ratingArchives(ctx, cursor.ActorID, cursor.Dimension)
+	if err != nil {
+		return nil, err
+	}
+	for _, archive := range archives {
+		rep.Alpha += archive.Alpha
+		rep.Beta += archive.Beta
+		rep.TotalEvents += archive.Count
+	}
+
+	attestations, err := ctx.GetStub().GetStateByRange(attestationPrefix(cursor.ActorID), attestationPrefix(cursor.ActorID)+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attestations: %v", err)
+	}
+	defer attestations.Close()
+
+	for attestations.HasNext() {
+		queryResponse, err := attestations.Next()
+		if err != nil {
+			return nil, err
+		}
+		var attestation Attestation
+		if err := json.Unmarshal(queryResponse.Value, &attestation); err != nil {
+			continue
+		}
+		if attestation.Dimension == cursor.Dimension {
+			rep.Alpha += fixedFromFloat(config.AttestationWeight)
+		}
+	}
+
+	vouchesPrefix := vouchPrefix(cursor.ActorID) + cursor.Dimension + ":"
+	vouches, err := ctx.GetStub().GetStateByRange(vouchesPrefix, vouchesPrefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vouches: %v", err)
+	}
+	defer vouches.Close()
+
+	for vouches.HasNext() {
+		queryResponse, err := vouches.Next()
+		if err != nil {
+			return nil, err
+		}
+		var vouch Vouch
+		if err := json.Unmarshal(queryResponse.Value, &vouch); err != nil {
+			continue
+		}
+		rep.Alpha += fixedFromFloat(vouch.PriorBoost)
+	}
+
+	return rep, nil
+}
+
+// ratingOverturned reports whether any dispute of a rating was overturned
+func ratingOverturned(ctx contractapi.TransactionContextInterface, ratingID string) (bool, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(disputeByRatingIndex, []string{ratingID})
+	if err != nil {
+		return false, fmt.Errorf("failed to read disputes of %s: %v", ratingID, err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return false, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			continue
+		}
+		dispute, err := getDispute(ctx, parts[1])
+		if err != nil {
+			return false, err
+		}
+		if dispute != nil && dispute.Status == "overturned" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// storedReputationBaseline returns an actor's stored reputation, nil if
+// none, and a fingerprint of its record that changes whenever it is written
+func storedReputationBaseline(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+) (*Reputation, string, error) {
+	repJSON, err := getStateWithLegacy(ctx, reputationStateKey(actorID, dimension), legacyReputationKey(actorID, dimension))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read reputation: %v", err)
+	}
+	if repJSON == nil {
+		return nil, "", nil
+	}
+
+	var rep Reputation
+	if err := json.Unmarshal(repJSON, &rep); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal reputation: %v", err)
+	}
+	hash := sha256.Sum256(repJSON)
+	return &rep, hex.EncodeToString(hash[:]), nil
+}
+
+// getReputationRecompute returns the running recomputation of an actor's
+// reputation, or nil
+func getReputationRecompute(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	dimension string,
+) (*ReputationRecompute, error) {
+	cursorJSON, err := ctx.GetStub().GetState(recomputeStateKey(actorID, dimension))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recompute cursor: %v", err)
+	}
+	if cursorJSON == nil {
+		return nil, nil
+	}
+
+	var cursor ReputationRecompute
+	if err := json.Unmarshal(cursorJSON, &cursor); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal recompute cursor: %v", err)
+	}
+	return &cursor, nil
+}
+
+// putReputationRecompute stores the cursor of a running recomputation
+func putReputationRecompute(ctx contractapi.TransactionContextInterface, cursor *ReputationRecompute) error {
+	cursorJSON, err := marshalCanonical(cursor)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recompute cursor: %v", err)
+	}
+	if err := ctx.GetStub().PutState(recomputeStateKey(cursor.ActorID, cursor.Dimension), cursorJSON); err != nil {
+		return fmt.Errorf("failed to store recompute cursor: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// RISK QUERIES
+// ============================================================================
+
+// GetActorsBelowThreshold lists actors whose decayed score in a dimension is
+// at or below maxScore, worst first. Actors with fewer than minEvents
+// ratings are left out, so newly initialized actors sitting near the prior
+// do not flood the list.
+func (rc *ReputationContract) GetActorsBelowThreshold(
+	ctx contractapi.TransactionContextInterface,
+	dimension string,
+	maxScoreStr string,
+	minEventsStr string,
+) ([]map[string]interface{}, error) {
+	maxScore, err := strconv.ParseFloat(maxScoreStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maxScore: %v", err)
+	}
+
+	minEvents, err := strconv.Atoi(minEventsStr)
+	if err != nil || minEvents < 0 {
+		return nil, fmt.Errorf("invalid minEvents: must be a non-negative integer")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateQueryDimension(config, dimension); err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(reputationByDimensionIndex, []string{dimension})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	showUnlisted := canSeeUnlisted(ctx)
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]interface{}{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			continue
+		}
+		actorID := parts[1]
+
+		rep, err := getStoredReputation(ctx, actorID, dimension)
+		if err != nil || rep == nil || rep.TotalEvents < minEvents {
+			continue
+		}
+
+		if !showUnlisted {
+			if listed, _ := isListed(ctx, actorID); !listed {
+				continue
+			}
+		}
+
+		effectiveRep := applyDynamicDecayAt(rep, config, now)
+		score := reputationScore(effectiveRep)
+		if score > maxScore {
+			continue
+		}
+
+		ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+		suspended, _ := isBanned(ctx, actorID)
+		archived, _ := isDeactivated(ctx, actorID)
+
+		results = append(results, map[string]interface{}{
+			"actorId":     actorID,
+			"dimension":   dimension,
+			"score":       score,
+			"ci_lower":    ci[0],
+			"ci_upper":    ci[1],
+			"totalEvents": rep.TotalEvents,
+			"lastUpdated": rep.LastTs,
+			"suspended":   suspended,
+			"archived":    archived,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i]["score"].(float64) < results[j]["score"].(float64)
+	})
+
+	return results, nil
+}
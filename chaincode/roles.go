@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// TIME-LIMITED ROLE GRANTS
+// ============================================================================
+
+// Role lists map normalized IDs to true; the matching expiry maps hold the
+// unix time after which a grant no longer counts. Grants without an expiry
+// entry are permanent.
+const (
+	adminListKey        = "ADMIN_LIST"
+	adminExpiryKey      = "ADMIN_EXPIRY"
+	arbitratorListKey   = "ARBITRATOR_LIST"
+	arbitratorExpiryKey = "ARBITRATOR_EXPIRY"
+)
+
+// GrantTemporaryAdmin adds an administrator whose grant expires at expiresAt
+func (gc *GovernanceContract) GrantTemporaryAdmin(
+	ctx contractapi.TransactionContextInterface,
+	adminID string,
+	expiresAtStr string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: only admin can add admins")
+	}
+
+	return grantTemporaryRole(ctx, adminListKey, adminExpiryKey, "adminId", "AdminUpdated", adminID, expiresAtStr)
+}
+
+// GrantTemporaryArbitrator adds an arbitrator whose grant expires at expiresAt
+func (gc *GovernanceContract) GrantTemporaryArbitrator(
+	ctx contractapi.TransactionContextInterface,
+	arbitratorID string,
+	expiresAtStr string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: only admin can add arbitrators")
+	}
+
+	return grantTemporaryRole(ctx, arbitratorListKey, arbitratorExpiryKey, "arbitratorId", "ArbitratorUpdated", arbitratorID, expiresAtStr)
+}
+
+// PruneExpiredRoles removes expired admin and arbitrator grants. Expired
+// grants are already ignored by the authorization checks, so anyone may call
+// this to keep the role lists tidy.
+func (gc *GovernanceContract) PruneExpiredRoles(
+	ctx contractapi.TransactionContextInterface,
+) (int, error) {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	prunedAdmins, err := pruneRole(ctx, adminListKey, adminExpiryKey, now)
+	if err != nil {
+		return 0, err
+	}
+	prunedArbitrators, err := pruneRole(ctx, arbitratorListKey, arbitratorExpiryKey, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"admins":      prunedAdmins,
+		"arbitrators": prunedArbitrators,
+	}
+	emitEvent(ctx, "RolesPruned", eventPayload)
+
+	return len(prunedAdmins) + len(prunedArbitrators), nil
+}
+
+// ============================================================================
+// ROLE HELPERS
+// ============================================================================
+
+// roleGranted reports whether the ID holds an unexpired grant in the list
+func roleGranted(
+	ctx contractapi.TransactionContextInterface,
+	listKey string,
+	expiryKey string,
+	id string,
+) bool {
+	list, err := getRoleList(ctx, listKey)
+	if err != nil || !list[id] {
+		return false
+	}
+
+	expiries, err := getRoleExpiries(ctx, expiryKey)
+	if err != nil {
+		return false
+	}
+	expiresAt, temporary := expiries[id]
+	if !temporary {
+		return true
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return false
+	}
+	return now < expiresAt
+}
+
+// grantTemporaryRole adds the ID to the role list with an expiry
+func grantTemporaryRole(
+	ctx contractapi.TransactionContextInterface,
+	listKey string,
+	expiryKey string,
+	idField string,
+	eventName string,
+	id string,
+	expiresAtStr string,
+) error {
+	expiresAt, err := strconv.ParseInt(expiresAtStr, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expiry timestamp: %v", err)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+	if expiresAt <= now {
+		return fmt.Errorf("expiry must be in the future")
+	}
+
+	normalizedID := resolveIdentity(ctx, id)
+
+	list, err := getRoleList(ctx, listKey)
+	if err != nil {
+		return err
+	}
+	list[normalizedID] = true
+
+	expiries, err := getRoleExpiries(ctx, expiryKey)
+	if err != nil {
+		return err
+	}
+	expiries[normalizedID] = expiresAt
+
+	listJSON, _ := marshalCanonical(list)
+	if err := ctx.GetStub().PutState(listKey, listJSON); err != nil {
+		return fmt.Errorf("failed to update role list: %v", err)
+	}
+	expiriesJSON, _ := marshalCanonical(expiries)
+	if err := ctx.GetStub().PutState(expiryKey, expiriesJSON); err != nil {
+		return fmt.Errorf("failed to update role expiries: %v", err)
+	}
+	for _, key := range []string{listKey, expiryKey} {
+		if err := protectKey(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		idField:     normalizedID,
+		"action":    "added",
+		"expiresAt": expiresAt,
+	}
+	emitEvent(ctx, eventName, eventPayload)
+
+	return nil
+}
+
+// clearRoleExpiry drops any expiry for the ID, making its grant permanent
+// (or, after removal from the list, leaving no stale expiry behind)
+func clearRoleExpiry(
+	ctx contractapi.TransactionContextInterface,
+	expiryKey string,
+	id string,
+) error {
+	expiries, err := getRoleExpiries(ctx, expiryKey)
+	if err != nil {
+		return err
+	}
+	if _, exists := expiries[id]; !exists {
+		return nil
+	}
+
+	delete(expiries, id)
+	expiriesJSON, _ := marshalCanonical(expiries)
+	if err := ctx.GetStub().PutState(expiryKey, expiriesJSON); err != nil {
+		return fmt.Errorf("failed to update role expiries: %v", err)
+	}
+
+	return nil
+}
+
+// pruneRole removes expired grants from a role list and returns their IDs,
+// sorted
+func pruneRole(
+	ctx contractapi.TransactionContextInterface,
+	listKey string,
+	expiryKey string,
+	now int64,
+) ([]string, error) {
+	list, err := getRoleList(ctx, listKey)
+	if err != nil {
+		return nil, err
+	}
+	expiries, err := getRoleExpiries(ctx, expiryKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []string{}
+	for id, expiresAt := range expiries {
+		if now >= expiresAt {
+			delete(list, id)
+			delete(expiries, id)
+			pruned = append(pruned, id)
+		}
+	}
+	if len(pruned) == 0 {
+		return pruned, nil
+	}
+	// Map order differs between endorsers; the IDs go into the event
+	sort.Strings(pruned)
+
+	listJSON, _ := marshalCanonical(list)
+	if err := ctx.GetStub().PutState(listKey, listJSON); err != nil {
+		return nil, fmt.Errorf("failed to update role list: %v", err)
+	}
+	expiriesJSON, _ := marshalCanonical(expiries)
+	if err := ctx.GetStub().PutState(expiryKey, expiriesJSON); err != nil {
+		return nil, fmt.Errorf("failed to update role expiries: %v", err)
+	}
+
+	return pruned, nil
+}
+
+// getRoleList loads a role list, returning an empty list if none exists
+func getRoleList(ctx contractapi.TransactionContextInterface, listKey string) (map[string]bool, error) {
+	list := make(map[string]bool)
+
+	listJSON, err := ctx.GetStub().GetState(listKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role list: %v", err)
+	}
+	if listJSON != nil {
+		if err := json.Unmarshal(listJSON, &list); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal role list: %v", err)
+		}
+	}
+
+	return list, nil
+}
+
+// getRoleExpiries loads a role expiry map, returning an empty map if none exists
+func getRoleExpiries(ctx contractapi.TransactionContextInterface, expiryKey string) (map[string]int64, error) {
+	expiries := make(map[string]int64)
+
+	expiriesJSON, err := ctx.GetStub().GetState(expiryKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role expiries: %v", err)
+	}
+	if expiriesJSON != nil {
+		if err := json.Unmarshal(expiriesJSON, &expiries); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal role expiries: %v", err)
+		}
+	}
+
+	return expiries, nil
+}
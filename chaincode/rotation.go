@@ -0,0 +1,330 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// CERTIFICATE ROTATION
+// ============================================================================
+
+// TrustedCAProposal collects admin approvals to register a CA certificate
+type TrustedCAProposal struct {
+	MSPID       string          `json:"mspId"`
+	Fingerprint string          `json:"fingerprint"`
+	CACertPEM   string          `json:"caCertPem"`
+	Approvals   map[string]bool `json:"approvals"`
+	ProposedAt  int64           `json:"proposedAt"`
+}
+
+// RegisterTrustedCA records an admin's approval to trust a CA certificate
+// for the admin's own MSP. The CA is registered once the configured number
+// of distinct admins have approved it. Rotation proofs are only accepted for
+// old certificates issued by a registered CA, since the chaincode cannot
+// otherwise tell a re-issued certificate from a forged one.
+func (rc *ReputationContract) RegisterTrustedCA(
+	ctx contractapi.TransactionContextInterface,
+	mspID string,
+	caCertPEM string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	callerMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller MSP: %v", err)
+	}
+	if callerMSPID != mspID {
+		return fmt.Errorf("unauthorized: admins of %s can only register CAs for their own MSP", callerMSPID)
+	}
+
+	normalizedAdminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+
+	caCert, err := parseCertificatePEM(caCertPEM)
+	if err != nil {
+		return fmt.Errorf("invalid CA certificate: %v", err)
+	}
+	if !caCert.IsCA {
+		return fmt.Errorf("certificate is not a CA certificate")
+	}
+	fingerprint := fmt.Sprintf("%x", sha256.Sum256(caCert.Raw))
+
+	caKey, err := ctx.GetStub().CreateCompositeKey(trustedCAObjectType, []string{mspID, fingerprint})
+	if err != nil {
+		return fmt.Errorf("failed to create CA key: %v", err)
+	}
+	registered, err := ctx.GetStub().GetState(caKey)
+	if err != nil {
+		return fmt.Errorf("failed to read CA certificate: %v", err)
+	}
+	if registered != nil {
+		return fmt.Errorf("CA already trusted for %s: %s", mspID, fingerprint)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	proposalKey, err := ctx.GetStub().CreateCompositeKey(trustedCAProposalObjectType, []string{mspID, fingerprint})
+	if err != nil {
+		return fmt.Errorf("failed to create CA proposal key: %v", err)
+	}
+	proposal, err := getTrustedCAProposal(ctx, proposalKey)
+	if err != nil {
+		return err
+	}
+	if proposal == nil {
+		proposal = &TrustedCAProposal{
+			MSPID:       mspID,
+			Fingerprint: fingerprint,
+			CACertPEM:   caCertPEM,
+			Approvals:   make(map[string]bool),
+			ProposedAt:  now,
+		}
+	}
+	if proposal.Approvals[normalizedAdminID] {
+		return fmt.Errorf("CA already approved by %s", normalizedAdminID)
+	}
+	proposal.Approvals[normalizedAdminID] = true
+
+	if len(proposal.Approvals) < config.TrustedCAApprovals {
+		proposalJSON, err := marshalCanonical(proposal)
+		if err != nil {
+			return fmt.Errorf("failed to marshal CA proposal: %v", err)
+		}
+		if err := ctx.GetStub().PutState(proposalKey, proposalJSON); err != nil {
+			return fmt.Errorf("failed to store CA proposal: %v", err)
+		}
+
+		// Emit event
+		eventPayload := map[string]interface{}{
+			"mspId":       mspID,
+			"fingerprint": fingerprint,
+			"approverId":  normalizedAdminID,
+			"approvals":   len(proposal.Approvals),
+		}
+		emitEvent(ctx, "TrustedCAApproved", eventPayload)
+		return nil
+	}
+
+	if err := ctx.GetStub().PutState(caKey, []byte(proposal.CACertPEM)); err != nil {
+		return fmt.Errorf("failed to store CA certificate: %v", err)
+	}
+	if err := ctx.GetStub().DelState(proposalKey); err != nil {
+		return fmt.Errorf("failed to delete CA proposal: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"mspId":       mspID,
+		"fingerprint": fingerprint,
+		"subject":     caCert.Subject.String(),
+		"approvals":   len(proposal.Approvals),
+	}
+	emitEvent(ctx, "TrustedCARegistered", eventPayload)
+
+	return nil
+}
+
+// RotateIdentity moves the actor behind an old certificate onto the calling
+// (new) certificate. The old certificate must chain to a trusted CA of the
+// caller's MSP and have signed the challenge returned by
+// GetRotationChallenge; the new certificate proves itself by signing the
+// transaction. The old certificate may already be expired. Under the cn
+// identity mode an actor's ID does not name its MSP, so the actor must also
+// have staked from the caller's MSP: a CA of another organization cannot
+// issue a certificate that takes it over.
+func (rc *ReputationContract) RotateIdentity(
+	ctx contractapi.TransactionContextInterface,
+	oldCertPEM string,
+	oldSignatureB64 string,
+) error {
+	newID, err := certificateIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get caller ID: %v", err)
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get caller MSP: %v", err)
+	}
+
+	oldCert, err := parseCertificatePEM(oldCertPEM)
+	if err != nil {
+		return fmt.Errorf("invalid old certificate: %v", err)
+	}
+
+	if err := verifyIssuedByTrustedCA(ctx, mspID, oldCert); err != nil {
+		return err
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	oldFabricID := base64.StdEncoding.EncodeToString(
+		[]byte(fmt.Sprintf("x509::%s::%s", oldCert.Subject.String(), oldCert.Issuer.String())),
+	)
+	oldID := identityForMode(config.IdentityMode, mspID, oldFabricID)
+	if oldID == newID {
+		return fmt.Errorf("old and new certificates map to the same actor")
+	}
+
+	if config.IdentityMode == identityModeCN {
+		oldStake, err := getOrInitStake(ctx, oldID)
+		if err != nil {
+			return err
+		}
+		if oldStake.MSPID != mspID {
+			return fmt.Errorf("actor %s is not known to belong to %s", oldID, mspID)
+		}
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(oldSignatureB64)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	challenge := rotationChallenge(ctx, oldID, newID)
+	if err := oldCert.CheckSignature(x509.ECDSAWithSHA256, []byte(challenge), signature); err != nil {
+		return fmt.Errorf("old certificate signature does not match challenge: %v", err)
+	}
+
+	moved, err := moveActorRecords(ctx, config, oldID, newID)
+	if err != nil {
+		return err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Keep references to the old ID resolving to the actor
+	link := &IdentityLink{
+		AliasID:     oldID,
+		CanonicalID: newID,
+		Status:      "active",
+		CreatedAt:   now,
+		ConfirmedAt: now,
+	}
+	if err := putIdentityLink(ctx, link, "IdentityLinked"); err != nil {
+		return err
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(aliasOfIndex, []string{newID, oldID})
+	if err != nil {
+		return fmt.Errorf("failed to create alias index key: %v", err)
+	}
+	if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to store alias index: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"oldId": oldID,
+		"newId": newID,
+		"moved": moved,
+	}
+	emitEvent(ctx, "IdentityRotated", eventPayload)
+
+	return nil
+}
+
+// GetRotationChallenge returns the message the old certificate must sign
+// (ECDSA with SHA-256, ASN.1 encoded) to rotate oldID onto the caller
+func (rc *ReputationContract) GetRotationChallenge(
+	ctx contractapi.TransactionContextInterface,
+	oldID string,
+) (string, error) {
+	newID, err := certificateIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller ID: %v", err)
+	}
+
+	return rotationChallenge(ctx, normalizeIdentity(oldID), newID), nil
+}
+
+// ============================================================================
+// ROTATION HELPERS
+// ============================================================================
+
+// rotationChallenge binds a rotation proof to both IDs and the channel
+func rotationChallenge(ctx contractapi.TransactionContextInterface, oldID string, newID string) string {
+	return fmt.Sprintf("repcc:rotate:%s:%s:%s", ctx.GetStub().GetChannelID(), oldID, newID)
+}
+
+// getTrustedCAProposal loads a CA proposal, returning nil if none exists
+func getTrustedCAProposal(ctx contractapi.TransactionContextInterface, proposalKey string) (*TrustedCAProposal, error) {
+	proposalJSON, err := ctx.GetStub().GetState(proposalKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA proposal: %v", err)
+	}
+	if proposalJSON == nil {
+		return nil, nil
+	}
+
+	var proposal TrustedCAProposal
+	if err := json.Unmarshal(proposalJSON, &proposal); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal CA proposal: %v", err)
+	}
+	if proposal.Approvals == nil {
+		proposal.Approvals = make(map[string]bool)
+	}
+	return &proposal, nil
+}
+
+// verifyIssuedByTrustedCA checks the certificate was signed by one of the
+// CAs registered for the MSP
+func verifyIssuedByTrustedCA(
+	ctx contractapi.TransactionContextInterface,
+	mspID string,
+	cert *x509.Certificate,
+) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(trustedCAObjectType, []string{mspID})
+	if err != nil {
+		return fmt.Errorf("failed to read trusted CAs: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		caCert, err := parseCertificatePEM(string(queryResponse.Value))
+		if err != nil {
+			continue
+		}
+		if cert.CheckSignatureFrom(caCert) == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("certificate not issued by a trusted CA of %s", mspID)
+}
+
+// parseCertificatePEM decodes a single PEM-encoded X.509 certificate
+func parseCertificatePEM(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
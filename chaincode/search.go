@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// ACTOR SEARCH
+// ============================================================================
+
+// Profiles are indexed under ACTOR_SEARCH composite keys of the form
+// [typeBucket, c1, c1c2, c1c2c3, searchName, actorID], once under the "*"
+// bucket and once under the actor's type. A search prefix of up to
+// searchPrefixDepth characters maps directly onto a partial composite key.
+
+const (
+	searchAllTypes    = "*"
+	searchPrefixDepth = 3
+	maxSearchPageSize = 100
+)
+
+// ActorSearchResult is one match of SearchActors
+type ActorSearchResult struct {
+	ActorID     string `json:"actorId"`
+	DisplayName string `json:"displayName"`
+	ActorType   string `json:"actorType"`
+	Verified    bool   `json:"verified"`
+}
+
+// ActorSearchPage is a page of SearchActors results
+type ActorSearchPage struct {
+	Results  []ActorSearchResult `json:"results"`
+	Bookmark string              `json:"bookmark"` // pass to the next call; "" when exhausted
+}
+
+// SearchActors finds profiles whose display name (or, without one, actor ID)
+// starts with prefix, case-insensitively, optionally restricted to an actor
+// type. Prefixes longer than three characters are matched on the first three
+// through the index and filtered on the rest, so such pages may hold fewer
+// than pageSize results while a bookmark is still returned.
+func (rc *ReputationContract) SearchActors(
+	ctx contractapi.TransactionContextInterface,
+	prefix string,
+	actorType string,
+	bookmark string,
+	pageSize int,
+) (*ActorSearchPage, error) {
+	if pageSize <= 0 || pageSize > maxSearchPageSize {
+		return nil, fmt.Errorf("pageSize must be between 1 and %d", maxSearchPageSize)
+	}
+
+	bucket := searchAllTypes
+	if actorType != "" {
+		if !validActorTypes[actorType] {
+			return nil, fmt.Errorf("invalid actor type: %s", actorType)
+		}
+		bucket = actorType
+	}
+
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	attributes := append([]string{bucket}, searchPrefixes(prefix)...)
+
+	resultsIterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		"ACTOR_SEARCH", attributes, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search actors: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	showUnlisted := canSeeUnlisted(ctx)
+
+	page := &ActorSearchPage{Results: []ActorSearchResult{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != searchPrefixDepth+3 {
+			continue
+		}
+		if !strings.HasPrefix(parts[searchPrefixDepth+1], prefix) {
+			continue
+		}
+
+		profile, err := getOrInitProfile(ctx, parts[searchPrefixDepth+2])
+		if err != nil {
+			continue
+		}
+		if profile.Unlisted && !showUnlisted {
+			continue
+		}
+
+		page.Results = append(page.Results, ActorSearchResult{
+			ActorID:     profile.ActorID,
+			DisplayName: profile.DisplayName,
+			ActorType:   profile.ActorType,
+			Verified:    profile.Verified,
+		})
+	}
+
+	if int(metadata.FetchedRecordsCount) == pageSize {
+		page.Bookmark = metadata.Bookmark
+	}
+
+	return page, nil
+}
+
+// ============================================================================
+// SEARCH HELPERS
+// ============================================================================
+
+// reindexProfile replaces the search index entries of the previous version
+// of a profile (nil if it is new) with those of the current version
+func reindexProfile(
+	ctx contractapi.TransactionContextInterface,
+	previous *ActorProfile,
+	current *ActorProfile,
+) error {
+	if previous != nil {
+		for _, key := range searchIndexKeys(ctx, previous) {
+			if err := ctx.GetStub().DelState(key); err != nil {
+				return fmt.Errorf("failed to delete search index: %v", err)
+			}
+		}
+	}
+
+	for _, key := range searchIndexKeys(ctx, current) {
+		if err := ctx.GetStub().PutState(key, []byte{0x00}); err != nil {
+			return fmt.Errorf("failed to store search index: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// searchIndexKeys returns the ACTOR_SEARCH keys of a profile
+func searchIndexKeys(ctx contractapi.TransactionContextInterface, profile *ActorProfile) []string {
+	if profile.Purged {
+		return nil
+	}
+
+	name := strings.ToLower(profile.DisplayName)
+	if name == "" {
+		name = profile.ActorID
+	}
+
+	buckets := []string{searchAllTypes}
+	if profile.ActorType != "" {
+		buckets = append(buckets, profile.ActorType)
+	}
+
+	var keys []string
+	for _, bucket := range buckets {
+		attributes := append([]string{bucket}, searchPrefixes(name)...)
+		for len(attributes) < searchPrefixDepth+1 {
+			attributes = append(attributes, "")
+		}
+		attributes = append(attributes, name, profile.ActorID)
+
+		key, err := ctx.GetStub().CreateCompositeKey("ACTOR_SEARCH", attributes)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// searchPrefixes returns the leading 1..searchPrefixDepth character prefixes
+// of s
+func searchPrefixes(s string) []string {
+	runes := []rune(s)
+	var prefixes []string
+	for i := 1; i <= len(runes) && i <= searchPrefixDepth; i++ {
+		prefixes = append(prefixes, string(runes[:i]))
+	}
+	return prefixes
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// SERVICE ACCOUNTS
+// ============================================================================
+
+// Bots and oracles are enrolled with serviceAccountAttribute=true. Their
+// ratings carry source=automated and their rater weight is capped at
+// SystemConfig.ServiceMaxRaterWeight instead of MaxRaterWeight.
+
+const serviceAccountAttribute = "repcc.serviceAccount"
+
+// Rating sources
+const (
+	ratingSourceHuman     = "human"
+	ratingSourceAutomated = "automated"
+)
+
+// isServiceAccount reports whether the calling certificate is a service
+// account
+func isServiceAccount(ctx contractapi.TransactionContextInterface) (bool, error) {
+	val, ok, err := ctx.GetClientIdentity().GetAttributeValue(serviceAccountAttribute)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s attribute: %v", serviceAccountAttribute, err)
+	}
+	return ok && val == "true", nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// DISPUTE SETTLEMENT
+// ============================================================================
+
+// defaultSettlementFunction is called on the settlement chaincode when the
+// config names a chaincode but no function
+const defaultSettlementFunction = "Transfer"
+
+// invokeStatusOK is the response status of a successful chaincode call
+// (shim.OK)
+const invokeStatusOK = 200
+
+// settleDispute compensates the actor harmed by an overturned rating with
+// the treasury's share of the amount slashed from the rater. The amount
+// leaves the treasury and is paid by calling the configured token chaincode
+// as function(recipientId, amount, disputeId) in this transaction, so a
+// failed transfer fails the resolution with it. Does nothing when no
+// settlement chaincode is configured.
+func settleDispute(
+	ctx contractapi.TransactionContextInterface,
+	config *SystemConfig,
+	dispute *Dispute,
+	amount float64,
+) error {
+	if config.SettlementChaincode == "" || amount <= 0 {
+		return nil
+	}
+
+	err := recordTreasuryOutflow(ctx, amount, dispute.ActorID, "dispute settlement", dispute.DisputeID)
+	if err != nil {
+		return err
+	}
+
+	args := [][]byte{
+		[]byte(config.SettlementFunction),
+		[]byte(dispute.ActorID),
+		[]byte(strconv.FormatFloat(amount, 'f', -1, 64)),
+		[]byte(dispute.DisputeID),
+	}
+	response := ctx.GetStub().InvokeChaincode(config.SettlementChaincode, args, config.SettlementChannel)
+	if response.Status != invokeStatusOK {
+		return fmt.Errorf("failed to settle dispute %s on %s: %s", dispute.DisputeID, config.SettlementChaincode, response.Message)
+	}
+	if err := updateMetrics(ctx, func(m *MetricsDay) { m.SettledAmount += amount }); err != nil {
+		return err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"disputeId":   dispute.DisputeID,
+		"recipientId": dispute.ActorID,
+		"amount":      amount,
+		"chaincode":   config.SettlementChaincode,
+		"function":    config.SettlementFunction,
+	}
+	emitEvent(ctx, "DisputeSettled", eventPayload)
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// SHARDED AGGREGATES
+// ============================================================================
+
+// Aggregates updated by otherwise unrelated transactions - daily metrics,
+// dimension statistics and the treasury - are spread over aggregateShards
+// keys "<key>:<shard>". A transaction reads and writes only the shard its
+// transaction ID selects, so concurrent transactions invalidate each other
+// only when they land on the same shard, and queries add the shards up.
+// Each shard holds a share of every total and may go negative on its own;
+// only the sum is meaningful. Aggregates written before sharding keep their
+// unsharded key, which is added in as one more share and never written
+// again.
+
+// aggregateShards is the number of keys each aggregate is spread over
+const aggregateShards = 16
+
+// aggregateShard returns the shard updated by this transaction. It depends
+// only on the transaction ID, so every endorser selects the same shard.
+func aggregateShard(ctx contractapi.TransactionContextInterface) int {
+	hash := sha256.Sum256([]byte(ctx.GetStub().GetTxID()))
+	return int(binary.BigEndian.Uint32(hash[:4]) % aggregateShards)
+}
+
+// aggregateShardKey returns the key of one shard of an aggregate
+func aggregateShardKey(key string, shard int) string {
+	return fmt.Sprintf("%s:%02d", key, shard)
+}
+
+// aggregateKeys returns an aggregate's unsharded key followed by the keys of
+// all its shards
+func aggregateKeys(key string) []string {
+	keys := []string{key}
+	for shard := 0; shard < aggregateShards; shard++ {
+		keys = append(keys, aggregateShardKey(key, shard))
+	}
+	return keys
+}
+
+// readAggregate passes the key and committed value of every stored share of
+// an aggregate to add, reading them in one round trip where the shim
+// supports it
+func readAggregate(
+	ctx contractapi.TransactionContextInterface,
+	key string,
+	add func(shardKey string, value []byte) error,
+) error {
+	keys := aggregateKeys(key)
+	if err := prefetchState(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to read %s: %v", key, err)
+	}
+
+	for _, shardKey := range keys {
+		value, err := readState(ctx, shardKey)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", shardKey, err)
+		}
+		if value == nil {
+			continue
+		}
+		if err := add(shardKey, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
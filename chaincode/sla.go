@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// SLA METRIC INGESTION
+// ============================================================================
+
+// Monitoring services registered by an admin submit the SLA measurements
+// of an order, such as its on-time percentage or defect rate. Each
+// dimension with a rule in SystemConfig.SLARules whose metric was measured
+// becomes one rating of the supplier by the service, source automated, so
+// the measurements pass the same checks and weighting as any rating: the
+// service needs the minimum stake and its weight comes from its
+// metareputation, capped at ServiceMaxRaterWeight. A service reports each
+// order once.
+
+// SLARule maps one SLA metric to rating values in a dimension: a
+// measurement at Best or beyond rates 1, at Worst or beyond rates 0, and
+// values in between are interpolated linearly. Best is below Worst for
+// metrics where lower is better, such as a defect rate.
+type SLARule struct {
+	Metric string  `json:"metric"` // e.g. onTimePercent, defectRate
+	Best   float64 `json:"best"`
+	Worst  float64 `json:"worst"`
+}
+
+// MonitoringService is an oracle trusted to report SLA measurements
+type MonitoringService struct {
+	ServiceID    string `json:"serviceId"`
+	Active       bool   `json:"active"`
+	RegisteredBy string `json:"registeredBy"`
+	RegisteredAt int64  `json:"registeredAt"`
+}
+
+// SLAMeasurement records a service's measurements of an order and the
+// ratings they became
+type SLAMeasurement struct {
+	ServiceID    string             `json:"serviceId"`
+	SupplierID   string             `json:"supplierId"`
+	OrderID      string             `json:"orderId"`
+	Measurements map[string]float64 `json:"measurements"` // metric -> measured value
+	RatingIDs    map[string]string  `json:"ratingIds"`    // dimension -> rating
+	Timestamp    int64              `json:"timestamp"`
+	TxID         string             `json:"txId"`
+}
+
+// RegisterMonitoringService trusts an identity to submit SLA measurements
+func (gc *GovernanceContract) RegisterMonitoringService(
+	ctx contractapi.TransactionContextInterface,
+	serviceID string,
+) error {
+	return setMonitoringService(ctx, resolveIdentity(ctx, serviceID), true)
+}
+
+// RevokeMonitoringService stops accepting measurements from a service.
+// Ratings it already submitted are kept.
+func (gc *GovernanceContract) RevokeMonitoringService(
+	ctx contractapi.TransactionContextInterface,
+	serviceID string,
+) error {
+	normalizedServiceID := resolveIdentity(ctx, serviceID)
+
+	service, err := getMonitoringService(ctx, normalizedServiceID)
+	if err != nil {
+		return err
+	}
+	if service == nil {
+		return fmt.Errorf("monitoring service not found: %s", normalizedServiceID)
+	}
+
+	return setMonitoringService(ctx, normalizedServiceID, false)
+}
+
+// SubmitSLAMeasurement converts the caller's SLA measurements of an order
+// (a JSON object of metric names to numbers) into ratings of the supplier,
+// one per dimension with a matching rule, and returns the rating IDs by
+// dimension
+func (rtc *RatingContract) SubmitSLAMeasurement(
+	ctx contractapi.TransactionContextInterface,
+	orderID string,
+	supplierID string,
+	measurementsJSON string,
+	timestampStr string,
+) (map[string]string, error) {
+	normalizedServiceID, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service ID: %v", err)
+	}
+
+	service, err := getMonitoringService(ctx, normalizedServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if service == nil || !service.Active {
+		return nil, fmt.Errorf("unauthorized: %s is not a registered monitoring service", normalizedServiceID)
+	}
+
+	var measurements map[string]float64
+	if err := json.Unmarshal([]byte(measurementsJSON), &measurements); err != nil {
+		return nil, fmt.Errorf("invalid measurements JSON: %v", err)
+	}
+	for metric, value := range measurements {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("invalid measurement %s: must be a finite number", metric)
+		}
+	}
+
+	key := slaMeasurementKey(normalizedServiceID, orderID)
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SLA measurement: %v", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("order %s already measured by %s", orderID, normalizedServiceID)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rate in dimension order so every endorser produces the same write set
+	dimensions := make([]string, 0, len(config.SLARules))
+	for dimension := range config.SLARules {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Strings(dimensions)
+
+	ratingIDs := map[string]string{}
+	for _, dimension := range dimensions {
+		rule := config.SLARules[dimension]
+		measured, ok := measurements[rule.Metric]
+		if !ok {
+			continue
+		}
+
+		value := rule.ratingValue(measured)
+		evidence := fmt.Sprintf("sla:%s %s=%g", orderID, rule.Metric, measured)
+		submission, err := validateRating(ctx, normalizedServiceID, supplierID, dimension, value.String(), evidence, timestampStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rate %s: %v", dimension, err)
+		}
+		submission.automated = true
+		if err := computeRating(ctx, submission, ""); err != nil {
+			return nil, err
+		}
+		if err := writeRating(ctx, submission, nil); err != nil {
+			return nil, err
+		}
+		ratingIDs[dimension] = submission.rating.RatingID
+	}
+	if len(ratingIDs) == 0 {
+		return nil, fmt.Errorf("no SLA rule maps the measured metrics")
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	measurement := SLAMeasurement{
+		ServiceID:    normalizedServiceID,
+		SupplierID:   resolveIdentity(ctx, supplierID),
+		OrderID:      orderID,
+		Measurements: measurements,
+		RatingIDs:    ratingIDs,
+		Timestamp:    now,
+		TxID:         ctx.GetStub().GetTxID(),
+	}
+	measurementJSON, err := marshalCanonical(measurement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SLA measurement: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, measurementJSON); err != nil {
+		return nil, fmt.Errorf("failed to store SLA measurement: %v", err)
+	}
+
+	emitEvent(ctx, "SLAMeasurementRecorded", measurement, key)
+
+	return ratingIDs, nil
+}
+
+// GetSLAMeasurement retrieves a service's measurements of an order
+func (rc *ReputationContract) GetSLAMeasurement(
+	ctx contractapi.TransactionContextInterface,
+	serviceID string,
+	orderID string,
+) (*SLAMeasurement, error) {
+	measurementJSON, err := ctx.GetStub().GetState(slaMeasurementKey(resolveIdentity(ctx, serviceID), orderID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SLA measurement: %v", err)
+	}
+	if measurementJSON == nil {
+		return nil, fmt.Errorf("no SLA measurement of order %s by %s", orderID, serviceID)
+	}
+
+	var measurement SLAMeasurement
+	if err := json.Unmarshal(measurementJSON, &measurement); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal SLA measurement: %v", err)
+	}
+	return &measurement, nil
+}
+
+// ============================================================================
+// SLA HELPERS
+// ============================================================================
+
+// ratingValue maps a measurement to a rating value, rounded to fixed point
+// so that every endorser rates the same value
+func (rule SLARule) ratingValue(measured float64) Fixed {
+	value := (measured - rule.Worst) / (rule.Best - rule.Worst)
+	return fixedFromFloat(math.Max(0, math.Min(1, value)))
+}
+
+// validateSLARules checks that every rule maps a named metric onto a
+// distinct best and worst value in a valid dimension
+func validateSLARules(config *SystemConfig) error {
+	for dimension, rule := range config.SLARules {
+		if !config.ValidDimensions[dimension] {
+			return fmt.Errorf("SLA rule for unknown dimension: %s", dimension)
+		}
+		if rule.Metric == "" {
+			return fmt.Errorf("SLA rule for %s needs a metric", dimension)
+		}
+		if rule.Best == rule.Worst || math.IsNaN(rule.Best) || math.IsNaN(rule.Worst) ||
+			math.IsInf(rule.Best, 0) || math.IsInf(rule.Worst, 0) {
+			return fmt.Errorf("SLA rule for %s needs distinct finite best and worst values", dimension)
+		}
+	}
+	return nil
+}
+
+// setMonitoringService registers or revokes a monitoring service
+func setMonitoringService(ctx contractapi.TransactionContextInterface, serviceID string, active bool) error {
+	normalizedAdminID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get admin ID: %v", err)
+	}
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	service := MonitoringService{
+		ServiceID:    serviceID,
+		Active:       active,
+		RegisteredBy: normalizedAdminID,
+		RegisteredAt: now,
+	}
+	serviceJSON, err := marshalCanonical(service)
+	if err != nil {
+		return fmt.Errorf("failed to marshal monitoring service: %v", err)
+	}
+	if err := ctx.GetStub().PutState(monitoringServiceKey(serviceID), serviceJSON); err != nil {
+		return fmt.Errorf("failed to store monitoring service: %v", err)
+	}
+
+	action := "registered"
+	if !active {
+		action = "revoked"
+	}
+	emitEvent(ctx, "MonitoringServiceUpdated", map[string]interface{}{
+		"serviceId": serviceID,
+		"action":    action,
+	}, monitoringServiceKey(serviceID))
+
+	return nil
+}
+
+// getMonitoringService loads a monitoring service, returning nil if it was
+// never registered
+func getMonitoringService(ctx contractapi.TransactionContextInterface, serviceID string) (*MonitoringService, error) {
+	serviceJSON, err := ctx.GetStub().GetState(monitoringServiceKey(serviceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read monitoring service: %v", err)
+	}
+	if serviceJSON == nil {
+		return nil, nil
+	}
+
+	var service MonitoringService
+	if err := json.Unmarshal(serviceJSON, &service); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal monitoring service: %v", err)
+	}
+	return &service, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// STAKE MOVEMENT EVENTS
+// ============================================================================
+
+// Stake movements between an actor's free balance and locked funds, emitted
+// so off-chain accounting can follow Locked as well as Balance:
+//
+//	StakeLocked     funds moved from balance to locked (dispute initiation or bond posted)
+//	StakeUnlocked   locked funds released unsettled (dispute withdrawn or expired)
+//	StakeRefunded   locked funds returned after settlement (dispute resolved)
+//	StakeReleased   free balance paid out to an actor leaving (offboarding completed)
+//
+// Slashes are taken from the free balance and from unbonding withdrawals,
+// and reported as StakeSlashed.
+const (
+	stakeLockedEvent   = "StakeLocked"
+	stakeUnlockedEvent = "StakeUnlocked"
+	stakeRefundedEvent = "StakeRefunded"
+	stakeReleasedEvent = "StakeReleased"
+)
+
+// emitStakeMovement emits one stake movement event. stake is the record
+// after the movement; disputeID names the dispute the funds are held for, ""
+// for funds not held for a dispute.
+func emitStakeMovement(
+	ctx contractapi.TransactionContextInterface,
+	eventType string,
+	stake *Stake,
+	amount float64,
+	disputeID string,
+) {
+	eventPayload := map[string]interface{}{
+		"actorId":   stake.ActorID,
+		"amount":    amount,
+		"balance":   stake.Balance,
+		"locked":    stake.Locked,
+		"disputeId": disputeID,
+	}
+	emitEvent(ctx, eventType, eventPayload, stakeStateKey(stake.ActorID))
+}
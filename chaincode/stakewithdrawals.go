@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// STAKE WITHDRAWALS
+// ============================================================================
+
+// An actor takes stake out in two steps. WithdrawStake moves the amount from
+// the free balance into Locked and opens a withdrawal that unbonds for
+// SystemConfig.UnbondingPeriod: the funds no longer count towards rating
+// eligibility and cannot back bonds or disputes, but stay on the ledger
+// while disputes of the actor's recent ratings can still be raised.
+// ClaimWithdrawal then releases them, once the period has passed and no
+// dispute of the actor's ratings is pending. A slash of the actor's stake
+// also takes its share of every withdrawal still unbonding.
+
+// Stake withdrawal statuses
+const (
+	stakeWithdrawalUnbonding = "unbonding"
+	stakeWithdrawalClaimed   = "claimed"
+)
+
+// stakeWithdrawalByActorIndex files unbonding withdrawals by their actor:
+// actorId~withdrawalId
+const stakeWithdrawalByActorIndex = "STAKE_WITHDRAWAL_BY_ACTOR"
+
+// StakeWithdrawal is an actor's request to take stake out
+type StakeWithdrawal struct {
+	WithdrawalID string  `json:"withdrawalId"`
+	ActorID      string  `json:"actorId"`
+	Amount       float64 `json:"amount"` // less any slashes while unbonding
+	Status       string  `json:"status"` // unbonding, claimed
+	RequestedAt  int64   `json:"requestedAt"`
+	AvailableAt  int64   `json:"availableAt"` // end of the unbonding period
+	ClaimedAt    int64   `json:"claimedAt"`
+}
+
+// WithdrawStake starts unbonding amount of the caller's free balance and
+// returns the withdrawal's ID
+func (sc *StakeContract) WithdrawStake(
+	ctx contractapi.TransactionContextInterface,
+	amountStr string,
+) (string, error) {
+	amount, err := strconv.ParseFloat(amountStr, 64)
+	if err != nil || amount <= 0 {
+		return "", fmt.Errorf("invalid amount: must be positive number")
+	}
+
+	normalizedID, err := callerIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get actor ID: %v", err)
+	}
+
+	banned, err := isBanned(ctx, normalizedID)
+	if err != nil {
+		return "", err
+	}
+	if banned {
+		return "", fmt.Errorf("actor is banned: %s", normalizedID)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	stake, err := getOrInitStake(ctx, normalizedID)
+	if err != nil {
+		return "", err
+	}
+	if stake.Balance < amount {
+		return "", fmt.Errorf("insufficient stake: have %f free, requested %f", stake.Balance, amount)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	stake.Balance -= amount
+	stake.Locked += amount
+	stake.UpdatedAt = now
+	if err := putStake(ctx, stake); err != nil {
+		return "", err
+	}
+
+	withdrawal := &StakeWithdrawal{
+		WithdrawalID: newStakeWithdrawalID(ctx.GetStub().GetTxID()),
+		ActorID:      normalizedID,
+		Amount:       amount,
+		Status:       stakeWithdrawalUnbonding,
+		RequestedAt:  now,
+		AvailableAt:  now + config.UnbondingPeriod,
+	}
+	if err := putStakeWithdrawal(ctx, withdrawal); err != nil {
+		return "", err
+	}
+	if err := putIndexEntry(ctx, stakeWithdrawalByActorIndex, stakeWithdrawalActorAttributes(withdrawal)); err != nil {
+		return "", err
+	}
+
+	eventPayload := map[string]interface{}{
+		"withdrawalId": withdrawal.WithdrawalID,
+		"actorId":      normalizedID,
+		"amount":       amount,
+		"balance":      stake.Balance,
+		"locked":       stake.Locked,
+		"availableAt":  withdrawal.AvailableAt,
+	}
+	emitEvent(ctx, "StakeWithdrawalRequested", eventPayload, withdrawal.WithdrawalID, stakeStateKey(normalizedID))
+
+	return withdrawal.WithdrawalID, nil
+}
+
+// ClaimWithdrawal releases an unbonded withdrawal's funds from the actor's
+// locked stake. Either the actor or an admin may call it.
+func (sc *StakeContract) ClaimWithdrawal(
+	ctx contractapi.TransactionContextInterface,
+	withdrawalID string,
+) (*StakeWithdrawal, error) {
+	withdrawal, err := getStakeWithdrawal(ctx, withdrawalID)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal == nil {
+		return nil, fmt.Errorf("stake withdrawal not found: %s", withdrawalID)
+	}
+
+	callerID, err := callerIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller ID: %v", err)
+	}
+	if callerID != withdrawal.ActorID && !isAdmin(ctx) {
+		return nil, fmt.Errorf("unauthorized: only the actor or an admin can claim a withdrawal")
+	}
+	if withdrawal.Status != stakeWithdrawalUnbonding {
+		return nil, fmt.Errorf("stake withdrawal already %s: %s", withdrawal.Status, withdrawalID)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if now < withdrawal.AvailableAt {
+		return nil, fmt.Errorf("stake unbonding until %d", withdrawal.AvailableAt)
+	}
+
+	pending, err := disputesWithStatus(ctx, "pending", func(dispute *Dispute) bool {
+		return dispute.RaterID == withdrawal.ActorID
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("disputes against the actor's ratings are still pending")
+	}
+
+	stake, err := getOrInitStake(ctx, withdrawal.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	if stake.Locked < withdrawal.Amount {
+		return nil, fmt.Errorf("locked stake %f does not cover withdrawal of %f", stake.Locked, withdrawal.Amount)
+	}
+
+	stake.Locked -= withdrawal.Amount
+	stake.UpdatedAt = now
+	if err := putStake(ctx, stake); err != nil {
+		return nil, err
+	}
+
+	withdrawal.Status = stakeWithdrawalClaimed
+	withdrawal.ClaimedAt = now
+	if err := putStakeWithdrawal(ctx, withdrawal); err != nil {
+		return nil, err
+	}
+	if err := delIndexEntry(ctx, stakeWithdrawalByActorIndex, stakeWithdrawalActorAttributes(withdrawal)); err != nil {
+		return nil, err
+	}
+
+	eventPayload := map[string]interface{}{
+		"withdrawalId": withdrawal.WithdrawalID,
+		"actorId":      withdrawal.ActorID,
+		"amount":       withdrawal.Amount,
+		"balance":      stake.Balance,
+		"locked":       stake.Locked,
+	}
+	emitEvent(ctx, "StakeWithdrawn", eventPayload, withdrawal.WithdrawalID, stakeStateKey(withdrawal.ActorID))
+
+	return withdrawal, nil
+}
+
+// GetStakeWithdrawal retrieves a stake withdrawal
+func (sc *StakeContract) GetStakeWithdrawal(
+	ctx contractapi.TransactionContextInterface,
+	withdrawalID string,
+) (*StakeWithdrawal, error) {
+	withdrawal, err := getStakeWithdrawal(ctx, withdrawalID)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal == nil {
+		return nil, fmt.Errorf("stake withdrawal not found: %s", withdrawalID)
+	}
+	return withdrawal, nil
+}
+
+// ============================================================================
+// STAKE WITHDRAWAL HELPERS
+// ============================================================================
+
+// getStakeWithdrawal loads a stake withdrawal, returning nil if it does not
+// exist
+func getStakeWithdrawal(ctx contractapi.TransactionContextInterface, withdrawalID string) (*StakeWithdrawal, error) {
+	if err := validateRecordID(withdrawalID, stakeWithdrawalIDPrefix); err != nil {
+		return nil, err
+	}
+
+	withdrawalJSON, err := ctx.GetStub().GetState(withdrawalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stake withdrawal: %v", err)
+	}
+	if withdrawalJSON == nil {
+		return nil, nil
+	}
+
+	var withdrawal StakeWithdrawal
+	if err := json.Unmarshal(withdrawalJSON, &withdrawal); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stake withdrawal: %v", err)
+	}
+	return &withdrawal, nil
+}
+
+// putStakeWithdrawal stores a stake withdrawal
+func putStakeWithdrawal(ctx contractapi.TransactionContextInterface, withdrawal *StakeWithdrawal) error {
+	withdrawalJSON, err := marshalCanonical(withdrawal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stake withdrawal: %v", err)
+	}
+	if err := ctx.GetStub().PutState(withdrawal.WithdrawalID, withdrawalJSON); err != nil {
+		return fmt.Errorf("failed to store stake withdrawal: %v", err)
+	}
+	return nil
+}
+
+// unbondingWithdrawals returns an actor's withdrawals still unbonding
+func unbondingWithdrawals(ctx contractapi.TransactionContextInterface, actorID string) ([]*StakeWithdrawal, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(stakeWithdrawalByActorIndex, []string{actorID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", stakeWithdrawalByActorIndex, err)
+	}
+	defer resultsIterator.Close()
+
+	var withdrawals []*StakeWithdrawal
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, parts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) == 0 {
+			continue
+		}
+		withdrawal, err := getStakeWithdrawal(ctx, parts[len(parts)-1])
+		if err != nil {
+			return nil, err
+		}
+		if withdrawal != nil && withdrawal.Status == stakeWithdrawalUnbonding {
+			withdrawals = append(withdrawals, withdrawal)
+		}
+	}
+	return withdrawals, nil
+}
+
+// stakeWithdrawalActorAttributes returns a withdrawal's
+// stakeWithdrawalByActorIndex key attributes
+func stakeWithdrawalActorAttributes(withdrawal *StakeWithdrawal) []string {
+	return []string{withdrawal.ActorID, withdrawal.WithdrawalID}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// DIMENSION STATISTICS
+// ============================================================================
+
+// statsBuckets is the number of equal-width score histogram buckets
+const statsBuckets = 10
+
+// DimensionStats aggregates the reputation records of one dimension. It is
+// updated whenever a reputation record is written, using scores as of each
+// actor's last update, and stored as shards (see aggregateShards).
+type DimensionStats struct {
+	Dimension   string `json:"dimension"`
+	ActorCount  int    `json:"actorCount"`
+	RatingCount int    `json:"ratingCount"`
+	ScoreSum    Fixed  `json:"scoreSumMicros"`
+	Histogram   []int  `json:"histogram"` // actors per score bucket [i/10, (i+1)/10)
+	UpdatedAt   int64  `json:"updatedAt"`
+}
+
+// DimensionStatsView is the GetDimensionStats result
+type DimensionStatsView struct {
+	Dimension   string  `json:"dimension"`
+	ActorCount  int     `json:"actorCount"`
+	RatingCount int     `json:"ratingCount"`
+	MeanScore   float64 `json:"meanScore"`
+	MedianScore float64 `json:"medianScore"` // interpolated from the histogram
+	Histogram   []int   `json:"histogram"`
+	UpdatedAt   int64   `json:"updatedAt"`
+}
+
+// GetDimensionStats returns actor and rating counts, mean and median score
+// and a score histogram for a dimension
+func (rc *ReputationContract) GetDimensionStats(
+	ctx contractapi.TransactionContextInterface,
+	dimension string,
+) (*DimensionStatsView, error) {
+	stats, err := getDimensionStats(ctx, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &DimensionStatsView{
+		Dimension:   dimension,
+		ActorCount:  stats.ActorCount,
+		RatingCount: stats.RatingCount,
+		Histogram:   stats.Histogram,
+		UpdatedAt:   stats.UpdatedAt,
+	}
+	if stats.ActorCount > 0 {
+		view.MeanScore = stats.ScoreSum.Float() / float64(stats.ActorCount)
+		view.MedianScore = histogramMedian(stats.Histogram, stats.ActorCount)
+	}
+
+	return view, nil
+}
+
+// DimensionRollup is the compact per-dimension summary of GetDimensionRollups
+type DimensionRollup struct {
+	Dimension   string  `json:"dimension"`
+	ActorCount  int     `json:"actorCount"`
+	RatingCount int     `json:"ratingCount"`
+	ScoreSum    float64 `json:"scoreSum"`
+	MeanScore   float64 `json:"meanScore"`
+}
+
+// GetDimensionRollups returns the maintained actor count, rating count and
+// score sum of every configured dimension and meta-dimension, one state
+// read per dimension
+func (rc *ReputationContract) GetDimensionRollups(
+	ctx contractapi.TransactionContextInterface,
+) ([]DimensionRollup, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := []string{}
+	for dimension := range config.ValidDimensions {
+		dimensions = append(dimensions, dimension)
+	}
+	for _, metaDimension := range config.MetaDimensions {
+		if !config.ValidDimensions[metaDimension] {
+			dimensions = append(dimensions, metaDimension)
+		}
+	}
+	sort.Strings(dimensions)
+
+	rollups := []DimensionRollup{}
+	for _, dimension := range dimensions {
+		stats, err := getDimensionStats(ctx, dimension)
+		if err != nil {
+			return nil, err
+		}
+
+		rollup := DimensionRollup{
+			Dimension:   dimension,
+			ActorCount:  stats.ActorCount,
+			RatingCount: stats.RatingCount,
+			ScoreSum:    stats.ScoreSum.Float(),
+		}
+		if stats.ActorCount > 0 {
+			rollup.MeanScore = stats.ScoreSum.Float() / float64(stats.ActorCount)
+		}
+		rollups = append(rollups, rollup)
+	}
+
+	return rollups, nil
+}
+
+// ============================================================================
+// STATISTICS HELPERS
+// ============================================================================
+
+// updateDimensionStats replaces the contribution of the previous version of
+// a reputation record (nil if new) with that of the current version, in this
+// transaction's shard
+func updateDimensionStats(
+	ctx contractapi.TransactionContextInterface,
+	previous *Reputation,
+	current *Reputation,
+) error {
+	stats, err := getDimensionStatsShard(ctx, current.Dimension)
+	if err != nil {
+		return err
+	}
+
+	if previous != nil {
+		score := scoreFixed(previous)
+		stats.ActorCount--
+		stats.RatingCount -= previous.TotalEvents
+		stats.ScoreSum -= score
+		stats.Histogram[scoreBucket(score.Float())]--
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	score := scoreFixed(current)
+	stats.ActorCount++
+	stats.RatingCount += current.TotalEvents
+	stats.ScoreSum += score
+	stats.Histogram[scoreBucket(score.Float())]++
+	stats.UpdatedAt = now
+
+	return putDimensionStats(ctx, stats)
+}
+
+// scoreBucket returns the histogram bucket of a score
+func scoreBucket(score float64) int {
+	bucket := int(score * statsBuckets)
+	if bucket < 0 {
+		return 0
+	}
+	if bucket >= statsBuckets {
+		return statsBuckets - 1
+	}
+	return bucket
+}
+
+// histogramMedian estimates the median score by linear interpolation within
+// the bucket holding the middle actor
+func histogramMedian(histogram []int, total int) float64 {
+	half := float64(total) / 2
+	seen := 0.0
+	for i, count := range histogram {
+		if count == 0 {
+			continue
+		}
+		if seen+float64(count) >= half {
+			within := (half - seen) / float64(count)
+			return (float64(i) + within) / statsBuckets
+		}
+		seen += float64(count)
+	}
+	return 0
+}
+
+// getDimensionStats returns a dimension's statistics, adding up its shards
+// with this transaction's own updates
+func getDimensionStats(ctx contractapi.TransactionContextInterface, dimension string) (*DimensionStats, error) {
+	stats := &DimensionStats{Dimension: dimension, Histogram: make([]int, statsBuckets)}
+
+	pending := pendingDimensionStats(ctx, dimension)
+	ownShard := aggregateShardKey(dimensionStatsKey(dimension), aggregateShard(ctx))
+	err := readAggregate(ctx, dimensionStatsKey(dimension), func(shardKey string, value []byte) error {
+		if pending != nil && shardKey == ownShard {
+			return nil
+		}
+		var share DimensionStats
+		if err := json.Unmarshal(value, &share); err != nil {
+			return fmt.Errorf("failed to unmarshal dimension stats: %v", err)
+		}
+		stats.add(&share)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		stats.add(pending)
+	}
+
+	// Sums carried over from float records can be off by rounding
+	if stats.ActorCount == 0 || stats.ScoreSum < 0 {
+		stats.ScoreSum = 0
+	}
+
+	return stats, nil
+}
+
+// add accumulates another share of the same dimension's statistics
+func (stats *DimensionStats) add(share *DimensionStats) {
+	stats.ActorCount += share.ActorCount
+	stats.RatingCount += share.RatingCount
+	stats.ScoreSum += share.ScoreSum
+	for i := 0; i < len(share.Histogram) && i < statsBuckets; i++ {
+		stats.Histogram[i] += share.Histogram[i]
+	}
+	if share.UpdatedAt > stats.UpdatedAt {
+		stats.UpdatedAt = share.UpdatedAt
+	}
+}
+
+// getDimensionStatsShard loads the share of a dimension's statistics this
+// transaction updates, preferring the copy it already updated
+func getDimensionStatsShard(ctx contractapi.TransactionContextInterface, dimension string) (*DimensionStats, error) {
+	if stats := pendingDimensionStats(ctx, dimension); stats != nil {
+		return stats, nil
+	}
+
+	shardKey := aggregateShardKey(dimensionStatsKey(dimension), aggregateShard(ctx))
+	statsJSON, err := readState(ctx, shardKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dimension stats: %v", err)
+	}
+
+	stats := &DimensionStats{Dimension: dimension}
+	if statsJSON != nil {
+		if err := json.Unmarshal(statsJSON, stats); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dimension stats: %v", err)
+		}
+	}
+	if len(stats.Histogram) != statsBuckets {
+		stats.Histogram = make([]int, statsBuckets)
+	}
+
+	return stats, nil
+}
+
+// putDimensionStats stores this transaction's share of a dimension's
+// statistics and remembers it for the rest of the transaction
+func putDimensionStats(ctx contractapi.TransactionContextInterface, stats *DimensionStats) error {
+	statsJSON, err := marshalCanonical(stats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dimension stats: %v", err)
+	}
+
+	shardKey := aggregateShardKey(dimensionStatsKey(stats.Dimension), aggregateShard(ctx))
+	if err := ctx.GetStub().PutState(shardKey, statsJSON); err != nil {
+		return fmt.Errorf("failed to store dimension stats: %v", err)
+	}
+
+	setPendingDimensionStats(ctx, stats)
+	return nil
+}
+
+// resetDimensionStats zeroes a dimension's statistics, clearing every shard,
+// ahead of a full RebuildIndexes pass
+func resetDimensionStats(ctx contractapi.TransactionContextInterface, dimension string) error {
+	for _, key := range aggregateKeys(dimensionStatsKey(dimension)) {
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return fmt.Errorf("failed to reset dimension stats: %v", err)
+		}
+	}
+
+	reset := &DimensionStats{Dimension: dimension, Histogram: make([]int, statsBuckets)}
+	return putDimensionStats(ctx, reset)
+}
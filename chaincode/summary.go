@@ -0,0 +1,158 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// ACTOR SUMMARY
+// ============================================================================
+
+// summaryRecentWindow is how far back GetActorSummary counts recent ratings
+const summaryRecentWindow = 30 * 86400 // 30 days in seconds
+
+// DimensionScore is one dimension's decayed score with its confidence interval
+type DimensionScore struct {
+	Score       float64 `json:"score"`
+	CILower     float64 `json:"ci_lower"`
+	CIUpper     float64 `json:"ci_upper"`
+	TotalEvents int     `json:"totalEvents"`
+	LastUpdated int64   `json:"lastUpdated"`
+}
+
+// ActorSummary collects everything a dashboard shows about an actor
+type ActorSummary struct {
+	ActorID               string                    `json:"actorId"`
+	Profile               *ActorProfile             `json:"profile"`
+	Scores                map[string]DimensionScore `json:"scores"`
+	Stake                 *Stake                    `json:"stake"`
+	OpenDisputes          []Dispute                 `json:"openDisputes"`
+	RecentRatingsGiven    int                       `json:"recentRatingsGiven"`
+	RecentRatingsReceived int                       `json:"recentRatingsReceived"`
+	Badges                []string                  `json:"badges"`
+	Suspended             bool                      `json:"suspended"`
+	Archived              bool                      `json:"archived"`
+}
+
+// GetActorSummary returns an actor's scores, stake, open disputes, recent
+// rating counts and badges in a single call
+func (rc *ReputationContract) GetActorSummary(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) (*ActorSummary, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalizedActorID := resolveIdentity(ctx, actorID)
+
+	summary := &ActorSummary{
+		ActorID: normalizedActorID,
+		Scores:  make(map[string]DimensionScore),
+	}
+
+	if summary.Profile, err = getOrInitProfile(ctx, normalizedActorID); err != nil {
+		return nil, err
+	}
+	if summary.Stake, err = getOrInitStake(ctx, normalizedActorID); err != nil {
+		return nil, err
+	}
+	if summary.Suspended, err = isBanned(ctx, normalizedActorID); err != nil {
+		return nil, err
+	}
+	if summary.Archived, err = isDeactivated(ctx, normalizedActorID); err != nil {
+		return nil, err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for dimension := range config.ValidDimensions {
+		rep, err := getOrInitReputation(ctx, normalizedActorID, dimension, config)
+		if err != nil {
+			return nil, err
+		}
+
+		effectiveRep := applyDynamicDecayAt(rep, config, now)
+		ci := calculateWilsonCI(effectiveRep.Alpha.Float(), effectiveRep.Beta.Float(), 0.95)
+
+		summary.Scores[dimension] = DimensionScore{
+			Score:       reputationScore(effectiveRep),
+			CILower:     ci[0],
+			CIUpper:     ci[1],
+			TotalEvents: rep.TotalEvents,
+			LastUpdated: rep.LastTs,
+		}
+	}
+
+	// Open disputes on either side of the actor's ratings
+	summary.OpenDisputes, err = disputesWithStatus(ctx, "pending", func(dispute *Dispute) bool {
+		return dispute.ActorID == normalizedActorID || dispute.RaterID == normalizedActorID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	since := now - summaryRecentWindow
+
+	// Inverted timestamps sit at position 1 (by rater) and 2 (by actor)
+	if summary.RecentRatingsGiven, err = countIndexedSince(ctx, ratingByRaterIndex, []string{normalizedActorID}, 1, since); err != nil {
+		return nil, err
+	}
+	if summary.RecentRatingsReceived, err = countIndexedSince(ctx, ratingByActorIndex, []string{normalizedActorID}, 2, since); err != nil {
+		return nil, err
+	}
+
+	summary.Badges = actorBadges(summary)
+
+	return summary, nil
+}
+
+// ============================================================================
+// SUMMARY HELPERS
+// ============================================================================
+
+// actorBadges derives display badges from a summary
+func actorBadges(summary *ActorSummary) []string {
+	badges := []string{}
+
+	if summary.Profile.Verified {
+		badges = append(badges, "verified")
+	}
+	if summary.Profile.ActorType != "" {
+		badges = append(badges, summary.Profile.ActorType)
+	}
+
+	var dimensions []string
+	rated := false
+	for dimension, score := range summary.Scores {
+		if score.TotalEvents > 0 {
+			rated = true
+		}
+		// Trusted means the lower CI bound alone clears the bar
+		if score.CILower >= 0.7 {
+			dimensions = append(dimensions, dimension)
+		}
+	}
+	sort.Strings(dimensions)
+	for _, dimension := range dimensions {
+		badges = append(badges, "trusted_"+dimension)
+	}
+
+	if !rated {
+		badges = append(badges, "newcomer")
+	}
+	if summary.Suspended {
+		badges = append(badges, "suspended")
+	}
+	if summary.Archived {
+		badges = append(badges, "archived")
+	}
+
+	return badges
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/v2/shim"
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// ============================================================================
+// TENANTS
+// ============================================================================
+
+// One deployment can host several independent reputation spaces, one per
+// marketplace. A transaction selects its tenant with the transient field
+// "tenant"; the caller's "tenant" attribute lists the tenants it belongs to,
+// comma-separated, and a caller with a single tenant may leave the field
+// out. Callers without the attribute use the default space and cannot select
+// a tenant. Within a tenant every state and private data key carries the
+// prefix "TENANT:<id>:" (after the leading 0x00 of composite keys), applied
+// by wrapping the stub before the transaction runs, so the config,
+// dimensions, admin and arbitrator lists and all records are per tenant
+// while the rest of the chaincode addresses keys as usual. The default
+// space keeps the unprefixed layout. The "admin" and "arbitrator"
+// certificate attributes only apply in the default space; within a tenant
+// a role comes from the tenant's role lists or from the "adminTenants" and
+// "arbitratorTenants" attributes naming it.
+
+const (
+	tenantTransientKey = "tenant"
+	tenantAttribute    = "tenant"
+	tenantKeyPrefix    = "TENANT:"
+	maxTenantIDLength  = 63
+
+	compositeKeyNamespace = "\x00"
+)
+
+// selectTenant is the before-transaction hook resolving the transaction's
+// tenant and scoping the stub to it
+func selectTenant(ctx contractapi.TransactionContextInterface) error {
+	rctx, ok := ctx.(*ReputationContext)
+	if !ok {
+		return nil
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to read transient data: %v", err)
+	}
+	tenant := string(transient[tenantTransientKey])
+
+	var memberships []string
+	if value, found, _ := ctx.GetClientIdentity().GetAttributeValue(tenantAttribute); found && value != "" {
+		for _, member := range strings.Split(value, ",") {
+			memberships = append(memberships, strings.TrimSpace(member))
+		}
+	}
+
+	if tenant == "" {
+		switch len(memberships) {
+		case 0:
+			return nil
+		case 1:
+			tenant = memberships[0]
+		default:
+			return fmt.Errorf("tenant required: caller belongs to %s", strings.Join(memberships, ", "))
+		}
+	}
+	if err := validateTenantID(tenant); err != nil {
+		return err
+	}
+	member := false
+	for _, membership := range memberships {
+		member = member || membership == tenant
+	}
+	if !member {
+		return fmt.Errorf("unauthorized: caller does not belong to tenant %s", tenant)
+	}
+
+	rctx.tenant = tenant
+	rctx.SetStub(&tenantStub{ChaincodeStubInterface: ctx.GetStub(), prefix: tenantKeyPrefix + tenant + ":"})
+	return nil
+}
+
+// currentTenant returns the transaction's tenant, "" for the default space
+func currentTenant(ctx contractapi.TransactionContextInterface) string {
+	if rctx, ok := ctx.(*ReputationContext); ok {
+		return rctx.tenant
+	}
+	return ""
+}
+
+// tenantRoleAttributeSuffix turns a role attribute name into the name of
+// the attribute listing the tenants the role is granted in, e.g.
+// "adminTenants"
+const tenantRoleAttributeSuffix = "Tenants"
+
+// roleAttributeGranted reports whether the caller's certificate grants a
+// role in the transaction's tenant. In the default space the role attribute
+// itself must be "true"; within a tenant only the role's tenant list
+// counts, so a role granted by certificate in one space never carries over
+// to another.
+func roleAttributeGranted(ctx contractapi.TransactionContextInterface, role string) bool {
+	tenant := currentTenant(ctx)
+	if tenant == "" {
+		value, found, _ := ctx.GetClientIdentity().GetAttributeValue(role)
+		return found && value == "true"
+	}
+
+	value, found, _ := ctx.GetClientIdentity().GetAttributeValue(role + tenantRoleAttributeSuffix)
+	if !found {
+		return false
+	}
+	for _, granted := range strings.Split(value, ",") {
+		if strings.TrimSpace(granted) == tenant {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTenantID accepts 1 to maxTenantIDLength lowercase letters, digits
+// and hyphens, starting with a letter or digit
+func validateTenantID(tenant string) error {
+	if tenant == "" || len(tenant) > maxTenantIDLength || tenant[0] == '-' {
+		return fmt.Errorf("invalid tenant: %q", tenant)
+	}
+	for _, r := range tenant {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return fmt.Errorf("invalid tenant: %q", tenant)
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// TENANT STUB
+// ============================================================================
+
+// tenantStub scopes a stub to one tenant's keys. Keys passed in are
+// prefixed and keys handed back, including bookmarks, are stripped, so the
+// chaincode sees the tenant's records under their usual keys and cannot
+// address another tenant's. Rich queries cannot be scoped and are refused.
+type tenantStub struct {
+	shim.ChaincodeStubInterface
+	prefix string // "TENANT:<id>:"
+}
+
+// key maps a chaincode key to the tenant's ledger key
+func (s *tenantStub) key(key string) string {
+	if strings.HasPrefix(key, compositeKeyNamespace) {
+		return compositeKeyNamespace + s.prefix + key[len(compositeKeyNamespace):]
+	}
+	return s.prefix + key
+}
+
+// unkey maps a ledger key of the tenant back to the chaincode key
+func (s *tenantStub) unkey(key string) string {
+	if strings.HasPrefix(key, compositeKeyNamespace) {
+		return compositeKeyNamespace + strings.TrimPrefix(key[len(compositeKeyNamespace):], s.prefix)
+	}
+	return strings.TrimPrefix(key, s.prefix)
+}
+
+// rangeKeys maps the bounds of a range query, where "" leaves a bound open
+func (s *tenantStub) rangeKeys(startKey string, endKey string) (string, string) {
+	if endKey == "" {
+		return s.key(startKey), s.prefix[:len(s.prefix)-1] + ";"
+	}
+	return s.key(startKey), s.key(endKey)
+}
+
+// bookmark maps a bookmark passed in; "" starts at the first page
+func (s *tenantStub) bookmark(bookmark string) string {
+	if bookmark == "" {
+		return ""
+	}
+	return s.key(bookmark)
+}
+
+// unbookmark maps a returned bookmark back
+func (s *tenantStub) unbookmark(metadata *peer.QueryResponseMetadata) *peer.QueryResponseMetadata {
+	if metadata != nil && metadata.Bookmark != "" {
+		metadata.Bookmark = s.unkey(metadata.Bookmark)
+	}
+	return metadata
+}
+
+func (s *tenantStub) GetState(key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetState(s.key(key))
+}
+
+// GetMultipleStates reads several tenant keys, in one request where the
+// underlying shim supports it (see multiStateReader)
+func (s *tenantStub) GetMultipleStates(keys ...string) ([][]byte, error) {
+	mapped := make([]string, len(keys))
+	for i, key := range keys {
+		mapped[i] = s.key(key)
+	}
+	if reader, batched := s.ChaincodeStubInterface.(multiStateReader); batched {
+		return reader.GetMultipleStates(mapped...)
+	}
+
+	values := make([][]byte, len(mapped))
+	for i, key := range mapped {
+		value, err := s.ChaincodeStubInterface.GetState(key)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (s *tenantStub) PutState(key string, value []byte) error {
+	return s.ChaincodeStubInterface.PutState(s.key(key), value)
+}
+
+func (s *tenantStub) DelState(key string) error {
+	return s.ChaincodeStubInterface.DelState(s.key(key))
+}
+
+func (s *tenantStub) SetStateValidationParameter(key string, ep []byte) error {
+	return s.ChaincodeStubInterface.SetStateValidationParameter(s.key(key), ep)
+}
+
+func (s *tenantStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetStateValidationParameter(s.key(key))
+}
+
+func (s *tenantStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey = s.rangeKeys(startKey, endKey)
+	iterator, err := s.ChaincodeStubInterface.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantIterator{StateQueryIteratorInterface: iterator, stub: s}, nil
+}
+
+func (s *tenantStub) GetStateByRangeWithPagination(
+	startKey, endKey string,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	startKey, endKey = s.rangeKeys(startKey, endKey)
+	iterator, metadata, err := s.ChaincodeStubInterface.GetStateByRangeWithPagination(startKey, endKey, pageSize, s.bookmark(bookmark))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tenantIterator{StateQueryIteratorInterface: iterator, stub: s}, s.unbookmark(metadata), nil
+}
+
+func (s *tenantStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	iterator, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKey(s.prefix+objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantIterator{StateQueryIteratorInterface: iterator, stub: s}, nil
+}
+
+func (s *tenantStub) GetStateByPartialCompositeKeyWithPagination(
+	objectType string,
+	keys []string,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	iterator, metadata, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKeyWithPagination(
+		s.prefix+objectType, keys, pageSize, s.bookmark(bookmark))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tenantIterator{StateQueryIteratorInterface: iterator, stub: s}, s.unbookmark(metadata), nil
+}
+
+func (s *tenantStub) GetAllStatesCompositeKeyWithPagination(
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return nil, nil, fmt.Errorf("GetAllStatesCompositeKeyWithPagination is not supported within a tenant")
+}
+
+func (s *tenantStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, fmt.Errorf("rich queries are not supported within a tenant")
+}
+
+func (s *tenantStub) GetQueryResultWithPagination(
+	query string,
+	pageSize int32,
+	bookmark string,
+) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return nil, nil, fmt.Errorf("rich queries are not supported within a tenant")
+}
+
+func (s *tenantStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return s.ChaincodeStubInterface.GetHistoryForKey(s.key(key))
+}
+
+func (s *tenantStub) GetPrivateData(collection, key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetPrivateData(collection, s.key(key))
+}
+
+func (s *tenantStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetPrivateDataHash(collection, s.key(key))
+}
+
+func (s *tenantStub) PutPrivateData(collection string, key string, value []byte) error {
+	return s.ChaincodeStubInterface.PutPrivateData(collection, s.key(key), value)
+}
+
+func (s *tenantStub) DelPrivateData(collection, key string) error {
+	return s.ChaincodeStubInterface.DelPrivateData(collection, s.key(key))
+}
+
+func (s *tenantStub) PurgePrivateData(collection, key string) error {
+	return s.ChaincodeStubInterface.PurgePrivateData(collection, s.key(key))
+}
+
+func (s *tenantStub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	return s.ChaincodeStubInterface.SetPrivateDataValidationParameter(collection, s.key(key), ep)
+}
+
+func (s *tenantStub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	return s.ChaincodeStubInterface.GetPrivateDataValidationParameter(collection, s.key(key))
+}
+
+func (s *tenantStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey = s.rangeKeys(startKey, endKey)
+	iterator, err := s.ChaincodeStubInterface.GetPrivateDataByRange(collection, startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantIterator{StateQueryIteratorInterface: iterator, stub: s}, nil
+}
+
+func (s *tenantStub) GetPrivateDataByPartialCompositeKey(
+	collection, objectType string,
+	keys []string,
+) (shim.StateQueryIteratorInterface, error) {
+	iterator, err := s.ChaincodeStubInterface.GetPrivateDataByPartialCompositeKey(collection, s.prefix+objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantIterator{StateQueryIteratorInterface: iterator, stub: s}, nil
+}
+
+func (s *tenantStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, fmt.Errorf("rich queries are not supported within a tenant")
+}
+
+// tenantIterator strips the tenant prefix from the keys of query results
+type tenantIterator struct {
+	shim.StateQueryIteratorInterface
+	stub *tenantStub
+}
+
+func (it *tenantIterator) Next() (*queryresult.KV, error) {
+	kv, err := it.StateQueryIteratorInterface.Next()
+	if err != nil || kv == nil {
+		return kv, err
+	}
+	return &queryresult.KV{Namespace: kv.Namespace, Key: it.stub.unkey(kv.Key), Value: kv.Value}, nil
+}
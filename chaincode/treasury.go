@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// TREASURY DATA STRUCTURES
+// ============================================================================
+
+// Treasury holds the pooled funds collected from slashing. It is stored as
+// shards (see aggregateShards); the balance is only checked against their
+// sum.
+type Treasury struct {
+	Balance      float64 `json:"balance"`
+	TotalInflow  float64 `json:"totalInflow"`
+	TotalOutflow float64 `json:"totalOutflow"`
+	UpdatedAt    int64   `json:"updatedAt"`
+}
+
+// TreasuryEntry records a single movement of treasury funds
+type TreasuryEntry struct {
+	EntryID     string  `json:"entryId"`
+	Direction   string  `json:"direction"` // inflow, outflow
+	Amount      float64 `json:"amount"`
+	Counterpart string  `json:"counterpart"` // source for inflows, destination for outflows
+	Purpose     string  `json:"purpose"`
+	Reference   string  `json:"reference"` // withdrawal ID or originating record
+	Timestamp   int64   `json:"timestamp"`
+	TxID        string  `json:"txId"`
+}
+
+// TreasuryWithdrawal is a proposed outflow awaiting admin approvals
+type TreasuryWithdrawal struct {
+	WithdrawalID string          `json:"withdrawalId"`
+	Destination  string          `json:"destination"`
+	Amount       float64         `json:"amount"`
+	Purpose      string          `json:"purpose"`
+	ProposerID   string          `json:"proposerId"`
+	Approvals    map[string]bool `json:"approvals"`
+	Status       string          `json:"status"` // pending, executed, cancelled
+	CreatedAt    int64           `json:"createdAt"`
+	ExecutedAt   int64           `json:"executedAt"`
+}
+
+// TreasuryView is the GetTreasury response
+type TreasuryView struct {
+	Treasury *Treasury       `json:"treasury"`
+	History  []TreasuryEntry `json:"history"`
+}
+
+// ============================================================================
+// TREASURY FUNCTIONS
+// ============================================================================
+
+// ProposeTreasuryWithdrawal opens a withdrawal that executes once enough
+// distinct admins have approved it. The proposer counts as the first approval.
+func (gc *GovernanceContract) ProposeTreasuryWithdrawal(
+	ctx contractapi.TransactionContextInterface,
+	destination string,
+	amountStr string,
+	purpose string,
+) (string, error) {
+	if !isAdmin(ctx) {
+		return "", fmt.Errorf("unauthorized: admin role required")
+	}
+
+	amount, err := strconv.ParseFloat(amountStr, 64)
+	if err != nil || amount <= 0 {
+		return "", fmt.Errorf("invalid amount: must be positive number")
+	}
+
+	normalizedProposerID, err := callerIdentity(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get proposer ID: %v", err)
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	withdrawalID := newWithdrawalID(ctx.GetStub().GetTxID())
+	withdrawal := TreasuryWithdrawal{
+		WithdrawalID: withdrawalID,
+		Destination:  resolveIdentity(ctx, destination),
+		Amount:       amount,
+		Purpose:      purpose,
+		ProposerID:   normalizedProposerID,
+		Approvals:    map[string]bool{normalizedProposerID: true},
+		Status:       "pending",
+		CreatedAt:    now,
+	}
+
+	if err := maybeExecuteWithdrawal(ctx, &withdrawal); err != nil {
+		return "", err
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"withdrawalId": withdrawalID,
+		"destination":  withdrawal.Destination,
+		"amount":       amount,
+		"purpose":      purpose,
+		"proposerId":   normalizedProposerID,
+	}
+	emitEvent(ctx, "TreasuryWithdrawalProposed", eventPayload, withdrawalID)
+
+	return withdrawalID, nil
+}
+
+// ApproveTreasuryWithdrawal records an admin approval and executes the
+// withdrawal when the configured threshold is reached
+func (gc *GovernanceContract) ApproveTreasuryWithdrawal(
+	ctx contractapi.TransactionContextInterface,
+	withdrawalID string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	withdrawal, err := getTreasuryWithdrawal(ctx, withdrawalID)
+	if err != nil {
+		return err
+	}
+	if withdrawal.Status != "pending" {
+		return fmt.Errorf("withdrawal is not pending: %s", withdrawal.Status)
+	}
+
+	normalizedApproverID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get approver ID: %v", err)
+	}
+
+	if withdrawal.Approvals[normalizedApproverID] {
+		return fmt.Errorf("withdrawal already approved by %s", normalizedApproverID)
+	}
+	withdrawal.Approvals[normalizedApproverID] = true
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"withdrawalId": withdrawalID,
+		"approverId":   normalizedApproverID,
+		"approvals":    len(withdrawal.Approvals),
+	}
+	emitEvent(ctx, "TreasuryWithdrawalApproved", eventPayload, withdrawalID)
+
+	return maybeExecuteWithdrawal(ctx, withdrawal)
+}
+
+// CancelTreasuryWithdrawal withdraws a pending proposal
+func (gc *GovernanceContract) CancelTreasuryWithdrawal(
+	ctx contractapi.TransactionContextInterface,
+	withdrawalID string,
+) error {
+	if !isAdmin(ctx) {
+		return fmt.Errorf("unauthorized: admin role required")
+	}
+
+	withdrawal, err := getTreasuryWithdrawal(ctx, withdrawalID)
+	if err != nil {
+		return err
+	}
+	if withdrawal.Status != "pending" {
+		return fmt.Errorf("withdrawal is not pending: %s", withdrawal.Status)
+	}
+
+	withdrawal.Status = "cancelled"
+
+	withdrawalJSON, err := marshalCanonical(withdrawal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal withdrawal: %v", err)
+	}
+	err = ctx.GetStub().PutState(withdrawalID, withdrawalJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store withdrawal: %v", err)
+	}
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"withdrawalId": withdrawalID,
+	}
+	emitEvent(ctx, "TreasuryWithdrawalCancelled", eventPayload, withdrawalID)
+
+	return nil
+}
+
+// GetTreasury returns the treasury balance and its inflow/outflow history
+func (gc *GovernanceContract) GetTreasury(
+	ctx contractapi.TransactionContextInterface,
+) (*TreasuryView, error) {
+	treasury, err := getTreasury(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("TREASURY_ENTRY:", "TREASURY_ENTRY:~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read treasury history: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	var history []TreasuryEntry
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry TreasuryEntry
+		if err := json.Unmarshal(queryResponse.Value, &entry); err != nil {
+			continue
+		}
+		history = append(history, entry)
+	}
+
+	return &TreasuryView{Treasury: treasury, History: history}, nil
+}
+
+// GetTreasuryWithdrawal retrieves a specific withdrawal proposal
+func (gc *GovernanceContract) GetTreasuryWithdrawal(
+	ctx contractapi.TransactionContextInterface,
+	withdrawalID string,
+) (*TreasuryWithdrawal, error) {
+	return getTreasuryWithdrawal(ctx, withdrawalID)
+}
+
+// ============================================================================
+// TREASURY HELPERS
+// ============================================================================
+
+// maybeExecuteWithdrawal pays out the withdrawal if it has enough approvals,
+// then stores it in its resulting state
+func maybeExecuteWithdrawal(
+	ctx contractapi.TransactionContextInterface,
+	withdrawal *TreasuryWithdrawal,
+) error {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(withdrawal.Approvals) >= config.TreasuryApprovals {
+		err = recordTreasuryOutflow(ctx, withdrawal.Amount, withdrawal.Destination, withdrawal.Purpose, withdrawal.WithdrawalID)
+		if err != nil {
+			return err
+		}
+
+		// Credit the destination's stake balance
+		stake, err := getOrInitStake(ctx, withdrawal.Destination)
+		if err != nil {
+			return err
+		}
+		stake.Balance += withdrawal.Amount
+		stake.UpdatedAt = now
+
+		stakeKey := stakeStateKey(withdrawal.Destination)
+		stakeJSON, err := marshalCanonical(stake)
+		if err != nil {
+			return fmt.Errorf("failed to marshal stake: %v", err)
+		}
+		err = ctx.GetStub().PutState(stakeKey, stakeJSON)
+		if err != nil {
+			return fmt.Errorf("failed to store stake: %v", err)
+		}
+
+		withdrawal.Status = "executed"
+		withdrawal.ExecutedAt = now
+
+		// Emit event
+		eventPayload := map[string]interface{}{
+			"withdrawalId": withdrawal.WithdrawalID,
+			"destination":  withdrawal.Destination,
+			"amount":       withdrawal.Amount,
+			"purpose":      withdrawal.Purpose,
+		}
+		emitEvent(ctx, "TreasuryWithdrawalExecuted", eventPayload, withdrawal.WithdrawalID)
+	}
+
+	withdrawalJSON, err := marshalCanonical(withdrawal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal withdrawal: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(withdrawal.WithdrawalID, withdrawalJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store withdrawal: %v", err)
+	}
+
+	return protectKey(ctx, withdrawal.WithdrawalID)
+}
+
+// recordTreasuryInflow credits the treasury and appends a history entry
+func recordTreasuryInflow(
+	ctx contractapi.TransactionContextInterface,
+	amount float64,
+	source string,
+	purpose string,
+	reference string,
+) error {
+	treasury, err := getTreasuryShard(ctx)
+	if err != nil {
+		return err
+	}
+
+	treasury.Balance += amount
+	treasury.TotalInflow += amount
+
+	return putTreasury(ctx, treasury, "inflow", amount, source, purpose, reference)
+}
+
+// recordTreasuryOutflow debits the treasury and appends a history entry
+func recordTreasuryOutflow(
+	ctx contractapi.TransactionContextInterface,
+	amount float64,
+	destination string,
+	purpose string,
+	reference string,
+) error {
+	total, err := getTreasury(ctx)
+	if err != nil {
+		return err
+	}
+	if total.Balance < amount {
+		return fmt.Errorf("insufficient treasury balance: have %f, require %f", total.Balance, amount)
+	}
+
+	treasury, err := getTreasuryShard(ctx)
+	if err != nil {
+		return err
+	}
+
+	treasury.Balance -= amount
+	treasury.TotalOutflow += amount
+
+	return putTreasury(ctx, treasury, "outflow", amount, destination, purpose, reference)
+}
+
+// putTreasury stores this transaction's treasury shard together with its
+// history entry
+func putTreasury(
+	ctx contractapi.TransactionContextInterface,
+	treasury *Treasury,
+	direction string,
+	amount float64,
+	counterpart string,
+	purpose string,
+	reference string,
+) error {
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	treasury.UpdatedAt = now
+
+	treasuryJSON, err := marshalCanonical(treasury)
+	if err != nil {
+		return fmt.Errorf("failed to marshal treasury: %v", err)
+	}
+
+	shardKey := aggregateShardKey(treasuryKey, aggregateShard(ctx))
+	err = ctx.GetStub().PutState(shardKey, treasuryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store treasury: %v", err)
+	}
+	if err := protectKey(ctx, shardKey); err != nil {
+		return err
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	entryID := treasuryEntryKey(now, txID, direction)
+	entry := TreasuryEntry{
+		EntryID:     entryID,
+		Direction:   direction,
+		Amount:      amount,
+		Counterpart: counterpart,
+		Purpose:     purpose,
+		Reference:   reference,
+		Timestamp:   now,
+		TxID:        txID,
+	}
+
+	entryJSON, err := marshalCanonical(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal treasury entry: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(entryID, entryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store treasury entry: %v", err)
+	}
+
+	return protectKey(ctx, entryID)
+}
+
+// getTreasury returns the treasury totals, adding up its shards
+func getTreasury(ctx contractapi.TransactionContextInterface) (*Treasury, error) {
+	treasury := &Treasury{}
+	err := readAggregate(ctx, treasuryKey, func(shardKey string, value []byte) error {
+		var share Treasury
+		if err := json.Unmarshal(value, &share); err != nil {
+			return fmt.Errorf("failed to unmarshal treasury: %v", err)
+		}
+		treasury.Balance += share.Balance
+		treasury.TotalInflow += share.TotalInflow
+		treasury.TotalOutflow += share.TotalOutflow
+		if share.UpdatedAt > treasury.UpdatedAt {
+			treasury.UpdatedAt = share.UpdatedAt
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return treasury, nil
+}
+
+// getTreasuryShard loads or initializes the treasury shard this transaction
+// updates
+func getTreasuryShard(ctx contractapi.TransactionContextInterface) (*Treasury, error) {
+	treasuryJSON, err := readState(ctx, aggregateShardKey(treasuryKey, aggregateShard(ctx)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read treasury: %v", err)
+	}
+
+	if treasuryJSON == nil {
+		now, err := txUnixTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Treasury{UpdatedAt: now}, nil
+	}
+
+	var treasury Treasury
+	if err := json.Unmarshal(treasuryJSON, &treasury); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal treasury: %v", err)
+	}
+
+	return &treasury, nil
+}
+
+// getTreasuryWithdrawal loads a withdrawal proposal
+func getTreasuryWithdrawal(
+	ctx contractapi.TransactionContextInterface,
+	withdrawalID string,
+) (*TreasuryWithdrawal, error) {
+	if err := validateRecordID(withdrawalID, withdrawalIDPrefix); err != nil {
+		return nil, err
+	}
+
+	withdrawalJSON, err := ctx.GetStub().GetState(withdrawalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read withdrawal: %v", err)
+	}
+	if withdrawalJSON == nil {
+		return nil, fmt.Errorf("withdrawal not found: %s", withdrawalID)
+	}
+
+	var withdrawal TreasuryWithdrawal
+	if err := json.Unmarshal(withdrawalJSON, &withdrawal); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal withdrawal: %v", err)
+	}
+	if withdrawal.Approvals == nil {
+		withdrawal.Approvals = make(map[string]bool)
+	}
+
+	return &withdrawal, nil
+}
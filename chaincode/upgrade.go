@@ -0,0 +1,396 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// STATE SCHEMA MIGRATION
+// ============================================================================
+
+// The layout of stored records is versioned by STATE_VERSION. Ledgers
+// initialized before the version was recorded are at version 1; InitConfig
+// records currentStateVersion for new ones. When an upgrade changes key
+// formats or record schemas, a migration from the previous version is added
+// to stateMigrations and currentStateVersion is bumped. Operators then call
+// UpgradeState repeatedly after installing the new chaincode until the
+// state reaches the current version.
+const (
+	stateVersionKey   = "STATE_VERSION"
+	stateMigrationKey = "STATE_MIGRATION" // progress cursor of the running migration
+
+	currentStateVersion = 4
+)
+
+// stateMigrationStep rewrites the records under one key prefix
+type stateMigrationStep struct {
+	prefix  string
+	migrate func(ctx contractapi.TransactionContextInterface, key string, value []byte) (bool, error)
+}
+
+// stateMigrations lists, by the version they upgrade from, the steps that
+// bring the state to the next version
+var stateMigrations = map[int][]stateMigrationStep{
+	// 1 -> 2: stake, reputation and rater/actor pair records of
+	// MSP-qualified actors move to their namespaced keys
+	1: {
+		{"STAKE:", migrateStakeKey},
+		{"REPUTATION:", migrateReputationKey},
+		{"RATER_ACTOR:", migrateRaterActorKey},
+	},
+	// 2 -> 3: ratings written before rating sources record one
+	2: {
+		{"RATING:", migrateRatingSource},
+	},
+	// 3 -> 4: reputation parameters and dimension score sums move from
+	// floats to fixed point
+	3: {
+		{"REPUTATION:", migrateReputationFixedPoint},
+		{"DIMENSION_STATS:", migrateDimensionStatsFixedPoint},
+	},
+}
+
+// StateMigration is the progress cursor of a migration between versions
+type StateMigration struct {
+	FromVersion int    `json:"fromVersion"`
+	Step        int    `json:"step"`     // index into the version's steps
+	Bookmark    string `json:"bookmark"` // position within the step's prefix
+	Migrated    int    `json:"migrated"` // records rewritten so far
+}
+
+// StateUpgradeProgress reports one batch of UpgradeState
+type StateUpgradeProgress struct {
+	FromVersion    int  `json:"fromVersion"`
+	StateVersion   int  `json:"stateVersion"` // version after this batch
+	CurrentVersion int  `json:"currentVersion"`
+	Step           int  `json:"step"`
+	Steps          int  `json:"steps"`
+	Scanned        int  `json:"scanned"`  // records read in this batch
+	Migrated       int  `json:"migrated"` // records rewritten in this migration so far
+	Done           bool `json:"done"`     // the state is at currentVersion
+}
+
+// UpgradeState migrates one batch of up to pageSize records from fromVersion
+// to the next version. The position is kept in state, so the call is simply
+// repeated, with fromVersion set to the reported stateVersion, until done is
+// true. Each batch is a separate transaction; a batch that fails to commit
+// is retried from the same position.
+func (gc *GovernanceContract) UpgradeState(
+	ctx contractapi.TransactionContextInterface,
+	fromVersion int,
+	pageSize int,
+) (*StateUpgradeProgress, error) {
+	if !isAdmin(ctx) {
+		return nil, fmt.Errorf("unauthorized: admin role required")
+	}
+	pageSize, err := resolvePageSize(ctx, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := getStateVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fromVersion != version {
+		return nil, fmt.Errorf("state is at version %d, not %d", version, fromVersion)
+	}
+	if version >= currentStateVersion {
+		return &StateUpgradeProgress{
+			FromVersion:    version,
+			StateVersion:   version,
+			CurrentVersion: currentStateVersion,
+			Done:           true,
+		}, nil
+	}
+
+	steps := stateMigrations[version]
+	cursor, err := getStateMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil || cursor.FromVersion != version {
+		cursor = &StateMigration{FromVersion: version}
+	}
+
+	progress := &StateUpgradeProgress{
+		FromVersion:    version,
+		StateVersion:   version,
+		CurrentVersion: currentStateVersion,
+		Step:           cursor.Step,
+		Steps:          len(steps),
+	}
+
+	if cursor.Step < len(steps) {
+		step := steps[cursor.Step]
+		resultsIterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(
+			step.prefix, step.prefix[:len(step.prefix)-1]+";", int32(pageSize), cursor.Bookmark)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s records: %v", step.prefix, err)
+		}
+		defer resultsIterator.Close()
+
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				return nil, err
+			}
+			progress.Scanned++
+
+			migrated, err := step.migrate(ctx, queryResponse.Key, queryResponse.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to migrate %s: %v", queryResponse.Key, err)
+			}
+			if migrated {
+				cursor.Migrated++
+			}
+		}
+
+		if int(metadata.FetchedRecordsCount) == pageSize {
+			cursor.Bookmark = metadata.Bookmark
+		} else {
+			cursor.Step++
+			cursor.Bookmark = ""
+		}
+	}
+
+	progress.Migrated = cursor.Migrated
+
+	if cursor.Step < len(steps) {
+		progress.Step = cursor.Step
+		if err := putStateMigration(ctx, cursor); err != nil {
+			return nil, err
+		}
+		return progress, nil
+	}
+
+	// Every step is complete
+	if err := ctx.GetStub().DelState(stateMigrationKey); err != nil {
+		return nil, fmt.Errorf("failed to clear migration cursor: %v", err)
+	}
+	if err := putStateVersion(ctx, version+1); err != nil {
+		return nil, err
+	}
+
+	progress.Step = len(steps)
+	progress.StateVersion = version + 1
+	progress.Done = progress.StateVersion >= currentStateVersion
+
+	// Emit event
+	eventPayload := map[string]interface{}{
+		"fromVersion": version,
+		"toVersion":   version + 1,
+		"migrated":    cursor.Migrated,
+	}
+	emitEvent(ctx, "StateUpgraded", eventPayload)
+
+	return progress, nil
+}
+
+// ============================================================================
+// MIGRATION STEPS
+// ============================================================================
+
+// moveRecord stores value under newKey and deletes oldKey. A record already
+// present under newKey was written after namespacing and is newer, so the
+// old record is dropped instead.
+func moveRecord(ctx contractapi.TransactionContextInterface, oldKey string, newKey string, value []byte) error {
+	existing, err := ctx.GetStub().GetState(newKey)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", newKey, err)
+	}
+	if existing == nil {
+		if err := ctx.GetStub().PutState(newKey, value); err != nil {
+			return fmt.Errorf("failed to store %s: %v", newKey, err)
+		}
+	}
+	if err := ctx.GetStub().DelState(oldKey); err != nil {
+		return fmt.Errorf("failed to delete %s: %v", oldKey, err)
+	}
+	return nil
+}
+
+// migrateStakeKey moves a stake record to its namespaced key
+func migrateStakeKey(ctx contractapi.TransactionContextInterface, key string, value []byte) (bool, error) {
+	var stake Stake
+	if err := json.Unmarshal(value, &stake); err != nil || stake.ActorID == "" {
+		return false, nil
+	}
+	newKey := stakeStateKey(stake.ActorID)
+	if newKey == key {
+		return false, nil
+	}
+	return true, moveRecord(ctx, key, newKey, value)
+}
+
+// migrateReputationKey moves a reputation record to its namespaced key
+func migrateReputationKey(ctx contractapi.TransactionContextInterface, key string, value []byte) (bool, error) {
+	var rep Reputation
+	if err := json.Unmarshal(value, &rep); err != nil || rep.ActorID == "" {
+		return false, nil
+	}
+	newKey := reputationStateKey(rep.ActorID, rep.Dimension)
+	if newKey == key {
+		return false, nil
+	}
+	return true, moveRecord(ctx, key, newKey, value)
+}
+
+// migrateRaterActorKey moves a rater/actor pair record to the key
+// namespaced by its rater
+func migrateRaterActorKey(ctx contractapi.TransactionContextInterface, key string, value []byte) (bool, error) {
+	var pair struct {
+		RaterID   string `json:"raterId"`
+		ActorID   string `json:"actorId"`
+		Dimension string `json:"dimension"`
+	}
+	if err := json.Unmarshal(value, &pair); err != nil || pair.RaterID == "" {
+		return false, nil
+	}
+	newKey := raterActorStateKey(pair.RaterID, pair.ActorID, pair.Dimension)
+	if newKey == key {
+		return false, nil
+	}
+	return true, moveRecord(ctx, key, newKey, value)
+}
+
+// migrateRatingSource marks ratings without a source as human-submitted,
+// the only kind that existed before service accounts
+func migrateRatingSource(ctx contractapi.TransactionContextInterface, key string, value []byte) (bool, error) {
+	var rating Rating
+	if err := json.Unmarshal(value, &rating); err != nil || rating.RatingID == "" || rating.Source != "" {
+		return false, nil
+	}
+
+	rating.Source = ratingSourceHuman
+	ratingJSON, err := marshalCanonical(rating)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal rating: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, ratingJSON); err != nil {
+		return false, fmt.Errorf("failed to store rating: %v", err)
+	}
+	return true, nil
+}
+
+// migrateReputationFixedPoint rewrites a reputation record stored with float
+// alpha and beta in fixed point. Rounding can shift the posterior mean in its
+// last digits, so the leaderboard entry is re-keyed as well.
+func migrateReputationFixedPoint(ctx contractapi.TransactionContextInterface, key string, value []byte) (bool, error) {
+	var legacy struct {
+		Alpha *float64 `json:"alpha"`
+		Beta  *float64 `json:"beta"`
+	}
+	if err := json.Unmarshal(value, &legacy); err != nil || legacy.Alpha == nil || legacy.Beta == nil {
+		return false, nil
+	}
+	var rep Reputation
+	if err := json.Unmarshal(value, &rep); err != nil || rep.ActorID == "" {
+		return false, nil
+	}
+
+	repJSON, err := marshalCanonical(rep)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal reputation: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, repJSON); err != nil {
+		return false, fmt.Errorf("failed to store reputation: %v", err)
+	}
+
+	oldRank := scoreRank(*legacy.Alpha / (*legacy.Alpha + *legacy.Beta))
+	if newRank := leaderboardRank(&rep); newRank != oldRank {
+		if err := delIndexEntry(ctx, leaderboardIndex, []string{rep.Dimension, oldRank, rep.ActorID}); err != nil {
+			return false, err
+		}
+		if err := putIndexEntry(ctx, leaderboardIndex, []string{rep.Dimension, newRank, rep.ActorID}); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// migrateDimensionStatsFixedPoint rewrites dimension statistics stored with
+// a float score sum in fixed point
+func migrateDimensionStatsFixedPoint(ctx contractapi.TransactionContextInterface, key string, value []byte) (bool, error) {
+	var legacy struct {
+		ScoreSum *float64 `json:"scoreSum"`
+	}
+	if err := json.Unmarshal(value, &legacy); err != nil || legacy.ScoreSum == nil {
+		return false, nil
+	}
+	var stats DimensionStats
+	if err := json.Unmarshal(value, &stats); err != nil || stats.Dimension == "" {
+		return false, nil
+	}
+
+	statsJSON, err := marshalCanonical(stats)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal dimension stats: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, statsJSON); err != nil {
+		return false, fmt.Errorf("failed to store dimension stats: %v", err)
+	}
+	return true, nil
+}
+
+// ============================================================================
+// STATE VERSION HELPERS
+// ============================================================================
+
+// getStateVersion returns the schema version of the stored records
+func getStateVersion(ctx contractapi.TransactionContextInterface) (int, error) {
+	versionBytes, err := ctx.GetStub().GetState(stateVersionKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read state version: %v", err)
+	}
+	if versionBytes == nil {
+		return 1, nil
+	}
+
+	version, err := strconv.Atoi(string(versionBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid state version: %v", err)
+	}
+	return version, nil
+}
+
+// putStateVersion records the schema version of the stored records
+func putStateVersion(ctx contractapi.TransactionContextInterface, version int) error {
+	if err := ctx.GetStub().PutState(stateVersionKey, []byte(strconv.Itoa(version))); err != nil {
+		return fmt.Errorf("failed to store state version: %v", err)
+	}
+	return nil
+}
+
+// getStateMigration returns the running migration's cursor, or nil
+func getStateMigration(ctx contractapi.TransactionContextInterface) (*StateMigration, error) {
+	cursorJSON, err := ctx.GetStub().GetState(stateMigrationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration cursor: %v", err)
+	}
+	if cursorJSON == nil {
+		return nil, nil
+	}
+
+	var cursor StateMigration
+	if err := json.Unmarshal(cursorJSON, &cursor); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal migration cursor: %v", err)
+	}
+	return &cursor, nil
+}
+
+// putStateMigration stores the running migration's cursor
+func putStateMigration(ctx contractapi.TransactionContextInterface, cursor *StateMigration) error {
+	cursorJSON, err := marshalCanonical(cursor)
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration cursor: %v", err)
+	}
+	if err := ctx.GetStub().PutState(stateMigrationKey, cursorJSON); err != nil {
+		return fmt.Errorf("failed to store migration cursor: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// INPUT VALIDATION
+// ============================================================================
+
+// Every transaction's arguments pass through validateArguments before the
+// function runs. All arguments must be valid UTF-8 without control
+// characters other than line breaks and tabs, and at most maxArgumentLength
+// bytes; functions listed in transactionArguments additionally have each
+// positional argument checked by its kind. Every failing argument is
+// reported at once, by name, so a client can fix a request in one round
+// trip. Functions still parse their arguments, but leave presence, length
+// and format limits to this layer.
+
+const (
+	maxArgumentLength = 65536 // any argument, including JSON documents
+	maxIDLength       = 1024  // actor IDs, which may be base64 X.509 identities
+	maxDimensionName  = 64
+	maxEvidenceLength = 2048
+	maxTextLength     = 1024 // reasons, notes and purposes
+)
+
+// FieldError is one invalid argument
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError reports every invalid argument of a transaction
+type ValidationError struct {
+	Function string       `json:"function"`
+	Fields   []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return fmt.Sprintf("invalid arguments to %s: %s", e.Function, strings.Join(messages, "; "))
+}
+
+// argument names a positional argument and checks it, returning "" if valid
+type argument struct {
+	name  string
+	check func(value string) string
+}
+
+// transactionArguments lists, by function name, the checks of each
+// positional argument. Functions sharing a name across contracts share their
+// arguments.
+var transactionArguments = map[string][]argument{
+	"SubmitRating":             {actorIDArg("actorId"), dimensionArg, ratingValueArg, evidenceArg, timestampArg},
+	"SubmitRatingOnBehalf":     {actorIDArg("onBehalfOf"), actorIDArg("actorId"), dimensionArg, ratingValueArg, evidenceArg, timestampArg},
+	"SubmitRatingIdempotent":   {idempotencyKeyArg, actorIDArg("actorId"), dimensionArg, ratingValueArg, evidenceArg, timestampArg},
+	"SubmitRatingsBatch":       {{"ratingsJSON", required}},
+	"SubmitConfidentialRating": {actorIDArg("actorId"), actorIDArg("actorMspId"), dimensionArg, evidenceArg, timestampArg},
+	"InitiateDispute":          {recordIDArg("ratingId", ratingIDPrefix), textArg("reason", true)},
+	"ResolveDispute":           {recordIDArg("disputeId", disputeIDPrefix), verdictArg, textArg("arbitratorNotes", false)},
+	"AssignArbitrationPanel":   {recordIDArg("disputeId", disputeIDPrefix), {"arbitratorIds", required}},
+	"CastVerdict":              {recordIDArg("disputeId", disputeIDPrefix), verdictArg, textArg("notes", false)},
+	"GetRating":                {recordIDArg("ratingId", ratingIDPrefix)},
+	"GetDispute":               {recordIDArg("disputeId", disputeIDPrefix)},
+	"ExpireDispute":            {recordIDArg("disputeId", disputeIDPrefix)},
+	"AddStake":                 {amountArg("amount")},
+	"WithdrawStake":            {amountArg("amount")},
+	"ClaimWithdrawal":          {recordIDArg("withdrawalId", stakeWithdrawalIDPrefix)},
+	"GetStakeWithdrawal":       {recordIDArg("withdrawalId", stakeWithdrawalIDPrefix)},
+	"GetReputation":            {actorIDArg("actorId"), dimensionArg},
+	"GetReputationHistory":     {actorIDArg("actorId"), dimensionArg},
+	"BanActor":                 {actorIDArg("actorId"), textArg("reason", false)}, // required to propose, not to approve
+	"UnbanActor":               {actorIDArg("actorId")},
+	"ProposeTreasuryWithdrawal": {
+		actorIDArg("destination"), amountArg("amount"), textArg("purpose", true),
+	},
+	"ApproveTreasuryWithdrawal": {recordIDArg("withdrawalId", withdrawalIDPrefix)},
+	"GetTreasuryWithdrawal":     {recordIDArg("withdrawalId", withdrawalIDPrefix)},
+	"RegisterCredentialIssuer":  {actorIDArg("issuerId"), {"publicKeyPem", required}},
+	"RecomputeReputation":       {actorIDArg("actorId"), dimensionArg},
+	"RequireReputation":         {actorIDArg("actorId"), dimensionArg, scoreArg("minScore"), countArg("minEvents")},
+	"RequireStake":              {actorIDArg("actorId"), amountArg("minStake")},
+	"PostBond":                  {textArg("orderId", true), actorIDArg("buyerId"), dimensionArg, amountArg("amount")},
+	"GetBond":                   {recordIDArg("bondId", bondIDPrefix)},
+	"GetFraudSignal":            {recordIDArg("signalId", fraudSignalIDPrefix)},
+	"GetFraudSignalsForActor":   {actorIDArg("actorId")},
+	"ApplyForMembership":        {textArg("application", true)},
+	"ReviewApplication":         {actorIDArg("actorId"), decisionArg, textArg("notes", false)},
+	"GetMembership":             {actorIDArg("actorId")},
+	"RegisterMonitoringService": {actorIDArg("serviceId")},
+	"RevokeMonitoringService":   {actorIDArg("serviceId")},
+	"SubmitSLAMeasurement":      {textArg("orderId", true), actorIDArg("supplierId"), {"measurements", required}, timestampArg},
+	"GetSLAMeasurement":         {actorIDArg("serviceId"), textArg("orderId", true)},
+	"BrowseDirectory":           {textArg("region", false), textArg("category", false), tierArg},
+	"GetDirectoryListing":       {actorIDArg("actorId")},
+	"GetRatingArchives":         {actorIDArg("actorId"), dimensionArg},
+}
+
+// validateArguments is the before-transaction hook applying the checks above
+func validateArguments(ctx contractapi.TransactionContextInterface) error {
+	function, params := ctx.GetStub().GetFunctionAndParameters()
+	function = function[strings.LastIndex(function, ":")+1:]
+
+	verr := &ValidationError{Function: function}
+	for i, param := range params {
+		name := fmt.Sprintf("argument %d", i+1)
+		var check func(string) string
+		if args := transactionArguments[function]; i < len(args) {
+			name, check = args[i].name, args[i].check
+		}
+
+		if message := checkText(param, maxArgumentLength); message != "" {
+			verr.Fields = append(verr.Fields, FieldError{Field: name, Message: message})
+			continue
+		}
+		if check != nil {
+			if message := check(param); message != "" {
+				verr.Fields = append(verr.Fields, FieldError{Field: name, Message: message})
+			}
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// ============================================================================
+// ARGUMENT KINDS
+// ============================================================================
+
+// checkText rejects invalid UTF-8, control characters other than line
+// breaks and tabs, and values over maxLength bytes
+func checkText(value string, maxLength int) string {
+	if len(value) > maxLength {
+		return fmt.Sprintf("must be at most %d bytes", maxLength)
+	}
+	if !utf8.ValidString(value) {
+		return "must be valid UTF-8"
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return "must not contain control characters"
+		}
+	}
+	return ""
+}
+
+func required(value string) string {
+	if value == "" {
+		return "is required"
+	}
+	return ""
+}
+
+func actorIDArg(name string) argument {
+	return argument{name, func(value string) string {
+		if value == "" {
+			return "is required"
+		}
+		if len(value) > maxIDLength {
+			return fmt.Sprintf("must be at most %d bytes", maxIDLength)
+		}
+		if strings.TrimSpace(value) != value {
+			return "must not have leading or trailing spaces"
+		}
+		return ""
+	}}
+}
+
+var dimensionArg = argument{"dimension", func(value string) string {
+	if value == "" || len(value) > maxDimensionName {
+		return fmt.Sprintf("must be 1 to %d bytes", maxDimensionName)
+	}
+	if strings.ContainsAny(value, ": ") {
+		return "must not contain colons or spaces"
+	}
+	return ""
+}}
+
+var ratingValueArg = scoreArg("value")
+
+func scoreArg(name string) argument {
+	return argument{name, func(value string) string {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || v < 0 || v > 1 {
+			return "must be a number between 0 and 1"
+		}
+		return ""
+	}}
+}
+
+func countArg(name string) argument {
+	return argument{name, func(value string) string {
+		if v, err := strconv.Atoi(value); err != nil || v < 0 {
+			return "must be a non-negative integer"
+		}
+		return ""
+	}}
+}
+
+var evidenceArg = argument{"evidence", func(value string) string {
+	if len(value) > maxEvidenceLength {
+		return fmt.Sprintf("must be at most %d bytes", maxEvidenceLength)
+	}
+	return ""
+}}
+
+var timestampArg = argument{"timestamp", func(value string) string {
+	if v, err := strconv.ParseInt(value, 10, 64); err != nil || v <= 0 {
+		return "must be a positive integer"
+	}
+	return ""
+}}
+
+var idempotencyKeyArg = argument{"idempotencyKey", func(value string) string {
+	if value == "" || len(value) > maxIdempotencyKeyLength {
+		return fmt.Sprintf("must be 1 to %d bytes", maxIdempotencyKeyLength)
+	}
+	return ""
+}}
+
+var verdictArg = argument{"verdict", func(value string) string {
+	if value != "upheld" && value != "overturned" {
+		return "must be 'upheld' or 'overturned'"
+	}
+	return ""
+}}
+
+var decisionArg = argument{"decision", func(value string) string {
+	if value != "approve" && value != "reject" {
+		return "must be 'approve' or 'reject'"
+	}
+	return ""
+}}
+
+var tierArg = argument{"tier", func(value string) string {
+	if value != "" && !validTiers[value] {
+		return "must be unrated, bronze, silver, gold or empty"
+	}
+	return ""
+}}
+
+func recordIDArg(name string, prefix string) argument {
+	return argument{name, func(value string) string {
+		if err := validateRecordID(value, prefix); err != nil {
+			return fmt.Sprintf("must be a %s ID", recordKind(prefix))
+		}
+		return ""
+	}}
+}
+
+func amountArg(name string) argument {
+	return argument{name, func(value string) string {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v <= 0 {
+			return "must be a positive number"
+		}
+		return ""
+	}}
+}
+
+func textArg(name string, isRequired bool) argument {
+	return argument{name, func(value string) string {
+		if isRequired && strings.TrimSpace(value) == "" {
+			return "is required"
+		}
+		if len(value) > maxTextLength {
+			return fmt.Sprintf("must be at most %d bytes", maxTextLength)
+		}
+		return ""
+	}}
+}
+
+// chainHooks runs before-transaction hooks in order, stopping at the first
+// error
+func chainHooks(hooks ...func(contractapi.TransactionContextInterface) error) func(contractapi.TransactionContextInterface) error {
+	return func(ctx contractapi.TransactionContextInterface) error {
+		for _, hook := range hooks {
+			if err := hook(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
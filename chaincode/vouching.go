@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/v2/contractapi"
+)
+
+// ============================================================================
+// VOUCHING
+// ============================================================================
+
+// Vouch records that an established rater vouched for a newcomer in a
+// dimension. The voucher's metareputation is penalized once if the newcomer
+// is later slashed or banned.
+type Vouch struct {
+	VoucherID   string  `json:"voucherId"`
+	ActorID     string  `json:"actorId"`
+	Dimension   string  `json:"dimension"`
+	PriorBoost  float64 `json:"priorBoost"`
+	CreatedAt   int64   `json:"createdAt"`
+	Penalized   bool    `json:"penalized"`
+	PenaltyNote string  `json:"penaltyNote,omitempty"`
+}
+
+// VouchForActor lets a caller whose metareputation in the dimension is at
+// least VouchMinMetaScore improve a newcomer's prior by VouchPriorWeight.
+// Only actors with no ratings yet in the dimension can be vouched for.
+func (rc *ReputationContract) VouchForActor(
+	ctx contractapi.TransactionContextInterface,
+	newActorID string,
+	dimension string,
+) error {
+	voucherID, err := callerIdentity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get voucher ID: %v", err)
+	}
+
+	actorID := resolveIdentity(ctx, newActorID)
+	if voucherID == actorID {
+		return fmt.Errorf("cannot vouch for yourself")
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.ValidDimensions[dimension] {
+		return fmt.Errorf("invalid dimension: %s", dimension)
+	}
+	metaDimension, exists := config.MetaDimensions[dimension]
+	if !exists {
+		return fmt.Errorf("no meta-dimension for %s", dimension)
+	}
+
+	if banned, err := isBanned(ctx, voucherID); err != nil {
+		return err
+	} else if banned {
+		return fmt.Errorf("voucher is banned: %s", voucherID)
+	}
+
+	metaRep, err := getOrInitReputation(ctx, voucherID, metaDimension, config)
+	if err != nil {
+		return err
+	}
+	metaScore := reputationScore(metaRep)
+	if metaScore < config.VouchMinMetaScore {
+		return fmt.Errorf("insufficient metareputation to vouch: %.3f < %.3f", metaScore, config.VouchMinMetaScore)
+	}
+
+	vouchKey := vouchStateKey(actorID, dimension, voucherID)
+	existing, err := ctx.GetStub().GetState(vouchKey)
+	if err != nil {
+		return fmt.Errorf("failed to read vouch: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("already vouched for %s in %s", actorID, dimension)
+	}
+
+	rep, err := getOrInitReputation(ctx, actorID, dimension, config)
+	if err != nil {
+		return err
+	}
+	if rep.TotalEvents > 0 {
+		return fmt.Errorf("actor is not a newcomer in %s", dimension)
+	}
+
+	rep.Alpha += fixedFromFloat(config.VouchPriorWeight)
+
+	if err := putReputation(ctx, rep); err != nil {
+		return err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	vouch := Vouch{
+		VoucherID:  voucherID,
+		ActorID:    actorID,
+		Dimension:  dimension,
+		PriorBoost: config.VouchPriorWeight,
+		CreatedAt:  now,
+	}
+
+	vouchJSON, err := marshalCanonical(vouch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vouch: %v", err)
+	}
+	err = ctx.GetStub().PutState(vouchKey, vouchJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store vouch: %v", err)
+	}
+
+	// Emit event
+	emitEvent(ctx, "ActorVouched", vouch, vouchKey)
+
+	return nil
+}
+
+// GetVouches lists the vouches recorded for an actor
+func (rc *ReputationContract) GetVouches(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+) ([]Vouch, error) {
+	return getVouches(ctx, resolveIdentity(ctx, actorID))
+}
+
+// ============================================================================
+// VOUCHING HELPERS
+// ============================================================================
+
+// penalizeVouchers lowers the metareputation of everyone who vouched for an
+// actor that has been slashed or banned. Each vouch is penalized only once.
+func penalizeVouchers(
+	ctx contractapi.TransactionContextInterface,
+	actorID string,
+	reason string,
+) error {
+	vouches, err := getVouches(ctx, actorID)
+	if err != nil {
+		return err
+	}
+	if len(vouches) == 0 {
+		return nil
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	now, err := txUnixTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, vouch := range vouches {
+		if vouch.Penalized {
+			continue
+		}
+
+		metaDimension, exists := config.MetaDimensions[vouch.Dimension]
+		if exists {
+			metaRep, err := getOrInitReputation(ctx, vouch.VoucherID, metaDimension, config)
+			if err != nil {
+				return err
+			}
+			metaRep.Beta += fixedFromFloat(config.VouchPenalty)
+			metaRep.LastTs = now
+
+			if err := putReputation(ctx, metaRep); err != nil {
+				return err
+			}
+		}
+
+		vouch.Penalized = true
+		vouch.PenaltyNote = reason
+
+		vouchJSON, err := marshalCanonical(vouch)
+		if err != nil {
+			return fmt.Errorf("failed to marshal vouch: %v", err)
+		}
+		vouchKey := vouchStateKey(vouch.ActorID, vouch.Dimension, vouch.VoucherID)
+		err = ctx.GetStub().PutState(vouchKey, vouchJSON)
+		if err != nil {
+			return fmt.Errorf("failed to store vouch: %v", err)
+		}
+
+		// Emit event
+		eventPayload := map[string]interface{}{
+			"voucherId": vouch.VoucherID,
+			"actorId":   vouch.ActorID,
+			"dimension": vouch.Dimension,
+			"penalty":   config.VouchPenalty,
+			"reason":    reason,
+		}
+		emitEvent(ctx, "VoucherPenalized", eventPayload, vouchKey)
+	}
+
+	return nil
+}
+
+// getVouches loads every vouch recorded for an actor (normalized ID)
+func getVouches(ctx contractapi.TransactionContextInterface, actorID string) ([]Vouch, error) {
+	prefix := vouchPrefix(actorID)
+	resultsIterator, err := ctx.GetStub().GetStateByRange(prefix, prefix+"~")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vouches: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	vouches := []Vouch{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var vouch Vouch
+		if err := json.Unmarshal(queryResponse.Value, &vouch); err != nil {
+			continue
+		}
+		vouches = append(vouches, vouch)
+	}
+
+	return vouches, nil
+}
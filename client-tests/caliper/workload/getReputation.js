@@ -0,0 +1,44 @@
+'use strict';
+
+const { WorkloadModuleBase } = require('@hyperledger/caliper-core');
+
+/**
+ * Queries the reputation of the synthetic actors rated by submitRating.js.
+ *
+ * Round arguments:
+ *   contractId   chaincode name (default repcc)
+ *   actors       number of synthetic actors (default 100)
+ *   actorPrefix  prefix of the actor IDs (default loadgen-actor-)
+ *   dimension    dimension to query (default quality)
+ */
+class GetReputationWorkload extends WorkloadModuleBase {
+    async initializeWorkloadModule(workerIndex, totalWorkers, roundIndex, roundArguments, sutAdapter, sutContext) {
+        await super.initializeWorkloadModule(workerIndex, totalWorkers, roundIndex, roundArguments, sutAdapter, sutContext);
+
+        this.contractId = roundArguments.contractId || 'repcc';
+        this.actors = roundArguments.actors || 100;
+        this.actorPrefix = roundArguments.actorPrefix || 'loadgen-actor-';
+        this.dimension = roundArguments.dimension || 'quality';
+        this.seq = 0;
+    }
+
+    async submitTransaction() {
+        const seq = this.seq++;
+
+        await this.sutAdapter.sendRequests({
+            contractId: this.contractId,
+            contractFunction: 'GetReputation',
+            contractArguments: [
+                this.actorPrefix + ((seq * this.totalWorkers + this.workerIndex) % this.actors),
+                this.dimension
+            ],
+            readOnly: true
+        });
+    }
+}
+
+function createWorkloadModule() {
+    return new GetReputationWorkload();
+}
+
+module.exports.createWorkloadModule = createWorkloadModule;
@@ -0,0 +1,66 @@
+'use strict';
+
+const { WorkloadModuleBase } = require('@hyperledger/caliper-core');
+
+/**
+ * Submits ratings against a pool of synthetic actors. A smaller pool
+ * concentrates writes on fewer reputation keys and raises the MVCC conflict
+ * rate, which Caliper reports as failed transactions.
+ *
+ * Round arguments:
+ *   contractId   chaincode name (default repcc)
+ *   actors       number of synthetic actors (default 100)
+ *   actorPrefix  prefix of the actor IDs (default loadgen-actor-)
+ *   dimension    dimension to rate (default quality)
+ *   stake        stake each worker adds before the round; 0 to skip (default 0)
+ */
+class SubmitRatingWorkload extends WorkloadModuleBase {
+    async initializeWorkloadModule(workerIndex, totalWorkers, roundIndex, roundArguments, sutAdapter, sutContext) {
+        await super.initializeWorkloadModule(workerIndex, totalWorkers, roundIndex, roundArguments, sutAdapter, sutContext);
+
+        this.contractId = roundArguments.contractId || 'repcc';
+        this.actors = roundArguments.actors || 100;
+        this.actorPrefix = roundArguments.actorPrefix || 'loadgen-actor-';
+        this.dimension = roundArguments.dimension || 'quality';
+        this.seq = 0;
+        this.lastTimestamp = 0;
+
+        if (roundArguments.stake) {
+            await this.sutAdapter.sendRequests({
+                contractId: this.contractId,
+                contractFunction: 'stake:AddStake',
+                contractArguments: [String(roundArguments.stake)],
+                readOnly: false
+            });
+        }
+    }
+
+    async submitTransaction() {
+        const seq = this.seq++;
+
+        // Rating IDs are derived from the timestamp, so keep it increasing
+        // and give each worker its own residue modulo the worker count
+        let timestamp = Math.max(Date.now(), this.lastTimestamp + 1);
+        timestamp += (this.workerIndex - (timestamp % this.totalWorkers) + this.totalWorkers) % this.totalWorkers;
+        this.lastTimestamp = timestamp;
+
+        await this.sutAdapter.sendRequests({
+            contractId: this.contractId,
+            contractFunction: 'rating:SubmitRating',
+            contractArguments: [
+                this.actorPrefix + ((seq * this.totalWorkers + this.workerIndex) % this.actors),
+                this.dimension,
+                String((seq % 101) / 100),
+                'caliper',
+                String(timestamp)
+            ],
+            readOnly: false
+        });
+    }
+}
+
+function createWorkloadModule() {
+    return new SubmitRatingWorkload();
+}
+
+module.exports.createWorkloadModule = createWorkloadModule;
@@ -116,7 +116,7 @@ async function main() {
 // --- Test Logic Functions ---
 
 /**
- * Runs the "AddStake" setup for all test users.
+ * Runs the "stake:AddStake" setup for all test users.
  */
 async function setupTestUsers(client) {
     console.log(`\n--- Setup Phase: Adding stake to all ${allTestUsers.length} test users ---`);
@@ -132,7 +132,7 @@ async function setupTestUsers(client) {
             const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
             
             try {
-                await userContract.submitTransaction('AddStake', stakeAmount);
+                await userContract.submitTransaction('stake:AddStake', stakeAmount);
                 process.stdout.write('✓\n');
                 await sleep(1000); // 1s wait for consensus
             } catch (err) {
@@ -141,7 +141,7 @@ async function setupTestUsers(client) {
                 } else {
                     process.stdout.write(' (1st attempt failed, retrying...)');
                     await sleep(2000); // Wait 2 seconds
-                    await userContract.submitTransaction('AddStake', stakeAmount);
+                    await userContract.submitTransaction('stake:AddStake', stakeAmount);
                     process.stdout.write('✓ (Retry success)\n');
                     await sleep(1000);
                 }
@@ -224,7 +224,7 @@ async function runPerformanceTest(client, queryContract) {
     for (let i = 0; i < READ_LATENCY_TESTS; i++) {
         start = process.hrtime.bigint();
         // FIX: Use queryContract (connected as buyer1) for all reads
-        await queryContract.evaluateTransaction('GetStake', readVictim);
+        await queryContract.evaluateTransaction('stake:GetStake', readVictim);
         end = process.hrtime.bigint();
         totalTime += (end - start);
     }
@@ -349,7 +349,7 @@ async function submitStake(client, username, amount, suppressErrors = false) {
     try {
         userGateway = await newGatewayForUser(client, username);
         const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
-        await userContract.submitTransaction('AddStake', amount);
+        await userContract.submitTransaction('stake:AddStake', amount);
     } catch(err) {
         if (suppressErrors && (err.message.includes('stake already exists') || err.message.includes('failed to endorse transaction'))) {
             // Ignore errors
@@ -365,7 +365,7 @@ async function submitRating(client, queryContract, username, rating, victim, sup
         userGateway = await newGatewayForUser(client, username);
         const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
 
-        const tx = userContract.newProposal('SubmitRating', {
+        const tx = userContract.newProposal('rating:SubmitRating', {
             arguments: [
                 victim,
                 testDimension,
@@ -379,8 +379,8 @@ async function submitRating(client, queryContract, username, rating, victim, sup
         
         // --- FIX: Query for the rating ID instead of assuming it ---
         await sleep(500); // wait for commit
-        const resultBytes = await queryContract.evaluateTransaction('GetRatingsByRater', username);
-        const ratings = JSON.parse(utf8Decoder.decode(resultBytes));
+        const resultBytes = await queryContract.evaluateTransaction('rating:GetRatingsByRater', username, '', '100');
+        const ratings = JSON.parse(utf8Decoder.decode(resultBytes)).ratings;
         if (!ratings || ratings.length === 0) {
             throw new Error(`Could not find rating for user ${username} after submit`);
         }
@@ -401,8 +401,8 @@ async function createDispute(client, queryContract, username, ratingId, reason)
         userGateway = await newGatewayForUser(client, username);
         const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
 
-        // --- FIX: Call 'InitiateDispute' to match your chaincode ---
-        const tx = userContract.newProposal('InitiateDispute', {
+        // --- FIX: Call 'dispute:InitiateDispute' to match your chaincode ---
+        const tx = userContract.newProposal('dispute:InitiateDispute', {
             arguments: [
                 ratingId,
                 reason,
@@ -414,8 +414,8 @@ async function createDispute(client, queryContract, username, ratingId, reason)
 
         // --- FIX: Query for the dispute ID instead of assuming it ---
         await sleep(500); // wait for commit
-        const resultBytes = await queryContract.evaluateTransaction('GetDisputesByStatus', 'pending');
-        const disputes = JSON.parse(utf8Decoder.decode(resultBytes));
+        const resultBytes = await queryContract.evaluateTransaction('dispute:GetDisputesByStatus', 'pending', '', '100');
+        const disputes = JSON.parse(utf8Decoder.decode(resultBytes)).disputes;
         if (!disputes || disputes.length === 0) {
             throw new Error(`Could not find pending dispute for user ${username} after submit`);
         }
@@ -435,7 +435,7 @@ async function resolveDispute(client, username, disputeId, resolution, comments)
         const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
 
         await userContract.submitTransaction(
-            'ResolveDispute',
+            'dispute:ResolveDispute',
             disputeId,
             resolution,
             comments
@@ -466,7 +466,7 @@ async function getChainStake(queryContract, username) {
     try {
         // FIX: Use queryContract (connected as buyer1)
         const normalizedUsername = username.toLowerCase();
-        const resultBytes = await queryContract.evaluateTransaction('GetStake', normalizedUsername);
+        const resultBytes = await queryContract.evaluateTransaction('stake:GetStake', normalizedUsername);
         const resultJson = utf8Decoder.decode(resultBytes);
         return JSON.parse(resultJson); // Return the full stake object
     } catch (err) {
@@ -129,14 +129,14 @@ async function testSequentialReadLatency(adminContract) {
     // Create a dummy record to read by staking the Admin user
     const readVictim = adminUser; // We will read the Admin's stake
     try {
-        await adminContract.submitTransaction('AddStake', '12345');
+        await adminContract.submitTransaction('stake:AddStake', '12345');
     } catch (err) {
         // Ignore if stake already exists
     }
     
     for (let i = 0; i < TEST_RUNS_SEQUENTIAL; i++) {
         const start = process.hrtime.bigint();
-        await adminContract.evaluateTransaction('GetStake', readVictim);
+        await adminContract.evaluateTransaction('stake:GetStake', readVictim);
         const end = process.hrtime.bigint();
         totalTime += (end - start);
     }
@@ -166,7 +166,7 @@ async function testConcurrentReadThroughput(adminContract) {
     const readVictim = adminUser; // Use the same admin stake record
     const start = process.hrtime.bigint();
     for (let i = 0; i < TEST_RUNS_CONCURRENT; i++) {
-        promises.push(adminContract.evaluateTransaction('GetStake', readVictim));
+        promises.push(adminContract.evaluateTransaction('stake:GetStake', readVictim));
         
         if (promises.length >= CONCURRENCY_LEVEL || i === TEST_RUNS_CONCURRENT - 1) {
             await Promise.all(promises);
@@ -286,7 +286,7 @@ async function submitStake(client, username, amount, suppressErrors = false) {
     try {
         userGateway = await newGatewayForUser(client, username);
         const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
-        await userContract.submitTransaction('AddStake', amount);
+        await userContract.submitTransaction('stake:AddStake', amount);
     } catch(err) {
         if (suppressErrors && (err.message.includes('stake already exists') || err.message.includes('failed to endorse transaction'))) {
             // Ignore errors during setup
@@ -302,7 +302,7 @@ async function submitRating(client, username, rating, victim, suppressErrors = f
         userGateway = await newGatewayForUser(client, username);
         const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
         await userContract.submitTransaction(
-            'SubmitRating',
+            'rating:SubmitRating',
             victim, 
             'quality', // Use a consistent dimension for testing
             rating.toString(),
@@ -127,7 +127,7 @@ async function setupTestUsers(client) {
             const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
             
             try {
-                await userContract.submitTransaction('AddStake', minStake);
+                await userContract.submitTransaction('stake:AddStake', minStake);
                 process.stdout.write('✓\n');
                 await sleep(1000); // 1s wait
             } catch (err) {
@@ -136,7 +136,7 @@ async function setupTestUsers(client) {
                 } else {
                     process.stdout.write(' (1st attempt failed, retrying...)');
                     await sleep(2000); 
-                    await userContract.submitTransaction('AddStake', minStake);
+                    await userContract.submitTransaction('stake:AddStake', minStake);
                     process.stdout.write('✓ (Retry success)\n');
                     await sleep(1000);
                 }
@@ -313,7 +313,7 @@ async function submitStake(client, username, amount) {
     try {
         userGateway = await newGatewayForUser(client, username);
         const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
-        await userContract.submitTransaction('AddStake', amount);
+        await userContract.submitTransaction('stake:AddStake', amount);
     } catch(err) {
         if (!err.message.includes('stake already exists') && !err.message.includes('failed to endorse transaction')) {
             throw err;
@@ -332,7 +332,7 @@ async function submitRating(client, username, rating, victim = victimSupplier) {
         userGateway = await newGatewayForUser(client, username);
         const userContract = userGateway.getNetwork(channelName).getContract(chaincodeName);
         await userContract.submitTransaction(
-            'SubmitRating',
+            'rating:SubmitRating',
             victim, 
             testDimension,
             rating.toString(),
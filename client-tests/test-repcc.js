@@ -42,7 +42,7 @@ async function main() {
 
         // Test 1: Get Config
         console.log('Test 1: Get System Configuration');
-        const config = await contract.evaluateTransaction('GetConfig');
+        const config = await contract.evaluateTransaction('governance:GetConfig');
         const configObj = JSON.parse(config.toString());
         console.log('  Min Stake Required:', configObj.minStakeRequired);
         console.log('  Valid Dimensions:', Object.keys(configObj.validDimensions).join(', '));
@@ -51,7 +51,7 @@ async function main() {
 
         // Test 2: Add Stake
         console.log('Test 2: Add Stake');
-        await contract.submitTransaction('AddStake', '50000');
+        await contract.submitTransaction('stake:AddStake', '50000');
         console.log('  ✓ Stake added: 50,000\n');
 
         // Test 3: Submit Ratings
@@ -69,7 +69,7 @@ async function main() {
                 
                 try {
                     const result = await contract.submitTransaction(
-                        'SubmitRating',
+                        'rating:SubmitRating',
                         actor,
                         dimension,
                         value,
@@ -120,7 +120,7 @@ async function main() {
             
             perfRatings.push(
                 contract.submitTransaction(
-                    'SubmitRating',
+                    'rating:SubmitRating',
                     actor,
                     dimension,
                     value,
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The analytical queries served from the mirror. They scan and aggregate
+// across all actors, which chaincode cannot do within endorsement limits.
+// Each reads one tenant, selected by the tenant query parameter and
+// defaulting to the default space.
+
+const (
+	defaultLimit = 50
+	maxLimit     = 1000
+	queryTimeout = 30 * time.Second
+)
+
+func newAPI(store *Store) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", store.handleHealth)
+	mux.HandleFunc("GET /dimensions", store.handleDimensions)
+	mux.HandleFunc("GET /dimensions/{dimension}/distribution", store.handleDistribution)
+	mux.HandleFunc("GET /raters", store.handleRaters)
+	mux.HandleFunc("GET /disputes/monthly", store.handleDisputesMonthly)
+	mux.HandleFunc("GET /actors/{actorId}/trend", store.handleActorTrend)
+	mux.Handle("POST /graphql", newGraphQLHandler(store))
+	return mux
+}
+
+// handleHealth reports the checkpoint, so lag can be compared with the
+// channel height
+func (s *Store) handleHealth(w http.ResponseWriter, r *http.Request) {
+	blockNumber, txID, ok, err := s.Checkpoint(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"indexed":     ok,
+		"blockNumber": blockNumber,
+		"txId":        txID,
+	})
+}
+
+// handleDimensions summarizes each dimension: actors, mean score, ratings
+// and mean rating value
+func (s *Store) handleDimensions(w http.ResponseWriter, r *http.Request) {
+	s.query(w, r, `
+		SELECT rep.dimension, rep.actors, rep.mean_score,
+			COALESCE(rat.ratings, 0), COALESCE(rat.mean_value, 0)
+		FROM (
+			SELECT dimension, COUNT(*) AS actors, AVG(score) AS mean_score
+			FROM reputations WHERE tenant = $1 GROUP BY dimension
+		) rep
+		LEFT JOIN (
+			SELECT dimension, COUNT(*) AS ratings, AVG(value) AS mean_value
+			FROM ratings WHERE tenant = $1 GROUP BY dimension
+		) rat ON rat.dimension = rep.dimension
+		ORDER BY rep.dimension`,
+		[]string{"dimension", "actors", "meanScore", "ratings", "meanValue"}, requestTenant(r))
+}
+
+// handleDistribution returns a ten-bucket histogram of scores in a dimension
+func (s *Store) handleDistribution(w http.ResponseWriter, r *http.Request) {
+	s.query(w, r, `
+		SELECT LEAST(width_bucket(score, 0, 1, 10), 10) AS bucket, COUNT(*)
+		FROM reputations WHERE tenant = $1 AND dimension = $2
+		GROUP BY bucket ORDER BY bucket`,
+		[]string{"bucket", "actors"}, requestTenant(r), r.PathValue("dimension"))
+}
+
+// handleRaters ranks raters by ratings submitted, with the disputes raised
+// against them and the share overturned
+func (s *Store) handleRaters(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	s.query(w, r, `
+		SELECT rat.rater_id, rat.ratings, rat.mean_value,
+			COALESCE(d.disputes, 0), COALESCE(d.overturned, 0),
+			COALESCE(d.overturned::float / NULLIF(d.disputes, 0), 0)
+		FROM (
+			SELECT rater_id, COUNT(*) AS ratings, AVG(value) AS mean_value
+			FROM ratings WHERE tenant = $1 GROUP BY rater_id
+		) rat
+		LEFT JOIN (
+			SELECT rater_id, COUNT(*) AS disputes,
+				COUNT(*) FILTER (WHERE status = 'overturned') AS overturned
+			FROM disputes WHERE tenant = $1 GROUP BY rater_id
+		) d ON d.rater_id = rat.rater_id
+		ORDER BY rat.ratings DESC, rat.rater_id
+		LIMIT $2`,
+		[]string{"raterId", "ratings", "meanValue", "disputes", "overturned", "overturnRate"}, requestTenant(r), limit)
+}
+
+// handleDisputesMonthly counts disputes by month opened and status
+func (s *Store) handleDisputesMonthly(w http.ResponseWriter, r *http.Request) {
+	s.query(w, r, `
+		SELECT to_char(date_trunc('month', to_timestamp(created_at)), 'YYYY-MM') AS month, status, COUNT(*)
+		FROM disputes WHERE tenant = $1
+		GROUP BY month, status ORDER BY month, status`,
+		[]string{"month", "status", "disputes"}, requestTenant(r))
+}
+
+// handleActorTrend returns an actor's weekly rating count and weighted mean
+// value in a dimension
+func (s *Store) handleActorTrend(w http.ResponseWriter, r *http.Request) {
+	dimension := r.URL.Query().Get("dimension")
+	if dimension == "" {
+		http.Error(w, "dimension is required", http.StatusBadRequest)
+		return
+	}
+	s.query(w, r, `
+		SELECT to_char(date_trunc('week', to_timestamp(ts)), 'YYYY-MM-DD') AS week, COUNT(*),
+			SUM(value * weight) / NULLIF(SUM(weight) FILTER (WHERE value IS NOT NULL), 0)
+		FROM ratings WHERE tenant = $1 AND actor_id = $2 AND dimension = $3
+		GROUP BY week ORDER BY week`,
+		[]string{"week", "ratings", "weightedMeanValue"}, requestTenant(r), r.PathValue("actorId"), dimension)
+}
+
+// query runs a read-only query and writes its rows as JSON objects keyed by
+// columns
+func (s *Store) query(w http.ResponseWriter, r *http.Request, query string, columns []string, args ...interface{}) {
+	ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		result := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			result[column] = normalizeValue(values[i])
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, results)
+}
+
+// normalizeValue converts driver values that do not encode as JSON
+// naturally, such as numeric columns returned as bytes
+func normalizeValue(value interface{}) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+		return f
+	}
+	return string(b)
+}
+
+// requestTenant returns the tenant a request reads, "" for the default space
+func requestTenant(r *http.Request) string {
+	return r.URL.Query().Get("tenant")
+}
+
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return defaultLimit, true
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > maxLimit {
+		http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+		return 0, false
+	}
+	return limit, true
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if err == sql.ErrNoRows {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("query failed: %v", err)
+	http.Error(w, "query failed", http.StatusInternalServerError)
+}
@@ -0,0 +1,641 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// The GraphQL API serves the mirrored records and the relationships between
+// them, for dashboards that browse records rather than aggregate them. Lists
+// are connections paged by opaque keyset cursors, which stay valid while the
+// indexer adds rows. A query reads one tenant, selected by the tenant query
+// parameter of the request as for the analytical queries.
+
+const (
+	graphQLMaxDepth     = 12
+	graphQLMaxBodyBytes = 1 << 20
+)
+
+const graphQLSchema = `
+"Unix time as recorded by the chaincode. Values above 2147483647 are passed as strings or variables."
+scalar Timestamp
+
+schema {
+	query: Query
+}
+
+type Query {
+	"An actor that has rated, been rated, raised a dispute or staked"
+	actor(id: ID!): Actor
+	actors(idPrefix: String, dimension: String, first: Int = 50, after: String): ActorConnection!
+	rating(id: ID!): Rating
+	"Ratings, newest first"
+	ratings(actorId: ID, raterId: ID, dimension: String, source: String, since: Timestamp, until: Timestamp, first: Int = 50, after: String): RatingConnection!
+	reputation(actorId: ID!, dimension: String!): Reputation
+	"Reputations, highest score first"
+	reputations(actorId: ID, dimension: String, minScore: Float, maxScore: Float, minEvents: Int, first: Int = 50, after: String): ReputationConnection!
+	dispute(id: ID!): Dispute
+	"Disputes, newest first"
+	disputes(status: String, dimension: String, actorId: ID, raterId: ID, initiatorId: ID, arbitratorId: ID, first: Int = 50, after: String): DisputeConnection!
+}
+
+type Actor {
+	id: ID!
+	stake: Stake
+	reputations(dimension: String): [Reputation!]!
+	ratingsReceived(raterId: ID, dimension: String, source: String, since: Timestamp, until: Timestamp, first: Int = 50, after: String): RatingConnection!
+	ratingsGiven(actorId: ID, dimension: String, source: String, since: Timestamp, until: Timestamp, first: Int = 50, after: String): RatingConnection!
+	"Disputes the actor raised"
+	disputesRaised(status: String, dimension: String, first: Int = 50, after: String): DisputeConnection!
+	"Disputes raised against ratings the actor submitted"
+	disputesAgainst(status: String, dimension: String, first: Int = 50, after: String): DisputeConnection!
+}
+
+type Stake {
+	balance: Float!
+	locked: Float!
+	updatedAt: Timestamp!
+	blockNumber: Int!
+}
+
+type Rating {
+	id: ID!
+	rater: Actor!
+	actor: Actor!
+	dimension: String!
+	"Null for confidential ratings"
+	value: Float
+	weight: Float!
+	timestamp: Timestamp!
+	source: String!
+	submittedBy: String!
+	txId: String!
+	blockNumber: Int!
+	disputes: [Dispute!]!
+	"The rated actor's current reputation in the rating's dimension"
+	reputation: Reputation
+}
+
+type Reputation {
+	actor: Actor!
+	dimension: String!
+	score: Float!
+	ciLower: Float!
+	ciUpper: Float!
+	totalEvents: Int!
+	lastUpdated: Timestamp!
+	blockNumber: Int!
+	ratings(raterId: ID, source: String, since: Timestamp, until: Timestamp, first: Int = 50, after: String): RatingConnection!
+}
+
+type Dispute {
+	id: ID!
+	"Null if the rating is not in the mirror"
+	rating: Rating
+	initiator: Actor!
+	rater: Actor!
+	actor: Actor!
+	"Null until an arbitrator resolves the dispute"
+	arbitrator: Actor
+	dimension: String!
+	status: String!
+	createdAt: Timestamp!
+	resolvedAt: Timestamp
+	blockNumber: Int!
+}
+
+type PageInfo {
+	"Pass as after to fetch the next page"
+	endCursor: String
+	hasNextPage: Boolean!
+}
+
+type ActorConnection {
+	nodes: [Actor!]!
+	pageInfo: PageInfo!
+	totalCount: Int!
+}
+
+type RatingConnection {
+	nodes: [Rating!]!
+	pageInfo: PageInfo!
+	totalCount: Int!
+}
+
+type ReputationConnection {
+	nodes: [Reputation!]!
+	pageInfo: PageInfo!
+	totalCount: Int!
+}
+
+type DisputeConnection {
+	nodes: [Dispute!]!
+	pageInfo: PageInfo!
+	totalCount: Int!
+}
+`
+
+// errQueryFailed is returned to clients in place of database errors, which
+// are logged instead
+var errQueryFailed = errors.New("query failed")
+
+// graphQLHandler executes GraphQL queries POSTed as JSON
+type graphQLHandler struct {
+	schema *graphql.Schema
+}
+
+func newGraphQLHandler(store *Store) *graphQLHandler {
+	schema := graphql.MustParseSchema(graphQLSchema, &queryResolver{s: store},
+		graphql.UseStringDescriptions(), graphql.MaxDepth(graphQLMaxDepth))
+	return &graphQLHandler{schema: schema}
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP runs one query. Field errors are reported in the response next
+// to the data that could be resolved, as GraphQL clients expect.
+func (h *graphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, graphQLMaxBodyBytes))
+	if err := decoder.Decode(&req); err != nil || req.Query == "" {
+		http.Error(w, "body must be a JSON object with a query", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, tenantContextKey{}, requestTenant(r))
+	writeJSON(w, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// tenantContextKey holds the tenant a query reads in its context
+type tenantContextKey struct{}
+
+// ============================================================================
+// QUERY
+// ============================================================================
+
+// queryResolver resolves the root fields
+type queryResolver struct {
+	s *Store
+}
+
+// store returns the Store scoped to the tenant the query reads. The
+// resolvers below the root keep it, so related records come from the same
+// tenant.
+func (q *queryResolver) store(ctx context.Context) *Store {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return q.s.forTenant(tenant)
+}
+
+// actorIDs lists every actor the mirror knows of, with their tenants
+const actorIDs = `(
+	SELECT tenant, actor_id AS id FROM reputations
+	UNION SELECT tenant, actor_id FROM ratings
+	UNION SELECT tenant, rater_id FROM ratings
+	UNION SELECT tenant, initiator_id FROM disputes
+	UNION SELECT tenant, actor_id FROM stakes
+) actors`
+
+func (q *queryResolver) Actor(ctx context.Context, args struct{ ID graphql.ID }) (*actorResolver, error) {
+	s := q.store(ctx)
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+actorIDs+` WHERE tenant = $1 AND id = $2)`,
+		s.tenant, string(args.ID)).Scan(&exists)
+	if err != nil {
+		return nil, queryFailed(err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	return &actorResolver{s: s, id: string(args.ID)}, nil
+}
+
+func (q *queryResolver) Actors(ctx context.Context, args struct {
+	IDPrefix  *string
+	Dimension *string
+	pageArgs
+}) (*actorConnection, error) {
+	s := q.store(ctx)
+	where := &sqlWhere{}
+	if args.IDPrefix != nil {
+		where.add("id LIKE ?", likeEscaper.Replace(*args.IDPrefix)+"%")
+	}
+	if args.Dimension != nil {
+		where.add("id IN (SELECT actor_id FROM reputations WHERE tenant = ? AND dimension = ?)", s.tenant, *args.Dimension)
+	}
+
+	conn := &actorConnection{}
+	var err error
+	conn.page, err = s.fetchPage(ctx, actorListing, where, args.pageArgs, func(rows *sql.Rows) ([]interface{}, error) {
+		actor := &actorResolver{s: s}
+		if err := rows.Scan(&actor.id); err != nil {
+			return nil, err
+		}
+		conn.nodes = append(conn.nodes, actor)
+		return []interface{}{actor.id}, nil
+	})
+	return conn, err
+}
+
+func (q *queryResolver) Rating(ctx context.Context, args struct{ ID graphql.ID }) (*ratingResolver, error) {
+	return q.store(ctx).rating(ctx, whereEqual("rating_id", string(args.ID)))
+}
+
+func (q *queryResolver) Ratings(ctx context.Context, args ratingArgs) (*ratingConnection, error) {
+	return q.store(ctx).ratingPage(ctx, &sqlWhere{}, args)
+}
+
+func (q *queryResolver) Reputation(ctx context.Context, args struct {
+	ActorID   graphql.ID
+	Dimension string
+}) (*reputationResolver, error) {
+	where := whereEqual("actor_id", string(args.ActorID))
+	where.add("dimension = ?", args.Dimension)
+	return q.store(ctx).reputation(ctx, where)
+}
+
+func (q *queryResolver) Reputations(ctx context.Context, args struct {
+	ActorID   *graphql.ID
+	Dimension *string
+	MinScore  *float64
+	MaxScore  *float64
+	MinEvents *int32
+	pageArgs
+}) (*reputationConnection, error) {
+	where := &sqlWhere{}
+	where.optional("actor_id = ?", args.ActorID)
+	where.optional("dimension = ?", args.Dimension)
+	where.optional("score >= ?", args.MinScore)
+	where.optional("score <= ?", args.MaxScore)
+	where.optional("total_events >= ?", args.MinEvents)
+	return q.store(ctx).reputationPage(ctx, where, args.pageArgs)
+}
+
+func (q *queryResolver) Dispute(ctx context.Context, args struct{ ID graphql.ID }) (*disputeResolver, error) {
+	return q.store(ctx).dispute(ctx, whereEqual("dispute_id", string(args.ID)))
+}
+
+func (q *queryResolver) Disputes(ctx context.Context, args struct {
+	disputeArgs
+	ActorID      *graphql.ID
+	RaterID      *graphql.ID
+	InitiatorID  *graphql.ID
+	ArbitratorID *graphql.ID
+}) (*disputeConnection, error) {
+	where := &sqlWhere{}
+	where.optional("actor_id = ?", args.ActorID)
+	where.optional("rater_id = ?", args.RaterID)
+	where.optional("initiator_id = ?", args.InitiatorID)
+	where.optional("arbitrator_id = ?", args.ArbitratorID)
+	return q.store(ctx).disputePage(ctx, where, args.disputeArgs)
+}
+
+// ============================================================================
+// PAGING
+// ============================================================================
+
+// pageArgs are the paging arguments of every connection
+type pageArgs struct {
+	First int32
+	After *string
+}
+
+// listing describes where one kind of record is read from. Pages are
+// ordered by columns that all sort in the same direction and together
+// identify a row, so a cursor holds the last row's values of them.
+type listing struct {
+	from       string
+	columns    string
+	order      []string
+	descending bool
+}
+
+var (
+	actorListing = listing{
+		from:    actorIDs,
+		columns: "id",
+		order:   []string{"id"},
+	}
+	ratingListing = listing{
+		from:       "ratings",
+		columns:    "rating_id, rater_id, actor_id, dimension, value, weight, ts, source, submitted_by, tx_id, block_number",
+		order:      []string{"ts", "rating_id"},
+		descending: true,
+	}
+	reputationListing = listing{
+		from:       "reputations",
+		columns:    "actor_id, dimension, score, ci_lower, ci_upper, total_events, last_updated, block_number",
+		order:      []string{"score", "actor_id", "dimension"},
+		descending: true,
+	}
+	disputeListing = listing{
+		from:       "disputes",
+		columns:    "dispute_id, rating_id, initiator_id, rater_id, actor_id, dimension, status, arbitrator_id, created_at, resolved_at, block_number",
+		order:      []string{"created_at", "dispute_id"},
+		descending: true,
+	}
+	stakeListing = listing{
+		from:    "stakes",
+		columns: "actor_id, balance, locked, updated_at, block_number",
+		order:   []string{"actor_id"},
+	}
+)
+
+// page is the part of a connection shared by every node type
+type page struct {
+	info  pageInfo
+	count func(ctx context.Context) (int32, error)
+}
+
+func (p *page) PageInfo() *pageInfo {
+	return &p.info
+}
+
+// TotalCount counts every node matching the filters, across all pages. It
+// is only queried when selected.
+func (p *page) TotalCount(ctx context.Context) (int32, error) {
+	return p.count(ctx)
+}
+
+type pageInfo struct {
+	endCursor   *string
+	hasNextPage bool
+}
+
+func (p *pageInfo) EndCursor() *string {
+	return p.endCursor
+}
+
+func (p *pageInfo) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+// fetchPage reads the page of l after args.After within s's tenant. scan
+// reads the current row into the caller's nodes and returns its values of
+// the order columns.
+func (s *Store) fetchPage(ctx context.Context, l listing, where *sqlWhere, args pageArgs, scan func(rows *sql.Rows) ([]interface{}, error)) (page, error) {
+	if args.First < 1 || args.First > maxLimit {
+		return page{}, fmt.Errorf("first must be between 1 and %d", maxLimit)
+	}
+	where.add("tenant = ?", s.tenant)
+
+	countQuery := "SELECT COUNT(*) FROM " + l.from + where.clause()
+	countArgs := append([]interface{}(nil), where.args...)
+	p := page{count: func(ctx context.Context) (int32, error) {
+		var count int32
+		if err := s.db.QueryRowContext(ctx, countQuery, countArgs...).Scan(&count); err != nil {
+			return 0, queryFailed(err)
+		}
+		return count, nil
+	}}
+
+	if args.After != nil {
+		values, err := decodeCursor(*args.After, len(l.order))
+		if err != nil {
+			return page{}, err
+		}
+		comparison := ">"
+		if l.descending {
+			comparison = "<"
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		where.add(fmt.Sprintf("(%s) %s (%s)", strings.Join(l.order, ", "), comparison, placeholders), values...)
+	}
+
+	var last []interface{}
+	fetched := 0
+	err := s.selectRows(ctx, l, where, int(args.First)+1, func(rows *sql.Rows) error {
+		if fetched == int(args.First) {
+			p.info.hasNextPage = true
+			return nil
+		}
+		fetched++
+		var err error
+		last, err = scan(rows)
+		return err
+	})
+	if err != nil {
+		return page{}, err
+	}
+
+	if last != nil {
+		cursor, err := encodeCursor(last)
+		if err != nil {
+			return page{}, queryFailed(err)
+		}
+		p.info.endCursor = &cursor
+	}
+	return p, nil
+}
+
+// fetchRows reads rows of l within s's tenant in page order, at most limit
+// of them unless limit is 0
+func (s *Store) fetchRows(ctx context.Context, l listing, where *sqlWhere, limit int, scan func(rows *sql.Rows) error) error {
+	where.add("tenant = ?", s.tenant)
+	return s.selectRows(ctx, l, where, limit, scan)
+}
+
+// selectRows runs fetchRows' query with where as given
+func (s *Store) selectRows(ctx context.Context, l listing, where *sqlWhere, limit int, scan func(rows *sql.Rows) error) error {
+	direction := " ASC"
+	if l.descending {
+		direction = " DESC"
+	}
+	query := "SELECT " + l.columns + " FROM " + l.from + where.clause() +
+		" ORDER BY " + strings.Join(l.order, direction+", ") + direction
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, where.args...)
+	if err != nil {
+		return queryFailed(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return queryFailed(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryFailed(err)
+	}
+	return nil
+}
+
+// encodeCursor renders order column values as an opaque cursor
+func encodeCursor(values []interface{}) (string, error) {
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(valuesJSON), nil
+}
+
+// decodeCursor parses a cursor of a listing with the given number of order
+// columns
+func decodeCursor(cursor string, columns int) ([]interface{}, error) {
+	invalid := errors.New("after is not a valid cursor")
+
+	valuesJSON, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(valuesJSON)))
+	decoder.UseNumber()
+
+	var values []interface{}
+	if err := decoder.Decode(&values); err != nil || len(values) != columns {
+		return nil, invalid
+	}
+	for i, value := range values {
+		switch v := value.(type) {
+		case string:
+		case json.Number:
+			// Passed as text, so Postgres parses it as the column's type
+			values[i] = v.String()
+		default:
+			return nil, invalid
+		}
+	}
+	return values, nil
+}
+
+// ============================================================================
+// FILTERS
+// ============================================================================
+
+// sqlWhere builds a WHERE clause. Conditions are written with ? for their
+// arguments, which are numbered in the order added.
+type sqlWhere struct {
+	conditions []string
+	args       []interface{}
+}
+
+func whereEqual(column string, value interface{}) *sqlWhere {
+	where := &sqlWhere{}
+	where.add(column+" = ?", value)
+	return where
+}
+
+// add appends a condition and its arguments
+func (w *sqlWhere) add(condition string, args ...interface{}) {
+	for _, arg := range args {
+		w.args = append(w.args, arg)
+		condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(w.args)), 1)
+	}
+	w.conditions = append(w.conditions, condition)
+}
+
+// optional appends a condition on an optional argument, unless the argument
+// was not given
+func (w *sqlWhere) optional(condition string, arg interface{}) {
+	var value interface{}
+	switch v := arg.(type) {
+	case *string:
+		if v == nil {
+			return
+		}
+		value = *v
+	case *graphql.ID:
+		if v == nil {
+			return
+		}
+		value = string(*v)
+	case *float64:
+		if v == nil {
+			return
+		}
+		value = *v
+	case *int32:
+		if v == nil {
+			return
+		}
+		value = *v
+	case *Timestamp:
+		if v == nil {
+			return
+		}
+		value = int64(*v)
+	default:
+		panic(fmt.Sprintf("unsupported optional argument %T", arg))
+	}
+	w.add(condition, value)
+}
+
+func (w *sqlWhere) clause() string {
+	if len(w.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conditions, " AND ")
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// queryFailed logs a database error and hides it from the client
+func queryFailed(err error) error {
+	log.Printf("graphql query failed: %v", err)
+	return errQueryFailed
+}
+
+// ============================================================================
+// SCALARS
+// ============================================================================
+
+// Timestamp is a Unix time as recorded by the chaincode. It has its own
+// scalar because millisecond times do not fit GraphQL's 32-bit Int.
+type Timestamp int64
+
+// ImplementsGraphQLType maps Timestamp to its scalar
+func (Timestamp) ImplementsGraphQLType(name string) bool {
+	return name == "Timestamp"
+}
+
+// UnmarshalGraphQL reads a Timestamp argument given as a number or a string.
+// The schema parser reads integer literals as Int, so large ones must be
+// quoted.
+func (t *Timestamp) UnmarshalGraphQL(input interface{}) error {
+	switch v := input.(type) {
+	case int32:
+		*t = Timestamp(v)
+	case int64:
+		*t = Timestamp(v)
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return fmt.Errorf("timestamp must be a whole number, got %v", v)
+		}
+		*t = Timestamp(v)
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("timestamp must be a whole number, got %q", v)
+		}
+		*t = Timestamp(parsed)
+	default:
+		return fmt.Errorf("timestamp must be a whole number, got %T", input)
+	}
+	return nil
+}
+
+// MarshalJSON writes the Timestamp as a JSON number
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(t), 10), nil
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// Resolvers of the GraphQL object types. Each holds one mirrored row;
+// related records are read when their fields are selected.
+
+// ratingArgs filter rating connections. Fields a parent record fixes are
+// left out of its schema arguments.
+type ratingArgs struct {
+	ActorID   *graphql.ID
+	RaterID   *graphql.ID
+	Dimension *string
+	Source    *string
+	Since     *Timestamp
+	Until     *Timestamp
+	pageArgs
+}
+
+// disputeArgs filter dispute connections
+type disputeArgs struct {
+	Status    *string
+	Dimension *string
+	pageArgs
+}
+
+// ============================================================================
+// ACTORS
+// ============================================================================
+
+type actorResolver struct {
+	s  *Store
+	id string
+}
+
+type actorConnection struct {
+	page
+	nodes []*actorResolver
+}
+
+func (c *actorConnection) Nodes() []*actorResolver {
+	return c.nodes
+}
+
+func (a *actorResolver) ID() graphql.ID {
+	return graphql.ID(a.id)
+}
+
+func (a *actorResolver) Stake(ctx context.Context) (*stakeResolver, error) {
+	var stake *stakeResolver
+	err := a.s.fetchRows(ctx, stakeListing, whereEqual("actor_id", a.id), 1, func(rows *sql.Rows) error {
+		stake = &stakeResolver{}
+		return rows.Scan(&stake.actorID, &stake.balance, &stake.locked, &stake.updatedAt, &stake.blockNumber)
+	})
+	return stake, err
+}
+
+func (a *actorResolver) Reputations(ctx context.Context, args struct{ Dimension *string }) ([]*reputationResolver, error) {
+	where := whereEqual("actor_id", a.id)
+	where.optional("dimension = ?", args.Dimension)
+
+	reputations := []*reputationResolver{}
+	err := a.s.fetchRows(ctx, reputationListing, where, 0, func(rows *sql.Rows) error {
+		rep, err := a.s.scanReputation(rows)
+		reputations = append(reputations, rep)
+		return err
+	})
+	return reputations, err
+}
+
+func (a *actorResolver) RatingsReceived(ctx context.Context, args ratingArgs) (*ratingConnection, error) {
+	return a.s.ratingPage(ctx, whereEqual("actor_id", a.id), args)
+}
+
+func (a *actorResolver) RatingsGiven(ctx context.Context, args ratingArgs) (*ratingConnection, error) {
+	return a.s.ratingPage(ctx, whereEqual("rater_id", a.id), args)
+}
+
+func (a *actorResolver) DisputesRaised(ctx context.Context, args disputeArgs) (*disputeConnection, error) {
+	return a.s.disputePage(ctx, whereEqual("initiator_id", a.id), args)
+}
+
+func (a *actorResolver) DisputesAgainst(ctx context.Context, args disputeArgs) (*disputeConnection, error) {
+	return a.s.disputePage(ctx, whereEqual("rater_id", a.id), args)
+}
+
+// ============================================================================
+// STAKES
+// ============================================================================
+
+type stakeResolver struct {
+	actorID     string
+	balance     float64
+	locked      float64
+	updatedAt   Timestamp
+	blockNumber int32
+}
+
+func (st *stakeResolver) Balance() float64 {
+	return st.balance
+}
+
+func (st *stakeResolver) Locked() float64 {
+	return st.locked
+}
+
+func (st *stakeResolver) UpdatedAt() Timestamp {
+	return st.updatedAt
+}
+
+func (st *stakeResolver) BlockNumber() int32 {
+	return st.blockNumber
+}
+
+// ============================================================================
+// RATINGS
+// ============================================================================
+
+type ratingResolver struct {
+	s           *Store
+	id          string
+	raterID     string
+	actorID     string
+	dimension   string
+	value       sql.NullFloat64
+	weight      float64
+	timestamp   Timestamp
+	source      string
+	submittedBy string
+	txID        string
+	blockNumber int32
+}
+
+type ratingConnection struct {
+	page
+	nodes []*ratingResolver
+}
+
+func (c *ratingConnection) Nodes() []*ratingResolver {
+	return c.nodes
+}
+
+func (s *Store) scanRating(rows *sql.Rows) (*ratingResolver, error) {
+	r := &ratingResolver{s: s}
+	err := rows.Scan(&r.id, &r.raterID, &r.actorID, &r.dimension, &r.value, &r.weight, &r.timestamp,
+		&r.source, &r.submittedBy, &r.txID, &r.blockNumber)
+	return r, err
+}
+
+// rating reads the first rating matching where, or nil
+func (s *Store) rating(ctx context.Context, where *sqlWhere) (*ratingResolver, error) {
+	var rating *ratingResolver
+	err := s.fetchRows(ctx, ratingListing, where, 1, func(rows *sql.Rows) error {
+		var err error
+		rating, err = s.scanRating(rows)
+		return err
+	})
+	return rating, err
+}
+
+// ratingPage pages the ratings matching where and the filter arguments
+func (s *Store) ratingPage(ctx context.Context, where *sqlWhere, args ratingArgs) (*ratingConnection, error) {
+	where.optional("actor_id = ?", args.ActorID)
+	where.optional("rater_id = ?", args.RaterID)
+	where.optional("dimension = ?", args.Dimension)
+	where.optional("source = ?", args.Source)
+	where.optional("ts >= ?", args.Since)
+	where.optional("ts < ?", args.Until)
+
+	conn := &ratingConnection{}
+	var err error
+	conn.page, err = s.fetchPage(ctx, ratingListing, where, args.pageArgs, func(rows *sql.Rows) ([]interface{}, error) {
+		rating, err := s.scanRating(rows)
+		if err != nil {
+			return nil, err
+		}
+		conn.nodes = append(conn.nodes, rating)
+		return []interface{}{rating.timestamp, rating.id}, nil
+	})
+	return conn, err
+}
+
+func (r *ratingResolver) ID() graphql.ID {
+	return graphql.ID(r.id)
+}
+
+func (r *ratingResolver) Rater() *actorResolver {
+	return &actorResolver{s: r.s, id: r.raterID}
+}
+
+func (r *ratingResolver) Actor() *actorResolver {
+	return &actorResolver{s: r.s, id: r.actorID}
+}
+
+func (r *ratingResolver) Dimension() string {
+	return r.dimension
+}
+
+func (r *ratingResolver) Value() *float64 {
+	if !r.value.Valid {
+		return nil
+	}
+	return &r.value.Float64
+}
+
+func (r *ratingResolver) Weight() float64 {
+	return r.weight
+}
+
+func (r *ratingResolver) Timestamp() Timestamp {
+	return r.timestamp
+}
+
+func (r *ratingResolver) Source() string {
+	return r.source
+}
+
+func (r *ratingResolver) SubmittedBy() string {
+	return r.submittedBy
+}
+
+func (r *ratingResolver) TxID() string {
+	return r.txID
+}
+
+func (r *ratingResolver) BlockNumber() int32 {
+	return r.blockNumber
+}
+
+func (r *ratingResolver) Disputes(ctx context.Context) ([]*disputeResolver, error) {
+	disputes := []*disputeResolver{}
+	err := r.s.fetchRows(ctx, disputeListing, whereEqual("rating_id", r.id), 0, func(rows *sql.Rows) error {
+		dispute, err := r.s.scanDispute(rows)
+		disputes = append(disputes, dispute)
+		return err
+	})
+	return disputes, err
+}
+
+func (r *ratingResolver) Reputation(ctx context.Context) (*reputationResolver, error) {
+	where := whereEqual("actor_id", r.actorID)
+	where.add("dimension = ?", r.dimension)
+	return r.s.reputation(ctx, where)
+}
+
+// ============================================================================
+// REPUTATIONS
+// ============================================================================
+
+type reputationResolver struct {
+	s           *Store
+	actorID     string
+	dimension   string
+	score       float64
+	ciLower     float64
+	ciUpper     float64
+	totalEvents int32
+	lastUpdated Timestamp
+	blockNumber int32
+}
+
+type reputationConnection struct {
+	page
+	nodes []*reputationResolver
+}
+
+func (c *reputationConnection) Nodes() []*reputationResolver {
+	return c.nodes
+}
+
+func (s *Store) scanReputation(rows *sql.Rows) (*reputationResolver, error) {
+	rep := &reputationResolver{s: s}
+	err := rows.Scan(&rep.actorID, &rep.dimension, &rep.score, &rep.ciLower, &rep.ciUpper, &rep.totalEvents,
+		&rep.lastUpdated, &rep.blockNumber)
+	return rep, err
+}
+
+// reputation reads the first reputation matching where, or nil
+func (s *Store) reputation(ctx context.Context, where *sqlWhere) (*reputationResolver, error) {
+	var rep *reputationResolver
+	err := s.fetchRows(ctx, reputationListing, where, 1, func(rows *sql.Rows) error {
+		var err error
+		rep, err = s.scanReputation(rows)
+		return err
+	})
+	return rep, err
+}
+
+// reputationPage pages the reputations matching where
+func (s *Store) reputationPage(ctx context.Context, where *sqlWhere, args pageArgs) (*reputationConnection, error) {
+	conn := &reputationConnection{}
+	var err error
+	conn.page, err = s.fetchPage(ctx, reputationListing, where, args, func(rows *sql.Rows) ([]interface{}, error) {
+		rep, err := s.scanReputation(rows)
+		if err != nil {
+			return nil, err
+		}
+		conn.nodes = append(conn.nodes, rep)
+		return []interface{}{rep.score, rep.actorID, rep.dimension}, nil
+	})
+	return conn, err
+}
+
+func (rep *reputationResolver) Actor() *actorResolver {
+	return &actorResolver{s: rep.s, id: rep.actorID}
+}
+
+func (rep *reputationResolver) Dimension() string {
+	return rep.dimension
+}
+
+func (rep *reputationResolver) Score() float64 {
+	return rep.score
+}
+
+func (rep *reputationResolver) CILower() float64 {
+	return rep.ciLower
+}
+
+func (rep *reputationResolver) CIUpper() float64 {
+	return rep.ciUpper
+}
+
+func (rep *reputationResolver) TotalEvents() int32 {
+	return rep.totalEvents
+}
+
+func (rep *reputationResolver) LastUpdated() Timestamp {
+	return rep.lastUpdated
+}
+
+func (rep *reputationResolver) BlockNumber() int32 {
+	return rep.blockNumber
+}
+
+func (rep *reputationResolver) Ratings(ctx context.Context, args ratingArgs) (*ratingConnection, error) {
+	where := whereEqual("actor_id", rep.actorID)
+	where.add("dimension = ?", rep.dimension)
+	return rep.s.ratingPage(ctx, where, args)
+}
+
+// ============================================================================
+// DISPUTES
+// ============================================================================
+
+type disputeResolver struct {
+	s            *Store
+	id           string
+	ratingID     string
+	initiatorID  string
+	raterID      string
+	actorID      string
+	dimension    string
+	status       string
+	arbitratorID string
+	createdAt    Timestamp
+	resolvedAt   Timestamp
+	blockNumber  int32
+}
+
+type disputeConnection struct {
+	page
+	nodes []*disputeResolver
+}
+
+func (c *disputeConnection) Nodes() []*disputeResolver {
+	return c.nodes
+}
+
+func (s *Store) scanDispute(rows *sql.Rows) (*disputeResolver, error) {
+	d := &disputeResolver{s: s}
+	err := rows.Scan(&d.id, &d.ratingID, &d.initiatorID, &d.raterID, &d.actorID, &d.dimension, &d.status,
+		&d.arbitratorID, &d.createdAt, &d.resolvedAt, &d.blockNumber)
+	return d, err
+}
+
+// dispute reads the first dispute matching where, or nil
+func (s *Store) dispute(ctx context.Context, where *sqlWhere) (*disputeResolver, error) {
+	var dispute *disputeResolver
+	err := s.fetchRows(ctx, disputeListing, where, 1, func(rows *sql.Rows) error {
+		var err error
+		dispute, err = s.scanDispute(rows)
+		return err
+	})
+	return dispute, err
+}
+
+// disputePage pages the disputes matching where and the filter arguments
+func (s *Store) disputePage(ctx context.Context, where *sqlWhere, args disputeArgs) (*disputeConnection, error) {
+	where.optional("status = ?", args.Status)
+	where.optional("dimension = ?", args.Dimension)
+
+	conn := &disputeConnection{}
+	var err error
+	conn.page, err = s.fetchPage(ctx, disputeListing, where, args.pageArgs, func(rows *sql.Rows) ([]interface{}, error) {
+		dispute, err := s.scanDispute(rows)
+		if err != nil {
+			return nil, err
+		}
+		conn.nodes = append(conn.nodes, dispute)
+		return []interface{}{dispute.createdAt, dispute.id}, nil
+	})
+	return conn, err
+}
+
+func (d *disputeResolver) ID() graphql.ID {
+	return graphql.ID(d.id)
+}
+
+func (d *disputeResolver) Rating(ctx context.Context) (*ratingResolver, error) {
+	return d.s.rating(ctx, whereEqual("rating_id", d.ratingID))
+}
+
+func (d *disputeResolver) Initiator() *actorResolver {
+	return &actorResolver{s: d.s, id: d.initiatorID}
+}
+
+func (d *disputeResolver) Rater() *actorResolver {
+	return &actorResolver{s: d.s, id: d.raterID}
+}
+
+func (d *disputeResolver) Actor() *actorResolver {
+	return &actorResolver{s: d.s, id: d.actorID}
+}
+
+func (d *disputeResolver) Arbitrator() *actorResolver {
+	if d.arbitratorID == "" {
+		return nil
+	}
+	return &actorResolver{s: d.s, id: d.arbitratorID}
+}
+
+func (d *disputeResolver) Dimension() string {
+	return d.dimension
+}
+
+func (d *disputeResolver) Status() string {
+	return d.status
+}
+
+func (d *disputeResolver) CreatedAt() Timestamp {
+	return d.createdAt
+}
+
+func (d *disputeResolver) ResolvedAt() *Timestamp {
+	if d.resolvedAt == 0 {
+		return nil
+	}
+	return &d.resolvedAt
+}
+
+func (d *disputeResolver) BlockNumber() int32 {
+	return d.blockNumber
+}
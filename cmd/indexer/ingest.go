@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+
+	"github.com/raddadalmaayn/am-reputation/pkg/client"
+)
+
+// reputation is one row of the reputations table
+type reputation struct {
+	client.DimensionScore
+	actorID   string
+	dimension string
+}
+
+// changeSet is everything one chaincode transaction changed in the mirror
+type changeSet struct {
+	blockNumber uint64
+	txID        string
+	tenant      string // tenant the transaction ran in
+	events      []client.EventEnvelope
+	ratings     []client.Rating
+	reputations []reputation
+	disputes    []client.Dispute
+	stakes      []client.Stake
+}
+
+// refreshSet collects the records a transaction's events touched. Events
+// carry IDs rather than whole records, so the records are re-read through
+// the gateway; the mirror therefore converges on the latest committed state
+// rather than replaying each intermediate one.
+type refreshSet struct {
+	reputations map[string]bool // actor IDs, all dimensions
+	disputes    map[string]bool
+	stakes      map[string]bool
+}
+
+// storedCheckpoint resumes the event stream after the last stored event
+type storedCheckpoint struct {
+	blockNumber uint64
+	txID        string
+}
+
+func (c storedCheckpoint) BlockNumber() uint64   { return c.blockNumber }
+func (c storedCheckpoint) TransactionID() string { return c.txID }
+
+// Indexer applies chaincode events to the Store
+type Indexer struct {
+	store  *Store
+	client *client.Client
+}
+
+// Run follows chaincode events until ctx is cancelled, reconnecting from
+// the stored checkpoint after stream or storage errors
+func (ix *Indexer) Run(ctx context.Context, network *fabric.Network, chaincodeName string, startBlock uint64) error {
+	backoff := time.Second
+	for {
+		err := ix.follow(ctx, network, chaincodeName, startBlock)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("event stream interrupted, retrying in %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (ix *Indexer) follow(ctx context.Context, network *fabric.Network, chaincodeName string, startBlock uint64) error {
+	blockNumber, txID, ok, err := ix.store.Checkpoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	option := fabric.WithStartBlock(startBlock)
+	if ok {
+		option = fabric.WithCheckpoint(storedCheckpoint{blockNumber: blockNumber, txID: txID})
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := network.ChaincodeEvents(streamCtx, chaincodeName, option)
+	if err != nil {
+		return fmt.Errorf("failed to start event stream: %v", err)
+	}
+
+	for event := range events {
+		if err := ix.handle(ctx, event); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("event stream closed")
+}
+
+// handle applies one chaincode event, together with the events the same
+// transaction emitted before it
+func (ix *Indexer) handle(ctx context.Context, event *fabric.ChaincodeEvent) error {
+	events, err := client.DecodeEvents(event)
+	if err != nil {
+		log.Printf("skipping undecodable event: %v", err)
+		return nil
+	}
+
+	// A transaction runs in one tenant, which every event it emitted carries
+	changes := &changeSet{
+		blockNumber: event.BlockNumber,
+		txID:        event.TransactionID,
+		tenant:      events[0].Tenant,
+	}
+	refresh := &refreshSet{
+		reputations: map[string]bool{},
+		disputes:    map[string]bool{},
+		stakes:      map[string]bool{},
+	}
+
+	for _, e := range events {
+		changes.events = append(changes.events, e.EventEnvelope)
+		if err := collect(&e.EventEnvelope, changes, refresh); err != nil {
+			log.Printf("skipping malformed %s payload in tx %s: %v", e.EventType, event.TransactionID, err)
+		}
+	}
+
+	if err := ix.reread(ctx, changes, refresh); err != nil {
+		return err
+	}
+	return ix.store.Apply(ctx, changes)
+}
+
+// collect records what one event changed
+func collect(event *client.EventEnvelope, changes *changeSet, refresh *refreshSet) error {
+	var payload struct {
+		ActorID           string   `json:"actorId"`
+		RaterID           string   `json:"raterId"`
+		InitiatorID       string   `json:"initiatorId"`
+		DisputeID         string   `json:"disputeId"`
+		CancelledDisputes []string `json:"cancelledDisputes"`
+	}
+	if err := event.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	switch event.EventType {
+	case "RatingSubmitted":
+		var submitted client.RatingSubmittedEvent
+		if err := event.DecodePayload(&submitted); err != nil {
+			return err
+		}
+		changes.ratings = append(changes.ratings, client.Rating{
+			RatingID:    submitted.RatingID,
+			RaterID:     submitted.RaterID,
+			ActorID:     submitted.ActorID,
+			Dimension:   submitted.Dimension,
+			Value:       submitted.Value,
+			Weight:      submitted.Weight,
+			Timestamp:   submitted.Timestamp,
+			TxID:        event.TxID,
+			SubmittedBy: submitted.SubmittedBy,
+			Source:      submitted.Source,
+
+			Confidential: submitted.Confidential,
+			ValueHash:    submitted.ValueHash,
+		})
+		refresh.reputations[submitted.ActorID] = true
+
+	case "ReputationUpdated":
+		refresh.reputations[payload.ActorID] = true
+
+	case "DisputeInitiated", "DisputeResolved", "DisputeExpired":
+		refresh.disputes[payload.DisputeID] = true
+
+	case "StakeAdded", "StakeLocked", "StakeUnlocked", "StakeRefunded", "StakeReleased", "ActorOffboarded":
+		refresh.stakes[payload.ActorID] = true
+
+	case "StakeSlashed":
+		refresh.stakes[payload.RaterID] = true
+		if payload.InitiatorID != "" {
+			refresh.stakes[payload.InitiatorID] = true
+		}
+
+	case "ActorDeactivated":
+		refresh.stakes[payload.ActorID] = true
+		for _, disputeID := range payload.CancelledDisputes {
+			refresh.disputes[disputeID] = true
+		}
+	}
+	return nil
+}
+
+// reread loads the current state of every record the events touched, from
+// the tenant the transaction ran in. Resolving a dispute changes both
+// parties' reputations and stakes, so those are re-read as well.
+func (ix *Indexer) reread(ctx context.Context, changes *changeSet, refresh *refreshSet) error {
+	reader := ix.client.ForTenant(changes.tenant)
+
+	for disputeID := range refresh.disputes {
+		dispute, err := reader.GetDispute(ctx, disputeID)
+		if err != nil {
+			return err
+		}
+		changes.disputes = append(changes.disputes, *dispute)
+
+		if dispute.Status != "pending" {
+			refresh.reputations[dispute.ActorID] = true
+			refresh.reputations[dispute.RaterID] = true
+			refresh.stakes[dispute.RaterID] = true
+		}
+		refresh.stakes[dispute.InitiatorID] = true
+	}
+
+	for actorID := range refresh.reputations {
+		scores, err := reader.GetReputationAllDimensions(ctx, actorID)
+		if err != nil {
+			return err
+		}
+		for dimension, score := range scores {
+			changes.reputations = append(changes.reputations, reputation{
+				DimensionScore: score,
+				actorID:        actorID,
+				dimension:      dimension,
+			})
+		}
+	}
+
+	for actorID := range refresh.stakes {
+		stake, err := reader.GetStake(ctx, actorID)
+		if err != nil {
+			return err
+		}
+		changes.stakes = append(changes.stakes, *stake)
+	}
+
+	return nil
+}
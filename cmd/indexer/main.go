@@ -0,0 +1,111 @@
+// Command indexer mirrors the reputation chaincode into Postgres and serves
+// the analytical queries that are too heavy to run in chaincode.
+//
+// It follows the chaincode's events through the Fabric Gateway, re-reads
+// the records each event touches, and upserts them into the mirror together
+// with a checkpoint, so a restarted indexer resumes after the last event it
+// stored.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/raddadalmaayn/am-reputation/pkg/client"
+)
+
+// config holds the indexer's settings. Every flag can also be given in the
+// environment variable named in its usage text.
+type config struct {
+	gateway     client.ConnectionConfig
+	chaincode   string
+	databaseURL string
+	listenAddr  string
+	startBlock  uint64
+}
+
+func main() {
+	cfg := parseFlags()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := sql.Open("postgres", cfg.databaseURL)
+	if err != nil {
+		log.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	store := &Store{db: db}
+	if err := store.Migrate(ctx); err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
+	conn, err := client.Connect(cfg.gateway)
+	if err != nil {
+		log.Fatalf("failed to connect to gateway: %v", err)
+	}
+	defer conn.Close()
+
+	indexer := &Indexer{
+		store:  store,
+		client: client.New(conn.Network.GetContract(cfg.chaincode)),
+	}
+
+	server := &http.Server{
+		Addr:              cfg.listenAddr,
+		Handler:           newAPI(store),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("failed to serve API: %v", err)
+		}
+	}()
+
+	log.Printf("indexing %s on %s, serving on %s", cfg.chaincode, cfg.gateway.Channel, cfg.listenAddr)
+	if err := indexer.Run(ctx, conn.Network, cfg.chaincode, cfg.startBlock); err != nil && ctx.Err() == nil {
+		log.Printf("indexer stopped: %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+}
+
+func parseFlags() *config {
+	cfg := &config{}
+	flag.StringVar(&cfg.gateway.PeerEndpoint, "peer", envOr("INDEXER_PEER", "localhost:7051"), "gateway peer endpoint (INDEXER_PEER)")
+	flag.StringVar(&cfg.gateway.PeerHostAlias, "peer-host-alias", envOr("INDEXER_PEER_HOST_ALIAS", "peer0.org1.example.com"), "TLS server name of the peer (INDEXER_PEER_HOST_ALIAS)")
+	flag.StringVar(&cfg.gateway.TLSCertPath, "tls-cert", envOr("INDEXER_TLS_CERT", ""), "peer TLS CA certificate (INDEXER_TLS_CERT)")
+	flag.StringVar(&cfg.gateway.MSPID, "msp-id", envOr("INDEXER_MSP_ID", "Org1MSP"), "client MSP ID (INDEXER_MSP_ID)")
+	flag.StringVar(&cfg.gateway.CertPath, "cert", envOr("INDEXER_CERT", ""), "client certificate (INDEXER_CERT)")
+	flag.StringVar(&cfg.gateway.KeyPath, "key", envOr("INDEXER_KEY", ""), "client private key (INDEXER_KEY)")
+	flag.StringVar(&cfg.gateway.Channel, "channel", envOr("INDEXER_CHANNEL", "mychannel"), "channel name (INDEXER_CHANNEL)")
+	flag.StringVar(&cfg.chaincode, "chaincode", envOr("INDEXER_CHAINCODE", "repcc"), "chaincode name (INDEXER_CHAINCODE)")
+	flag.StringVar(&cfg.databaseURL, "database-url", envOr("INDEXER_DATABASE_URL", "postgres://localhost/reputation?sslmode=disable"), "Postgres connection URL (INDEXER_DATABASE_URL)")
+	flag.StringVar(&cfg.listenAddr, "listen", envOr("INDEXER_LISTEN", ":8080"), "HTTP listen address (INDEXER_LISTEN)")
+	flag.Uint64Var(&cfg.startBlock, "start-block", 0, "block to start from when there is no checkpoint")
+	flag.Parse()
+
+	if cfg.gateway.TLSCertPath == "" || cfg.gateway.CertPath == "" || cfg.gateway.KeyPath == "" {
+		log.Fatal("-tls-cert, -cert and -key are required")
+	}
+	return cfg
+}
+
+func envOr(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Store is the Postgres mirror. Rows are upserted by key, so applying the
+// same event twice leaves the mirror unchanged. Every record is keyed by its
+// tenant as well as its ID, since tenants share one ID space.
+type Store struct {
+	db     *sql.DB
+	tenant string // tenant queries read, "" for the default space
+}
+
+// forTenant returns a copy of s whose queries read tenant's records
+func (s *Store) forTenant(tenant string) *Store {
+	scoped := *s
+	scoped.tenant = tenant
+	return &scoped
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS indexer_checkpoint (
+	id           INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+	block_number BIGINT NOT NULL,
+	tx_id        TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS events (
+	tenant       TEXT NOT NULL DEFAULT '',
+	block_number BIGINT NOT NULL,
+	tx_id        TEXT NOT NULL,
+	seq          INT NOT NULL,
+	event_type   TEXT NOT NULL,
+	ts           BIGINT NOT NULL,
+	payload      JSONB NOT NULL,
+	PRIMARY KEY (tx_id, seq)
+);
+CREATE INDEX IF NOT EXISTS events_type_idx ON events (event_type, block_number);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS initiator_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS keys JSONB NOT NULL DEFAULT '[]';
+CREATE INDEX IF NOT EXISTS events_keys_idx ON events USING GIN (keys);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS ratings (
+	tenant       TEXT NOT NULL DEFAULT '',
+	rating_id    TEXT NOT NULL,
+	rater_id     TEXT NOT NULL,
+	actor_id     TEXT NOT NULL,
+	dimension    TEXT NOT NULL,
+	value        DOUBLE PRECISION, -- NULL for confidential ratings
+	weight       DOUBLE PRECISION NOT NULL,
+	ts           BIGINT NOT NULL,
+	source       TEXT NOT NULL,
+	submitted_by TEXT NOT NULL,
+	tx_id        TEXT NOT NULL,
+	block_number BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ratings_actor_idx ON ratings (actor_id, dimension, ts);
+CREATE INDEX IF NOT EXISTS ratings_rater_idx ON ratings (rater_id, ts);
+ALTER TABLE ratings ALTER COLUMN value DROP NOT NULL;
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE ratings DROP CONSTRAINT IF EXISTS ratings_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS ratings_key ON ratings (tenant, rating_id);
+
+CREATE TABLE IF NOT EXISTS reputations (
+	tenant       TEXT NOT NULL DEFAULT '',
+	actor_id     TEXT NOT NULL,
+	dimension    TEXT NOT NULL,
+	score        DOUBLE PRECISION NOT NULL,
+	ci_lower     DOUBLE PRECISION NOT NULL,
+	ci_upper     DOUBLE PRECISION NOT NULL,
+	total_events INT NOT NULL,
+	last_updated BIGINT NOT NULL,
+	block_number BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS reputations_dimension_idx ON reputations (dimension, score);
+ALTER TABLE reputations ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE reputations DROP CONSTRAINT IF EXISTS reputations_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS reputations_key ON reputations (tenant, actor_id, dimension);
+
+CREATE TABLE IF NOT EXISTS disputes (
+	tenant        TEXT NOT NULL DEFAULT '',
+	dispute_id    TEXT NOT NULL,
+	rating_id     TEXT NOT NULL,
+	initiator_id  TEXT NOT NULL,
+	rater_id      TEXT NOT NULL,
+	actor_id      TEXT NOT NULL,
+	dimension     TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	arbitrator_id TEXT NOT NULL,
+	created_at    BIGINT NOT NULL,
+	resolved_at   BIGINT NOT NULL,
+	block_number  BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS disputes_rater_idx ON disputes (rater_id, status);
+ALTER TABLE disputes ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE disputes DROP CONSTRAINT IF EXISTS disputes_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS disputes_key ON disputes (tenant, dispute_id);
+
+CREATE TABLE IF NOT EXISTS stakes (
+	tenant       TEXT NOT NULL DEFAULT '',
+	actor_id     TEXT NOT NULL,
+	balance      DOUBLE PRECISION NOT NULL,
+	locked       DOUBLE PRECISION NOT NULL,
+	updated_at   BIGINT NOT NULL,
+	block_number BIGINT NOT NULL
+);
+ALTER TABLE stakes ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT '';
+ALTER TABLE stakes DROP CONSTRAINT IF EXISTS stakes_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS stakes_key ON stakes (tenant, actor_id);
+`
+
+// Migrate creates the mirror tables if they do not exist
+func (s *Store) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("failed to create schema: %v", err)
+	}
+	return nil
+}
+
+// Checkpoint returns the block and transaction of the last stored event.
+// ok is false before the first event has been stored.
+func (s *Store) Checkpoint(ctx context.Context) (blockNumber uint64, txID string, ok bool, err error) {
+	row := s.db.QueryRowContext(ctx, `SELECT block_number, tx_id FROM indexer_checkpoint WHERE id = 1`)
+	if err := row.Scan(&blockNumber, &txID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", false, nil
+		}
+		return 0, "", false, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	return blockNumber, txID, true, nil
+}
+
+// Apply stores one transaction's changes and advances the checkpoint past
+// it, atomically
+func (s *Store) Apply(ctx context.Context, changes *changeSet) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for seq, event := range changes.events {
+		payload, err := json.Marshal(event.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		keys, err := json.Marshal(append([]string{}, event.Keys...))
+		if err != nil {
+			return fmt.Errorf("failed to marshal keys: %v", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO events (block_number, tx_id, seq, event_type, ts, payload, initiator_id, keys, tenant)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (tx_id, seq) DO NOTHING`,
+			changes.blockNumber, changes.txID, seq, event.EventType, event.Timestamp, payload,
+			event.InitiatorID, keys, event.Tenant)
+		if err != nil {
+			return fmt.Errorf("failed to store event: %v", err)
+		}
+	}
+
+	for _, rating := range changes.ratings {
+		var value interface{} = rating.Value
+		if rating.Confidential {
+			value = nil
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO ratings (tenant, rating_id, rater_id, actor_id, dimension, value, weight, ts, source, submitted_by, tx_id, block_number)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (tenant, rating_id) DO UPDATE SET
+				value = EXCLUDED.value, weight = EXCLUDED.weight, block_number = EXCLUDED.block_number`,
+			changes.tenant, rating.RatingID, rating.RaterID, rating.ActorID, rating.Dimension, value, rating.Weight,
+			rating.Timestamp, rating.Source, rating.SubmittedBy, changes.txID, changes.blockNumber)
+		if err != nil {
+			return fmt.Errorf("failed to store rating: %v", err)
+		}
+	}
+
+	for _, rep := range changes.reputations {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO reputations (tenant, actor_id, dimension, score, ci_lower, ci_upper, total_events, last_updated, block_number)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (tenant, actor_id, dimension) DO UPDATE SET
+				score = EXCLUDED.score, ci_lower = EXCLUDED.ci_lower, ci_upper = EXCLUDED.ci_upper,
+				total_events = EXCLUDED.total_events, last_updated = EXCLUDED.last_updated,
+				block_number = EXCLUDED.block_number`,
+			changes.tenant, rep.actorID, rep.dimension, rep.Score, rep.CILower, rep.CIUpper, rep.TotalEvents, rep.LastUpdated,
+			changes.blockNumber)
+		if err != nil {
+			return fmt.Errorf("failed to store reputation: %v", err)
+		}
+	}
+
+	for _, dispute := range changes.disputes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO disputes (tenant, dispute_id, rating_id, initiator_id, rater_id, actor_id, dimension, status, arbitrator_id, created_at, resolved_at, block_number)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (tenant, dispute_id) DO UPDATE SET
+				status = EXCLUDED.status, arbitrator_id = EXCLUDED.arbitrator_id,
+				resolved_at = EXCLUDED.resolved_at, block_number = EXCLUDED.block_number`,
+			changes.tenant, dispute.DisputeID, dispute.RatingID, dispute.InitiatorID, dispute.RaterID, dispute.ActorID,
+			dispute.Dimension, dispute.Status, dispute.ArbitratorID, dispute.CreatedAt, dispute.ResolvedAt,
+			changes.blockNumber)
+		if err != nil {
+			return fmt.Errorf("failed to store dispute: %v", err)
+		}
+	}
+
+	for _, stake := range changes.stakes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO stakes (tenant, actor_id, balance, locked, updated_at, block_number)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (tenant, actor_id) DO UPDATE SET
+				balance = EXCLUDED.balance, locked = EXCLUDED.locked,
+				updated_at = EXCLUDED.updated_at, block_number = EXCLUDED.block_number`,
+			changes.tenant, stake.ActorID, stake.Balance, stake.Locked, stake.UpdatedAt, changes.blockNumber)
+		if err != nil {
+			return fmt.Errorf("failed to store stake: %v", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO indexer_checkpoint (id, block_number, tx_id) VALUES (1, $1, $2)
+		ON CONFLICT (id) DO UPDATE SET block_number = EXCLUDED.block_number, tx_id = EXCLUDED.tx_id`,
+		changes.blockNumber, changes.txID)
+	if err != nil {
+		return fmt.Errorf("failed to store checkpoint: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
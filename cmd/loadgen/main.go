@@ -0,0 +1,268 @@
+// Command loadgen drives the chaincode's hot path at fixed rates and reports
+// latency and MVCC conflict rates, so that performance regressions show up
+// as numbers rather than anecdotes. Ratings are submitted as the configured
+// identity against a pool of synthetic actors; a smaller pool concentrates
+// writes on fewer reputation keys and raises the conflict rate.
+//
+//	loadgen -tls-cert ca.pem -cert user.pem -key user_sk \
+//	    -submit-rate 50 -query-rate 200 -actors 20 -duration 2m -json out.json
+//
+// Calls are made without client retries, so every MVCC conflict is counted.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/raddadalmaayn/am-reputation/pkg/client"
+)
+
+type config struct {
+	gateway        client.ConnectionConfig
+	chaincode      string
+	duration       time.Duration
+	submitRate     float64
+	queryRate      float64
+	workers        int
+	actors         int
+	actorPrefix    string
+	dimension      string
+	stake          float64
+	reportInterval time.Duration
+	jsonPath       string
+}
+
+// operation is one kind of call the generator issues
+type operation struct {
+	name  string
+	rate  float64
+	call  func(ctx context.Context, seq int64) error
+	stats *opStats
+}
+
+func main() {
+	cfg := parseFlags()
+
+	conn, err := client.Connect(cfg.gateway)
+	if err != nil {
+		log.Fatalf("failed to connect to gateway: %v", err)
+	}
+	defer conn.Close()
+
+	rep := client.New(conn.Network.GetContract(cfg.chaincode),
+		client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if cfg.stake > 0 {
+		if err := rep.AddStake(ctx, cfg.stake); err != nil {
+			log.Fatalf("failed to add stake: %v", err)
+		}
+		log.Printf("added stake %g", cfg.stake)
+	}
+
+	var lastTimestamp int64
+	operations := []*operation{
+		{
+			name: "SubmitRating",
+			rate: cfg.submitRate,
+			call: func(ctx context.Context, seq int64) error {
+				value := float64(seq%101) / 100
+				_, err := rep.SubmitRating(ctx, actorID(cfg, seq), cfg.dimension, value,
+					"loadgen", uniqueTimestamp(&lastTimestamp))
+				return err
+			},
+		},
+		{
+			name: "GetReputation",
+			rate: cfg.queryRate,
+			call: func(ctx context.Context, seq int64) error {
+				_, err := rep.GetReputation(ctx, actorID(cfg, seq), cfg.dimension)
+				return err
+			},
+		},
+	}
+	for _, op := range operations {
+		op.stats = newOpStats(op.name)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	log.Printf("running for %s: %g SubmitRating/s, %g GetReputation/s over %d actors with %d workers",
+		cfg.duration, cfg.submitRate, cfg.queryRate, cfg.actors, cfg.workers)
+	elapsed := run(runCtx, cfg, operations)
+
+	summary := newSummary(cfg, elapsed, operations)
+	summary.print(os.Stdout)
+
+	if cfg.jsonPath != "" {
+		summaryJSON, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to marshal summary: %v", err)
+		}
+		if err := os.WriteFile(cfg.jsonPath, append(summaryJSON, '\n'), 0o644); err != nil {
+			log.Fatalf("failed to write summary: %v", err)
+		}
+	}
+}
+
+// run issues calls at each operation's rate until ctx ends, then waits for
+// calls in flight. A call that finds every worker busy is dropped and
+// counted rather than queued, so that a slow network does not turn the
+// fixed rate into a burst later.
+func run(ctx context.Context, cfg *config, operations []*operation) time.Duration {
+	type job struct {
+		op  *operation
+		seq int64
+	}
+	jobs := make(chan job)
+
+	var workers sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for j := range jobs {
+				// In-flight calls finish even after the run ends
+				callCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+				start := time.Now()
+				err := j.op.call(callCtx, j.seq)
+				cancel()
+				j.op.stats.record(time.Since(start), err)
+			}
+		}()
+	}
+
+	start := time.Now()
+	var schedulers sync.WaitGroup
+	for _, op := range operations {
+		if op.rate <= 0 {
+			continue
+		}
+		schedulers.Add(1)
+		go func(op *operation) {
+			defer schedulers.Done()
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / op.rate))
+			defer ticker.Stop()
+
+			var seq int64
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					select {
+					case jobs <- job{op: op, seq: seq}:
+					default:
+						op.stats.drop()
+					}
+					seq++
+				}
+			}
+		}(op)
+	}
+
+	reportDone := make(chan struct{})
+	go func() {
+		defer close(reportDone)
+		if cfg.reportInterval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(cfg.reportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, op := range operations {
+					if op.rate > 0 {
+						log.Print(op.stats.interval())
+					}
+				}
+			}
+		}
+	}()
+
+	schedulers.Wait()
+	elapsed := time.Since(start)
+	close(jobs)
+	workers.Wait()
+	<-reportDone
+
+	return elapsed
+}
+
+// actorID picks the synthetic actor for a call
+func actorID(cfg *config, seq int64) string {
+	return cfg.actorPrefix + strconv.FormatInt(seq%int64(cfg.actors), 10)
+}
+
+// uniqueTimestamp returns the current time in milliseconds, advanced past
+// the last value returned, since rating IDs are derived from the timestamp
+func uniqueTimestamp(last *int64) int64 {
+	for {
+		previous := atomic.LoadInt64(last)
+		next := time.Now().UnixMilli()
+		if next <= previous {
+			next = previous + 1
+		}
+		if atomic.CompareAndSwapInt64(last, previous, next) {
+			return next
+		}
+	}
+}
+
+func parseFlags() *config {
+	cfg := &config{}
+	flag.StringVar(&cfg.gateway.PeerEndpoint, "peer", envOr("LOADGEN_PEER", "localhost:7051"), "gateway peer endpoint (LOADGEN_PEER)")
+	flag.StringVar(&cfg.gateway.PeerHostAlias, "peer-host-alias", envOr("LOADGEN_PEER_HOST_ALIAS", "peer0.org1.example.com"), "TLS server name of the peer (LOADGEN_PEER_HOST_ALIAS)")
+	flag.StringVar(&cfg.gateway.TLSCertPath, "tls-cert", envOr("LOADGEN_TLS_CERT", ""), "peer TLS CA certificate (LOADGEN_TLS_CERT)")
+	flag.StringVar(&cfg.gateway.MSPID, "msp-id", envOr("LOADGEN_MSP_ID", "Org1MSP"), "client MSP ID (LOADGEN_MSP_ID)")
+	flag.StringVar(&cfg.gateway.CertPath, "cert", envOr("LOADGEN_CERT", ""), "client certificate (LOADGEN_CERT)")
+	flag.StringVar(&cfg.gateway.KeyPath, "key", envOr("LOADGEN_KEY", ""), "client private key (LOADGEN_KEY)")
+	flag.StringVar(&cfg.gateway.Channel, "channel", envOr("LOADGEN_CHANNEL", "mychannel"), "channel name (LOADGEN_CHANNEL)")
+	flag.StringVar(&cfg.chaincode, "chaincode", envOr("LOADGEN_CHAINCODE", "repcc"), "chaincode name (LOADGEN_CHAINCODE)")
+	flag.DurationVar(&cfg.duration, "duration", time.Minute, "how long to generate load")
+	flag.Float64Var(&cfg.submitRate, "submit-rate", 10, "SubmitRating calls per second (0 disables)")
+	flag.Float64Var(&cfg.queryRate, "query-rate", 50, "GetReputation calls per second (0 disables)")
+	flag.IntVar(&cfg.workers, "workers", 32, "maximum calls in flight")
+	flag.IntVar(&cfg.actors, "actors", 100, "number of synthetic actors rated and queried")
+	flag.StringVar(&cfg.actorPrefix, "actor-prefix", "loadgen-actor-", "prefix of the synthetic actor IDs")
+	flag.StringVar(&cfg.dimension, "dimension", "quality", "dimension to rate and query")
+	flag.Float64Var(&cfg.stake, "stake", 0, "stake to add before starting, if the identity has none yet")
+	flag.DurationVar(&cfg.reportInterval, "report-interval", 10*time.Second, "interval of progress reports (0 disables)")
+	flag.StringVar(&cfg.jsonPath, "json", "", "write the final summary as JSON to this file")
+	flag.Parse()
+
+	if cfg.gateway.TLSCertPath == "" || cfg.gateway.CertPath == "" || cfg.gateway.KeyPath == "" {
+		log.Fatal("-tls-cert, -cert and -key are required")
+	}
+	if cfg.workers < 1 || cfg.actors < 1 {
+		log.Fatal("-workers and -actors must be at least 1")
+	}
+	if cfg.submitRate < 0 || cfg.queryRate < 0 {
+		log.Fatal("rates must not be negative")
+	}
+	if cfg.submitRate == 0 && cfg.queryRate == 0 {
+		log.Fatal("nothing to do: -submit-rate and -query-rate are both 0")
+	}
+	return cfg
+}
+
+func envOr(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
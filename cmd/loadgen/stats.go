@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// opStats accumulates the outcomes and latencies of one operation
+type opStats struct {
+	name string
+
+	mu        sync.Mutex
+	latencies []time.Duration // successful calls, whole run
+	recent    []time.Duration // successful calls since the last interval report
+	succeeded int
+	conflicts int // MVCC and phantom read conflicts
+	failed    int // every other error
+	dropped   int // not issued because every worker was busy
+	lastError string
+}
+
+func newOpStats(name string) *opStats {
+	return &opStats{name: name}
+}
+
+// record counts a finished call
+func (s *opStats) record(latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case err == nil:
+		s.succeeded++
+		s.latencies = append(s.latencies, latency)
+		s.recent = append(s.recent, latency)
+	case isConflict(err):
+		s.conflicts++
+	default:
+		s.failed++
+		s.lastError = err.Error()
+	}
+}
+
+// drop counts a call that was not issued
+func (s *opStats) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped++
+}
+
+// interval renders a progress line for the calls since the previous one
+func (s *opStats) interval() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recent := percentiles(s.recent)
+	s.recent = nil
+	return fmt.Sprintf("%s: %d ok, %d conflicts, %d failed, %d dropped; interval p50 %s p99 %s",
+		s.name, s.succeeded, s.conflicts, s.failed, s.dropped, recent.P50, recent.P99)
+}
+
+// isConflict reports whether a call failed validation on a read conflict
+func isConflict(err error) bool {
+	var commitErr *fabric.CommitError
+	if !errors.As(err, &commitErr) {
+		return false
+	}
+	return commitErr.Code == peer.TxValidationCode_MVCC_READ_CONFLICT ||
+		commitErr.Code == peer.TxValidationCode_PHANTOM_READ_CONFLICT
+}
+
+// ============================================================================
+// SUMMARY
+// ============================================================================
+
+// Latency is a latency distribution in milliseconds
+type Latency struct {
+	P50 Millis `json:"p50Ms"`
+	P90 Millis `json:"p90Ms"`
+	P99 Millis `json:"p99Ms"`
+	Max Millis `json:"maxMs"`
+}
+
+// Millis is a duration reported in milliseconds
+type Millis float64
+
+func (m Millis) String() string {
+	return fmt.Sprintf("%.1fms", float64(m))
+}
+
+// OperationSummary is the result of one operation over the run
+type OperationSummary struct {
+	Operation    string  `json:"operation"`
+	TargetRate   float64 `json:"targetRate"`
+	Throughput   float64 `json:"throughput"` // successful calls per second
+	Succeeded    int     `json:"succeeded"`
+	Conflicts    int     `json:"conflicts"`
+	Failed       int     `json:"failed"`
+	Dropped      int     `json:"dropped"`
+	ConflictRate float64 `json:"conflictRate"` // conflicts over calls issued
+	Latency      Latency `json:"latency"`
+	LastError    string  `json:"lastError,omitempty"`
+}
+
+// Summary is the final report, also written with -json for comparing runs
+type Summary struct {
+	Chaincode  string             `json:"chaincode"`
+	Actors     int                `json:"actors"`
+	Workers    int                `json:"workers"`
+	Elapsed    float64            `json:"elapsedSeconds"`
+	Operations []OperationSummary `json:"operations"`
+}
+
+func newSummary(cfg *config, elapsed time.Duration, operations []*operation) *Summary {
+	summary := &Summary{
+		Chaincode: cfg.chaincode,
+		Actors:    cfg.actors,
+		Workers:   cfg.workers,
+		Elapsed:   elapsed.Seconds(),
+	}
+
+	for _, op := range operations {
+		if op.rate <= 0 {
+			continue
+		}
+		s := op.stats
+		s.mu.Lock()
+		result := OperationSummary{
+			Operation:  op.name,
+			TargetRate: op.rate,
+			Throughput: float64(s.succeeded) / elapsed.Seconds(),
+			Succeeded:  s.succeeded,
+			Conflicts:  s.conflicts,
+			Failed:     s.failed,
+			Dropped:    s.dropped,
+			Latency:    percentiles(s.latencies),
+			LastError:  s.lastError,
+		}
+		if issued := s.succeeded + s.conflicts + s.failed; issued > 0 {
+			result.ConflictRate = float64(s.conflicts) / float64(issued)
+		}
+		s.mu.Unlock()
+		summary.Operations = append(summary.Operations, result)
+	}
+
+	return summary
+}
+
+// print writes the summary as a table
+func (s *Summary) print(w io.Writer) {
+	fmt.Fprintf(w, "\n%d actors, %d workers, %.1fs\n\n", s.Actors, s.Workers, s.Elapsed)
+	fmt.Fprintf(w, "%-14s %8s %8s %8s %9s %7s %7s %9s %9s %9s %9s\n",
+		"operation", "target/s", "ok/s", "ok", "conflicts", "failed", "dropped", "p50", "p90", "p99", "max")
+	for _, op := range s.Operations {
+		fmt.Fprintf(w, "%-14s %8.1f %8.1f %8d %8.1f%% %7d %7d %9s %9s %9s %9s\n",
+			op.Operation, op.TargetRate, op.Throughput, op.Succeeded, op.ConflictRate*100, op.Failed, op.Dropped,
+			op.Latency.P50, op.Latency.P90, op.Latency.P99, op.Latency.Max)
+	}
+	for _, op := range s.Operations {
+		if op.LastError != "" {
+			fmt.Fprintf(w, "\nlast %s error: %s\n", op.Operation, op.LastError)
+		}
+	}
+}
+
+// percentiles summarizes latencies, which it sorts in place
+func percentiles(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	at := func(p float64) Millis {
+		i := int(p * float64(len(latencies)-1))
+		return Millis(float64(latencies[i]) / float64(time.Millisecond))
+	}
+	return Latency{P50: at(0.50), P90: at(0.90), P99: at(0.99), Max: at(1)}
+}
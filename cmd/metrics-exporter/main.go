@@ -0,0 +1,131 @@
+// Command metrics-exporter serves the chaincode's operational counters
+// (GetMetrics) in the Prometheus text format. Each scrape evaluates
+// GetMetrics once, so the exporter keeps no state of its own.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/raddadalmaayn/am-reputation/pkg/client"
+)
+
+const scrapeTimeout = 10 * time.Second
+
+type config struct {
+	gateway    client.ConnectionConfig
+	chaincode  string
+	listenAddr string
+}
+
+// metric is one exported series, read from a day's counters
+type metric struct {
+	name  string
+	kind  string // counter, gauge
+	help  string
+	value func(*client.MetricsDay) float64
+}
+
+// totals are exported as all-time counters
+var totals = []metric{
+	{"reputation_ratings_total", "counter", "Ratings submitted.",
+		func(m *client.MetricsDay) float64 { return float64(m.Ratings) }},
+	{"reputation_disputes_opened_total", "counter", "Disputes opened.",
+		func(m *client.MetricsDay) float64 { return float64(m.DisputesOpened) }},
+	{"reputation_disputes_resolved_total", "counter", "Disputes resolved.",
+		func(m *client.MetricsDay) float64 { return float64(m.DisputesResolved) }},
+	{"reputation_disputes_overturned_total", "counter", "Disputes resolved as overturned.",
+		func(m *client.MetricsDay) float64 { return float64(m.DisputesOverturned) }},
+	{"reputation_stake_slashed_total", "counter", "Stake slashed from raters.",
+		func(m *client.MetricsDay) float64 { return m.SlashedAmount }},
+	{"reputation_settled_total", "counter", "Amount paid out through dispute settlement.",
+		func(m *client.MetricsDay) float64 { return m.SettledAmount }},
+}
+
+// today is exported as gauges over the current UTC day
+var today = []metric{
+	{"reputation_ratings_today", "gauge", "Ratings submitted so far in the current UTC day.",
+		func(m *client.MetricsDay) float64 { return float64(m.Ratings) }},
+	{"reputation_disputes_opened_today", "gauge", "Disputes opened so far in the current UTC day.",
+		func(m *client.MetricsDay) float64 { return float64(m.DisputesOpened) }},
+}
+
+func main() {
+	cfg := parseFlags()
+
+	conn, err := client.Connect(cfg.gateway)
+	if err != nil {
+		log.Fatalf("failed to connect to gateway: %v", err)
+	}
+	defer conn.Close()
+
+	rep := client.New(conn.Network.GetContract(cfg.chaincode))
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), scrapeTimeout)
+		defer cancel()
+
+		report, err := rep.GetMetrics(ctx, "", "")
+		if err != nil {
+			log.Printf("scrape failed: %v", err)
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			writeMetric(w, metric{name: "reputation_up", kind: "gauge", help: "Whether GetMetrics succeeded."}, 0)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetric(w, metric{name: "reputation_up", kind: "gauge", help: "Whether GetMetrics succeeded."}, 1)
+		for _, m := range totals {
+			writeMetric(w, m, m.value(&report.Totals))
+		}
+
+		current := &client.MetricsDay{}
+		day := time.Now().UTC().Format("2006-01-02")
+		if n := len(report.Days); n > 0 && report.Days[n-1].Day == day {
+			current = &report.Days[n-1]
+		}
+		for _, m := range today {
+			writeMetric(w, m, m.value(current))
+		}
+	})
+
+	log.Printf("exporting %s metrics on %s/metrics", cfg.chaincode, cfg.listenAddr)
+	server := &http.Server{Addr: cfg.listenAddr, ReadHeaderTimeout: 10 * time.Second}
+	log.Fatal(server.ListenAndServe())
+}
+
+func writeMetric(w io.Writer, m metric, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, value)
+}
+
+func parseFlags() *config {
+	cfg := &config{}
+	flag.StringVar(&cfg.gateway.PeerEndpoint, "peer", envOr("EXPORTER_PEER", "localhost:7051"), "gateway peer endpoint (EXPORTER_PEER)")
+	flag.StringVar(&cfg.gateway.PeerHostAlias, "peer-host-alias", envOr("EXPORTER_PEER_HOST_ALIAS", "peer0.org1.example.com"), "TLS server name of the peer (EXPORTER_PEER_HOST_ALIAS)")
+	flag.StringVar(&cfg.gateway.TLSCertPath, "tls-cert", envOr("EXPORTER_TLS_CERT", ""), "peer TLS CA certificate (EXPORTER_TLS_CERT)")
+	flag.StringVar(&cfg.gateway.MSPID, "msp-id", envOr("EXPORTER_MSP_ID", "Org1MSP"), "client MSP ID (EXPORTER_MSP_ID)")
+	flag.StringVar(&cfg.gateway.CertPath, "cert", envOr("EXPORTER_CERT", ""), "client certificate (EXPORTER_CERT)")
+	flag.StringVar(&cfg.gateway.KeyPath, "key", envOr("EXPORTER_KEY", ""), "client private key (EXPORTER_KEY)")
+	flag.StringVar(&cfg.gateway.Channel, "channel", envOr("EXPORTER_CHANNEL", "mychannel"), "channel name (EXPORTER_CHANNEL)")
+	flag.StringVar(&cfg.chaincode, "chaincode", envOr("EXPORTER_CHAINCODE", "repcc"), "chaincode name (EXPORTER_CHAINCODE)")
+	flag.StringVar(&cfg.listenAddr, "listen", envOr("EXPORTER_LISTEN", ":9464"), "HTTP listen address (EXPORTER_LISTEN)")
+	flag.Parse()
+
+	if cfg.gateway.TLSCertPath == "" || cfg.gateway.CertPath == "" || cfg.gateway.KeyPath == "" {
+		log.Fatal("-tls-cert, -cert and -key are required")
+	}
+	return cfg
+}
+
+func envOr(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+
+	"github.com/raddadalmaayn/am-reputation/pkg/client"
+)
+
+// Delivery headers. The signature is the hex HMAC-SHA256, keyed with the
+// webhook secret, of the timestamp header, a ".", and the body; receivers
+// should reject stale timestamps to prevent replays.
+const (
+	headerEvent     = "X-Reputation-Event"
+	headerDelivery  = "X-Reputation-Delivery"
+	headerTimestamp = "X-Reputation-Timestamp"
+	headerSignature = "X-Reputation-Signature"
+)
+
+// delivery is the body POSTed to a webhook
+type delivery struct {
+	BlockNumber uint64                `json:"blockNumber"`
+	Event       *client.EventEnvelope `json:"event"`
+}
+
+// Bridge forwards chaincode events to webhooks
+type Bridge struct {
+	webhooks     []*Webhook
+	checkpointer *fabric.FileCheckpointer
+	httpClient   *http.Client
+}
+
+func newBridge(webhooks []*Webhook, checkpointPath string) (*Bridge, error) {
+	checkpointer, err := fabric.NewFileCheckpointer(checkpointPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %v", err)
+	}
+
+	return &Bridge{
+		webhooks:     webhooks,
+		checkpointer: checkpointer,
+		httpClient:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Close releases the checkpoint file
+func (b *Bridge) Close() error {
+	return b.checkpointer.Close()
+}
+
+// Run follows chaincode events until ctx is cancelled, reconnecting from
+// the checkpoint when the stream breaks
+func (b *Bridge) Run(ctx context.Context, network *fabric.Network, chaincodeName string, startBlock uint64) error {
+	backoff := time.Second
+	for {
+		err := b.follow(ctx, network, chaincodeName, startBlock)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("event stream interrupted, retrying in %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (b *Bridge) follow(ctx context.Context, network *fabric.Network, chaincodeName string, startBlock uint64) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := network.ChaincodeEvents(streamCtx, chaincodeName,
+		fabric.WithStartBlock(startBlock), fabric.WithCheckpoint(b.checkpointer))
+	if err != nil {
+		return fmt.Errorf("failed to start event stream: %v", err)
+	}
+
+	for event := range events {
+		decoded, err := client.DecodeEvents(event)
+		if err != nil {
+			log.Printf("skipping undecodable event: %v", err)
+		}
+		for i := range decoded {
+			b.dispatch(ctx, &decoded[i], i)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := b.checkpointer.CheckpointChaincodeEvent(event); err != nil {
+			return fmt.Errorf("failed to checkpoint: %v", err)
+		}
+	}
+	return fmt.Errorf("event stream closed")
+}
+
+// dispatch delivers one event to every matching webhook in parallel
+func (b *Bridge) dispatch(ctx context.Context, event *client.Event, seq int) {
+	body, err := json.Marshal(delivery{BlockNumber: event.BlockNumber, Event: &event.EventEnvelope})
+	if err != nil {
+		log.Printf("failed to marshal %s event: %v", event.EventType, err)
+		return
+	}
+	deliveryID := event.TxID + "/" + strconv.Itoa(seq)
+
+	var wg sync.WaitGroup
+	for _, webhook := range b.webhooks {
+		if !webhook.Matches(event) {
+			continue
+		}
+		wg.Add(1)
+		go func(webhook *Webhook) {
+			defer wg.Done()
+			if err := b.deliver(ctx, webhook, event.EventType, deliveryID, body); err != nil {
+				log.Printf("giving up delivering %s to %s: %v", deliveryID, webhook.Name, err)
+			}
+		}(webhook)
+	}
+	wg.Wait()
+}
+
+// deliver POSTs body to a webhook, retrying network errors, 429 and 5xx
+// responses with exponential backoff
+func (b *Bridge) deliver(ctx context.Context, webhook *Webhook, eventType string, deliveryID string, body []byte) error {
+	backoff := initialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		retry, err = b.post(ctx, webhook, eventType, deliveryID, body)
+		if err == nil || !retry || attempt >= webhook.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying
+func (b *Bridge) post(ctx context.Context, webhook *Webhook, eventType string, deliveryID string, body []byte) (bool, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerEvent, eventType)
+	req.Header.Set(headerDelivery, deliveryID)
+	req.Header.Set(headerTimestamp, timestamp)
+	req.Header.Set(headerSignature, "sha256="+sign(webhook.Secret, timestamp, body))
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+}
+
+// sign computes the delivery signature
+func sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,84 @@
+// Command webhooks delivers reputation chaincode events to HTTP webhooks.
+//
+// Each webhook in the config file receives the events matching its tenant,
+// event type and actor filters as signed JSON POSTs. Deliveries are retried with
+// backoff; the bridge checkpoints a transaction's events once every
+// matching webhook has accepted them or given up, so after a restart it
+// resumes where it stopped.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/raddadalmaayn/am-reputation/pkg/client"
+)
+
+type config struct {
+	gateway        client.ConnectionConfig
+	chaincode      string
+	webhooksPath   string
+	checkpointPath string
+	startBlock     uint64
+}
+
+func main() {
+	cfg := parseFlags()
+
+	webhooks, err := loadWebhooks(cfg.webhooksPath)
+	if err != nil {
+		log.Fatalf("failed to load webhooks: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := client.Connect(cfg.gateway)
+	if err != nil {
+		log.Fatalf("failed to connect to gateway: %v", err)
+	}
+	defer conn.Close()
+
+	bridge, err := newBridge(webhooks, cfg.checkpointPath)
+	if err != nil {
+		log.Fatalf("failed to start bridge: %v", err)
+	}
+	defer bridge.Close()
+
+	log.Printf("delivering %s events on %s to %d webhooks", cfg.chaincode, cfg.gateway.Channel, len(webhooks))
+	if err := bridge.Run(ctx, conn.Network, cfg.chaincode, cfg.startBlock); err != nil && ctx.Err() == nil {
+		log.Printf("bridge stopped: %v", err)
+	}
+}
+
+func parseFlags() *config {
+	cfg := &config{}
+	flag.StringVar(&cfg.gateway.PeerEndpoint, "peer", envOr("WEBHOOKS_PEER", "localhost:7051"), "gateway peer endpoint (WEBHOOKS_PEER)")
+	flag.StringVar(&cfg.gateway.PeerHostAlias, "peer-host-alias", envOr("WEBHOOKS_PEER_HOST_ALIAS", "peer0.org1.example.com"), "TLS server name of the peer (WEBHOOKS_PEER_HOST_ALIAS)")
+	flag.StringVar(&cfg.gateway.TLSCertPath, "tls-cert", envOr("WEBHOOKS_TLS_CERT", ""), "peer TLS CA certificate (WEBHOOKS_TLS_CERT)")
+	flag.StringVar(&cfg.gateway.MSPID, "msp-id", envOr("WEBHOOKS_MSP_ID", "Org1MSP"), "client MSP ID (WEBHOOKS_MSP_ID)")
+	flag.StringVar(&cfg.gateway.CertPath, "cert", envOr("WEBHOOKS_CERT", ""), "client certificate (WEBHOOKS_CERT)")
+	flag.StringVar(&cfg.gateway.KeyPath, "key", envOr("WEBHOOKS_KEY", ""), "client private key (WEBHOOKS_KEY)")
+	flag.StringVar(&cfg.gateway.Channel, "channel", envOr("WEBHOOKS_CHANNEL", "mychannel"), "channel name (WEBHOOKS_CHANNEL)")
+	flag.StringVar(&cfg.chaincode, "chaincode", envOr("WEBHOOKS_CHAINCODE", "repcc"), "chaincode name (WEBHOOKS_CHAINCODE)")
+	flag.StringVar(&cfg.webhooksPath, "config", envOr("WEBHOOKS_CONFIG", "webhooks.json"), "webhook definitions (WEBHOOKS_CONFIG)")
+	flag.StringVar(&cfg.checkpointPath, "checkpoint", envOr("WEBHOOKS_CHECKPOINT", "webhooks.checkpoint"), "checkpoint file (WEBHOOKS_CHECKPOINT)")
+	flag.Uint64Var(&cfg.startBlock, "start-block", 0, "block to start from when there is no checkpoint")
+	flag.Parse()
+
+	if cfg.gateway.TLSCertPath == "" || cfg.gateway.CertPath == "" || cfg.gateway.KeyPath == "" {
+		log.Fatal("-tls-cert, -cert and -key are required")
+	}
+	return cfg
+}
+
+func envOr(name string, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/raddadalmaayn/am-reputation/pkg/client"
+)
+
+// Webhook is one delivery target. Empty EventTypes or Actors match every
+// event. Tenants lists the tenants whose events it receives, "" standing for
+// the default space; left empty, it receives the default space's only, so a
+// webhook never sees another tenant's events, or its actors' namesakes there,
+// unless it names that tenant.
+type Webhook struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret,omitempty"`    // HMAC key
+	SecretEnv   string   `json:"secretEnv,omitempty"` // or the variable holding it
+	EventTypes  []string `json:"eventTypes,omitempty"`
+	Actors      []string `json:"actors,omitempty"`
+	Tenants     []string `json:"tenants,omitempty"`
+	MaxAttempts int      `json:"maxAttempts,omitempty"`
+
+	eventTypes map[string]bool
+	actors     map[string]bool
+	tenants    map[string]bool
+}
+
+const (
+	defaultMaxAttempts = 5
+	initialBackoff     = time.Second
+	maxBackoff         = time.Minute
+)
+
+// actorFields are the payload fields naming actors an event concerns
+var actorFields = []string{
+	"actorId", "raterId", "initiatorId", "voucherId",
+	"aliasId", "canonicalId", "oldId", "newId", "legacyId",
+}
+
+// loadWebhooks reads and validates the webhook definitions file:
+//
+//	{"webhooks": [{"name": "marketplace", "url": "https://...",
+//	  "secretEnv": "MARKETPLACE_SECRET", "eventTypes": ["RatingSubmitted"]}]}
+func loadWebhooks(path string) ([]*Webhook, error) {
+	configJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var file struct {
+		Webhooks []*Webhook `json:"webhooks"`
+	}
+	if err := json.Unmarshal(configJSON, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if len(file.Webhooks) == 0 {
+		return nil, fmt.Errorf("no webhooks defined in %s", path)
+	}
+
+	for _, webhook := range file.Webhooks {
+		if _, err := url.ParseRequestURI(webhook.URL); err != nil {
+			return nil, fmt.Errorf("webhook %s: invalid url: %v", webhook.Name, err)
+		}
+		if webhook.SecretEnv != "" {
+			webhook.Secret = os.Getenv(webhook.SecretEnv)
+		}
+		if webhook.Secret == "" {
+			return nil, fmt.Errorf("webhook %s: secret is required", webhook.Name)
+		}
+		if webhook.MaxAttempts <= 0 {
+			webhook.MaxAttempts = defaultMaxAttempts
+		}
+		webhook.eventTypes = toSet(webhook.EventTypes)
+		webhook.actors = toSet(webhook.Actors)
+		webhook.tenants = toSet(webhook.Tenants)
+		if len(webhook.tenants) == 0 {
+			webhook.tenants[""] = true
+		}
+	}
+
+	return file.Webhooks, nil
+}
+
+// Matches reports whether the webhook wants an event
+func (w *Webhook) Matches(event *client.Event) bool {
+	if !w.tenants[event.Tenant] {
+		return false
+	}
+	if len(w.eventTypes) > 0 && !w.eventTypes[event.EventType] {
+		return false
+	}
+	if len(w.actors) == 0 {
+		return true
+	}
+
+	var payload map[string]interface{}
+	if err := event.DecodePayload(&payload); err != nil {
+		return false
+	}
+	for _, field := range actorFields {
+		if actorID, ok := payload[field].(string); ok && w.actors[actorID] {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
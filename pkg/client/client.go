@@ -0,0 +1,397 @@
+// Package client is a typed Go client for the reputation chaincode. It wraps
+// a Fabric Gateway contract, converting arguments to the chaincode's string
+// parameters and results to the structs in types.go, and retries calls that
+// failed without committing.
+//
+//	contract := gateway.GetNetwork("mychannel").GetContract("repcc")
+//	rep := client.New(contract)
+//	ratingID, err := rep.SubmitRating(ctx, "supplier1", "quality", 0.9, "", time.Now().Unix())
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Client calls the reputation chaincode through a Fabric Gateway contract
+type Client struct {
+	contract *fabric.Contract
+	retry    RetryPolicy
+	tenant   string // tenant selected for every call, "" for the caller's default
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithRetryPolicy replaces DefaultRetryPolicy. A policy with MaxAttempts 1
+// disables retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithTenant selects the tenant every call runs in, passed in the transient
+// field "tenant". The caller's certificate must list the tenant.
+func WithTenant(tenant string) Option {
+	return func(c *Client) {
+		c.tenant = tenant
+	}
+}
+
+// New returns a Client for the given contract
+func New(contract *fabric.Contract, options ...Option) *Client {
+	c := &Client{
+		contract: contract,
+		retry:    DefaultRetryPolicy,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// ForTenant returns a copy of the client whose calls run in the tenant
+func (c *Client) ForTenant(tenant string) *Client {
+	scoped := *c
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// ============================================================================
+// TRANSACTIONS
+// ============================================================================
+
+// SubmitRating rates an actor in a dimension and returns the rating ID
+func (c *Client) SubmitRating(
+	ctx context.Context,
+	actorID string,
+	dimension string,
+	value float64,
+	evidence string,
+	timestamp int64,
+) (string, error) {
+	result, err := c.Submit(ctx, "rating:SubmitRating", actorID, dimension, formatFloat(value), evidence, formatInt(timestamp))
+	return string(result), err
+}
+
+// SubmitRatingIdempotent submits a rating under an idempotency key, such as
+// an order reference. Resubmitting with the same key, including after a
+// timeout whose outcome is unknown, returns the original rating ID instead of
+// rating twice.
+func (c *Client) SubmitRatingIdempotent(
+	ctx context.Context,
+	idempotencyKey string,
+	actorID string,
+	dimension string,
+	value float64,
+	evidence string,
+	timestamp int64,
+) (string, error) {
+	result, err := c.Submit(ctx, "rating:SubmitRatingIdempotent", idempotencyKey, actorID, dimension, formatFloat(value), evidence, formatInt(timestamp))
+	return string(result), err
+}
+
+// SubmitRatingOnBehalf submits a rating for another rater through a
+// registered service account
+func (c *Client) SubmitRatingOnBehalf(
+	ctx context.Context,
+	onBehalfOf string,
+	actorID string,
+	dimension string,
+	value float64,
+	evidence string,
+	timestamp int64,
+) (string, error) {
+	result, err := c.Submit(ctx, "rating:SubmitRatingOnBehalf", onBehalfOf, actorID, dimension, formatFloat(value), evidence, formatInt(timestamp))
+	return string(result), err
+}
+
+// SubmitConfidentialRating rates an actor without recording the value in
+// world state. The value and salt travel in the transient map, so
+// endorsingOrgs should name only the rater's and the actor's organizations.
+func (c *Client) SubmitConfidentialRating(
+	ctx context.Context,
+	actorID string,
+	actorMSPID string,
+	dimension string,
+	value float64,
+	salt string,
+	evidence string,
+	timestamp int64,
+	endorsingOrgs []string,
+) (string, error) {
+	var result []byte
+	err := c.retry.do(ctx, func() error {
+		var err error
+		result, err = c.contract.SubmitWithContext(ctx, "rating:SubmitConfidentialRating",
+			fabric.WithArguments(actorID, actorMSPID, dimension, evidence, formatInt(timestamp)),
+			c.withTransient(map[string][]byte{
+				"value": []byte(formatFloat(value)),
+				"salt":  []byte(salt),
+			}),
+			fabric.WithEndorsingOrganizations(endorsingOrgs...))
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to submit SubmitConfidentialRating: %w", err)
+	}
+	return string(result), nil
+}
+
+// AddStake deposits stake for the calling identity
+func (c *Client) AddStake(ctx context.Context, amount float64) error {
+	_, err := c.Submit(ctx, "stake:AddStake", formatFloat(amount))
+	return err
+}
+
+// InitiateDispute challenges a rating and returns the dispute ID
+func (c *Client) InitiateDispute(ctx context.Context, ratingID string, reason string) (string, error) {
+	result, err := c.Submit(ctx, "dispute:InitiateDispute", ratingID, reason)
+	return string(result), err
+}
+
+// ResolveDispute records an arbitrator's verdict, "upheld" or "overturned"
+func (c *Client) ResolveDispute(ctx context.Context, disputeID string, verdict string, notes string) error {
+	_, err := c.Submit(ctx, "dispute:ResolveDispute", disputeID, verdict, notes)
+	return err
+}
+
+// ============================================================================
+// QUERIES
+// ============================================================================
+
+// GetReputation returns an actor's decayed reputation in a dimension
+func (c *Client) GetReputation(ctx context.Context, actorID string, dimension string) (*Reputation, error) {
+	var rep Reputation
+	if err := c.evaluateInto(ctx, &rep, "GetReputation", actorID, dimension); err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+// GetReputationAllDimensions returns an actor's scores keyed by dimension
+func (c *Client) GetReputationAllDimensions(ctx context.Context, actorID string) (map[string]DimensionScore, error) {
+	var scores map[string]DimensionScore
+	if err := c.evaluateInto(ctx, &scores, "GetReputationAllDimensions", actorID); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// GetStake returns an actor's stake
+func (c *Client) GetStake(ctx context.Context, actorID string) (*Stake, error) {
+	var stake Stake
+	if err := c.evaluateInto(ctx, &stake, "stake:GetStake", actorID); err != nil {
+		return nil, err
+	}
+	return &stake, nil
+}
+
+// GetRating returns a rating by ID
+func (c *Client) GetRating(ctx context.Context, ratingID string) (*Rating, error) {
+	var rating Rating
+	if err := c.evaluateInto(ctx, &rating, "rating:GetRating", ratingID); err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// GetConfidentialRatingValue returns the private value of a confidential
+// rating. The gateway evaluates on peers of its own organization, which
+// must be the rater's or the actor's.
+func (c *Client) GetConfidentialRatingValue(ctx context.Context, ratingID string) (*ConfidentialRatingValue, error) {
+	var value ConfidentialRatingValue
+	if err := c.evaluateInto(ctx, &value, "rating:GetConfidentialRatingValue", ratingID); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// GetDispute returns a dispute by ID
+func (c *Client) GetDispute(ctx context.Context, disputeID string) (*Dispute, error) {
+	var dispute Dispute
+	if err := c.evaluateInto(ctx, &dispute, "dispute:GetDispute", disputeID); err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// GetRatingHistory returns a page of an actor's ratings in a dimension. Pass
+// an empty bookmark for the first page and a pageSize of 0 for the
+// configured default.
+func (c *Client) GetRatingHistory(
+	ctx context.Context,
+	actorID string,
+	dimension string,
+	filter RatingFilter,
+	bookmark string,
+	pageSize int,
+) (*RatingPage, error) {
+	var page RatingPage
+	err := c.evaluateInto(ctx, &page, "rating:GetRatingHistory", actorID, dimension,
+		formatBound(filter.MinValue), formatBound(filter.MaxValue),
+		formatBound(filter.MinWeight), formatBound(filter.MaxWeight),
+		filter.Sort, bookmark, strconv.Itoa(pageSize))
+	if err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetDisputesByStatus returns a page of disputes with a status
+func (c *Client) GetDisputesByStatus(ctx context.Context, status string, bookmark string, pageSize int) (*DisputePage, error) {
+	var page DisputePage
+	if err := c.evaluateInto(ctx, &page, "dispute:GetDisputesByStatus", status, bookmark, strconv.Itoa(pageSize)); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetActorsByDimension returns a page of actors scoring at least minScore
+func (c *Client) GetActorsByDimension(
+	ctx context.Context,
+	dimension string,
+	minScore float64,
+	bookmark string,
+	pageSize int,
+) (*ActorScorePage, error) {
+	var page ActorScorePage
+	if err := c.evaluateInto(ctx, &page, "GetActorsByDimension", dimension, formatFloat(minScore), bookmark, strconv.Itoa(pageSize)); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetScore returns an actor's score through the cross-chaincode interface
+func (c *Client) GetScore(ctx context.Context, actorID string, dimension string) (*Score, error) {
+	var score Score
+	if err := c.evaluateInto(ctx, &score, "GetScore", actorID, dimension); err != nil {
+		return nil, err
+	}
+	return &score, nil
+}
+
+// CheckThreshold reports whether an actor meets a minimum score
+func (c *Client) CheckThreshold(ctx context.Context, actorID string, dimension string, minScore float64) (*Threshold, error) {
+	var threshold Threshold
+	if err := c.evaluateInto(ctx, &threshold, "CheckThreshold", actorID, dimension, formatFloat(minScore)); err != nil {
+		return nil, err
+	}
+	return &threshold, nil
+}
+
+// CheckReputationThreshold returns the endorsed yes/no statement of whether
+// an actor meets a minimum score over a minimum number of events
+func (c *Client) CheckReputationThreshold(ctx context.Context, actorID string, dimension string, minScore float64, minEvents int) (*ThresholdAttestation, error) {
+	var attestation ThresholdAttestation
+	if err := c.evaluateInto(ctx, &attestation, "CheckReputationThreshold", actorID, dimension, formatFloat(minScore), strconv.Itoa(minEvents)); err != nil {
+		return nil, err
+	}
+	return &attestation, nil
+}
+
+// GetWeight returns the weight a rater's ratings in a dimension carry
+func (c *Client) GetWeight(ctx context.Context, raterID string, dimension string) (*Weight, error) {
+	var weight Weight
+	if err := c.evaluateInto(ctx, &weight, "GetWeight", raterID, dimension); err != nil {
+		return nil, err
+	}
+	return &weight, nil
+}
+
+// GetEventsSince returns a page of journaled events from transactions at or
+// after sinceTs (unix seconds), ordered by transaction time
+func (c *Client) GetEventsSince(ctx context.Context, sinceTs int64, bookmark string, pageSize int) (*EventPage, error) {
+	var page EventPage
+	if err := c.evaluateInto(ctx, &page, "GetEventsSince", formatInt(sinceTs), bookmark, strconv.Itoa(pageSize)); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetMetrics returns daily operational counters between two UTC days
+// (YYYY-MM-DD, inclusive; "" for open bounds)
+func (c *Client) GetMetrics(ctx context.Context, fromDay string, toDay string) (*MetricsReport, error) {
+	var report MetricsReport
+	if err := c.evaluateInto(ctx, &report, "GetMetrics", fromDay, toDay); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ============================================================================
+// UNTYPED CALLS
+// ============================================================================
+
+// Submit submits any transaction with string arguments, retrying per the
+// client's policy, and returns the raw result
+func (c *Client) Submit(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var result []byte
+	err := c.retry.do(ctx, func() error {
+		var err error
+		result, err = c.contract.SubmitWithContext(ctx, name, fabric.WithArguments(args...), c.withTransient(nil))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// Evaluate evaluates any query with string arguments, retrying per the
+// client's policy, and returns the raw result
+func (c *Client) Evaluate(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var result []byte
+	err := c.retry.do(ctx, func() error {
+		var err error
+		result, err = c.contract.EvaluateWithContext(ctx, name, fabric.WithArguments(args...), c.withTransient(nil))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", name, err)
+	}
+	return result, nil
+}
+
+func (c *Client) evaluateInto(ctx context.Context, result interface{}, name string, args ...string) error {
+	resultJSON, err := c.Evaluate(ctx, name, args...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resultJSON, result); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result: %w", name, err)
+	}
+	return nil
+}
+
+// withTransient passes transient data together with the client's tenant
+func (c *Client) withTransient(transient map[string][]byte) fabric.ProposalOption {
+	if c.tenant != "" {
+		if transient == nil {
+			transient = map[string][]byte{}
+		}
+		transient["tenant"] = []byte(c.tenant)
+	}
+	return fabric.WithTransient(transient)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func formatInt(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+// formatBound renders an optional bound, "" meaning none
+func formatBound(bound *float64) string {
+	if bound == nil {
+		return ""
+	}
+	return formatFloat(*bound)
+}
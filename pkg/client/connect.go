@@ -0,0 +1,105 @@
+package client
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ConnectionConfig locates a gateway peer and the identity to use with it
+type ConnectionConfig struct {
+	PeerEndpoint  string // host:port
+	PeerHostAlias string // TLS server name of the peer
+	TLSCertPath   string // peer TLS CA certificate, PEM
+	MSPID         string
+	CertPath      string // client certificate, PEM
+	KeyPath       string // client private key, PEM
+	Channel       string
+}
+
+// Connection is a Fabric Gateway session bound to one channel
+type Connection struct {
+	grpcConn *grpc.ClientConn
+	Gateway  *fabric.Gateway
+	Network  *fabric.Network
+}
+
+// Connect opens a gateway session for services that talk to the chaincode
+// with a file-based identity
+func Connect(cfg ConnectionConfig) (*Connection, error) {
+	tlsCertPEM, err := os.ReadFile(cfg.TLSCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS certificate: %v", err)
+	}
+	tlsCert, err := identity.CertificateFromPEM(tlsCertPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TLS certificate: %v", err)
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(tlsCert)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, cfg.PeerHostAlias)
+
+	grpcConn, err := grpc.NewClient(cfg.PeerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection: %v", err)
+	}
+
+	id, sign, err := loadIdentity(cfg)
+	if err != nil {
+		grpcConn.Close()
+		return nil, err
+	}
+
+	gateway, err := fabric.Connect(id, fabric.WithSign(sign), fabric.WithClientConnection(grpcConn))
+	if err != nil {
+		grpcConn.Close()
+		return nil, fmt.Errorf("failed to connect gateway: %v", err)
+	}
+
+	return &Connection{
+		grpcConn: grpcConn,
+		Gateway:  gateway,
+		Network:  gateway.GetNetwork(cfg.Channel),
+	}, nil
+}
+
+// Close ends the gateway session and its gRPC connection
+func (conn *Connection) Close() {
+	conn.Gateway.Close()
+	conn.grpcConn.Close()
+}
+
+func loadIdentity(cfg ConnectionConfig) (*identity.X509Identity, identity.Sign, error) {
+	certPEM, err := os.ReadFile(cfg.CertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read certificate: %v", err)
+	}
+	cert, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	id, err := identity.NewX509Identity(cfg.MSPID, cert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create identity: %v", err)
+	}
+
+	keyPEM, err := os.ReadFile(cfg.KeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read private key: %v", err)
+	}
+	key, err := identity.PrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	sign, err := identity.NewPrivateKeySign(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create signer: %v", err)
+	}
+
+	return id, sign, nil
+}
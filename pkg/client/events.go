@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// EventEnvelope is the wrapper around every chaincode event payload; see
+// docs/events.md for the payload of each event type
+type EventEnvelope struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	EventType     string          `json:"eventType"`
+	TxID          string          `json:"txId"`
+	Timestamp     int64           `json:"timestamp"`
+	Payload       json.RawMessage `json:"payload"`
+	Preceding     []EventEnvelope `json:"preceding,omitempty"`
+
+	// InitiatorID is the canonical ID of the submitter. Keys are the state
+	// keys of the records the transaction acted on, such as the rating or
+	// dispute behind a ReputationUpdated or StakeSlashed event, followed by
+	// those the event changed.
+	InitiatorID string   `json:"initiatorId,omitempty"`
+	Keys        []string `json:"keys,omitempty"`
+
+	// Tenant is the tenant the transaction ran in, "" for the default space
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// DecodePayload unmarshals the payload into one of the payload types below,
+// or any struct matching the documented fields
+func (e *EventEnvelope) DecodePayload(payload interface{}) error {
+	if err := json.Unmarshal(e.Payload, payload); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", e.EventType, err)
+	}
+	return nil
+}
+
+// Event is one decoded chaincode event and the block it committed in
+type Event struct {
+	BlockNumber uint64
+	EventEnvelope
+}
+
+// DecodeEvents unwraps a chaincode event into the events its transaction
+// emitted, oldest first. Fabric delivers only a transaction's last event,
+// which carries the earlier ones in Preceding.
+func DecodeEvents(event *fabric.ChaincodeEvent) ([]Event, error) {
+	var envelope EventEnvelope
+	if err := json.Unmarshal(event.Payload, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode event %s in tx %s: %w", event.EventName, event.TransactionID, err)
+	}
+
+	events := make([]Event, 0, len(envelope.Preceding)+1)
+	for _, preceding := range envelope.Preceding {
+		preceding.Preceding = nil
+		events = append(events, Event{BlockNumber: event.BlockNumber, EventEnvelope: preceding})
+	}
+	envelope.Preceding = nil
+	events = append(events, Event{BlockNumber: event.BlockNumber, EventEnvelope: envelope})
+
+	return events, nil
+}
+
+// JournalEntry is one event read back from the chaincode's event journal.
+// Sequence is its position among its transaction's events.
+type JournalEntry struct {
+	Sequence int           `json:"sequence"`
+	Event    EventEnvelope `json:"event"`
+}
+
+// EventPage is one page of GetEventsSince
+type EventPage struct {
+	Entries      []JournalEntry `json:"entries"`
+	Bookmark     string         `json:"bookmark"`
+	FetchedCount int32          `json:"fetchedCount"`
+}
+
+// Events streams the chaincode's decoded events until ctx is cancelled.
+// Events that cannot be decoded are skipped. Options are passed to the
+// gateway, e.g. fabric.WithStartBlock or fabric.WithCheckpoint.
+func Events(
+	ctx context.Context,
+	network *fabric.Network,
+	chaincodeName string,
+	options ...fabric.ChaincodeEventsOption,
+) (<-chan Event, error) {
+	raw, err := network.ChaincodeEvents(ctx, chaincodeName, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start event stream: %w", err)
+	}
+
+	decoded := make(chan Event)
+	go func() {
+		defer close(decoded)
+		for event := range raw {
+			events, err := DecodeEvents(event)
+			if err != nil {
+				continue
+			}
+			for _, e := range events {
+				select {
+				case decoded <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return decoded, nil
+}
+
+// Payloads of the most used event types
+
+// RatingSubmittedEvent is the RatingSubmitted payload
+type RatingSubmittedEvent struct {
+	RatingID    string  `json:"ratingId"`
+	RaterID     string  `json:"raterId"`
+	ActorID     string  `json:"actorId"`
+	Dimension   string  `json:"dimension"`
+	Value       float64 `json:"value"` // 0 for confidential ratings
+	Weight      float64 `json:"weight"`
+	Timestamp   int64   `json:"timestamp"`
+	Source      string  `json:"source"`
+	SubmittedBy string  `json:"submittedBy,omitempty"`
+
+	Confidential bool   `json:"confidential,omitempty"`
+	ValueHash    string `json:"valueHash,omitempty"`
+
+	PendingUntil int64 `json:"pendingUntil,omitempty"` // set while the rating waits out the challenge window
+}
+
+// ReputationUpdatedEvent is the ReputationUpdated payload
+type ReputationUpdatedEvent struct {
+	ActorID     string  `json:"actorId"`
+	Dimension   string  `json:"dimension"`
+	NewScore    float64 `json:"newScore"`
+	TotalEvents int     `json:"totalEvents"`
+	RatingID    string  `json:"ratingId"`
+}
+
+// DisputeInitiatedEvent is the DisputeInitiated payload
+type DisputeInitiatedEvent struct {
+	DisputeID   string `json:"disputeId"`
+	RatingID    string `json:"ratingId"`
+	InitiatorID string `json:"initiatorId"`
+	Reason      string `json:"reason"`
+}
+
+// DisputeResolvedEvent is the DisputeResolved payload
+type DisputeResolvedEvent struct {
+	DisputeID       string `json:"disputeId"`
+	Verdict         string `json:"verdict"`
+	RaterWasCorrect bool   `json:"raterWasCorrect"`
+	Dimension       string `json:"dimension"`
+}
+
+// StakeMovementEvent is the StakeLocked, StakeUnlocked, StakeRefunded and
+// StakeReleased payload
+type StakeMovementEvent struct {
+	ActorID   string  `json:"actorId"`
+	Amount    float64 `json:"amount"`
+	Balance   float64 `json:"balance"`
+	Locked    float64 `json:"locked"`
+	DisputeID string  `json:"disputeId"`
+}
+
+// StakeSlashedEvent is the StakeSlashed payload
+type StakeSlashedEvent struct {
+	RaterID        string  `json:"raterId"`
+	SlashAmount    float64 `json:"slashAmount"`
+	NewBalance     float64 `json:"newBalance"`
+	InitiatorID    string  `json:"initiatorId"`
+	InitiatorShare float64 `json:"initiatorShare"`
+	TreasuryShare  float64 `json:"treasuryShare"`
+}
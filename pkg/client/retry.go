@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	fabric "github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy controls how failed calls are retried. Only failures after
+// which the transaction certainly did not commit are retried, so a retried
+// submit never applies twice.
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts, including the first
+	InitialBackoff time.Duration // wait before the second attempt
+	MaxBackoff     time.Duration // cap on the doubling wait
+}
+
+// DefaultRetryPolicy retries up to three times over roughly two seconds
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// do runs call until it succeeds, fails permanently, or the attempts or
+// ctx run out
+func (p RetryPolicy) do(ctx context.Context, call func() error) error {
+	backoff := p.InitialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = call()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// retryable reports whether err is transient and leaves no committed
+// transaction behind. Submit and commit status errors are not retried: the
+// orderer may have accepted the transaction.
+func retryable(err error) bool {
+	var commitErr *fabric.CommitError
+	if errors.As(err, &commitErr) {
+		return commitErr.Code == peer.TxValidationCode_MVCC_READ_CONFLICT ||
+			commitErr.Code == peer.TxValidationCode_PHANTOM_READ_CONFLICT
+	}
+
+	var submitErr *fabric.SubmitError
+	var commitStatusErr *fabric.CommitStatusError
+	if errors.As(err, &submitErr) || errors.As(err, &commitStatusErr) {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
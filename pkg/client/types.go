@@ -0,0 +1,194 @@
+package client
+
+// Records as the reputation chaincode returns them. Field names and JSON
+// tags follow chaincode/contract.go and chaincode/pagination.go; keep them
+// in step when the chaincode records change.
+
+// Rating is a single rating of an actor in a dimension
+type Rating struct {
+	RatingID    string  `json:"ratingId"`
+	RaterID     string  `json:"raterId"`
+	ActorID     string  `json:"actorId"`
+	Dimension   string  `json:"dimension"`
+	Value       float64 `json:"value"`
+	Weight      float64 `json:"weight"`
+	Evidence    string  `json:"evidence"`
+	Timestamp   int64   `json:"timestamp"`
+	TxID        string  `json:"txId"`
+	SubmittedBy string  `json:"submittedBy,omitempty"`
+	Source      string  `json:"source,omitempty"`
+
+	// Confidential ratings carry a salted hash instead of the value
+	Confidential bool   `json:"confidential,omitempty"`
+	ValueHash    string `json:"valueHash,omitempty"`
+
+	// End of the challenge window of a rating not yet applied
+	PendingUntil int64 `json:"pendingUntil,omitempty"`
+}
+
+// ConfidentialRatingValue is the private part of a confidential rating
+type ConfidentialRatingValue struct {
+	RatingID string  `json:"ratingId"`
+	Value    float64 `json:"value"`
+	Salt     string  `json:"salt"`
+}
+
+// Stake is an actor's free and locked stake
+type Stake struct {
+	ActorID   string  `json:"actorId"`
+	Balance   float64 `json:"balance"`
+	Locked    float64 `json:"locked"`
+	UpdatedAt int64   `json:"updatedAt"`
+}
+
+// Dispute is a challenge to a rating
+type Dispute struct {
+	DisputeID       string `json:"disputeId"`
+	RatingID        string `json:"ratingId"`
+	InitiatorID     string `json:"initiatorId"`
+	RaterID         string `json:"raterId"`
+	ActorID         string `json:"actorId"`
+	Dimension       string `json:"dimension"`
+	Reason          string `json:"reason"`
+	Status          string `json:"status"` // pending, upheld, overturned, withdrawn, expired
+	ArbitratorID    string `json:"arbitratorId"`
+	ArbitratorNotes string `json:"arbitratorNotes"`
+	CreatedAt       int64  `json:"createdAt"`
+	ResolvedAt      int64  `json:"resolvedAt"`
+	Deadline        int64  `json:"deadline,omitempty"`
+}
+
+// Reputation is an actor's decayed reputation in one dimension, as returned
+// by GetReputation
+type Reputation struct {
+	ActorID     string  `json:"actorId"`
+	Dimension   string  `json:"dimension"`
+	Score       float64 `json:"score"`
+	Alpha       float64 `json:"alpha"`
+	Beta        float64 `json:"beta"`
+	CILower     float64 `json:"ci_lower"`
+	CIUpper     float64 `json:"ci_upper"`
+	TotalEvents int     `json:"totalEvents"`
+	LastUpdated int64   `json:"lastUpdated"`
+	Suspended   bool    `json:"suspended"`
+	Verified    bool    `json:"verified"`
+	Archived    bool    `json:"archived"`
+}
+
+// DimensionScore is an actor's decayed score in one dimension, as returned
+// by GetReputationAllDimensions and GetReputationsBulk
+type DimensionScore struct {
+	Score       float64 `json:"score"`
+	CILower     float64 `json:"ci_lower"`
+	CIUpper     float64 `json:"ci_upper"`
+	TotalEvents int     `json:"totalEvents"`
+	LastUpdated int64   `json:"lastUpdated"`
+}
+
+// ActorScore is one result of GetActorsByDimension
+type ActorScore struct {
+	ActorID   string  `json:"actorId"`
+	Dimension string  `json:"dimension"`
+	Score     float64 `json:"score"`
+	Suspended bool    `json:"suspended"`
+	Verified  bool    `json:"verified"`
+	Archived  bool    `json:"archived"`
+}
+
+// RatingPage is one page of a rating query
+type RatingPage struct {
+	Ratings      []Rating `json:"ratings"`
+	Bookmark     string   `json:"bookmark"`
+	FetchedCount int32    `json:"fetchedCount"`
+	TotalCount   int      `json:"totalCount"`
+}
+
+// DisputePage is one page of a dispute query
+type DisputePage struct {
+	Disputes     []Dispute `json:"disputes"`
+	Bookmark     string    `json:"bookmark"`
+	FetchedCount int32     `json:"fetchedCount"`
+	TotalCount   int       `json:"totalCount"`
+}
+
+// ActorScorePage is one page of GetActorsByDimension
+type ActorScorePage struct {
+	Actors       []ActorScore `json:"actors"`
+	Bookmark     string       `json:"bookmark"`
+	FetchedCount int32        `json:"fetchedCount"`
+	TotalCount   int          `json:"totalCount"`
+}
+
+// RatingFilter bounds a GetRatingHistory query. Nil bounds are not applied;
+// an empty Sort means newest first.
+type RatingFilter struct {
+	MinValue  *float64
+	MaxValue  *float64
+	MinWeight *float64
+	MaxWeight *float64
+	Sort      string // field:direction over timestamp, value or weight
+}
+
+// Score is the GetScore response
+type Score struct {
+	V         int     `json:"v"`
+	ActorID   string  `json:"actorId"`
+	Dimension string  `json:"dimension"`
+	Score     float64 `json:"score"`
+	CILower   float64 `json:"ciLower"`
+	Events    int     `json:"events"`
+	AsOf      int64   `json:"asOf"`
+}
+
+// Threshold is the CheckThreshold response
+type Threshold struct {
+	V         int     `json:"v"`
+	ActorID   string  `json:"actorId"`
+	Dimension string  `json:"dimension"`
+	MinScore  float64 `json:"minScore"`
+	Score     float64 `json:"score"`
+	Suspended bool    `json:"suspended"`
+	Pass      bool    `json:"pass"`
+	AsOf      int64   `json:"asOf"`
+}
+
+// ThresholdAttestation is the CheckReputationThreshold statement. MinScore
+// is the six-decimal string the chaincode attested.
+type ThresholdAttestation struct {
+	ActorID       string `json:"actorId"`
+	AsOf          int64  `json:"asOf"`
+	ConfigVersion int    `json:"configVersion"`
+	Dimension     string `json:"dimension"`
+	MinEvents     int    `json:"minEvents"`
+	MinScore      string `json:"minScore"`
+	Pass          bool   `json:"pass"`
+	Type          string `json:"type"`
+}
+
+// Weight is the GetWeight response
+type Weight struct {
+	V         int     `json:"v"`
+	RaterID   string  `json:"raterId"`
+	Dimension string  `json:"dimension"`
+	Weight    float64 `json:"weight"`
+	AsOf      int64   `json:"asOf"`
+}
+
+// MetricsDay holds one UTC day's operational counters
+type MetricsDay struct {
+	Day                string  `json:"day"`
+	Ratings            int     `json:"ratings"`
+	DisputesOpened     int     `json:"disputesOpened"`
+	DisputesResolved   int     `json:"disputesResolved"`
+	DisputesOverturned int     `json:"disputesOverturned"`
+	SlashedAmount      float64 `json:"slashedAmount"`
+	SettledAmount      float64 `json:"settledAmount"`
+}
+
+// MetricsReport is the GetMetrics response
+type MetricsReport struct {
+	From   string       `json:"from"`
+	To     string       `json:"to"`
+	Days   []MetricsDay `json:"days"`
+	Totals MetricsDay   `json:"totals"`
+}